@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/hibp"
+	"io"
+	"os"
+	"strings"
+)
+
+// bloomFilter writes a bloom filter of the hashes in the file of the filename flag, in the format of the
+// downloadable Pwned Passwords files, to out. The file is read twice, first to count the hashes.
+func bloomFilter(out io.Writer, opts options) error {
+	if opts.filename == "-" {
+		return fmt.Errorf("bloom-filter requires a file, as it is read twice")
+	}
+
+	f, err := os.Open(opts.filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var count uint64
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) != "" {
+			count++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	filter, err := hibp.NewBloomFilter(count, opts.fpRate)
+	if err != nil {
+		return err
+	}
+	if err := filter.AddHashes(f); err != nil {
+		return err
+	}
+	_, err = filter.WriteTo(out)
+	return err
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/shamir"
+	"io"
+	"io/ioutil"
+)
+
+// combine reconstructs a split master key from the files holding the raw shares, as stored in the share key
+// of the secrets, and prints it base64 encoded
+func combine(out io.Writer, files []string) error {
+	if len(files) < 2 {
+		return fmt.Errorf("combine requires at least two share files")
+	}
+
+	shares := make([][]byte, len(files))
+	for i, file := range files {
+		var err error
+		if shares[i], err = ioutil.ReadFile(file); err != nil {
+			return err
+		}
+	}
+
+	key, err := shamir.Combine(shares)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(out, base64.StdEncoding.EncodeToString(key))
+	return err
+}
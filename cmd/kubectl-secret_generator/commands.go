@@ -0,0 +1,219 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/controller/secret"
+	"io"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// managedSecrets returns the secrets of the namespace which are managed by the secret generator, sorted by
+// namespace and name. If names are given, only these secrets are returned.
+func managedSecrets(opts options, names []string) ([]corev1.Secret, error) {
+	list, err := opts.clientset.CoreV1().Secrets(opts.namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	wanted := map[string]bool{}
+	for _, name := range names {
+		wanted[name] = true
+	}
+
+	found := map[string]bool{}
+	var secrets []corev1.Secret
+	for _, s := range list.Items {
+		if !secret.IsManaged(s.Annotations) || (len(wanted) > 0 && !wanted[s.Name]) {
+			continue
+		}
+		found[s.Name] = true
+		secrets = append(secrets, s)
+	}
+
+	var missing []string
+	for name := range wanted {
+		if !found[name] {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, fmt.Errorf("secrets not found or not managed by the secret generator: %s", strings.Join(missing, ", "))
+	}
+
+	sort.Slice(secrets, func(i, j int) bool {
+		if secrets[i].Namespace != secrets[j].Namespace {
+			return secrets[i].Namespace < secrets[j].Namespace
+		}
+		return secrets[i].Name < secrets[j].Name
+	})
+	return secrets, nil
+}
+
+// newTable returns a writer aligning columns, and a func writing a row which includes the namespace
+// only when listing the secrets of all namespaces
+func newTable(out io.Writer, opts options, header ...string) (*tabwriter.Writer, func(s *corev1.Secret, columns ...string)) {
+	w := tabwriter.NewWriter(out, 0, 8, 3, ' ', 0)
+	row := func(s *corev1.Secret, columns ...string) {
+		if opts.allNamespaces {
+			fmt.Fprintf(w, "%s\t", s.Namespace)
+		}
+		fmt.Fprintf(w, "%s\t%s\n", s.Name, strings.Join(columns, "\t"))
+	}
+
+	if opts.allNamespaces {
+		fmt.Fprint(w, "NAMESPACE\t")
+	}
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	return w, row
+}
+
+// list prints the managed secrets with the type and names of their generated fields
+func list(out io.Writer, opts options) error {
+	secrets, err := managedSecrets(opts, opts.args)
+	if err != nil {
+		return err
+	}
+
+	w, row := newTable(out, opts, "NAME", "TYPE", "FIELDS", "GENERATED")
+	for i := range secrets {
+		s := &secrets[i]
+		sType, fields := s.Annotations[secret.AnnotationSecretType], s.Annotations[secret.AnnotationSecretAutoGenerate]
+		if _, ok := s.Annotations[secret.AnnotationSecretSpec]; ok {
+			sType, fields = "spec", "-"
+		}
+		row(s, orNone(sType), orNone(fields), orNone(s.Annotations[secret.AnnotationSecretAutoGeneratedAt]))
+	}
+	return w.Flush()
+}
+
+// age prints the time since the values of the managed secrets were generated or last rotated,
+// and the time until their next scheduled rotation
+func age(out io.Writer, opts options) error {
+	secrets, err := managedSecrets(opts, opts.args)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	w, row := newTable(out, opts, "NAME", "AGE", "LAST ROTATION", "NEXT ROTATION")
+	for i := range secrets {
+		s := &secrets[i]
+		row(s, since(s.Annotations[secret.AnnotationSecretAutoGeneratedAt], now),
+			since(s.Annotations[secret.AnnotationSecretRotatedAt], now), nextRotation(s, now))
+	}
+	return w.Flush()
+}
+
+// regenerate annotates the given secrets, or all managed secrets of the namespace, to be regenerated
+func regenerate(out io.Writer, opts options) error {
+	if opts.allNamespaces {
+		return fmt.Errorf("regenerate does not support --all-namespaces")
+	}
+	if len(opts.args) == 0 && !opts.all {
+		return fmt.Errorf("either secret names or --all must be given")
+	}
+
+	secrets, err := managedSecrets(opts, opts.args)
+	if err != nil {
+		return err
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{secret.AnnotationSecretRegenerate: "true"},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, s := range secrets {
+		if _, err := opts.clientset.CoreV1().Secrets(s.Namespace).Patch(s.Name, types.MergePatchType, patch); err != nil {
+			return fmt.Errorf("could not annotate secret %s: %w", s.Name, err)
+		}
+		fmt.Fprintf(out, "secret/%s annotated for regeneration\n", s.Name)
+	}
+	return nil
+}
+
+// rollback annotates the secret to restore the given fields, or all fields, to their previous value kept in
+// its history secret
+func rollback(out io.Writer, opts options) error {
+	if opts.allNamespaces {
+		return fmt.Errorf("rollback does not support --all-namespaces")
+	}
+	if len(opts.args) == 0 {
+		return fmt.Errorf("the name of the secret must be given")
+	}
+
+	secrets, err := managedSecrets(opts, opts.args[:1])
+	if err != nil {
+		return err
+	}
+	s := secrets[0]
+	if _, ok := s.Annotations[secret.AnnotationSecretValueHistory]; !ok {
+		return fmt.Errorf("secret %s keeps no value history, set the %s annotation", s.Name, secret.AnnotationSecretValueHistory)
+	}
+
+	fields := "true"
+	if len(opts.args) > 1 {
+		fields = strings.Join(opts.args[1:], ",")
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{secret.AnnotationSecretRollback: fields},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err := opts.clientset.CoreV1().Secrets(s.Namespace).Patch(s.Name, types.MergePatchType, patch); err != nil {
+		return fmt.Errorf("could not annotate secret %s: %w", s.Name, err)
+	}
+	fmt.Fprintf(out, "secret/%s annotated for rollback\n", s.Name)
+	return nil
+}
+
+// since returns the human readable time since the RFC 3339 timestamp, or <none> if it is not set
+func since(timestamp string, now time.Time) string {
+	t, err := time.Parse(time.RFC3339, timestamp)
+	if err != nil {
+		return orNone("")
+	}
+	return duration.HumanDuration(now.Sub(t))
+}
+
+// nextRotation returns the human readable time until the next rotation of the secret
+func nextRotation(s *corev1.Secret, now time.Time) string {
+	if secret.IsPinned(s.Annotations) {
+		return "pinned"
+	}
+
+	next, scheduled, err := secret.NextRotation(s, now)
+	switch {
+	case err != nil:
+		return "invalid"
+	case !scheduled:
+		return orNone("")
+	case next == 0:
+		return "overdue"
+	}
+	return "in " + duration.HumanDuration(next)
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "<none>"
+	}
+	return s
+}
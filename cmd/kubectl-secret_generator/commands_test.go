@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/controller/secret"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestOptions(args ...string) options {
+	generatedAt := time.Now().Add(-48 * time.Hour).Format(time.RFC3339)
+	return options{
+		clientset: fake.NewSimpleClientset(
+			&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "db", Annotations: map[string]string{
+				secret.AnnotationSecretAutoGenerate:    "password",
+				secret.AnnotationSecretAutoGeneratedAt: generatedAt,
+				secret.AnnotationSecretRotateAfter:     "24h",
+				secret.AnnotationSecretValueHistory:    "1",
+			}}},
+			&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "api", Annotations: map[string]string{
+				secret.AnnotationSecretAutoGenerate:    "token",
+				secret.AnnotationSecretAutoGeneratedAt: generatedAt,
+			}}},
+			&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "unmanaged"}},
+		),
+		namespace: "default",
+		args:      args,
+	}
+}
+
+func TestListShowsManagedSecrets(t *testing.T) {
+	out := &bytes.Buffer{}
+	if err := list(out, newTestOptions()); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[1], "api") || !strings.HasPrefix(lines[2], "db") {
+		t.Errorf("expected the managed secrets sorted by name, got\n%s", out)
+	}
+}
+
+func TestManagedSecretsOnlyReturnsNamedSecrets(t *testing.T) {
+	secrets, err := managedSecrets(newTestOptions(), []string{"db"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(secrets) != 1 || secrets[0].Name != "db" {
+		t.Errorf("expected only db, got %d secrets", len(secrets))
+	}
+
+	if _, err := managedSecrets(newTestOptions(), []string{"db", "unmanaged"}); err == nil {
+		t.Error("expected an error for the unmanaged secret")
+	}
+}
+
+func TestAgeShowsOverdueRotations(t *testing.T) {
+	out := &bytes.Buffer{}
+	if err := age(out, newTestOptions("db")); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(out.String(), "2d") || !strings.Contains(out.String(), "overdue") {
+		t.Errorf("expected the age and overdue rotation of db, got\n%s", out)
+	}
+}
+
+func TestRegenerateAnnotatesSecrets(t *testing.T) {
+	opts := newTestOptions("db")
+	if err := regenerate(&bytes.Buffer{}, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := opts.clientset.CoreV1().Secrets("default").Get("db", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if db.Annotations[secret.AnnotationSecretRegenerate] != "true" {
+		t.Error("expected db to be annotated for regeneration")
+	}
+
+	if err := regenerate(&bytes.Buffer{}, newTestOptions("unmanaged")); err == nil {
+		t.Error("expected unmanaged secrets not to be regenerated")
+	}
+}
+
+func TestRollbackAnnotatesSecret(t *testing.T) {
+	opts := newTestOptions("db", "password")
+	if err := rollback(&bytes.Buffer{}, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := opts.clientset.CoreV1().Secrets("default").Get("db", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if db.Annotations[secret.AnnotationSecretRollback] != "password" {
+		t.Errorf("expected db to be annotated for rollback of password, got %q", db.Annotations[secret.AnnotationSecretRollback])
+	}
+
+	if err := rollback(&bytes.Buffer{}, newTestOptions("api")); err == nil {
+		t.Error("expected secrets without value history not to be rolled back")
+	}
+}
+
+func TestGeneratePrintsPopulatedSecrets(t *testing.T) {
+	in := strings.NewReader(`apiVersion: v1
+kind: Secret
+metadata:
+  name: database
+  annotations:
+    secret-generator.v1.mittwald.de/autogenerate: password
+stringData:
+  username: admin
+`)
+	out := &bytes.Buffer{}
+	if err := generate(in, out, options{namespace: "my-app"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{"kind: Secret", "namespace: my-app", "password: ", "username: YWRtaW4="} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("expected %q in the generated manifest, got\n%s", expected, out)
+		}
+	}
+}
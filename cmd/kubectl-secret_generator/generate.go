@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rsa"
+	"fmt"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/apis"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/controller/secret"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/sealing"
+	"io"
+	"io/ioutil"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/yaml"
+)
+
+// generate reads secret manifests from in and prints them with their values generated, without talking to a
+// cluster. Other objects of the manifests, e.g. password policies, are used while generating but not printed.
+// If a sealing certificate is given, SealedSecrets are printed instead of secrets.
+func generate(in io.Reader, out io.Writer, opts options) error {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		return err
+	}
+	if err := apis.AddToScheme(scheme); err != nil {
+		return err
+	}
+	decoder := serializer.NewCodecFactory(scheme).UniversalDeserializer()
+
+	var secrets []*corev1.Secret
+	var objects []runtime.Object
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(in))
+	for {
+		doc, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if len(bytes.TrimSpace(doc)) == 0 {
+			continue
+		}
+
+		obj, _, err := decoder.Decode(doc, nil, nil)
+		if err != nil {
+			return fmt.Errorf("could not decode manifest: %w", err)
+		}
+		if s, ok := obj.(*corev1.Secret); ok {
+			if s.Namespace == "" {
+				s.Namespace = opts.namespace
+			}
+			secrets = append(secrets, s)
+			continue
+		}
+		objects = append(objects, obj)
+	}
+
+	if err := secret.GenerateOffline(scheme, secrets, objects...); err != nil {
+		return err
+	}
+
+	var key *rsa.PublicKey
+	if opts.sealCert != "" {
+		var err error
+		if key, err = readSealingKey(opts.sealCert); err != nil {
+			return fmt.Errorf("could not read sealing certificate %s: %w", opts.sealCert, err)
+		}
+	}
+
+	for i, s := range secrets {
+		s.APIVersion, s.Kind = "v1", "Secret"
+
+		var manifest interface{} = s
+		if key != nil {
+			sealed, err := sealing.Seal(s, key)
+			if err != nil {
+				return fmt.Errorf("could not seal secret %s: %w", s.Name, err)
+			}
+			manifest = sealed
+		}
+
+		data, err := yaml.Marshal(manifest)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			fmt.Fprintln(out, "---")
+		}
+		if _, err := out.Write(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readSealingKey reads the public key of the sealed-secrets controller from the certificate file
+func readSealingKey(file string) (*rsa.PublicKey, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return sealing.PublicKey(data)
+}
@@ -0,0 +1,165 @@
+// kubectl-secret_generator is a kubectl plugin for inspecting secrets managed by the secret generator and
+// triggering their regeneration. Installed on the PATH, it is invoked as "kubectl secret-generator".
+package main
+
+import (
+	"fmt"
+	"github.com/spf13/pflag"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+	"os"
+
+	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
+	_ "k8s.io/client-go/plugin/pkg/client/auth"
+)
+
+const usage = `Inspect and regenerate secrets managed by the secret generator.
+
+Usage:
+  kubectl secret-generator list [-n NAMESPACE | -A]
+  kubectl secret-generator age [NAME...] [-n NAMESPACE | -A]
+  kubectl secret-generator regenerate (NAME... | --all) [-n NAMESPACE]
+  kubectl secret-generator rollback NAME [FIELD...] [-n NAMESPACE]
+  kubectl secret-generator generate -f FILE [--seal-cert CERT] [-n NAMESPACE]
+  kubectl secret-generator combine SHARE_FILE...
+  kubectl secret-generator bloom-filter -f HASH_FILE [--false-positive-rate RATE] > FILTER
+
+Commands:
+  list          List managed secrets and their generated fields
+  age           Show the time since the values of managed secrets were generated and until their next rotation
+  regenerate    Regenerate the values of the given secrets
+  rollback      Restore the given fields, or all fields, of a secret to their value before the last rotation
+  generate      Print the secrets of manifests with their values generated, without a cluster
+  combine       Print the base64 encoded master key reconstructed from shares of a split master key
+  bloom-filter  Write a bloom filter of downloaded Pwned Passwords SHA-1 hashes, for the hibp-source option
+
+Flags:
+`
+
+// options are the flags shared by all commands
+type options struct {
+	clientset     kubernetes.Interface
+	namespace     string
+	allNamespaces bool
+	all           bool
+	filename      string
+	sealCert      string
+	fpRate        float64
+	args          []string
+}
+
+func main() {
+	flags := pflag.NewFlagSet("kubectl-secret_generator", pflag.ContinueOnError)
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	overrides := &clientcmd.ConfigOverrides{}
+
+	var opts options
+	flags.StringVar(&loadingRules.ExplicitPath, "kubeconfig", "", "Path to the kubeconfig file")
+	flags.StringVar(&overrides.CurrentContext, "context", "", "Name of the kubeconfig context to use")
+	flags.StringVarP(&opts.namespace, "namespace", "n", "", "Namespace of the secrets, defaults to the namespace of the context")
+	flags.BoolVarP(&opts.allNamespaces, "all-namespaces", "A", false, "Show secrets of all namespaces")
+	flags.BoolVar(&opts.all, "all", false, "Regenerate all managed secrets of the namespace")
+	flags.StringVarP(&opts.filename, "filename", "f", "-", "Manifests of the secrets to generate, - reads from stdin")
+	flags.StringVar(&opts.sealCert, "seal-cert", "", "Certificate of the sealed-secrets controller, to print SealedSecrets instead of secrets")
+	flags.Float64Var(&opts.fpRate, "false-positive-rate", 0.001, "False positive rate of the bloom filter, lower rates result in larger filters")
+	flags.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(os.Args[1:]); err != nil {
+		os.Exit(2)
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	command := flags.Arg(0)
+	opts.args = flags.Args()[1:]
+
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, overrides)
+	if opts.namespace == "" {
+		namespace, _, err := clientConfig.Namespace()
+		switch {
+		case err == nil:
+			opts.namespace = namespace
+		case command == "generate" || command == "combine" || command == "bloom-filter":
+			// manifests can be generated without any kubeconfig
+			opts.namespace = metav1.NamespaceDefault
+		default:
+			fail(err)
+		}
+	}
+	if opts.allNamespaces {
+		opts.namespace = ""
+	}
+
+	// combine works without a cluster, shares are collected from their holders
+	if command == "combine" {
+		if err := combine(os.Stdout, opts.args); err != nil {
+			fail(err)
+		}
+		return
+	}
+
+	// bloom-filter works without a cluster, the filter is mounted into the controller
+	if command == "bloom-filter" {
+		if err := bloomFilter(os.Stdout, opts); err != nil {
+			fail(err)
+		}
+		return
+	}
+
+	// generate works without a cluster, e.g. for air-gapped installs
+	if command == "generate" {
+		if err := generateFile(opts); err != nil {
+			fail(err)
+		}
+		return
+	}
+
+	cfg, err := clientConfig.ClientConfig()
+	if err != nil {
+		fail(err)
+	}
+	if opts.clientset, err = kubernetes.NewForConfig(cfg); err != nil {
+		fail(err)
+	}
+
+	switch command {
+	case "list":
+		err = list(os.Stdout, opts)
+	case "age":
+		err = age(os.Stdout, opts)
+	case "regenerate":
+		err = regenerate(os.Stdout, opts)
+	case "rollback":
+		err = rollback(os.Stdout, opts)
+	default:
+		flags.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fail(err)
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "error:", err)
+	os.Exit(1)
+}
+
+// generateFile runs generate on the manifests of the filename flag
+func generateFile(opts options) error {
+	in := os.Stdin
+	if opts.filename != "-" {
+		f, err := os.Open(opts.filename)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	return generate(in, os.Stdout, opts)
+}
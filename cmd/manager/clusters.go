@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/apis"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/controller"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/controller/secret"
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"math"
+	"net/http"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+	"strconv"
+	"time"
+)
+
+var (
+	clusterUp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "secret_generator_cluster_up",
+		Help: "Whether the caches of the controllers of a remote cluster have synced",
+	}, []string{"cluster"})
+	clusterRestarts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "secret_generator_cluster_restarts_total",
+		Help: "Number of times the controllers of a remote cluster were restarted after failing",
+	}, []string{"cluster"})
+	clusterRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "secret_generator_cluster_api_requests_total",
+		Help: "Number of API requests made to a remote cluster, by response code",
+	}, []string{"cluster", "code"})
+)
+
+func init() {
+	crmetrics.Registry.MustRegister(clusterUp, clusterRestarts, clusterRequests)
+}
+
+// addClusters runs the controllers against the clusters of the given contexts of the kubeconfig file as well,
+// using the default kubeconfig loading rules if file is empty. The clusters are only reconciled while mgr is
+// the leader, and are separate failure domains: a cluster that can't be reached is retried with backoff
+// without affecting the others.
+func addClusters(mgr manager.Manager, file string, contexts []string, options manager.Options) error {
+	// The leader election, metrics and health probes of mgr cover all clusters
+	options.LeaderElection = false
+	options.MetricsBindAddress = "0"
+	options.HealthProbeBindAddress = ""
+
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if file != "" {
+		loadingRules = &clientcmd.ClientConfigLoadingRules{ExplicitPath: file}
+	}
+
+	for _, name := range contexts {
+		cfg, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules,
+			&clientcmd.ConfigOverrides{CurrentContext: name}).ClientConfig()
+		if err != nil {
+			return fmt.Errorf("could not load kubeconfig context %s: %w", name, err)
+		}
+		cfg.Wrap(countRequests(name))
+
+		if err := mgr.Add(&cluster{name: name, cfg: cfg, options: options}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cluster runs the controllers against a remote cluster
+type cluster struct {
+	name    string
+	cfg     *rest.Config
+	options manager.Options
+}
+
+// Start runs the controllers until stop is closed. Managers can't be restarted, so a new one is created
+// whenever the previous one failed, e.g. because the cluster could not be reached.
+func (c *cluster) Start(stop <-chan struct{}) error {
+	backoff := c.backoff()
+	for {
+		synced, err := c.run(stop)
+		select {
+		case <-stop:
+			return nil
+		default:
+		}
+
+		clusterUp.WithLabelValues(c.name).Set(0)
+		clusterRestarts.WithLabelValues(c.name).Inc()
+		if synced {
+			backoff = c.backoff()
+		}
+		delay := backoff.Step()
+		log.Error(err, "controllers of cluster failed", "cluster", c.name, "restartAfter", delay)
+
+		select {
+		case <-stop:
+			return nil
+		case <-time.After(delay):
+		}
+	}
+}
+
+func (c *cluster) backoff() wait.Backoff {
+	return wait.Backoff{Duration: 5 * time.Second, Factor: 2, Jitter: 0.5, Steps: math.MaxInt32, Cap: 5 * time.Minute}
+}
+
+// run creates a manager for the cluster and runs the controllers until stop is closed or the manager fails.
+// It returns whether the caches of the manager had synced.
+func (c *cluster) run(stop <-chan struct{}) (bool, error) {
+	mgr, err := manager.New(c.cfg, c.options)
+	if err != nil {
+		return false, err
+	}
+	if err := apis.AddToScheme(mgr.GetScheme()); err != nil {
+		return false, err
+	}
+	if err := controller.AddToManager(mgr); err != nil {
+		return false, err
+	}
+
+	setCluster(c.name, mgr)
+	defer func() {
+		setCluster(c.name, nil)
+		secret.ForgetResync(mgr)
+	}()
+
+	done := make(chan struct{})
+	synced := make(chan bool, 1)
+	go func() {
+		ok := mgr.GetCache().WaitForCacheSync(done)
+		if ok {
+			clusterUp.WithLabelValues(c.name).Set(1)
+			log.Info("caches of cluster synced", "cluster", c.name)
+		}
+		synced <- ok
+	}()
+
+	err = mgr.Start(stop)
+	close(done)
+	return <-synced, err
+}
+
+// countRequests counts the API requests made to the named cluster by response code
+func countRequests(cluster string) func(http.RoundTripper) http.RoundTripper {
+	return func(rt http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			resp, err := rt.RoundTrip(req)
+			code := "error"
+			if err == nil {
+				code = strconv.Itoa(resp.StatusCode)
+			}
+			clusterRequests.WithLabelValues(cluster, code).Inc()
+			return resp, err
+		})
+	}
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"github.com/fsnotify/fsnotify"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/controller/secret"
+	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"strings"
+)
+
+// configSections maps the keys of the structured sections of the config file to the options they set
+var configSections = map[string]string{
+	"defaults.rotateAfter":    "default-rotate-after",
+	"defaults.string.length":  "secret-length",
+	"defaults.string.charset": "secret-charset",
+	"defaults.ssh.length":     "ssh-key-length",
+	"policy.minLength":        "policy-min-length",
+	"policy.maxLength":        "policy-max-length",
+	"rotation.cooldown":       "rotation-cooldown",
+	"rotation.maxPerDay":      "max-regenerations-per-day",
+	"rotation.historyLimit":   "rotation-history-limit",
+	"rotation.expiryWarning":  "expiry-warning",
+}
+
+// loadConfig sets up v with the options of the flags, environment variables and config file and validates them.
+// Options not set by flags or environment variables are read from the config file.
+func loadConfig(v *viper.Viper) error {
+	// Import flags into viper and bind them to env vars
+	// flags are converted to upper-case, - is replaced with _
+	// secret-length -> SECRET_LENGTH
+	if err := v.BindPFlags(pflag.CommandLine); err != nil {
+		return fmt.Errorf("failed parsing pflag CommandLine: %w", err)
+	}
+
+	replacer := strings.NewReplacer("-", "_")
+	v.SetEnvKeyReplacer(replacer)
+
+	v.AutomaticEnv()
+
+	if configFile := v.GetString("config"); configFile != "" {
+		v.SetConfigFile(configFile)
+		if err := v.ReadInConfig(); err != nil {
+			return fmt.Errorf("could not read config file %s: %w", configFile, err)
+		}
+		applyConfigSections(v)
+	}
+
+	if v.GetString("pause-namespace") == "" {
+		// not available when running outside of a cluster, which disables pausing
+		if operatorNamespace, err := k8sutil.GetOperatorNamespace(); err == nil {
+			v.Set("pause-namespace", operatorNamespace)
+		}
+	}
+
+	return validateConfig(v)
+}
+
+// applyConfigSections sets the options configured in the structured sections of the config file, e.g.
+// defaults.string.length, as defaults, so that flags, environment variables and options set by their flag
+// names in the config file take precedence
+func applyConfigSections(v *viper.Viper) {
+	for key, option := range configSections {
+		if v.IsSet(key) {
+			v.SetDefault(option, v.Get(key))
+		}
+	}
+}
+
+// validateConfig checks the options which would otherwise only fail while reconciling
+func validateConfig(v *viper.Viper) error {
+	for _, option := range []string{"secret-length", "ssh-key-length"} {
+		if v.GetInt(option) <= 0 {
+			return fmt.Errorf("parameter %s must be positive, got %d", option, v.GetInt(option))
+		}
+	}
+
+	for _, option := range []string{"generator-plugins", "grpc-generator-plugins", "wasm-generator-plugins"} {
+		if _, err := secret.ParseGeneratorPlugins(v.GetString(option)); err != nil {
+			return fmt.Errorf("invalid %s: %w", option, err)
+		}
+	}
+	return nil
+}
+
+// watchConfig reloads the configuration when the config file changes, e.g. a mounted ConfigMap. Each reload
+// is read into a new viper instance and only published once it is valid, the previous configuration is kept
+// otherwise.
+func watchConfig(configFile string) {
+	w := viper.New()
+	w.SetConfigFile(configFile)
+	w.OnConfigChange(func(e fsnotify.Event) {
+		v := viper.New()
+		if err := loadConfig(v); err != nil {
+			log.Error(err, "rejected reloaded configuration, keeping the previous one", "file", e.Name)
+			return
+		}
+		if err := secret.PublishConfig(v); err != nil {
+			log.Error(err, "rejected reloaded configuration, keeping the previous one", "file", e.Name)
+			return
+		}
+		log.Info("reloaded configuration", "file", e.Name)
+	})
+	w.WatchConfig()
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/controller/secret"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"net/http"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sync"
+	"time"
+)
+
+// addHealthChecks registers the checks served on /healthz and /readyz. The controller is live as long
+// as it serves requests, and ready once its caches have synced, the apiserver is reachable and the
+// self-tests of the entropy source and generators pass.
+func addHealthChecks(mgr manager.Manager, cfg *rest.Config, selfTestInterval time.Duration) error {
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return err
+	}
+
+	tester := newSelfTester(selfTestInterval)
+	if err := mgr.Add(tester); err != nil {
+		return err
+	}
+
+	if err := mgr.AddHealthzCheck("ping", healthz.Ping); err != nil {
+		return err
+	}
+	if err := mgr.AddReadyzCheck("informers", cacheSynced(mgr)); err != nil {
+		return err
+	}
+	if err := mgr.AddReadyzCheck("self-test", tester.check); err != nil {
+		return err
+	}
+	return mgr.AddReadyzCheck("apiserver", apiserverReachable(clientset))
+}
+
+// selfTester runs the self-tests of the entropy source and generators on startup and periodically
+type selfTester struct {
+	interval time.Duration
+
+	mu  sync.RWMutex
+	err error
+}
+
+// newSelfTester returns a selfTester that already ran the self-tests once
+func newSelfTester(interval time.Duration) *selfTester {
+	t := &selfTester{interval: interval}
+	t.run()
+	return t
+}
+
+func (t *selfTester) run() {
+	err := secret.SelfTest()
+	if err != nil {
+		log.Error(err, "self-test failed")
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.err = err
+}
+
+// NeedLeaderElection makes the manager run the self-tests on all replicas, as all of them report readiness
+func (t *selfTester) NeedLeaderElection() bool {
+	return false
+}
+
+// Start runs the self-tests in the configured interval until stop is closed
+func (t *selfTester) Start(stop <-chan struct{}) error {
+	if t.interval <= 0 {
+		return nil
+	}
+
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			t.run()
+		case <-stop:
+			return nil
+		}
+	}
+}
+
+// check fails while the last self-test failed
+func (t *selfTester) check(*http.Request) error {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.err
+}
+
+// cacheSynced fails until the informers of the manager's cache have synced
+func cacheSynced(mgr manager.Manager) healthz.Checker {
+	// a closed channel makes WaitForCacheSync report the current state instead of waiting
+	stop := make(chan struct{})
+	close(stop)
+
+	return func(*http.Request) error {
+		if !mgr.GetCache().WaitForCacheSync(stop) {
+			return fmt.Errorf("informers have not synced")
+		}
+		return nil
+	}
+}
+
+// apiserverReachable fails if the health endpoint of the apiserver can't be reached
+func apiserverReachable(clientset kubernetes.Interface) healthz.Checker {
+	return func(*http.Request) error {
+		return clientset.Discovery().RESTClient().Get().AbsPath("/healthz").Do().Error()
+	}
+}
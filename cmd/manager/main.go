@@ -11,13 +11,24 @@ import (
 	"runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 	"strings"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 	"k8s.io/client-go/rest"
 
 	"github.com/mittwald/kubernetes-secret-generator/pkg/apis"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/audit"
 	"github.com/mittwald/kubernetes-secret-generator/pkg/controller"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/controller/secret"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/entropy"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/fips"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/generatorapi"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/resilience"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/secretcache"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/shutdown"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/syncer"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/tracing"
 	"github.com/mittwald/kubernetes-secret-generator/version"
 
 	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
@@ -60,32 +71,112 @@ func main() {
 	// controller-runtime)
 	pflag.CommandLine.AddGoFlagSet(flag.CommandLine)
 
+	pflag.String("config", "", "Path to a YAML config file defining generator defaults and policy settings. Flags override values from the file. Changes to options used while reconciling, e.g. secret-length, are applied without a restart.")
 	pflag.Bool("regenerate-insecure", false, "Set this to automatically regenerate secrets that were generated with an non-cryptographically secure PRNG.")
 	pflag.Int("secret-length", 40, "Secret length")
 	pflag.Int("ssh-key-length", 2048, "Default length of SSH Keys")
+	pflag.String("secret-charset", "", "Default characters generated strings are made of (defaults to base64 characters)")
+	pflag.Duration("default-rotate-after", 0, "Interval in which generated values without rotate-after or rotate-schedule annotation are rotated (0 disables rotation)")
+	pflag.Int("workers", 1, "Number of secrets reconciled in parallel")
+	pflag.Float64("startup-sync-qps", 0, "Maximum number of secret updates per second during the initial sync after startup (0 disables the limit)")
+	pflag.Int("startup-sync-burst", 10, "Number of secret updates allowed in a burst during the initial sync after startup")
+	pflag.Duration("startup-sync-period", 5*time.Minute, "Duration after startup during which secret updates are limited by startup-sync-qps")
+	pflag.Bool("cache-unmanaged-secret-data", true, "Cache the data of secrets without secret generator annotations. Disable to reduce memory usage on clusters with many or large secrets.")
+	pflag.Bool("metadata-only-watch", false, "Watch only the metadata of secrets and read secrets with secret generator annotations from the API server. Neither caches nor transfers the data of other secrets.")
+	pflag.Duration("resync-period", 10*time.Hour, "Interval in which all watched resources are reconciled again. Scheduled rotations don't depend on it.")
+	pflag.String("namespaces", "", "Comma-separated list of namespaces to watch, overriding WATCH_NAMESPACE")
+	pflag.String("exclude-namespaces", "", "Comma-separated list of namespaces whose secrets are ignored, e.g. kube-system")
+	pflag.String("namespace-selector", "", "Label selector namespaces have to match for their secrets to be processed, e.g. team=platform")
+	pflag.String("label-selector", "", "Label selector passed to the list and watch of secrets, so only matching secrets are delivered to the controller")
+	pflag.String("ignore-secret-types", "kubernetes.io/service-account-token,helm.sh/release.v1", "Comma-separated list of secret types that are never processed. Set to an empty string to process secrets of all types.")
+	pflag.Bool("dry-run", false, "Log and emit events describing the values that would be generated or rotated, without updating any secrets")
+	pflag.String("pause-namespace", "", "Namespace whose paused annotation suspends all generation and rotation, defaults to the namespace the controller runs in")
+	pflag.Int("policy-min-length", 0, "Minimum length a PasswordPolicy may request (0 disables the check)")
+	pflag.Int("policy-max-length", 0, "Maximum length a PasswordPolicy may request (0 disables the check)")
+	pflag.Bool("remediate-policy-drift", false, "Regenerate values that no longer comply with the secret length or password policy, e.g. after it was tightened")
+	pflag.Bool("regenerate-tampered", false, "Regenerate generated values that have been modified outside of the secret generator")
+	pflag.Bool("server-side-apply", false, "Write generated values using server-side apply with the secret-generator field manager")
+	pflag.Bool("enable-mutating-webhook", false, "Serve a mutating admission webhook that generates values when annotated secrets are created")
+	pflag.Bool("enable-validating-webhook", false, "Serve a validating admission webhook that rejects secrets with malformed generator annotations")
+	pflag.Int("webhook-port", 9443, "Port the admission webhook server listens on")
+	pflag.String("webhook-cert-dir", "/tmp/k8s-webhook-server/serving-certs", "Directory containing the tls.crt and tls.key of the admission webhook server")
+	pflag.Duration("rotation-cooldown", 0, "Minimum interval between regenerations of the same secret (0 disables the cooldown)")
+	pflag.Int("max-regenerations-per-day", 0, "Maximum number of regenerations of the same secret per day (0 disables the limit)")
+	pflag.Int("rotation-history-limit", 0, "Number of rotations recorded in the rotation-history annotation of a secret (0 disables the history)")
+	pflag.Duration("expiry-warning", 24*time.Hour, "Time before the expiry of a secret's values a warning event is emitted")
+	pflag.String("pre-rotation-hook-url", "", "URL that is asked before secrets are rotated. A 4xx response postpones the rotation.")
+	pflag.String("post-rotation-hook-urls", "", "Comma-separated list of URLs notified about rotated secrets, e.g. Slack incoming webhooks")
+	pflag.Duration("hook-timeout", 10*time.Second, "Timeout for calls to rotation hooks")
+	pflag.Bool("leader-elect", false, "Use lease-based leader election instead of the leader-for-life lock, allowing multiple replicas to fail over quickly")
+	pflag.String("leader-election-namespace", "", "Namespace of the leader election lock, defaults to the namespace the controller runs in")
+	pflag.Duration("leader-election-lease-duration", 15*time.Second, "Duration non-leader replicas wait before trying to acquire an unrenewed lease")
+	pflag.Duration("leader-election-renew-deadline", 10*time.Second, "Duration the leader retries renewing its lease before giving up leadership")
+	pflag.Duration("leader-election-retry-period", 2*time.Second, "Interval between attempts to acquire or renew the lease")
+	pflag.String("health-probe-bind-address", ":8081", "Address the /healthz and /readyz probe endpoints are served on (disabled if empty)")
+	pflag.Duration("self-test-interval", 10*time.Minute, "Interval in which the self-tests of the entropy source and generators are run after startup (0 runs them on startup only)")
+	pflag.Duration("shutdown-grace-period", 25*time.Second, "Time reconciles in flight are given to finish after SIGTERM or SIGINT before their API calls are aborted")
+	pflag.Bool("once", false, "Reconcile all watched secrets a single time and exit, with a non-zero exit code if any secret could not be reconciled, e.g. to run as a Job or in CI pipelines")
+	pflag.Int("shards", 0, "Number of shards namespaces are distributed on between replicas, which all reconcile secrets of the shards they hold a lease for (disabled if 0, conflicts with leader-elect)")
+	pflag.Int("shards-per-replica", 1, "Maximum number of shards held by a single replica")
+	pflag.String("cluster-contexts", "", "Comma-separated list of kubeconfig contexts of remote clusters whose secrets are reconciled in addition to those of the local cluster")
+	pflag.String("cluster-kubeconfig", "", "Path to the kubeconfig file defining the cluster-contexts (defaults to KUBECONFIG or ~/.kube/config)")
+	pflag.Int("circuit-breaker-threshold", 5, "Number of consecutive API calls failing because the API server is overloaded after which all API calls are suspended (disabled if 0)")
+	pflag.Duration("circuit-breaker-cooldown", 30*time.Second, "Time API calls are suspended for once the circuit breaker opened")
+	pflag.String("audit-sink", "", "Sink an audit record of every generation and rotation is written to: stdout, file:///path, an http(s) URL or kafka://broker-1:9092,broker-2:9092/topic (disabled if empty)")
+	pflag.String("otlp-endpoint", "", "OTLP gRPC endpoint spans of reconciles and their API calls are exported to, e.g. otel-collector:4317 (disabled if empty)")
+	pflag.Bool("otlp-insecure", false, "Connect to the OTLP endpoint without TLS")
+	pflag.String("pprof-bind-address", "", "Address the pprof profiling endpoints are served on, e.g. localhost:6060 (disabled if empty)")
+	pflag.String("generation-api-bind-address", "", "Address the HTTP API generating values into secrets on request is served on, e.g. :8443 (disabled if empty)")
+	pflag.String("generation-api-cert-dir", "", "Directory containing the tls.crt and tls.key of the generation API, required unless generation-api-insecure is set")
+	pflag.Bool("generation-api-insecure", false, "Serve the generation API without TLS, exposing bearer tokens to the network")
+	pflag.String("generator-plugins", "", "Comma-separated list of type=path pairs registering executables that generate the values of fields with custom types, e.g. totp=/plugins/totp")
+	pflag.String("grpc-generator-plugins", "", "Comma-separated list of type=path pairs registering long-running gRPC plugins that generate the values of fields with custom types, e.g. hsm=/plugins/hsm")
+	pflag.String("wasm-generator-plugins", "", "Comma-separated list of type=path pairs registering WebAssembly (wasm32-wasi) modules that generate the values of fields with custom types in a sandbox, e.g. totp=/plugins/totp.wasm")
+	pflag.Int("wasm-plugin-max-memory", 64, "Memory in MiB a single run of a WebAssembly generator plugin may use")
+	pflag.Duration("generator-plugin-timeout", 10*time.Second, "Timeout for a single run of a generator plugin")
+	pflag.String("grpc-bind-address", "", "Address the gRPC generator API is served on, e.g. :9090 (disabled if empty)")
+	pflag.String("grpc-cert-dir", "/etc/kubernetes-secret-generator-grpc", "Directory containing the tls.crt and tls.key of the gRPC generator API and the ca.crt client certificates are verified with")
+	pflag.String("vault-address", "", "Address of the HashiCorp Vault secrets annotated with a vault-path are written to, e.g. https://vault:8200 (disabled if empty)")
+	pflag.String("vault-kv-mount", "secret", "Mount path of the KV version 2 secrets engine secrets are written to")
+	pflag.String("vault-path-prefix", "kubernetes", "Prefix of the Vault paths secrets are written to, followed by their namespace and vault-path annotation")
+	pflag.String("vault-token", "", "Vault token, the controller logs in using the Kubernetes auth method if empty")
+	pflag.String("vault-auth-mount", "kubernetes", "Mount path of the Vault Kubernetes auth method")
+	pflag.String("vault-role", "secret-generator", "Role of the Vault Kubernetes auth method the controller logs in with")
+	pflag.Bool("aws-secrets-manager", false, "Write the values of secrets annotated with an aws-secret into AWS Secrets Manager, authenticating using IAM roles for service accounts or the default credential chain")
+	pflag.String("aws-region", "", "AWS region of Secrets Manager, defaults to AWS_REGION")
+	pflag.String("aws-secret-name-prefix", "kubernetes", "Prefix of the names of AWS secrets, followed by the namespace and aws-secret annotation of synced secrets")
+	pflag.Bool("gcp-secret-manager", false, "Write the values of secrets annotated with a gcp-secret into Google Secret Manager, authenticating using workload identity or the application default credentials")
+	pflag.String("gcp-project", "", "GCP project of Secret Manager, defaults to the project of the credentials")
+	pflag.String("gcp-secret-id-prefix", "kubernetes", "Prefix of the IDs of GCP secrets, followed by the namespace and gcp-secret annotation of synced secrets")
+	pflag.String("azure-key-vault-url", "", "URL of an Azure Key Vault (https://<vault>.vault.azure.net) to write the values of secrets annotated with an azure-secret into, authenticating using a managed identity")
+	pflag.String("azure-client-id", "", "Client ID of the user-assigned managed identity used for Azure Key Vault, defaults to the system-assigned identity")
+	pflag.String("azure-secret-name-prefix", "kubernetes", "Prefix of the names of Azure Key Vault secrets, followed by the namespace and azure-secret annotation of synced secrets")
+	pflag.String("onepassword-connect-host", "", "URL of a 1Password Connect server to write the values of secrets annotated with a onepassword-item into")
+	pflag.String("onepassword-connect-token", "", "Access token of the 1Password Connect server")
+	pflag.String("onepassword-vault", "", "ID of the 1Password vault items are written to")
+	pflag.String("onepassword-title-prefix", "kubernetes", "Prefix of the titles of 1Password items, followed by the namespace and onepassword-item annotation of synced secrets")
+	pflag.String("bitwarden-serve-url", "", "URL of the API of \"bw serve\" to write the values of secrets annotated with a bitwarden-item into Bitwarden or Vaultwarden")
+	pflag.String("bitwarden-organization", "", "ID of the Bitwarden organization items are shared with")
+	pflag.String("bitwarden-collection", "", "ID of the collection of the Bitwarden organization items are added to")
+	pflag.String("bitwarden-name-prefix", "kubernetes", "Prefix of the names of Bitwarden items, followed by the namespace and bitwarden-item annotation of synced secrets")
+	pflag.String("sealed-secrets-namespace", "kube-system", "Namespace of the sealed-secrets controller, whose active sealing key is used for secrets with the sealed-output annotation")
+	pflag.String("sops-backup-destination", "", "Write SOPS encrypted copies of generated secrets to configmap, file:///<dir> or s3://<bucket>/<prefix>")
+	pflag.String("sops-age-recipients", "", "Comma-separated list of age public keys SOPS backups are encrypted for")
+	pflag.String("sops-pgp-keyring", "", "File containing the armored PGP public keys SOPS backups are encrypted for")
+	pflag.String("sops-kms-arns", "", "Comma-separated list of ARNs of AWS KMS keys SOPS backups are encrypted for")
+	pflag.String("deterministic-seed-secret", "", "<namespace>/<name> of the secret whose seed key values of secrets with the deterministic annotation are derived from")
+	pflag.Bool("fips", false, "Restrict generators to FIPS 140 approved algorithms, requires building with GOEXPERIMENT=boringcrypto")
+	pflag.String("entropy-source", "crypto", "Source of randomness for generated values and keys: crypto, file:///<device>, egd:///<socket>, egd://<host>:<port> or pkcs11:///<module>?slot=<slot>&pin-file=<file>")
+	pflag.String("hibp-source", "", "Pwned Passwords API URL, e.g. https://api.pwnedpasswords.com, or file:///<path> of a bloom filter values of secrets with the pwned-check annotation are checked against")
+	pflag.String("forbidden-substrings", "", "Comma-separated list of substrings generated string values must not contain, ignoring case")
+	pflag.String("forbidden-substrings-file", "", "Path of a file listing substrings generated string values must not contain, one per line")
+	pflag.String("target-namespace-allowlist", "", "Comma-separated list of source:target namespace pairs StringSecrets and split master keys may create secrets in. A source of * matches all namespaces.")
 
 	pflag.Parse()
 
-	// Import flags into viper and bind them to env vars
-	// flags are converted to upper-case, - is replaced with _
-	// secret-length -> SECRET_LENGTH
-	err := viper.BindPFlags(pflag.CommandLine)
-	if err != nil {
-		log.Error(err, "failed parsing pflag CommandLine")
-		os.Exit(1)
-	}
-
-	replacer := strings.NewReplacer("-", "_")
-	viper.SetEnvKeyReplacer(replacer)
-
-	viper.AutomaticEnv()
-
-	if viper.GetInt("secret-length") == 0 {
-		panic(fmt.Errorf("parameter secret-length is set to 0"))
-	}
-
-	if viper.GetInt("ssh-key-length") == 0 {
-		panic(fmt.Errorf("parameter ssh-key-length is set to 0"))
+	// Options are read from flags, environment variables and the config file, which is watched below
+	if err := loadConfig(viper.GetViper()); err != nil {
+		panic(err)
 	}
 
 	// Use a zap logr.Logger implementation. If none of the zap
@@ -100,10 +191,15 @@ func main() {
 
 	printVersion()
 
-	namespace, err := k8sutil.GetWatchNamespace()
-	if err != nil {
-		log.Error(err, "Failed to get watch namespace")
-		os.Exit(1)
+	// The namespaces flag takes precedence over the WATCH_NAMESPACE set by the deployment
+	namespace := viper.GetString("namespaces")
+	if namespace == "" {
+		watchNamespace, err := k8sutil.GetWatchNamespace()
+		if err != nil {
+			log.Error(err, "Failed to get watch namespace")
+			os.Exit(1)
+		}
+		namespace = watchNamespace
 	}
 
 	// Get a config to talk to the apiserver
@@ -113,14 +209,22 @@ func main() {
 		os.Exit(1)
 	}
 
-	ctx := context.TODO()
-	// Become the leader before proceeding
-	err = leader.Become(ctx, "kubernetes-secret-generator-lock")
-	if err != nil {
-		log.Error(err, "")
+	if viper.GetInt("shards") > 0 && viper.GetBool("leader-elect") {
+		log.Error(fmt.Errorf("shards and leader-elect are mutually exclusive"), "")
 		os.Exit(1)
 	}
 
+	ctx := context.TODO()
+	// With sharding, all replicas reconcile secrets, and a single run must not wait for the running controller's lock
+	if !viper.GetBool("leader-elect") && viper.GetInt("shards") == 0 && !viper.GetBool("once") {
+		// Become the leader before proceeding
+		err = leader.Become(ctx, "kubernetes-secret-generator-lock")
+		if err != nil {
+			log.Error(err, "")
+			os.Exit(1)
+		}
+	}
+
 	restMapper := func(cfg *rest.Config) (meta.RESTMapper, error) {
 		return apiutil.NewDynamicRESTMapper(cfg)
 	}
@@ -130,6 +234,32 @@ func main() {
 		MapperProvider:     restMapper,
 		Namespace:          namespace,
 		MetricsBindAddress: fmt.Sprintf("%s:%d", metricsHost, metricsPort),
+		Port:               viper.GetInt("webhook-port"),
+		CertDir:            viper.GetString("webhook-cert-dir"),
+
+		HealthProbeBindAddress: viper.GetString("health-probe-bind-address"),
+	}
+
+	// Rotations and expiry are requeued for the time they are due, so the resync period only bounds how long
+	// changes missed by the watch, e.g. in external stores, go unnoticed
+	resyncPeriod := viper.GetDuration("resync-period")
+	if resyncPeriod > 0 {
+		options.SyncPeriod = &resyncPeriod
+	}
+
+	if viper.GetBool("leader-elect") {
+		// Replicas compete for a lease which expires if the leader stops renewing it,
+		// unlike the leader-for-life lock which is only released when the leader pod is deleted
+		leaseDuration := viper.GetDuration("leader-election-lease-duration")
+		renewDeadline := viper.GetDuration("leader-election-renew-deadline")
+		retryPeriod := viper.GetDuration("leader-election-retry-period")
+
+		options.LeaderElection = true
+		options.LeaderElectionID = "kubernetes-secret-generator-leader"
+		options.LeaderElectionNamespace = viper.GetString("leader-election-namespace")
+		options.LeaseDuration = &leaseDuration
+		options.RenewDeadline = &renewDeadline
+		options.RetryPeriod = &retryPeriod
 	}
 
 	// Add support for MultiNamespace set in WATCH_NAMESPACE (e.g ns1,ns2)
@@ -141,6 +271,21 @@ func main() {
 		options.NewCache = cache.MultiNamespacedCacheBuilder(strings.Split(namespace, ","))
 	}
 
+	secretOptions := secretcache.Options{
+		Namespaces:    strings.Split(namespace, ","),
+		LabelSelector: viper.GetString("label-selector"),
+	}
+	if viper.GetBool("metadata-only-watch") {
+		// Only the metadata of secrets is watched, secrets the controllers manage are read from the API server
+		options.NewCache = secretcache.NewMetadataCacheFunc(options.NewCache, secretOptions, secret.NeedsData)
+	} else if !viper.GetBool("cache-unmanaged-secret-data") {
+		// Secrets are watched separately, dropping the data of secrets the controllers don't manage before they are cached
+		options.NewCache = secretcache.NewCacheFunc(options.NewCache, secretOptions, secret.NeedsData)
+	} else {
+		// Secrets are always served by the secretcache informers, which export metrics about the health of their watch
+		options.NewCache = secretcache.NewCacheFunc(options.NewCache, secretOptions, secretcache.KeepAll)
+	}
+
 	// Create a new manager to provide shared dependencies and start components
 	mgr, err := manager.New(cfg, options)
 	if err != nil {
@@ -156,15 +301,195 @@ func main() {
 		os.Exit(1)
 	}
 
+	pluginHost, err := secret.SetupGRPCPlugins()
+	if err != nil {
+		log.Error(err, "could not set up gRPC generator plugins")
+		os.Exit(1)
+	}
+	if pluginHost != nil {
+		if err := mgr.Add(pluginHost); err != nil {
+			log.Error(err, "")
+			os.Exit(1)
+		}
+	}
+
+	if err := fips.Setup(viper.GetBool("fips")); err != nil {
+		log.Error(err, "could not enable FIPS mode")
+		os.Exit(1)
+	}
+	log.Info("FIPS mode", "enabled", fips.Enabled())
+
+	if err := entropy.Setup(viper.GetString("entropy-source")); err != nil {
+		log.Error(err, "could not set up entropy source")
+		os.Exit(1)
+	}
+
+	if err := secret.SetupHIBP(); err != nil {
+		log.Error(err, "could not set up Have I Been Pwned checks")
+		os.Exit(1)
+	}
+
+	// the global options are not modified after publishing them, reloaded configurations are published as
+	// new instances, so that changes to a mounted ConfigMap take effect without restarting the controller
+	if err := secret.PublishConfig(viper.GetViper()); err != nil {
+		log.Error(err, "could not read forbidden substrings")
+		os.Exit(1)
+	}
+	if configFile := viper.GetString("config"); configFile != "" {
+		watchConfig(configFile)
+	}
+
+	if err := secret.SetupSOPSBackup(); err != nil {
+		log.Error(err, "could not set up SOPS backups")
+		os.Exit(1)
+	}
+
+	wasmRuntime, err := secret.SetupWASMPlugins()
+	if err != nil {
+		log.Error(err, "could not set up WebAssembly generator plugins")
+		os.Exit(1)
+	}
+	if wasmRuntime != nil {
+		if err := mgr.Add(wasmRuntime); err != nil {
+			log.Error(err, "")
+			os.Exit(1)
+		}
+	}
+
+	resilience.Configure(viper.GetInt("circuit-breaker-threshold"), viper.GetDuration("circuit-breaker-cooldown"))
+
+	if spec := viper.GetString("audit-sink"); spec != "" {
+		sink, err := audit.NewSink(spec)
+		if err != nil {
+			log.Error(err, "could not set up audit sink")
+			os.Exit(1)
+		}
+		audit.SetSink(sink)
+	}
+
+	if addr := viper.GetString("vault-address"); addr != "" {
+		syncer.Register(syncer.NewVaultBackend(syncer.VaultOptions{
+			Address:    addr,
+			Mount:      viper.GetString("vault-kv-mount"),
+			PathPrefix: viper.GetString("vault-path-prefix"),
+			Token:      viper.GetString("vault-token"),
+			AuthMount:  viper.GetString("vault-auth-mount"),
+			Role:       viper.GetString("vault-role"),
+			TokenFile:  "/var/run/secrets/kubernetes.io/serviceaccount/token",
+		}))
+	}
+
+	if viper.GetBool("aws-secrets-manager") {
+		client, err := syncer.NewAWSClient(viper.GetString("aws-region"))
+		if err != nil {
+			log.Error(err, "could not set up AWS Secrets Manager client")
+			os.Exit(1)
+		}
+		syncer.Register(syncer.NewAWSBackend(client, viper.GetString("aws-secret-name-prefix")))
+	}
+
+	if viper.GetBool("gcp-secret-manager") {
+		backend, err := syncer.NewGCPBackend(ctx, viper.GetString("gcp-project"), viper.GetString("gcp-secret-id-prefix"))
+		if err != nil {
+			log.Error(err, "could not set up Google Secret Manager client")
+			os.Exit(1)
+		}
+		syncer.Register(backend)
+	}
+
+	if vaultURL := viper.GetString("azure-key-vault-url"); vaultURL != "" {
+		syncer.Register(syncer.NewAzureBackend(vaultURL, viper.GetString("azure-client-id"), viper.GetString("azure-secret-name-prefix")))
+	}
+
+	if host := viper.GetString("onepassword-connect-host"); host != "" {
+		syncer.Register(syncer.NewOnePasswordBackend(host, viper.GetString("onepassword-connect-token"),
+			viper.GetString("onepassword-vault"), viper.GetString("onepassword-title-prefix")))
+	}
+
+	if url := viper.GetString("bitwarden-serve-url"); url != "" {
+		syncer.Register(syncer.NewBitwardenBackend(url, viper.GetString("bitwarden-organization"),
+			viper.GetString("bitwarden-collection"), viper.GetString("bitwarden-name-prefix")))
+	}
+
+	if endpoint := viper.GetString("otlp-endpoint"); endpoint != "" {
+		shutdown, err := tracing.Setup(ctx, endpoint, viper.GetBool("otlp-insecure"))
+		if err != nil {
+			log.Error(err, "could not set up tracing")
+			os.Exit(1)
+		}
+		defer func() {
+			if err := shutdown(context.Background()); err != nil {
+				log.Error(err, "could not flush spans")
+			}
+		}()
+	}
+
+	// reconciling once audits, syncs and guards writes like the controller, so everything it uses is set up above
+	if viper.GetBool("once") {
+		reconciled, err := secret.RunOnce(mgr, strings.Split(namespace, ","))
+		if pluginHost != nil {
+			pluginHost.Kill()
+		}
+		if err != nil {
+			log.Error(err, "could not reconcile all secrets", "reconciled", reconciled)
+			os.Exit(1)
+		}
+		log.Info("reconciled all secrets", "reconciled", reconciled)
+		return
+	}
+
 	// Setup all Controllers
 	if err := controller.AddToManager(mgr); err != nil {
 		log.Error(err, "")
 		os.Exit(1)
 	}
 
+	if err := addHealthChecks(mgr, cfg, viper.GetDuration("self-test-interval")); err != nil {
+		log.Error(err, "")
+		os.Exit(1)
+	}
+
 	// Add the Metrics Service
 	addMetrics(ctx, cfg)
 
+	setCluster("", mgr)
+	resyncOnSIGHUP(strings.Split(namespace, ","))
+
+	if viper.GetInt("shards") > 0 {
+		if err := addSharding(mgr, cfg, strings.Split(namespace, ",")); err != nil {
+			log.Error(err, "")
+			os.Exit(1)
+		}
+	}
+
+	if contexts := viper.GetString("cluster-contexts"); contexts != "" {
+		// Remote clusters can't reach the webhook server of the controller
+		if viper.GetBool("enable-mutating-webhook") || viper.GetBool("enable-validating-webhook") {
+			log.Error(fmt.Errorf("webhooks are not supported with cluster-contexts"), "")
+			os.Exit(1)
+		}
+		if err := addClusters(mgr, viper.GetString("cluster-kubeconfig"), strings.Split(contexts, ","), options); err != nil {
+			log.Error(err, "")
+			os.Exit(1)
+		}
+	}
+
+	if addr := viper.GetString("pprof-bind-address"); addr != "" {
+		servePprof(addr)
+	}
+
+	if addr := viper.GetString("grpc-bind-address"); addr != "" {
+		server, err := generatorapi.NewServer(addr, viper.GetString("grpc-cert-dir"))
+		if err != nil {
+			log.Error(err, "could not set up gRPC generator API")
+			os.Exit(1)
+		}
+		if err := mgr.Add(server); err != nil {
+			log.Error(err, "")
+			os.Exit(1)
+		}
+	}
+
 	log.Info("Starting the Cmd.")
 
 	// Start the Cmd
@@ -172,6 +497,12 @@ func main() {
 		log.Error(err, "Manager exited non-zero")
 		os.Exit(1)
 	}
+
+	// The manager returns as soon as it is signaled, without waiting for the workers of the controllers
+	log.Info("Draining reconciles in flight")
+	if !shutdown.Drain(viper.GetDuration("shutdown-grace-period")) {
+		log.Info("Grace period expired, aborting reconciles in flight")
+	}
 }
 
 // addMetrics will create the Services and Service Monitors to allow the operator export the metrics by using
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"net/http"
+	"net/http/pprof"
+)
+
+// servePprof serves the pprof profiling endpoints on addr in the background. The handlers are registered
+// on a separate mux, so they are never exposed on the metrics or webhook ports.
+func servePprof(addr string) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	go func() {
+		log.Info("serving pprof endpoint", "address", addr)
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			log.Error(err, "pprof endpoint stopped")
+		}
+	}()
+}
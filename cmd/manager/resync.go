@@ -0,0 +1,56 @@
+package main
+
+import (
+	"github.com/mittwald/kubernetes-secret-generator/pkg/controller/secret"
+	"os"
+	"os/signal"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sync"
+	"syscall"
+)
+
+var (
+	clustersMu sync.Mutex
+	// clusters holds the running manager of each reconciled cluster by name, the local cluster has an empty name
+	clusters = map[string]manager.Manager{}
+)
+
+// setCluster records mgr as the running manager of the named cluster, or removes the cluster if mgr is nil
+func setCluster(name string, mgr manager.Manager) {
+	clustersMu.Lock()
+	defer clustersMu.Unlock()
+	if mgr == nil {
+		delete(clusters, name)
+		return
+	}
+	clusters[name] = mgr
+}
+
+// resyncOnSIGHUP queues all watched secrets of all clusters for reconciliation whenever the controller receives
+// SIGHUP. Secrets are listed from the apiserver, so secrets missing from the cache are found as well.
+func resyncOnSIGHUP(namespaces []string) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	go func() {
+		for range hup {
+			log.Info("received SIGHUP, resyncing all secrets")
+
+			clustersMu.Lock()
+			for name, mgr := range clusters {
+				// clusters whose caches have not synced yet must not hold up the others
+				go resync(name, mgr, namespaces)
+			}
+			clustersMu.Unlock()
+		}
+	}()
+}
+
+func resync(cluster string, mgr manager.Manager, namespaces []string) {
+	queued, err := secret.Resync(mgr, namespaces)
+	if err != nil {
+		log.Error(err, "could not resync secrets", "cluster", cluster, "queued", queued)
+		return
+	}
+	log.Info("queued secrets for reconciliation", "cluster", cluster, "count", queued)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/controller/secret"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/sharding"
+	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
+	"github.com/spf13/viper"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"os"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// addSharding makes the replicas of the controller distribute the namespaces between them using a lease per
+// shard. Whenever a shard is acquired, e.g. from a replica that stopped, its secrets are queued for reconciliation.
+func addSharding(mgr manager.Manager, cfg *rest.Config, namespaces []string) error {
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return err
+	}
+
+	leaseNamespace := viper.GetString("leader-election-namespace")
+	if leaseNamespace == "" {
+		if leaseNamespace, err = k8sutil.GetOperatorNamespace(); err != nil {
+			return fmt.Errorf("could not determine the namespace of the shard leases: %w", err)
+		}
+	}
+
+	identity := os.Getenv(k8sutil.PodNameEnvVar)
+	if identity == "" {
+		if identity, err = os.Hostname(); err != nil {
+			return err
+		}
+	}
+
+	coordinator := sharding.NewCoordinator(clientset.CoordinationV1(), sharding.Options{
+		Shards:           viper.GetInt("shards"),
+		ShardsPerReplica: viper.GetInt("shards-per-replica"),
+		Namespace:        leaseNamespace,
+		Name:             "kubernetes-secret-generator-shard",
+		Identity:         identity,
+		LeaseDuration:    viper.GetDuration("leader-election-lease-duration"),
+		RenewDeadline:    viper.GetDuration("leader-election-renew-deadline"),
+		RetryPeriod:      viper.GetDuration("leader-election-retry-period"),
+		OnAcquired: func(shard int) {
+			// secrets of other shards are skipped when reconciled
+			queued, err := secret.Resync(mgr, namespaces)
+			if err != nil {
+				log.Error(err, "could not queue secrets of acquired shard", "shard", shard)
+				return
+			}
+			log.Info("queued secrets of acquired shard", "shard", shard, "count", queued)
+		},
+	})
+	return mgr.Add(coordinator)
+}
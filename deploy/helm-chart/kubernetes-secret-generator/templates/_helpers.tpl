@@ -61,3 +61,21 @@ Create the name of the service account to use
     {{ default "default" .Values.serviceAccount.name }}
 {{- end -}}
 {{- end -}}
+
+{{/*
+Name of the secret containing the webhook server's certificate
+*/}}
+{{- define "kubernetes-secret-generator.webhookTLSSecret" -}}
+{{ default (printf "%s-webhook-tls" (include "kubernetes-secret-generator.fullname" .)) .Values.webhook.tlsSecret }}
+{{- end -}}
+
+{{/*
+Generator plugins as comma-separated list of type=path pairs
+*/}}
+{{- define "kubernetes-secret-generator.generatorPlugins" -}}
+{{- $plugins := list -}}
+{{- range $type, $path := . -}}
+{{- $plugins = append $plugins (printf "%s=%s" $type $path) -}}
+{{- end -}}
+{{ join "," $plugins }}
+{{- end -}}
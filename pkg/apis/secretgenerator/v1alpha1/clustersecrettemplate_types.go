@@ -0,0 +1,53 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ClusterSecretTemplateSpec defines the desired state of ClusterSecretTemplate
+type ClusterSecretTemplateSpec struct {
+	// NamespaceSelector selects the namespaces a secret is created in
+	NamespaceSelector metav1.LabelSelector `json:"namespaceSelector"`
+	// SecretName is the name of the secrets created in the selected namespaces, defaults to the name of the template
+	SecretName string `json:"secretName,omitempty"`
+	// Template describes the secrets created in the selected namespaces.
+	// Every namespace gets its own independently generated values.
+	Template SecretTemplate `json:"template"`
+}
+
+// ClusterSecretTemplateStatus defines the observed state of ClusterSecretTemplate
+type ClusterSecretTemplateStatus struct {
+	// ObservedGeneration is the most recent generation observed by the controller
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions describe the current state of the generated secrets
+	Conditions Conditions `json:"conditions,omitempty"`
+	// Namespaces lists the namespaces currently selected by the template
+	Namespaces []string `json:"namespaces,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClusterSecretTemplate is the Schema for the clustersecrettemplates API
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:path=clustersecrettemplates,scope=Cluster
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status"
+type ClusterSecretTemplate struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ClusterSecretTemplateSpec   `json:"spec,omitempty"`
+	Status ClusterSecretTemplateStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ClusterSecretTemplateList contains a list of ClusterSecretTemplate
+type ClusterSecretTemplateList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterSecretTemplate `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ClusterSecretTemplate{}, &ClusterSecretTemplateList{})
+}
@@ -0,0 +1,119 @@
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ConditionType is the type of a status condition
+type ConditionType string
+
+const (
+	// ConditionReady indicates whether the resource has been fully reconciled
+	ConditionReady ConditionType = "Ready"
+	// ConditionError indicates whether the last reconciliation of the resource failed
+	ConditionError ConditionType = "Error"
+	// ConditionLastRotation records when the values managed by the resource were last (re)generated
+	ConditionLastRotation ConditionType = "LastRotation"
+)
+
+// Condition describes the state of a resource at a certain point
+type Condition struct {
+	Type   ConditionType          `json:"type"`
+	Status corev1.ConditionStatus `json:"status"`
+	// LastTransitionTime is the last time the condition changed its status
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+	// Reason is a CamelCase reason for the condition's last transition
+	Reason string `json:"reason,omitempty"`
+	// Message is a human readable message indicating details about the transition
+	Message string `json:"message,omitempty"`
+}
+
+// Conditions is a list of status conditions, holding at most one condition per type
+type Conditions []Condition
+
+// GetCondition returns the condition of the given type, or nil if it is not set
+func (c Conditions) GetCondition(t ConditionType) *Condition {
+	for i := range c {
+		if c[i].Type == t {
+			return &c[i]
+		}
+	}
+	return nil
+}
+
+// IsTrue returns true if the condition of the given type is set and has status True
+func (c Conditions) IsTrue(t ConditionType) bool {
+	cond := c.GetCondition(t)
+	return cond != nil && cond.Status == corev1.ConditionTrue
+}
+
+// SetCondition adds or replaces the condition of the same type. The transition time is only
+// updated if the status of the condition changes.
+func (c *Conditions) SetCondition(cond Condition) {
+	existing := c.GetCondition(cond.Type)
+	if existing == nil {
+		if cond.LastTransitionTime.IsZero() {
+			cond.LastTransitionTime = metav1.Now()
+		}
+		*c = append(*c, cond)
+		return
+	}
+
+	if existing.Status != cond.Status || !cond.LastTransitionTime.IsZero() {
+		if cond.LastTransitionTime.IsZero() {
+			cond.LastTransitionTime = metav1.Now()
+		}
+		existing.LastTransitionTime = cond.LastTransitionTime
+	}
+	existing.Status = cond.Status
+	existing.Reason = cond.Reason
+	existing.Message = cond.Message
+}
+
+// MarkReady sets the Ready condition and clears the Error condition
+func (c *Conditions) MarkReady(reason, message string) {
+	c.SetCondition(Condition{
+		Type:    ConditionReady,
+		Status:  corev1.ConditionTrue,
+		Reason:  reason,
+		Message: message,
+	})
+	c.SetCondition(Condition{
+		Type:   ConditionError,
+		Status: corev1.ConditionFalse,
+		Reason: reason,
+	})
+}
+
+// MarkNotReady sets the Ready condition to False without flagging an error,
+// e.g. while waiting for another controller
+func (c *Conditions) MarkNotReady(reason, message string) {
+	c.SetCondition(Condition{
+		Type:    ConditionReady,
+		Status:  corev1.ConditionFalse,
+		Reason:  reason,
+		Message: message,
+	})
+	c.SetCondition(Condition{
+		Type:   ConditionError,
+		Status: corev1.ConditionFalse,
+		Reason: reason,
+	})
+}
+
+// MarkError sets the Error condition and marks the resource as not ready
+func (c *Conditions) MarkError(reason string, err error) {
+	c.SetCondition(Condition{
+		Type:    ConditionReady,
+		Status:  corev1.ConditionFalse,
+		Reason:  reason,
+		Message: err.Error(),
+	})
+	c.SetCondition(Condition{
+		Type:    ConditionError,
+		Status:  corev1.ConditionTrue,
+		Reason:  reason,
+		Message: err.Error(),
+	})
+}
@@ -0,0 +1,4 @@
+// Package v1alpha1 contains API Schema definitions for the secretgenerator v1alpha1 API group
+// +k8s:deepcopy-gen=package,register
+// +groupName=secretgenerator.mittwald.de
+package v1alpha1
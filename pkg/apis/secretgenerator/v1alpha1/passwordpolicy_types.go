@@ -0,0 +1,49 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PasswordPolicySpec defines the desired state of PasswordPolicy
+type PasswordPolicySpec struct {
+	// Length of the generated values. Falls back to the controller default if unset.
+	Length int `json:"length,omitempty"`
+	// Charset is the set of characters generated values are drawn from.
+	// If unset, values are base64 encoded random bytes.
+	Charset string `json:"charset,omitempty"`
+}
+
+// PasswordPolicyStatus defines the observed state of PasswordPolicy
+type PasswordPolicyStatus struct {
+	// ObservedGeneration is the most recent generation observed by the controller
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions describe the current state of the policy
+	Conditions Conditions `json:"conditions,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// PasswordPolicy is the Schema for the passwordpolicies API
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:path=passwordpolicies,scope=Namespaced
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status"
+type PasswordPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   PasswordPolicySpec   `json:"spec,omitempty"`
+	Status PasswordPolicyStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// PasswordPolicyList contains a list of PasswordPolicy
+type PasswordPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PasswordPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&PasswordPolicy{}, &PasswordPolicyList{})
+}
@@ -0,0 +1,50 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RotationRequestSpec defines the desired state of RotationRequest
+type RotationRequestSpec struct {
+	// Namespace restricts the rotation to secrets in the given namespace. Secrets in all namespaces are rotated if unset.
+	Namespace string `json:"namespace,omitempty"`
+	// Selector restricts the rotation to secrets matching the given labels. All managed secrets are rotated if unset.
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+}
+
+// RotationRequestStatus defines the observed state of RotationRequest
+type RotationRequestStatus struct {
+	// ObservedGeneration is the most recent generation observed by the controller
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions describe the current state of the request
+	Conditions Conditions `json:"conditions,omitempty"`
+	// Secrets lists the secrets regeneration has been requested for, as <namespace>/<name>
+	Secrets []string `json:"secrets,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// RotationRequest is the Schema for the rotationrequests API
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:path=rotationrequests,scope=Cluster
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status"
+type RotationRequest struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   RotationRequestSpec   `json:"spec,omitempty"`
+	Status RotationRequestStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// RotationRequestList contains a list of RotationRequest
+type RotationRequestList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []RotationRequest `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&RotationRequest{}, &RotationRequestList{})
+}
@@ -0,0 +1,73 @@
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SecretTemplate describes the contents of a secret with randomly generated string values
+type SecretTemplate struct {
+	// Fields lists the keys of the secret that are filled with randomly generated strings
+	Fields []string `json:"fields"`
+	// Length of the generated values. Falls back to the password policy or controller default if unset.
+	Length int `json:"length,omitempty"`
+	// PasswordPolicy is the name of a PasswordPolicy in the same namespace used for generation
+	PasswordPolicy string `json:"passwordPolicy,omitempty"`
+	// Data contains static values which are copied into the secret as they are
+	Data map[string]string `json:"data,omitempty"`
+	// Templates are Go templates rendered over the values of the secret, keyed by the field they are stored in
+	Templates map[string]string `json:"templates,omitempty"`
+	// Type of the created secret, defaults to Opaque
+	Type corev1.SecretType `json:"type,omitempty"`
+	// Prune deletes generated values of fields that are removed from Fields
+	Prune bool `json:"prune,omitempty"`
+}
+
+// StringSecretSpec defines the desired state of StringSecret
+type StringSecretSpec struct {
+	SecretTemplate `json:",inline"`
+
+	// KeepOnDelete prevents the secret from being garbage collected when the StringSecret is deleted
+	KeepOnDelete bool `json:"keepOnDelete,omitempty"`
+	// TargetNamespace is the namespace the secret is created in, defaults to the namespace of the StringSecret.
+	// Creating secrets in other namespaces has to be allowed by the controller configuration or the target namespace.
+	TargetNamespace string `json:"targetNamespace,omitempty"`
+}
+
+// StringSecretStatus defines the observed state of StringSecret
+type StringSecretStatus struct {
+	// ObservedGeneration is the most recent generation observed by the controller
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions describe the current state of the generated secret
+	Conditions Conditions `json:"conditions,omitempty"`
+	// SecretNamespace is the namespace the secret was created in, so it can be cleaned up when the target
+	// namespace changes
+	SecretNamespace string `json:"secretNamespace,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// StringSecret is the Schema for the stringsecrets API
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:path=stringsecrets,scope=Namespaced
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status"
+type StringSecret struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   StringSecretSpec   `json:"spec,omitempty"`
+	Status StringSecretStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// StringSecretList contains a list of StringSecret
+type StringSecretList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []StringSecret `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&StringSecret{}, &StringSecretList{})
+}
@@ -0,0 +1,105 @@
+// Package audit writes an append-only trail of the values generated and rotated by the controller to a sink
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// Record describes a change of generated values. It must never contain the values themselves.
+type Record struct {
+	Time time.Time `json:"time"`
+	// Actor is the field manager which requested the change, or the controller for changes it triggered itself
+	Actor     string `json:"actor"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Reason    string `json:"reason"`
+	// Generated maps the keys of newly generated values to the fingerprints of the values
+	Generated map[string]string `json:"generated,omitempty"`
+	// Rotated maps the keys of replaced values to the fingerprints of their new values
+	Rotated map[string]string `json:"rotated,omitempty"`
+	// Previous is the hash of the record written before this one, chaining the records so that
+	// removing or modifying one of them is evident
+	Previous string `json:"previous,omitempty"`
+	// Hash is the hash of this record, including the hash of the previous one
+	Hash string `json:"hash"`
+}
+
+// Sink is a destination audit records are written to
+type Sink interface {
+	Write(ctx context.Context, record Record) error
+}
+
+var (
+	mu   sync.Mutex
+	sink Sink
+	last string
+)
+
+// SetSink sets the sink records are written to. Records are discarded until it is set.
+func SetSink(s Sink) {
+	mu.Lock()
+	defer mu.Unlock()
+	sink = s
+	last = ""
+}
+
+// Enabled reports whether a sink records are written to has been set
+func Enabled() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return sink != nil
+}
+
+// Write chains the record to the previously written one and writes it to the sink
+func Write(ctx context.Context, record Record) error {
+	mu.Lock()
+	defer mu.Unlock()
+	if sink == nil {
+		return nil
+	}
+
+	record.Previous = last
+	record.Hash = ""
+	hash, err := hashRecord(record)
+	if err != nil {
+		return err
+	}
+	record.Hash = hash
+
+	if err := sink.Write(ctx, record); err != nil {
+		return err
+	}
+	last = hash
+	return nil
+}
+
+// hashRecord returns the hash of the JSON encoding of the record without its own hash
+func hashRecord(record Record) (string, error) {
+	record.Hash = ""
+	body, err := json.Marshal(record)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Verify checks that the records are an unbroken chain, i.e. that none of them has been modified and none
+// has been removed between them
+func Verify(records []Record) bool {
+	for i, record := range records {
+		hash, err := hashRecord(record)
+		if err != nil || hash != record.Hash {
+			return false
+		}
+		if i > 0 && record.Previous != records[i-1].Hash {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,54 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestRecordsAreChained(t *testing.T) {
+	buf := &bytes.Buffer{}
+	SetSink(&WriterSink{Writer: buf})
+	defer SetSink(nil)
+
+	require.NoError(t, Write(context.TODO(), Record{Namespace: "default", Name: "db", Generated: map[string]string{"password": "0123456789abcdef"}}))
+	require.NoError(t, Write(context.TODO(), Record{Namespace: "default", Name: "db", Rotated: map[string]string{"password": "fedcba9876543210"}}))
+
+	var records []Record
+	scanner := bufio.NewScanner(buf)
+	for scanner.Scan() {
+		record := Record{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		records = append(records, record)
+	}
+	require.Len(t, records, 2)
+	require.Empty(t, records[0].Previous)
+	require.Equal(t, records[0].Hash, records[1].Previous)
+	require.True(t, Verify(records))
+
+	records[0].Rotated = map[string]string{"password": "0123456789abcdef"}
+	require.False(t, Verify(records), "modified record must break the chain")
+}
+
+func TestNewSink(t *testing.T) {
+	sink, err := NewSink("stdout")
+	require.NoError(t, err)
+	require.IsType(t, &WriterSink{}, sink)
+
+	sink, err = NewSink("https://audit.example.com/records")
+	require.NoError(t, err)
+	require.IsType(t, &HTTPSink{}, sink)
+
+	sink, err = NewSink("kafka://broker-1:9092,broker-2:9092/audit")
+	require.NoError(t, err)
+	require.Equal(t, "audit", sink.(*KafkaSink).Writer.Topic)
+
+	_, err = NewSink("kafka://broker-1:9092")
+	require.Error(t, err)
+
+	_, err = NewSink("syslog://localhost")
+	require.Error(t, err)
+}
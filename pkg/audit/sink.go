@@ -0,0 +1,111 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/segmentio/kafka-go"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// NewSink returns the sink described by spec, which is either stdout, a file URL like file:///var/log/audit.log,
+// an http or https URL, or a Kafka URL listing the brokers and topic like kafka://broker-1:9092,broker-2:9092/audit
+func NewSink(spec string) (Sink, error) {
+	if spec == "stdout" {
+		return &WriterSink{Writer: os.Stdout}, nil
+	}
+
+	u, err := url.Parse(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid audit sink %q: %w", spec, err)
+	}
+
+	switch u.Scheme {
+	case "file":
+		f, err := os.OpenFile(u.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			return nil, err
+		}
+		return &WriterSink{Writer: f}, nil
+	case "http", "https":
+		return &HTTPSink{URL: spec, Timeout: 10 * time.Second}, nil
+	case "kafka":
+		topic := strings.TrimPrefix(u.Path, "/")
+		if u.Host == "" || topic == "" {
+			return nil, fmt.Errorf("invalid audit sink %q: brokers and topic are required", spec)
+		}
+		return &KafkaSink{Writer: &kafka.Writer{
+			Addr:         kafka.TCP(strings.Split(u.Host, ",")...),
+			Topic:        topic,
+			RequiredAcks: kafka.RequireAll,
+		}}, nil
+	}
+	return nil, fmt.Errorf("invalid audit sink %q: unsupported scheme %q", spec, u.Scheme)
+}
+
+// WriterSink writes records as JSON lines, e.g. to stdout or an append-only file
+type WriterSink struct {
+	Writer io.Writer
+}
+
+func (s *WriterSink) Write(_ context.Context, record Record) error {
+	return json.NewEncoder(s.Writer).Encode(record)
+}
+
+// HTTPSink posts records to an HTTP endpoint. Any 2xx response is considered successful.
+type HTTPSink struct {
+	URL     string
+	Timeout time.Duration
+}
+
+func (s *HTTPSink) Write(ctx context.Context, record Record) error {
+	body, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	if s.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("audit sink returned unexpected status %d", res.StatusCode)
+	}
+	return nil
+}
+
+// KafkaSink produces records to a Kafka topic, keyed by the namespace and name of the secret
+type KafkaSink struct {
+	Writer *kafka.Writer
+}
+
+func (s *KafkaSink) Write(ctx context.Context, record Record) error {
+	body, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return s.Writer.WriteMessages(ctx, kafka.Message{
+		Key:   []byte(record.Namespace + "/" + record.Name),
+		Value: body,
+	})
+}
@@ -0,0 +1,280 @@
+package clustersecrettemplate
+
+import (
+	"context"
+	"fmt"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/apis/secretgenerator/v1alpha1"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/controller/secret"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/controller/stringsecret"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/recovery"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/resilience"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/sharding"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/shutdown"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"reflect"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+	"strings"
+)
+
+var log = logf.Log.WithName("controller_clustersecrettemplate")
+
+const (
+	ownerKind = "ClusterSecretTemplate"
+
+	// LabelClusterSecretTemplate holds the UID of the ClusterSecretTemplate a secret was created from
+	LabelClusterSecretTemplate = "secret-generator.v1.mittwald.de/cluster-secret-template"
+)
+
+// Add creates a new ClusterSecretTemplate Controller and adds it to the Manager. The Manager will set fields on the Controller
+// and Start it when the Manager is Started.
+func Add(mgr manager.Manager) error {
+	return add(mgr, newReconciler(mgr))
+}
+
+// newReconciler returns a new reconcile.Reconciler
+func newReconciler(mgr manager.Manager) reconcile.Reconciler {
+	return &ReconcileClusterSecretTemplate{client: resilience.Client(mgr.GetClient()), scheme: mgr.GetScheme()}
+}
+
+// add adds a new Controller to mgr with r as the reconcile.Reconciler
+func add(mgr manager.Manager, r reconcile.Reconciler) error {
+	// Report panics of a single reconcile instead of crashing the controller
+	r = recovery.Recover("clustersecrettemplate-controller", v1alpha1.SchemeGroupVersion.WithKind("ClusterSecretTemplate"), mgr.GetEventRecorderFor("secret-generator"), r)
+
+	// Create a new controller
+	c, err := controller.New("clustersecrettemplate-controller", mgr, controller.Options{Reconciler: shutdown.Track(resilience.Guard(sharding.Filter(r)))})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to primary resource ClusterSecretTemplate
+	err = c.Watch(&source.Kind{Type: &v1alpha1.ClusterSecretTemplate{}}, &handler.EnqueueRequestForObject{})
+	if err != nil {
+		return err
+	}
+
+	// Namespaces being created or relabeled may change the selection of every template
+	err = c.Watch(&source.Kind{Type: &corev1.Namespace{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: allTemplates(mgr.GetClient()),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to the generated secrets
+	err = c.Watch(&source.Kind{Type: &corev1.Secret{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(mapSecretToOwner),
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func allTemplates(c client.Client) handler.ToRequestsFunc {
+	return func(_ handler.MapObject) []reconcile.Request {
+		list := &v1alpha1.ClusterSecretTemplateList{}
+		if err := c.List(context.TODO(), list); err != nil {
+			log.Error(err, "could not list cluster secret templates")
+			return nil
+		}
+
+		requests := make([]reconcile.Request, len(list.Items))
+		for i, t := range list.Items {
+			requests[i] = reconcile.Request{NamespacedName: types.NamespacedName{Name: t.Name}}
+		}
+		return requests
+	}
+}
+
+func mapSecretToOwner(a handler.MapObject) []reconcile.Request {
+	owner := a.Meta.GetAnnotations()[secret.AnnotationSecretOwner]
+	parts := strings.Split(owner, "/")
+	if len(parts) != 2 || parts[0] != ownerKind {
+		return nil
+	}
+
+	return []reconcile.Request{
+		{NamespacedName: types.NamespacedName{Name: parts[1]}},
+	}
+}
+
+// ownerAnnotation returns the value of the owner annotation for secrets created from the given template
+func ownerAnnotation(instance *v1alpha1.ClusterSecretTemplate) string {
+	return ownerKind + "/" + instance.Name
+}
+
+// blank assignment to verify that ReconcileClusterSecretTemplate implements reconcile.Reconciler
+var _ reconcile.Reconciler = &ReconcileClusterSecretTemplate{}
+
+// ReconcileClusterSecretTemplate reconciles a ClusterSecretTemplate object
+type ReconcileClusterSecretTemplate struct {
+	// This client, initialized using mgr.Client() above, is a split client
+	// that reads objects from the cache and writes to the apiserver
+	client client.Client
+	scheme *runtime.Scheme
+}
+
+// Reconcile ensures a secret described by the template exists in every selected namespace,
+// and removes secrets from namespaces which are no longer selected.
+func (r *ReconcileClusterSecretTemplate) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	reqLogger := log.WithValues("Request.Name", request.Name)
+	reqLogger.V(1).Info("Reconciling ClusterSecretTemplate")
+
+	instance := &v1alpha1.ClusterSecretTemplate{}
+	err := r.client.Get(context.TODO(), request.NamespacedName, instance)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			// Request object not found, could have been deleted after reconcile request.
+			// Owned objects are automatically garbage collected.
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	status := instance.Status.DeepCopy()
+	status.ObservedGeneration = instance.Generation
+
+	selector, err := metav1.LabelSelectorAsSelector(&instance.Spec.NamespaceSelector)
+	if err != nil {
+		status.Conditions.MarkError("InvalidNamespaceSelector", err)
+		return reconcile.Result{}, r.updateStatus(instance, status)
+	}
+
+	namespaceList := &corev1.NamespaceList{}
+	if err := r.client.List(context.TODO(), namespaceList, &client.ListOptions{LabelSelector: selector}); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	var namespaces, failed []string
+	pending := 0
+	for _, ns := range namespaceList.Items {
+		if !ns.DeletionTimestamp.IsZero() {
+			continue
+		}
+		namespaces = append(namespaces, ns.Name)
+
+		target := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      secretName(instance),
+				Namespace: ns.Name,
+			},
+		}
+
+		_, err := controllerutil.CreateOrUpdate(context.TODO(), r.client, target, func() error {
+			return r.mutateSecret(instance, target)
+		})
+		if err != nil {
+			reqLogger.Error(err, "could not create or update secret", "namespace", ns.Name)
+			failed = append(failed, ns.Name)
+			continue
+		}
+
+		if len(stringsecret.MissingFields(instance.Spec.Template, target)) > 0 {
+			pending++
+		}
+	}
+
+	if err := r.pruneSecrets(instance, namespaces); err != nil {
+		reqLogger.Error(err, "could not remove secrets from deselected namespaces")
+		failed = append(failed, "deselected namespaces")
+	}
+
+	status.Namespaces = namespaces
+	if len(failed) > 0 {
+		err = fmt.Errorf("could not reconcile secrets in %s", strings.Join(failed, ","))
+		status.Conditions.MarkError("SecretUpdateFailed", err)
+	} else if pending > 0 {
+		status.Conditions.MarkNotReady("Pending", fmt.Sprintf("%d of %d secrets are waiting for generation", pending, len(namespaces)))
+	} else {
+		status.Conditions.MarkReady("Generated", "")
+	}
+
+	if statusErr := r.updateStatus(instance, status); statusErr != nil {
+		reqLogger.Error(statusErr, "could not update status")
+		if err == nil {
+			err = statusErr
+		}
+	}
+
+	return reconcile.Result{}, err
+}
+
+func secretName(instance *v1alpha1.ClusterSecretTemplate) string {
+	if instance.Spec.SecretName == "" {
+		return instance.Name
+	}
+	return instance.Spec.SecretName
+}
+
+// mutateSecret applies the template to the given secret
+func (r *ReconcileClusterSecretTemplate) mutateSecret(instance *v1alpha1.ClusterSecretTemplate, target *corev1.Secret) error {
+	if target.Annotations == nil {
+		target.Annotations = make(map[string]string)
+	}
+	if target.Labels == nil {
+		target.Labels = make(map[string]string)
+	}
+
+	owner := ownerAnnotation(instance)
+	if !target.CreationTimestamp.IsZero() && target.Annotations[secret.AnnotationSecretOwner] != owner {
+		return fmt.Errorf("secret %s/%s already exists and is not managed by this ClusterSecretTemplate", target.Namespace, target.Name)
+	}
+
+	target.Annotations[secret.AnnotationSecretOwner] = owner
+	target.Labels[LabelClusterSecretTemplate] = string(instance.UID)
+	stringsecret.ApplyTemplate(instance.Spec.Template, target)
+
+	return controllerutil.SetControllerReference(instance, target, r.scheme)
+}
+
+// pruneSecrets deletes secrets created from the template in namespaces that are no longer selected
+func (r *ReconcileClusterSecretTemplate) pruneSecrets(instance *v1alpha1.ClusterSecretTemplate, namespaces []string) error {
+	list := &corev1.SecretList{}
+	err := r.client.List(context.TODO(), list, client.MatchingLabels{
+		LabelClusterSecretTemplate: string(instance.UID),
+	})
+	if err != nil {
+		return err
+	}
+
+	selected := make(map[string]bool, len(namespaces))
+	for _, ns := range namespaces {
+		selected[ns] = true
+	}
+
+	for i := range list.Items {
+		s := &list.Items[i]
+		if selected[s.Namespace] || s.Annotations[secret.AnnotationSecretOwner] != ownerAnnotation(instance) {
+			continue
+		}
+
+		log.Info("removing secret from deselected namespace", "namespace", s.Namespace, "name", s.Name)
+		if err := r.client.Delete(context.TODO(), s); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *ReconcileClusterSecretTemplate) updateStatus(instance *v1alpha1.ClusterSecretTemplate, status *v1alpha1.ClusterSecretTemplateStatus) error {
+	if reflect.DeepEqual(instance.Status, *status) {
+		return nil
+	}
+
+	instance.Status = *status
+	return r.client.Status().Update(context.TODO(), instance)
+}
@@ -0,0 +1,123 @@
+package clustersecrettemplate
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/apis"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/apis/secretgenerator/v1alpha1"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/controller/secret"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/util/flowcontrol"
+	"os"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"testing"
+)
+
+var mgr manager.Manager
+
+const labelSecretGeneratorTest = "kubernetes-secret-generator-test"
+
+func TestMain(m *testing.M) {
+	cfgPath := os.Getenv("KUBECONFIG")
+	cfg, err := clientcmd.BuildConfigFromFlags("", cfgPath)
+
+	if err != nil {
+		panic(err)
+	}
+
+	restMapper := func(cfg *rest.Config) (meta.RESTMapper, error) {
+		return apiutil.NewDynamicRESTMapper(cfg)
+	}
+
+	mgrOpts := manager.Options{
+		MapperProvider: restMapper,
+		NewClient: func(_ cache.Cache, config *rest.Config, options client.Options) (client.Client, error) {
+			config.RateLimiter = flowcontrol.NewFakeAlwaysRateLimiter()
+			return client.New(config, options)
+		},
+	}
+
+	mgr, err = manager.New(cfg, mgrOpts)
+	if err != nil {
+		panic(err)
+	}
+
+	if err := apis.AddToScheme(mgr.GetScheme()); err != nil {
+		panic(err)
+	}
+
+	code := m.Run()
+
+	os.Exit(code)
+}
+
+func doReconcile(t *testing.T, instance *v1alpha1.ClusterSecretTemplate) {
+	rec := ReconcileClusterSecretTemplate{mgr.GetClient(), mgr.GetScheme()}
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: instance.Name}}
+
+	_, err := rec.Reconcile(req)
+	require.NoError(t, err)
+}
+
+func TestSecretsAreStampedIntoSelectedNamespaces(t *testing.T) {
+	selector := uuid.New().String()
+
+	selected := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:   uuid.New().String(),
+		Labels: map[string]string{labelSecretGeneratorTest: selector},
+	}}
+	other := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name: uuid.New().String(),
+	}}
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), selected))
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), other))
+	defer mgr.GetClient().Delete(context.TODO(), selected)
+	defer mgr.GetClient().Delete(context.TODO(), other)
+
+	in := &v1alpha1.ClusterSecretTemplate{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: uuid.New().String(),
+		},
+		Spec: v1alpha1.ClusterSecretTemplateSpec{
+			NamespaceSelector: metav1.LabelSelector{
+				MatchLabels: map[string]string{labelSecretGeneratorTest: selector},
+			},
+			SecretName: "credentials",
+			Template: v1alpha1.SecretTemplate{
+				Fields: []string{"password"},
+			},
+		},
+	}
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), in))
+	defer mgr.GetClient().Delete(context.TODO(), in)
+
+	doReconcile(t, in)
+
+	out := &corev1.Secret{}
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{Namespace: selected.Name, Name: "credentials"}, out))
+	require.Equal(t, "password", out.Annotations[secret.AnnotationSecretAutoGenerate])
+	require.Equal(t, in.UID, metav1.GetControllerOf(out).UID)
+
+	err := mgr.GetClient().Get(context.TODO(), types.NamespacedName{Namespace: other.Name, Name: "credentials"}, &corev1.Secret{})
+	require.True(t, errors.IsNotFound(err))
+
+	// deselect the namespace again
+	selected.Labels = nil
+	require.NoError(t, mgr.GetClient().Update(context.TODO(), selected))
+
+	doReconcile(t, in)
+
+	err = mgr.GetClient().Get(context.TODO(), types.NamespacedName{Namespace: selected.Name, Name: "credentials"}, &corev1.Secret{})
+	require.True(t, errors.IsNotFound(err))
+}
@@ -0,0 +1,102 @@
+package passwordpolicy
+
+import (
+	"context"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/apis/secretgenerator/v1alpha1"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/controller/secret"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/recovery"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/resilience"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/sharding"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/shutdown"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"reflect"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+var log = logf.Log.WithName("controller_passwordpolicy")
+
+// Add creates a new PasswordPolicy Controller and adds it to the Manager. The Manager will set fields on the Controller
+// and Start it when the Manager is Started.
+func Add(mgr manager.Manager) error {
+	return add(mgr, newReconciler(mgr))
+}
+
+// newReconciler returns a new reconcile.Reconciler
+func newReconciler(mgr manager.Manager) reconcile.Reconciler {
+	return &ReconcilePasswordPolicy{client: resilience.Client(mgr.GetClient()), scheme: mgr.GetScheme()}
+}
+
+// add adds a new Controller to mgr with r as the reconcile.Reconciler
+func add(mgr manager.Manager, r reconcile.Reconciler) error {
+	// Report panics of a single reconcile instead of crashing the controller
+	r = recovery.Recover("passwordpolicy-controller", v1alpha1.SchemeGroupVersion.WithKind("PasswordPolicy"), mgr.GetEventRecorderFor("secret-generator"), r)
+
+	// Create a new controller
+	c, err := controller.New("passwordpolicy-controller", mgr, controller.Options{Reconciler: shutdown.Track(resilience.Guard(sharding.Filter(r)))})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to primary resource PasswordPolicy
+	err = c.Watch(&source.Kind{Type: &v1alpha1.PasswordPolicy{}}, &handler.EnqueueRequestForObject{})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// blank assignment to verify that ReconcilePasswordPolicy implements reconcile.Reconciler
+var _ reconcile.Reconciler = &ReconcilePasswordPolicy{}
+
+// ReconcilePasswordPolicy reconciles a PasswordPolicy object
+type ReconcilePasswordPolicy struct {
+	// This client, initialized using mgr.Client() above, is a split client
+	// that reads objects from the cache and writes to the apiserver
+	client client.Client
+	scheme *runtime.Scheme
+}
+
+// Reconcile validates a PasswordPolicy against the configured bounds and reports the result in its status
+func (r *ReconcilePasswordPolicy) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	reqLogger := log.WithValues("Request.Namespace", request.Namespace, "Request.Name", request.Name)
+	reqLogger.V(1).Info("Reconciling PasswordPolicy")
+
+	instance := &v1alpha1.PasswordPolicy{}
+	err := r.client.Get(context.TODO(), request.NamespacedName, instance)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	status := instance.Status.DeepCopy()
+	status.ObservedGeneration = instance.Generation
+
+	if err := secret.ValidatePasswordPolicy(instance.Spec); err != nil {
+		reqLogger.Info("password policy is invalid", "error", err.Error())
+		status.Conditions.MarkError("InvalidPolicy", err)
+	} else {
+		status.Conditions.MarkReady("ValidPolicy", "")
+	}
+
+	if reflect.DeepEqual(instance.Status, *status) {
+		return reconcile.Result{}, nil
+	}
+
+	instance.Status = *status
+	if err := r.client.Status().Update(context.TODO(), instance); err != nil {
+		reqLogger.Error(err, "could not update password policy status")
+		return reconcile.Result{}, err
+	}
+
+	return reconcile.Result{}, nil
+}
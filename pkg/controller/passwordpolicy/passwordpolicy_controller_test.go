@@ -0,0 +1,98 @@
+package passwordpolicy
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/apis"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/apis/secretgenerator/v1alpha1"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/util/flowcontrol"
+	"os"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"testing"
+)
+
+var mgr manager.Manager
+
+func TestMain(m *testing.M) {
+	cfgPath := os.Getenv("KUBECONFIG")
+	cfg, err := clientcmd.BuildConfigFromFlags("", cfgPath)
+
+	if err != nil {
+		panic(err)
+	}
+
+	restMapper := func(cfg *rest.Config) (meta.RESTMapper, error) {
+		return apiutil.NewDynamicRESTMapper(cfg)
+	}
+
+	mgrOpts := manager.Options{
+		MapperProvider: restMapper,
+		NewClient: func(_ cache.Cache, config *rest.Config, options client.Options) (client.Client, error) {
+			config.RateLimiter = flowcontrol.NewFakeAlwaysRateLimiter()
+			return client.New(config, options)
+		},
+	}
+
+	mgr, err = manager.New(cfg, mgrOpts)
+	if err != nil {
+		panic(err)
+	}
+
+	if err := apis.AddToScheme(mgr.GetScheme()); err != nil {
+		panic(err)
+	}
+
+	viper.Set("policy-min-length", 16)
+	viper.Set("policy-max-length", 64)
+
+	code := m.Run()
+
+	os.Exit(code)
+}
+
+func reconcilePolicy(t *testing.T, spec v1alpha1.PasswordPolicySpec) *v1alpha1.PasswordPolicy {
+	in := &v1alpha1.PasswordPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      uuid.New().String(),
+			Namespace: "default",
+		},
+		Spec: spec,
+	}
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), in))
+
+	rec := ReconcilePasswordPolicy{mgr.GetClient(), mgr.GetScheme()}
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: in.Name, Namespace: in.Namespace}}
+	_, err := rec.Reconcile(req)
+	require.NoError(t, err)
+
+	out := &v1alpha1.PasswordPolicy{}
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), req.NamespacedName, out))
+	require.NoError(t, mgr.GetClient().Delete(context.TODO(), out))
+	return out
+}
+
+func TestValidPolicyIsReady(t *testing.T) {
+	out := reconcilePolicy(t, v1alpha1.PasswordPolicySpec{Length: 32})
+
+	require.True(t, out.Status.Conditions.IsTrue(v1alpha1.ConditionReady))
+	require.False(t, out.Status.Conditions.IsTrue(v1alpha1.ConditionError))
+	require.Equal(t, out.Generation, out.Status.ObservedGeneration)
+}
+
+func TestInvalidPolicyIsNotReady(t *testing.T) {
+	out := reconcilePolicy(t, v1alpha1.PasswordPolicySpec{Length: 8})
+
+	require.False(t, out.Status.Conditions.IsTrue(v1alpha1.ConditionReady))
+	require.True(t, out.Status.Conditions.IsTrue(v1alpha1.ConditionError))
+}
@@ -0,0 +1,179 @@
+package rotationrequest
+
+import (
+	"context"
+	"fmt"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/apis/secretgenerator/v1alpha1"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/controller/secret"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/recovery"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/resilience"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/sharding"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/shutdown"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"reflect"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+	"strconv"
+	"strings"
+)
+
+var log = logf.Log.WithName("controller_rotationrequest")
+
+// Add creates a new RotationRequest Controller and adds it to the Manager. The Manager will set fields on the Controller
+// and Start it when the Manager is Started.
+func Add(mgr manager.Manager) error {
+	return add(mgr, newReconciler(mgr))
+}
+
+// newReconciler returns a new reconcile.Reconciler
+func newReconciler(mgr manager.Manager) reconcile.Reconciler {
+	return &ReconcileRotationRequest{client: resilience.Client(mgr.GetClient()), scheme: mgr.GetScheme()}
+}
+
+// add adds a new Controller to mgr with r as the reconcile.Reconciler
+func add(mgr manager.Manager, r reconcile.Reconciler) error {
+	// Report panics of a single reconcile instead of crashing the controller
+	r = recovery.Recover("rotationrequest-controller", v1alpha1.SchemeGroupVersion.WithKind("RotationRequest"), mgr.GetEventRecorderFor("secret-generator"), r)
+
+	// Create a new controller
+	c, err := controller.New("rotationrequest-controller", mgr, controller.Options{Reconciler: shutdown.Track(resilience.Guard(sharding.Filter(r)))})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to primary resource RotationRequest
+	err = c.Watch(&source.Kind{Type: &v1alpha1.RotationRequest{}}, &handler.EnqueueRequestForObject{})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// blank assignment to verify that ReconcileRotationRequest implements reconcile.Reconciler
+var _ reconcile.Reconciler = &ReconcileRotationRequest{}
+
+// ReconcileRotationRequest reconciles a RotationRequest object
+type ReconcileRotationRequest struct {
+	// This client, initialized using mgr.Client() above, is a split client
+	// that reads objects from the cache and writes to the apiserver
+	client client.Client
+	scheme *runtime.Scheme
+}
+
+// Reconcile requests regeneration of all managed secrets selected by a RotationRequest. Every generation of
+// a request is only carried out once, the secret controller then regenerates the values of the secrets.
+func (r *ReconcileRotationRequest) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	reqLogger := log.WithValues("Request.Name", request.Name)
+	reqLogger.V(1).Info("Reconciling RotationRequest")
+
+	instance := &v1alpha1.RotationRequest{}
+	err := r.client.Get(context.TODO(), request.NamespacedName, instance)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	if instance.Status.ObservedGeneration == instance.Generation && instance.Status.Conditions.IsTrue(v1alpha1.ConditionReady) {
+		// request has already been carried out
+		return reconcile.Result{}, nil
+	}
+
+	status := instance.Status.DeepCopy()
+	status.ObservedGeneration = instance.Generation
+
+	selector := labels.Everything()
+	if instance.Spec.Selector != nil {
+		selector, err = metav1.LabelSelectorAsSelector(instance.Spec.Selector)
+		if err != nil {
+			status.Conditions.MarkError("InvalidSelector", err)
+			return reconcile.Result{}, r.updateStatus(instance, status)
+		}
+	}
+
+	list := &corev1.SecretList{}
+	err = r.client.List(context.TODO(), list, &client.ListOptions{
+		Namespace:     instance.Spec.Namespace,
+		LabelSelector: selector,
+	})
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	var secrets, failed []string
+	marker := requestMarker(instance)
+	for i := range list.Items {
+		s := &list.Items[i]
+		if !secret.IsManaged(s.Annotations) || secret.IsIgnored(s.Annotations) || !s.DeletionTimestamp.IsZero() {
+			continue
+		}
+		name := s.Namespace + "/" + s.Name
+		if secret.IsPinned(s.Annotations) {
+			reqLogger.Info("skipping pinned secret", "secret", name)
+			continue
+		}
+		secrets = append(secrets, name)
+
+		if s.Annotations[secret.AnnotationSecretRotationRequest] == marker {
+			// regeneration has already been requested by this generation of the request
+			continue
+		}
+
+		if secret.DryRun() {
+			reqLogger.Info("dry run, not requesting regeneration of secret", "secret", name)
+			continue
+		}
+
+		patch := client.MergeFrom(s.DeepCopy())
+		s.Annotations[secret.AnnotationSecretRegenerate] = "yes"
+		s.Annotations[secret.AnnotationSecretRotationRequest] = marker
+		s.Annotations[secret.AnnotationSecretRequestedBy] = "RotationRequest/" + instance.Name
+		if err := r.client.Patch(context.TODO(), s, patch); err != nil {
+			reqLogger.Error(err, "could not request regeneration of secret", "secret", name)
+			failed = append(failed, name)
+		}
+	}
+
+	status.Secrets = secrets
+	if len(failed) > 0 {
+		err = fmt.Errorf("could not request regeneration of %s", strings.Join(failed, ","))
+		status.Conditions.MarkError("RegenerationFailed", err)
+	} else {
+		status.Conditions.MarkReady("RegenerationRequested", fmt.Sprintf("regeneration requested for %d secrets", len(secrets)))
+	}
+
+	if statusErr := r.updateStatus(instance, status); statusErr != nil {
+		reqLogger.Error(statusErr, "could not update status")
+		if err == nil {
+			err = statusErr
+		}
+	}
+
+	return reconcile.Result{}, err
+}
+
+// requestMarker identifies the generation of the request in the rotation-request annotation of secrets,
+// so that no secret is regenerated twice for the same request
+func requestMarker(instance *v1alpha1.RotationRequest) string {
+	return string(instance.UID) + "/" + strconv.FormatInt(instance.Generation, 10)
+}
+
+func (r *ReconcileRotationRequest) updateStatus(instance *v1alpha1.RotationRequest, status *v1alpha1.RotationRequestStatus) error {
+	if reflect.DeepEqual(instance.Status, *status) {
+		return nil
+	}
+
+	instance.Status = *status
+	return r.client.Status().Update(context.TODO(), instance)
+}
@@ -0,0 +1,168 @@
+package rotationrequest
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/apis"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/apis/secretgenerator/v1alpha1"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/controller/secret"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/util/flowcontrol"
+	"os"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"testing"
+)
+
+var mgr manager.Manager
+
+func TestMain(m *testing.M) {
+	cfgPath := os.Getenv("KUBECONFIG")
+	cfg, err := clientcmd.BuildConfigFromFlags("", cfgPath)
+
+	if err != nil {
+		panic(err)
+	}
+
+	restMapper := func(cfg *rest.Config) (meta.RESTMapper, error) {
+		return apiutil.NewDynamicRESTMapper(cfg)
+	}
+
+	mgrOpts := manager.Options{
+		MapperProvider: restMapper,
+		NewClient: func(_ cache.Cache, config *rest.Config, options client.Options) (client.Client, error) {
+			config.RateLimiter = flowcontrol.NewFakeAlwaysRateLimiter()
+			return client.New(config, options)
+		},
+	}
+
+	mgr, err = manager.New(cfg, mgrOpts)
+	if err != nil {
+		panic(err)
+	}
+
+	if err := apis.AddToScheme(mgr.GetScheme()); err != nil {
+		panic(err)
+	}
+
+	code := m.Run()
+
+	os.Exit(code)
+}
+
+func doReconcile(t *testing.T, instance *v1alpha1.RotationRequest) {
+	rec := ReconcileRotationRequest{mgr.GetClient(), mgr.GetScheme()}
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: instance.Name}}
+
+	_, err := rec.Reconcile(req)
+	require.NoError(t, err)
+}
+
+func newSecret(namespace string, labels, annotations map[string]string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        uuid.New().String(),
+			Namespace:   namespace,
+			Labels:      labels,
+			Annotations: annotations,
+		},
+		Data: map[string][]byte{"password": []byte("password")},
+	}
+}
+
+func TestSelectedSecretsAreRegenerated(t *testing.T) {
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: uuid.New().String()}}
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), ns))
+	defer mgr.GetClient().Delete(context.TODO(), ns)
+
+	managed := map[string]string{secret.AnnotationSecretAutoGenerate: "password"}
+	selected := newSecret(ns.Name, map[string]string{"app": "checkout"}, managed)
+	other := newSecret(ns.Name, map[string]string{"app": "billing"}, managed)
+	unmanaged := newSecret(ns.Name, map[string]string{"app": "checkout"}, nil)
+	pinned := newSecret(ns.Name, map[string]string{"app": "checkout"}, map[string]string{
+		secret.AnnotationSecretAutoGenerate: "password",
+		secret.AnnotationSecretPin:          "yes",
+	})
+	for _, s := range []*corev1.Secret{selected, other, unmanaged, pinned} {
+		require.NoError(t, mgr.GetClient().Create(context.TODO(), s))
+	}
+
+	in := &v1alpha1.RotationRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: uuid.New().String(),
+		},
+		Spec: v1alpha1.RotationRequestSpec{
+			Namespace: ns.Name,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"app": "checkout"},
+			},
+		},
+	}
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), in))
+	defer mgr.GetClient().Delete(context.TODO(), in)
+
+	doReconcile(t, in)
+
+	out := &corev1.Secret{}
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{Namespace: ns.Name, Name: selected.Name}, out))
+	require.Equal(t, "yes", out.Annotations[secret.AnnotationSecretRegenerate])
+	require.NotEmpty(t, out.Annotations[secret.AnnotationSecretRotationRequest])
+	require.Equal(t, "RotationRequest/"+in.Name, out.Annotations[secret.AnnotationSecretRequestedBy])
+
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{Namespace: ns.Name, Name: other.Name}, out))
+	require.NotContains(t, out.Annotations, secret.AnnotationSecretRegenerate)
+
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{Namespace: ns.Name, Name: unmanaged.Name}, out))
+	require.NotContains(t, out.Annotations, secret.AnnotationSecretRegenerate)
+
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{Namespace: ns.Name, Name: pinned.Name}, out))
+	require.NotContains(t, out.Annotations, secret.AnnotationSecretRegenerate)
+
+	request := &v1alpha1.RotationRequest{}
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{Name: in.Name}, request))
+	require.True(t, request.Status.Conditions.IsTrue(v1alpha1.ConditionReady))
+	require.Equal(t, []string{ns.Name + "/" + selected.Name}, request.Status.Secrets)
+}
+
+func TestRequestIsOnlyCarriedOutOnce(t *testing.T) {
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: uuid.New().String()}}
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), ns))
+	defer mgr.GetClient().Delete(context.TODO(), ns)
+
+	s := newSecret(ns.Name, nil, map[string]string{secret.AnnotationSecretAutoGenerate: "password"})
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), s))
+
+	in := &v1alpha1.RotationRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: uuid.New().String(),
+		},
+		Spec: v1alpha1.RotationRequestSpec{
+			Namespace: ns.Name,
+		},
+	}
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), in))
+	defer mgr.GetClient().Delete(context.TODO(), in)
+
+	doReconcile(t, in)
+
+	// the secret controller removes the annotation after regeneration
+	out := &corev1.Secret{}
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{Namespace: ns.Name, Name: s.Name}, out))
+	delete(out.Annotations, secret.AnnotationSecretRegenerate)
+	delete(out.Annotations, secret.AnnotationSecretRequestedBy)
+	require.NoError(t, mgr.GetClient().Update(context.TODO(), out))
+
+	doReconcile(t, in)
+
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{Namespace: ns.Name, Name: s.Name}, out))
+	require.NotContains(t, out.Annotations, secret.AnnotationSecretRegenerate)
+}
@@ -0,0 +1,58 @@
+package secret
+
+import (
+	"context"
+	"fmt"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/syncer"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// syncExternal mirrors the secret into all external stores it is configured for, if its data changed
+// since the last sync. It also adds the finalizer cleaning up the external copies on deletion.
+func syncExternal(desired *corev1.Secret) error {
+	backends := syncer.For(desired)
+	if len(backends) == 0 {
+		return nil
+	}
+
+	if !contains(desired.Finalizers, FinalizerExternalSync) {
+		desired.Finalizers = append(desired.Finalizers, FinalizerExternalSync)
+	}
+
+	checksum := syncer.Checksum(desired.Data)
+	if desired.Annotations[AnnotationSecretSyncChecksum] == checksum {
+		return nil
+	}
+
+	for _, b := range backends {
+		if err := b.Sync(context.TODO(), desired); err != nil {
+			return fmt.Errorf("could not sync to %s: %w", b.Name(), err)
+		}
+	}
+
+	desired.Annotations[AnnotationSecretSyncChecksum] = checksum
+	return nil
+}
+
+// finalizeExternalSync deletes or tombstones the external copies of a deleted secret,
+// depending on its sync-deletion-policy annotation, and removes the finalizer afterwards
+func (r *ReconcileSecret) finalizeExternalSync(instance *corev1.Secret) error {
+	if !contains(instance.Finalizers, FinalizerExternalSync) {
+		return nil
+	}
+
+	policy := syncer.DeletionPolicy(instance.Annotations[AnnotationSecretSyncDeletionPolicy])
+	if err := syncer.Finalize(context.TODO(), instance, policy); err != nil {
+		return err
+	}
+
+	var finalizers []string
+	for _, f := range instance.Finalizers {
+		if f != FinalizerExternalSync {
+			finalizers = append(finalizers, f)
+		}
+	}
+	instance.Finalizers = finalizers
+
+	return r.client.Update(context.TODO(), instance)
+}
@@ -0,0 +1,68 @@
+package secret
+
+import (
+	"context"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/syncer"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"testing"
+)
+
+const annotationTestSync = "secret-generator.v1.mittwald.de/test-sync"
+
+type fakeBackend struct {
+	synced  map[string]map[string][]byte
+	deleted []string
+}
+
+func (b *fakeBackend) Name() string { return "fake" }
+
+func (b *fakeBackend) Enabled(secret *corev1.Secret) bool {
+	return secret.Annotations[annotationTestSync] == "yes"
+}
+
+func (b *fakeBackend) Sync(_ context.Context, secret *corev1.Secret) error {
+	b.synced[secret.Name] = secret.Data
+	return nil
+}
+
+func (b *fakeBackend) Delete(_ context.Context, secret *corev1.Secret) error {
+	b.deleted = append(b.deleted, secret.Name)
+	return nil
+}
+
+func (b *fakeBackend) Tombstone(_ context.Context, _ *corev1.Secret) error {
+	return nil
+}
+
+var testBackend = &fakeBackend{synced: map[string]map[string][]byte{}}
+
+func init() {
+	syncer.Register(testBackend)
+}
+
+func TestExternalSyncAndCleanup(t *testing.T) {
+	in := newStringTestSecret("testfield", map[string]string{
+		annotationTestSync: "yes",
+	}, "")
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), in))
+
+	doReconcile(t, in, false)
+
+	out := &corev1.Secret{}
+	key := types.NamespacedName{Name: in.Name, Namespace: in.Namespace}
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), key, out))
+
+	require.Contains(t, out.Finalizers, FinalizerExternalSync)
+	require.Equal(t, out.Data["testfield"], testBackend.synced[in.Name]["testfield"])
+	require.Equal(t, syncer.Checksum(out.Data), out.Annotations[AnnotationSecretSyncChecksum])
+
+	require.NoError(t, mgr.GetClient().Delete(context.TODO(), out))
+	doReconcile(t, in, false)
+
+	require.Contains(t, testBackend.deleted, in.Name)
+	err := mgr.GetClient().Get(context.TODO(), key, &corev1.Secret{})
+	require.True(t, errors.IsNotFound(err))
+}
@@ -0,0 +1,56 @@
+package secret
+
+import (
+	"context"
+	"fmt"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/apis/secretgenerator/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"strings"
+)
+
+// passwordPolicy returns the spec of the PasswordPolicy referenced by the instance's
+// password-policy annotation, or nil if the instance does not reference one.
+// The policy has to live in the same namespace as the instance.
+func (r *ReconcileSecret) passwordPolicy(instance *corev1.Secret) (*v1alpha1.PasswordPolicySpec, error) {
+	name, ok := instance.Annotations[AnnotationSecretPasswordPolicy]
+	if !ok || name == "" {
+		return nil, nil
+	}
+
+	policy := &v1alpha1.PasswordPolicy{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: instance.Namespace, Name: name}, policy)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ValidatePasswordPolicy(policy.Spec); err != nil {
+		return nil, fmt.Errorf("password policy %s/%s is invalid: %w", policy.Namespace, policy.Name, err)
+	}
+
+	return &policy.Spec, nil
+}
+
+// ValidatePasswordPolicy checks the given policy against the bounds configured by the
+// cluster administrator
+func ValidatePasswordPolicy(spec v1alpha1.PasswordPolicySpec) error {
+	if spec.Length < 0 {
+		return fmt.Errorf("length must not be negative")
+	}
+
+	if min := policyMinLength(); spec.Length != 0 && spec.Length < min {
+		return fmt.Errorf("length %d is below the minimum of %d", spec.Length, min)
+	}
+
+	if max := policyMaxLength(); max > 0 && spec.Length > max {
+		return fmt.Errorf("length %d exceeds the maximum of %d", spec.Length, max)
+	}
+
+	if spec.Charset != "" {
+		if err := ensureUniqueness(strings.Split(spec.Charset, "")); err != nil {
+			return fmt.Errorf("charset is invalid: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package secret
+
+import (
+	"context"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/apis/secretgenerator/v1alpha1"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"strings"
+	"testing"
+)
+
+func newPasswordPolicy(spec v1alpha1.PasswordPolicySpec) *v1alpha1.PasswordPolicy {
+	return &v1alpha1.PasswordPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      getSecretName(),
+			Namespace: "default",
+			Labels: map[string]string{
+				labelSecretGeneratorTest: "yes",
+			},
+		},
+		Spec: spec,
+	}
+}
+
+func TestStringPasswordPolicy(t *testing.T) {
+	policy := newPasswordPolicy(v1alpha1.PasswordPolicySpec{
+		Length:  24,
+		Charset: "abc",
+	})
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), policy))
+
+	in := newStringTestSecret("testfield", map[string]string{
+		AnnotationSecretPasswordPolicy: policy.Name,
+	}, "")
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), in))
+
+	doReconcile(t, in, false)
+
+	out := &corev1.Secret{}
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{
+		Name:      in.Name,
+		Namespace: in.Namespace}, out))
+
+	val := string(out.Data["testfield"])
+	if len(val) != 24 {
+		t.Errorf("generated field has wrong length of %d", len(val))
+	}
+	if strings.Trim(val, "abc") != "" {
+		t.Errorf("generated field %s contains characters not in policy charset", val)
+	}
+}
+
+func TestStringPasswordPolicyMissing(t *testing.T) {
+	in := newStringTestSecret("testfield", map[string]string{
+		AnnotationSecretPasswordPolicy: "does-not-exist",
+	}, "")
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), in))
+
+	doReconcile(t, in, true)
+}
+
+func TestValidatePasswordPolicy(t *testing.T) {
+	viper.Set("policy-min-length", 16)
+	viper.Set("policy-max-length", 64)
+	defer viper.Set("policy-min-length", 0)
+	defer viper.Set("policy-max-length", 0)
+
+	require.NoError(t, ValidatePasswordPolicy(v1alpha1.PasswordPolicySpec{Length: 32}))
+	require.NoError(t, ValidatePasswordPolicy(v1alpha1.PasswordPolicySpec{}))
+	require.Error(t, ValidatePasswordPolicy(v1alpha1.PasswordPolicySpec{Length: 8}))
+	require.Error(t, ValidatePasswordPolicy(v1alpha1.PasswordPolicySpec{Length: 128}))
+	require.Error(t, ValidatePasswordPolicy(v1alpha1.PasswordPolicySpec{Length: 32, Charset: "aab"}))
+}
+
+func TestGeneratedSecretsFromCharset(t *testing.T) {
+	pwd, err := generateRandomStringFromCharset(20, "xyz")
+	if err != nil {
+		t.Error(err)
+	}
+
+	if len(pwd) != 20 {
+		t.Error("string length", "expected", 20, "got", len(pwd))
+	}
+
+	if strings.Trim(pwd, "xyz") != "" {
+		t.Error("string contains characters outside of charset", pwd)
+	}
+}
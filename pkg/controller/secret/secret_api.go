@@ -0,0 +1,333 @@
+package secret
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"net/http"
+	"path/filepath"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"strings"
+	"time"
+)
+
+// GenerationAPIPath is the path of the endpoint generating values into secrets on request
+const GenerationAPIPath = "/v1/generate"
+
+func generationAPIBindAddress() string {
+	return options().GetString("generation-api-bind-address")
+}
+
+func generationAPICertDir() string {
+	return options().GetString("generation-api-cert-dir")
+}
+
+func generationAPIInsecure() bool {
+	return options().GetBool("generation-api-insecure")
+}
+
+// generationWaitTimeout bounds how long a request waits for the controller to generate the value
+const generationWaitTimeout = 10 * time.Second
+
+// GenerationRequest asks for a value to be generated into a field of a secret, which is created if it doesn't exist
+type GenerationRequest struct {
+	Namespace string    `json:"namespace"`
+	Secret    string    `json:"secret"`
+	Field     FieldSpec `json:"field"`
+	// Regenerate replaces the value of the field if it already has one
+	Regenerate bool `json:"regenerate,omitempty"`
+}
+
+// GenerationReference refers to the keys of a secret holding a generated value, without containing the value
+type GenerationReference struct {
+	Namespace       string   `json:"namespace"`
+	Secret          string   `json:"secret"`
+	Keys            []string `json:"keys"`
+	ResourceVersion string   `json:"resourceVersion"`
+}
+
+// generationAPI generates values into secrets on behalf of callers authenticated by a service account or user
+// token, who must be allowed to write the secret themselves
+type generationAPI struct {
+	client    client.Client
+	reader    client.Reader
+	clientset kubernetes.Interface
+	// timeout bounds how long a request waits for the value to be generated
+	timeout time.Duration
+}
+
+// newGenerationAPI returns the generation API. It refuses to serve bearer tokens in plaintext unless the API
+// is explicitly configured to be insecure.
+func newGenerationAPI(mgr manager.Manager) (*generationAPI, error) {
+	if generationAPICertDir() == "" && !generationAPIInsecure() {
+		return nil, fmt.Errorf("the generation API requires generation-api-cert-dir, or generation-api-insecure to be served without TLS")
+	}
+
+	clientset, err := kubernetes.NewForConfig(mgr.GetConfig())
+	if err != nil {
+		return nil, err
+	}
+	return &generationAPI{
+		client:    mgr.GetClient(),
+		reader:    mgr.GetAPIReader(),
+		clientset: clientset,
+		timeout:   generationWaitTimeout,
+	}, nil
+}
+
+// NeedLeaderElection makes the manager serve the API on all replicas
+func (a *generationAPI) NeedLeaderElection() bool {
+	return false
+}
+
+// Start serves the API until stop is closed, using TLS unless it is configured to be insecure
+func (a *generationAPI) Start(stop <-chan struct{}) error {
+	mux := http.NewServeMux()
+	mux.Handle(GenerationAPIPath, a)
+	server := &http.Server{Addr: generationAPIBindAddress(), Handler: mux}
+	go func() {
+		<-stop
+		_ = server.Close()
+	}()
+
+	log.Info("serving generation API", "address", server.Addr)
+	var err error
+	if dir := generationAPICertDir(); dir != "" {
+		err = server.ListenAndServeTLS(filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"))
+	} else {
+		log.Info("serving generation API without TLS, as generation-api-insecure is set")
+		err = server.ListenAndServe()
+	}
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
+
+func (a *generationAPI) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "only POST is supported", http.StatusMethodNotAllowed)
+		return
+	}
+
+	token := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if token == "" || token == req.Header.Get("Authorization") {
+		http.Error(w, "a bearer token is required", http.StatusUnauthorized)
+		return
+	}
+	user, err := a.authenticate(token)
+	if err != nil {
+		log.Error(err, "could not authenticate generation request")
+		http.Error(w, "could not authenticate", http.StatusUnauthorized)
+		return
+	}
+
+	gr := &GenerationRequest{}
+	dec := json.NewDecoder(req.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(gr); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %s", err), http.StatusBadRequest)
+		return
+	}
+	if gr.Field.Type == "" {
+		gr.Field.Type = SecretTypeString
+	}
+	if gr.Namespace == "" || gr.Secret == "" {
+		http.Error(w, "namespace and secret must be set", http.StatusBadRequest)
+		return
+	}
+	if err := (&Spec{Fields: []FieldSpec{gr.Field}}).Validate(); err != nil {
+		http.Error(w, fmt.Sprintf("invalid field: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	reqLogger := log.WithValues("Request.Namespace", gr.Namespace, "Request.Name", gr.Secret, "user", user.Username)
+	ref, status, err := a.generate(user, gr)
+	if err != nil {
+		reqLogger.Error(err, "could not generate requested value", "field", gr.Field.Name)
+		http.Error(w, err.Error(), status)
+		return
+	}
+	reqLogger.Info("generated requested value", "field", gr.Field.Name)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(ref)
+}
+
+// authenticate returns the user the token belongs to
+func (a *generationAPI) authenticate(token string) (authenticationv1.UserInfo, error) {
+	review, err := a.clientset.AuthenticationV1().TokenReviews().Create(&authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: token},
+	})
+	if err != nil {
+		return authenticationv1.UserInfo{}, err
+	}
+	if !review.Status.Authenticated {
+		return authenticationv1.UserInfo{}, fmt.Errorf("token was not authenticated: %s", review.Status.Error)
+	}
+	return review.Status.User, nil
+}
+
+// authorize returns whether the user may perform the verb on the requested secret, so that the API can't be used
+// to write secrets the user has no access to
+func (a *generationAPI) authorize(user authenticationv1.UserInfo, verb string, gr *GenerationRequest) (bool, error) {
+	extra := map[string]authorizationv1.ExtraValue{}
+	for k, v := range user.Extra {
+		extra[k] = authorizationv1.ExtraValue(v)
+	}
+
+	review, err := a.clientset.AuthorizationV1().SubjectAccessReviews().Create(&authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   user.Username,
+			Groups: user.Groups,
+			UID:    user.UID,
+			Extra:  extra,
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: gr.Namespace,
+				Verb:      verb,
+				Resource:  "secrets",
+				Name:      gr.Secret,
+			},
+		},
+	})
+	if err != nil {
+		return false, err
+	}
+	return review.Status.Allowed, nil
+}
+
+// generate adds the requested field to the secret and waits for the controller to reconcile it, like any other
+// change to the secret, so that it is handled by the replica owning the secret and never concurrently with other
+// reconciles of it. It returns the HTTP status of the response, which is 202 Accepted if the value has not been
+// generated in time, e.g. because the controller is paused.
+func (a *generationAPI) generate(user authenticationv1.UserInfo, gr *GenerationRequest) (*GenerationReference, int, error) {
+	key := types.NamespacedName{Namespace: gr.Namespace, Name: gr.Secret}
+	instance := &corev1.Secret{}
+	err := a.reader.Get(context.TODO(), key, instance)
+	exists := err == nil
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, http.StatusInternalServerError, err
+	}
+
+	verb := "update"
+	if !exists {
+		verb = "create"
+	}
+	allowed, err := a.authorize(user, verb, gr)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	if !allowed {
+		return nil, http.StatusForbidden, fmt.Errorf("user %s may not %s secret %s/%s", user.Username, verb, gr.Namespace, gr.Secret)
+	}
+
+	if !exists {
+		instance = &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: gr.Namespace, Name: gr.Secret, Labels: SelectorLabels()}}
+	}
+	if err := addField(instance, gr.Field, gr.Regenerate); err != nil {
+		return nil, http.StatusConflict, err
+	}
+
+	if exists {
+		err = a.client.Update(context.TODO(), instance)
+	} else {
+		err = a.client.Create(context.TODO(), instance)
+	}
+	if errors.IsConflict(err) || errors.IsAlreadyExists(err) {
+		return nil, http.StatusConflict, fmt.Errorf("secret was changed concurrently, retry the request")
+	}
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+
+	// the write queues the secret for reconciliation by the controller
+	keys := gr.Field.keys()
+	generated := func() (bool, error) {
+		if err := a.reader.Get(context.TODO(), key, instance); err != nil {
+			return false, err
+		}
+		if _, ok := instance.Annotations[AnnotationSecretRegenerate]; ok {
+			return false, nil
+		}
+		for _, k := range keys {
+			if len(instance.Data[k]) == 0 {
+				return false, nil
+			}
+		}
+		return true, nil
+	}
+	err = wait.PollImmediate(time.Second/4, a.timeout, generated)
+	if err != nil && err != wait.ErrWaitTimeout {
+		return nil, http.StatusInternalServerError, err
+	}
+
+	ref := &GenerationReference{
+		Namespace:       instance.Namespace,
+		Secret:          instance.Name,
+		Keys:            keys,
+		ResourceVersion: instance.ResourceVersion,
+	}
+	if err == wait.ErrWaitTimeout {
+		return ref, http.StatusAccepted, nil
+	}
+	return ref, http.StatusOK, nil
+}
+
+// addField adds the field to the spec annotation of the secret, requesting its regeneration if asked to.
+// Secrets managed using the v1 annotations are not supported, and fields already in the spec can't be changed.
+func addField(instance *corev1.Secret, field FieldSpec, regenerate bool) error {
+	if instance.Annotations == nil {
+		instance.Annotations = map[string]string{}
+	}
+
+	spec := &Spec{}
+	if val, ok := instance.Annotations[AnnotationSecretSpec]; ok {
+		var err error
+		if spec, err = parseSpec(val); err != nil {
+			return err
+		}
+	} else if IsManaged(instance.Annotations) {
+		return fmt.Errorf("secret is managed using the %s annotation, which the API does not support", AnnotationSecretAutoGenerate)
+	}
+
+	found := false
+	for _, f := range spec.Fields {
+		if f.Name != field.Name {
+			continue
+		}
+		if f != field {
+			return fmt.Errorf("field %s is already generated with different parameters", field.Name)
+		}
+		found = true
+	}
+	if !found {
+		spec.Fields = append(spec.Fields, field)
+		if err := spec.Validate(); err != nil {
+			return err
+		}
+	}
+
+	encoded, err := json.Marshal(spec)
+	if err != nil {
+		return err
+	}
+	instance.Annotations[AnnotationSecretSpec] = string(encoded)
+
+	if regenerate {
+		if derives(instance.Annotations) {
+			return fmt.Errorf("derived values of the secret don't change when regenerated")
+		}
+		requestRegeneration(instance, []string{field.Name})
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package secret
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestGenerationAPI(t *testing.T, allowed bool) *generationAPI {
+	clientset := fake.NewSimpleClientset()
+	clientset.PrependReactor("create", "tokenreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authenticationv1.TokenReview)
+		review.Status.Authenticated = review.Spec.Token == "valid"
+		review.Status.User = authenticationv1.UserInfo{Username: "system:serviceaccount:default:app"}
+		return true, review, nil
+	})
+	clientset.PrependReactor("create", "subjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+		review.Status.Allowed = allowed
+		return true, review, nil
+	})
+
+	// the controller isn't running, so requests barely wait for values to be generated
+	return &generationAPI{client: mgr.GetClient(), reader: mgr.GetAPIReader(), clientset: clientset, timeout: time.Millisecond}
+}
+
+func postGenerationRequest(api *generationAPI, token string, gr GenerationRequest) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(gr)
+	req := httptest.NewRequest(http.MethodPost, GenerationAPIPath, strings.NewReader(string(body)))
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	api.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestGenerationAPIGeneratesIntoNewSecret(t *testing.T) {
+	api := newTestGenerationAPI(t, true)
+	name := getSecretName()
+	key := types.NamespacedName{Namespace: "default", Name: name}
+
+	gr := GenerationRequest{
+		Namespace: "default",
+		Secret:    name,
+		Field:     FieldSpec{Name: "password", Length: 20},
+	}
+	rec := postGenerationRequest(api, "valid", gr)
+	require.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+
+	out := &corev1.Secret{}
+	require.NoError(t, mgr.GetAPIReader().Get(context.TODO(), key, out))
+	defer func() {
+		_ = mgr.GetClient().Delete(context.TODO(), out)
+	}()
+	require.Empty(t, out.Data["password"])
+
+	doReconcile(t, out, false)
+
+	rec = postGenerationRequest(api, "valid", gr)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	ref := &GenerationReference{}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(ref))
+	require.Equal(t, []string{"password"}, ref.Keys)
+	require.NotContains(t, rec.Body.String(), "data")
+
+	require.NoError(t, mgr.GetAPIReader().Get(context.TODO(), key, out))
+	require.Len(t, out.Data["password"], 20)
+	require.Equal(t, out.ResourceVersion, ref.ResourceVersion)
+}
+
+func TestGenerationAPIRequiresTLS(t *testing.T) {
+	_, err := newGenerationAPI(mgr)
+	require.Error(t, err)
+
+	viper.Set("generation-api-insecure", true)
+	defer viper.Set("generation-api-insecure", false)
+	_, err = newGenerationAPI(mgr)
+	require.NoError(t, err)
+}
+
+func TestGenerationAPIRejectsUnauthenticatedAndUnauthorizedCallers(t *testing.T) {
+	gr := GenerationRequest{Namespace: "default", Secret: getSecretName(), Field: FieldSpec{Name: "password"}}
+
+	rec := postGenerationRequest(newTestGenerationAPI(t, true), "invalid", gr)
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = postGenerationRequest(newTestGenerationAPI(t, false), "valid", gr)
+	require.Equal(t, http.StatusForbidden, rec.Code)
+}
+
+func TestAddFieldRejectsChangedParameters(t *testing.T) {
+	instance := &corev1.Secret{}
+	require.NoError(t, addField(instance, FieldSpec{Name: "password", Type: SecretTypeString, Length: 20}, false))
+	require.NoError(t, addField(instance, FieldSpec{Name: "password", Type: SecretTypeString, Length: 20}, false))
+	require.Error(t, addField(instance, FieldSpec{Name: "password", Type: SecretTypeString, Length: 30}, false))
+
+	spec, err := parseSpec(instance.Annotations[AnnotationSecretSpec])
+	require.NoError(t, err)
+	require.Len(t, spec.Fields, 1)
+
+	legacy := newStringTestSecret("testfield", nil, "")
+	require.Error(t, addField(legacy, FieldSpec{Name: "password", Type: SecretTypeString}, false))
+}
+
+func TestAddFieldRejectsRegeneratingDerivedValues(t *testing.T) {
+	instance := &corev1.Secret{}
+	instance.Annotations = map[string]string{AnnotationSecretDeriveFrom: "master/key"}
+	require.NoError(t, addField(instance, FieldSpec{Name: "password", Type: SecretTypeString}, false))
+	require.Error(t, addField(instance, FieldSpec{Name: "password", Type: SecretTypeString}, true))
+}
@@ -0,0 +1,71 @@
+package secret
+
+import (
+	"context"
+	"github.com/go-logr/logr"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/audit"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/hook"
+	corev1 "k8s.io/api/core/v1"
+	"strings"
+	"time"
+)
+
+// auditActor is recorded as actor of changes the controller triggered itself, e.g. scheduled rotations
+const auditActor = "secret-generator"
+
+// auditChanges writes an audit record of the values generated and rotated by updating instance to desired.
+// Errors are only logged, the values have already been written.
+func auditChanges(reqLogger logr.Logger, instance, desired *corev1.Secret, reason hook.Reason) {
+	if !audit.Enabled() {
+		return
+	}
+
+	record := audit.Record{
+		Time:      time.Now().UTC(),
+		Actor:     requester(instance),
+		Namespace: desired.Namespace,
+		Name:      desired.Name,
+		Reason:    string(reason),
+		Generated: fingerprintsOf(desired, generatedKeys(instance.Data, desired.Data)),
+		Rotated:   fingerprintsOf(desired, replacedKeys(instance.Data, desired.Data)),
+	}
+	if len(record.Generated) == 0 && len(record.Rotated) == 0 {
+		return
+	}
+
+	if err := audit.Write(context.TODO(), record); err != nil {
+		reqLogger.Error(err, "could not write audit record")
+	}
+}
+
+// fingerprintsOf maps the given keys to the fingerprints of their values in the secret
+func fingerprintsOf(instance *corev1.Secret, keys []string) map[string]string {
+	if len(keys) == 0 {
+		return nil
+	}
+	fps := make(map[string]string, len(keys))
+	for _, key := range keys {
+		fps[key] = Fingerprint(instance.Data[key])
+	}
+	return fps
+}
+
+// requester returns the field manager which set the rollback or regenerate annotation of the secret, falling
+// back to the controller itself if neither annotation is set or its manager is unknown
+func requester(instance *corev1.Secret) string {
+	annotation := AnnotationSecretRollback
+	if _, ok := instance.Annotations[annotation]; !ok {
+		annotation = AnnotationSecretRegenerate
+	}
+	if _, ok := instance.Annotations[annotation]; !ok {
+		return auditActor
+	}
+
+	field := `"f:` + annotation + `"`
+	for _, entry := range instance.ManagedFields {
+		if entry.FieldsV1 != nil && strings.Contains(string(entry.FieldsV1.Raw), field) {
+			return entry.Manager
+		}
+	}
+	return auditActor
+}
@@ -0,0 +1,19 @@
+package secret
+
+import (
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+)
+
+func TestRequester(t *testing.T) {
+	in := newStringTestSecret("password", nil, "")
+	require.Equal(t, auditActor, requester(in))
+
+	in.Annotations[AnnotationSecretRegenerate] = "yes"
+	in.ManagedFields = []metav1.ManagedFieldsEntry{
+		{Manager: "helm", FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:metadata":{"f:labels":{}}}`)}},
+		{Manager: "kubectl", FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:metadata":{"f:annotations":{"f:` + AnnotationSecretRegenerate + `":{}}}}`)}},
+	}
+	require.Equal(t, "kubectl", requester(in))
+}
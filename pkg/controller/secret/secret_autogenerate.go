@@ -0,0 +1,49 @@
+package secret
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"sort"
+	"strings"
+)
+
+// AutoGenerateAll is the value of the autogenerate annotation generating every key of the secret whose value is empty
+const AutoGenerateAll = "*"
+
+// autogenerateKeys returns the keys listed in the autogenerate annotation. With AutoGenerateAll, these are all keys
+// of the secret with an empty value or a value generated before, as recorded in the generated-fields annotation.
+// Keys copied from other secrets or rendered from templates are never generated.
+func autogenerateKeys(instance *corev1.Secret) []string {
+	val := instance.Annotations[AnnotationSecretAutoGenerate]
+	if val != AutoGenerateAll {
+		return strings.Split(val, ",")
+	}
+
+	recorded := strings.Split(instance.Annotations[AnnotationSecretGeneratedFields], ",")
+	copied, _ := copySources(instance.Annotations[AnnotationSecretCopyFrom])
+	templates, _ := secretTemplates(instance.Annotations)
+
+	keys := make([]string, 0)
+	for key, value := range instance.Data {
+		if _, ok := copied[key]; ok {
+			continue
+		}
+		if _, ok := templates[key]; ok {
+			continue
+		}
+		if len(value) == 0 || contains(recorded, key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// recordAutogenerated records the generated keys of a secret using AutoGenerateAll, so that they are still
+// regenerated once they have a value
+func recordAutogenerated(instance *corev1.Secret, keys []string) {
+	if instance.Annotations[AnnotationSecretAutoGenerate] != AutoGenerateAll {
+		delete(instance.Annotations, AnnotationSecretGeneratedFields)
+		return
+	}
+	instance.Annotations[AnnotationSecretGeneratedFields] = strings.Join(keys, ",")
+}
@@ -0,0 +1,59 @@
+package secret
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"testing"
+)
+
+func TestAutogenerateKeys(t *testing.T) {
+	instance := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+			AnnotationSecretAutoGenerate:    AutoGenerateAll,
+			AnnotationSecretGeneratedFields: "token",
+			AnnotationSecretCopyFrom:        "other/key",
+		}},
+		Data: map[string][]byte{
+			"password": {},
+			"username": []byte("admin"),
+			"token":    []byte("generated"),
+			"key":      {},
+		},
+	}
+	require.Equal(t, []string{"password", "token"}, autogenerateKeys(instance))
+	require.Equal(t, []string{"password", "token"}, generatedFields(instance))
+
+	instance.Annotations[AnnotationSecretAutoGenerate] = "password,token"
+	require.Equal(t, []string{"password", "token"}, autogenerateKeys(instance))
+}
+
+func TestAutogenerateAllEmptyKeys(t *testing.T) {
+	in := newStringTestSecret(AutoGenerateAll, nil, "")
+	in.Data = map[string][]byte{
+		"password": {},
+		"token":    {},
+		"username": []byte("admin"),
+	}
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), in))
+	doReconcile(t, in, false)
+
+	out := &corev1.Secret{}
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{Name: in.Name, Namespace: in.Namespace}, out))
+	require.Len(t, out.Data["password"], secretLength())
+	require.Len(t, out.Data["token"], secretLength())
+	require.Equal(t, "admin", string(out.Data["username"]))
+	require.Equal(t, "password,token", out.Annotations[AnnotationSecretGeneratedFields])
+
+	// generated keys are still regenerated once they have a value, other keys are kept
+	password := out.Data["password"]
+	out.Annotations[AnnotationSecretRegenerate] = "yes"
+	require.NoError(t, mgr.GetClient().Update(context.TODO(), out))
+	doReconcile(t, out, false)
+
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{Name: in.Name, Namespace: in.Namespace}, out))
+	require.NotEqual(t, password, out.Data["password"])
+	require.Equal(t, "admin", string(out.Data["username"]))
+}
@@ -0,0 +1,229 @@
+package secret
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/fips"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/sops"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/syncer"
+	"io/ioutil"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// BackupKey is the key of the ConfigMap holding the SOPS encrypted backup of a secret
+const BackupKey = "secret.sops.json"
+
+// backupName returns the name of the ConfigMap holding the backup of the named secret
+func backupName(name string) string {
+	return name + "-sops"
+}
+
+// backupDestination stores SOPS encrypted secrets
+type backupDestination interface {
+	write(r *ReconcileSecret, secret *corev1.Secret, doc []byte) error
+}
+
+// sopsBackup writes encrypted copies of generated secrets, it is nil if no destination is configured
+var sopsBackup *backup
+
+type backup struct {
+	recipients  []sops.Recipient
+	destination backupDestination
+}
+
+// SetupSOPSBackup configures the SOPS encrypted backups of generated secrets from the sops-backup-destination
+// and the age, PGP and KMS recipients. Backups are disabled if no destination is configured.
+func SetupSOPSBackup() error {
+	dest := options().GetString("sops-backup-destination")
+	if dest == "" {
+		return nil
+	}
+
+	recipients, err := sops.AgeRecipients(options().GetString("sops-age-recipients"))
+	if err != nil {
+		return err
+	}
+	if len(recipients) > 0 {
+		if err := fips.Disallow("age", "X25519 and ChaCha20-Poly1305 are not approved, use PGP or KMS recipients for SOPS backups"); err != nil {
+			return err
+		}
+	}
+	if keyring := options().GetString("sops-pgp-keyring"); keyring != "" {
+		data, err := ioutil.ReadFile(keyring)
+		if err != nil {
+			return err
+		}
+		pgp, err := sops.PGPRecipients(bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		recipients = append(recipients, pgp...)
+	}
+	for _, keyARN := range strings.Split(options().GetString("sops-kms-arns"), ",") {
+		if keyARN = strings.TrimSpace(keyARN); keyARN == "" {
+			continue
+		}
+		parsed, err := arn.Parse(keyARN)
+		if err != nil {
+			return fmt.Errorf("invalid KMS key ARN %q: %w", keyARN, err)
+		}
+		sess, err := session.NewSession(aws.NewConfig().WithRegion(parsed.Region))
+		if err != nil {
+			return err
+		}
+		recipients = append(recipients, sops.KMSRecipient(kms.New(sess), keyARN))
+	}
+	if len(recipients) == 0 {
+		return fmt.Errorf("sops-backup-destination requires at least one age, PGP or KMS recipient")
+	}
+
+	destination, err := parseBackupDestination(dest)
+	if err != nil {
+		return err
+	}
+	sopsBackup = &backup{recipients: recipients, destination: destination}
+	return nil
+}
+
+// parseBackupDestination parses configmap, file:///<dir> or s3://<bucket>/<prefix>
+func parseBackupDestination(dest string) (backupDestination, error) {
+	if dest == "configmap" {
+		return configMapBackup{}, nil
+	}
+
+	u, err := url.Parse(dest)
+	if err != nil {
+		return nil, fmt.Errorf("invalid backup destination %q: %w", dest, err)
+	}
+	switch u.Scheme {
+	case "file":
+		if u.Path == "" {
+			return nil, fmt.Errorf("backup destination %q has no directory", dest)
+		}
+		return fileBackup{dir: u.Path}, nil
+	case "s3":
+		sess, err := session.NewSessionWithOptions(session.Options{
+			Config:            *aws.NewConfig().WithRegion(options().GetString("aws-region")),
+			SharedConfigState: session.SharedConfigEnable,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return s3Backup{client: s3.New(sess), bucket: u.Host, prefix: strings.TrimPrefix(u.Path, "/")}, nil
+	}
+	return nil, fmt.Errorf("invalid backup destination %q, must be configmap, file:///<dir> or s3://<bucket>/<prefix>", dest)
+}
+
+// backupSOPS writes a SOPS encrypted copy of desired to the backup destination, if its data changed since
+// the last backup. The backup is written before the secret is updated, so that no generated values are lost.
+func (r *ReconcileSecret) backupSOPS(desired *corev1.Secret) error {
+	if sopsBackup == nil {
+		return nil
+	}
+
+	checksum := syncer.Checksum(desired.Data)
+	if desired.Annotations[AnnotationSecretBackupChecksum] == checksum {
+		return nil
+	}
+
+	doc, err := sops.Encrypt(desired, sopsBackup.recipients, time.Now())
+	if err != nil {
+		return err
+	}
+	if err := sopsBackup.destination.write(r, desired, doc); err != nil {
+		return err
+	}
+
+	desired.Annotations[AnnotationSecretBackupChecksum] = checksum
+	return nil
+}
+
+// configMapBackup stores backups in ConfigMaps next to the secrets. They are not owned by the secret, so
+// that they are kept if the secret is deleted.
+type configMapBackup struct{}
+
+func (configMapBackup) write(r *ReconcileSecret, secret *corev1.Secret, doc []byte) error {
+	cm := &corev1.ConfigMap{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: secret.Namespace, Name: backupName(secret.Name)}, cm)
+	if errors.IsNotFound(err) {
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   secret.Namespace,
+				Name:        backupName(secret.Name),
+				Annotations: map[string]string{AnnotationSecretBackupOf: secret.Name},
+			},
+			Data: map[string]string{BackupKey: string(doc)},
+		}
+		return r.client.Create(context.TODO(), cm)
+	}
+	if err != nil {
+		return err
+	}
+
+	if cm.Annotations[AnnotationSecretBackupOf] != secret.Name {
+		return fmt.Errorf("ConfigMap %s already exists and is not a backup of this secret", cm.Name)
+	}
+	cm.Data = map[string]string{BackupKey: string(doc)}
+	return r.client.Update(context.TODO(), cm)
+}
+
+// fileBackup stores backups as <dir>/<namespace>/<name>.sops.json, e.g. on a persistent volume
+type fileBackup struct {
+	dir string
+}
+
+func (b fileBackup) write(_ *ReconcileSecret, secret *corev1.Secret, doc []byte) error {
+	dir := filepath.Join(b.dir, secret.Namespace)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	// replace the previous backup atomically
+	tmp, err := ioutil.TempFile(dir, "."+secret.Name)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(doc); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(dir, secret.Name+".sops.json"))
+}
+
+// s3Backup stores backups as <prefix>/<namespace>/<name>.sops.json in an S3 bucket. Enable versioning of the
+// bucket to keep the values of previous rotations.
+type s3Backup struct {
+	client s3iface.S3API
+	bucket string
+	prefix string
+}
+
+func (b s3Backup) write(_ *ReconcileSecret, secret *corev1.Secret, doc []byte) error {
+	_, err := b.client.PutObject(&s3.PutObjectInput{
+		Bucket:      aws.String(b.bucket),
+		Key:         aws.String(path.Join(b.prefix, secret.Namespace, secret.Name+".sops.json")),
+		Body:        bytes.NewReader(doc),
+		ContentType: aws.String("application/json"),
+	})
+	return err
+}
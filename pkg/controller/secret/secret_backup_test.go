@@ -0,0 +1,60 @@
+package secret
+
+import (
+	"filippo.io/age"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/sops"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBackupSOPSWritesEncryptedFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "backup")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	recipients, err := sops.AgeRecipients(identity.Recipient().String())
+	require.NoError(t, err)
+
+	sopsBackup = &backup{recipients: recipients, destination: fileBackup{dir: dir}}
+	defer func() { sopsBackup = nil }()
+
+	desired := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "billing", Name: "db", Annotations: map[string]string{}},
+		Data:       map[string][]byte{"password": []byte("secret")},
+	}
+	r := &ReconcileSecret{}
+	require.NoError(t, r.backupSOPS(desired))
+
+	file := filepath.Join(dir, "billing", "db.sops.json")
+	doc, err := ioutil.ReadFile(file)
+	require.NoError(t, err)
+	require.Contains(t, string(doc), identity.Recipient().String())
+	require.NotContains(t, string(doc), "c2VjcmV0")
+	require.NotEmpty(t, desired.Annotations[AnnotationSecretBackupChecksum])
+
+	// unchanged values are not backed up again
+	require.NoError(t, os.Remove(file))
+	require.NoError(t, r.backupSOPS(desired))
+	_, err = os.Stat(file)
+	require.True(t, os.IsNotExist(err))
+}
+
+func TestParseBackupDestination(t *testing.T) {
+	dest, err := parseBackupDestination("file:///var/backups")
+	require.NoError(t, err)
+	require.Equal(t, fileBackup{dir: "/var/backups"}, dest)
+
+	dest, err = parseBackupDestination("configmap")
+	require.NoError(t, err)
+	require.Equal(t, configMapBackup{}, dest)
+
+	_, err = parseBackupDestination("ftp://backups")
+	require.Error(t, err)
+}
@@ -0,0 +1,141 @@
+package secret
+
+import (
+	"fmt"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// maxDrawAttemptsLimit bounds the draw-attempts annotation
+const maxDrawAttemptsLimit = 1000
+
+// stringGenerator returns the StringGenerator for the instance, using its password policy and master key,
+// and the checks and number of draw attempts configured by its annotations
+func (r *ReconcileSecret) stringGenerator(log logr.Logger, instance *corev1.Secret, defaults generatorDefaults) (StringGenerator, error) {
+	policy, err := r.passwordPolicy(instance)
+	if err != nil {
+		return StringGenerator{}, err
+	}
+	derivation, err := r.derivation(instance)
+	if err != nil {
+		return StringGenerator{}, err
+	}
+	checks, err := valueChecks(instance)
+	if err != nil {
+		return StringGenerator{}, err
+	}
+	attempts, err := drawAttempts(instance.Annotations)
+	if err != nil {
+		return StringGenerator{}, err
+	}
+
+	pg := newStringGenerator(log, defaults, policy)
+	pg.derivation = derivation
+	pg.checks = checks
+	pg.attempts = attempts
+	return pg, nil
+}
+
+// valueChecks returns the checks generated string values of the instance have to pass
+func valueChecks(instance *corev1.Secret) ([]valueCheck, error) {
+	var checks []valueCheck
+
+	if val, ok := instance.Annotations[AnnotationSecretMustMatch]; ok {
+		patterns, err := mustMatchPatterns(val)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range patterns {
+			checks = append(checks, checkPattern(p))
+		}
+	}
+
+	if substrings := forbiddenSubstrings(); len(substrings) > 0 {
+		checks = append(checks, checkForbidden(substrings))
+	}
+
+	if instance.Annotations[AnnotationSecretPwnedCheck] == "true" {
+		if pwnedChecker == nil {
+			return nil, fmt.Errorf("%s requires the hibp-source to be configured", AnnotationSecretPwnedCheck)
+		}
+		checks = append(checks, checkPwned)
+	}
+
+	return checks, nil
+}
+
+// mustMatchPatterns compiles the patterns of the must-match annotation, one per line
+func mustMatchPatterns(val string) ([]*regexp.Regexp, error) {
+	var patterns []*regexp.Regexp
+	for _, line := range strings.Split(val, "\n") {
+		if line = strings.TrimSpace(line); line == "" {
+			continue
+		}
+		p, err := regexp.Compile(line)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s annotation: %w", AnnotationSecretMustMatch, err)
+		}
+		patterns = append(patterns, p)
+	}
+	if len(patterns) == 0 {
+		return nil, fmt.Errorf("%s annotation contains no pattern", AnnotationSecretMustMatch)
+	}
+	return patterns, nil
+}
+
+// checkPattern rejects values that don't match p
+func checkPattern(p *regexp.Regexp) valueCheck {
+	return func(value string) (string, error) {
+		if !p.MatchString(value) {
+			return fmt.Sprintf("doesn't match %s", p), nil
+		}
+		return "", nil
+	}
+}
+
+// drawAttempts returns the number of values drawn for a field until one passes the checks, from the
+// draw-attempts annotation or maxDrawAttempts
+func drawAttempts(annotations map[string]string) (int, error) {
+	val, ok := annotations[AnnotationSecretDrawAttempts]
+	if !ok {
+		return maxDrawAttempts, nil
+	}
+	attempts, err := strconv.Atoi(val)
+	if err != nil || attempts <= 0 || attempts > maxDrawAttemptsLimit {
+		return 0, fmt.Errorf("%s must be an integer between 1 and %d, got %q", AnnotationSecretDrawAttempts, maxDrawAttemptsLimit, val)
+	}
+	return attempts, nil
+}
+
+// forbiddenSubstrings returns the lower-cased substrings generated values must not contain, from the
+// forbidden-substrings option and file
+func forbiddenSubstrings() []string {
+	var substrings []string
+	if s, ok := config.Load().(*configSnapshot); ok {
+		// copy, so that concurrent workers don't append to the same backing array
+		substrings = append(substrings, s.forbidden...)
+	}
+	for _, s := range strings.Split(options().GetString("forbidden-substrings"), ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			substrings = append(substrings, strings.ToLower(s))
+		}
+	}
+	return substrings
+}
+
+// checkForbidden rejects values containing any of the substrings, ignoring case. The reason doesn't name
+// the substring, as it would reveal part of the value.
+func checkForbidden(substrings []string) valueCheck {
+	return func(value string) (string, error) {
+		value = strings.ToLower(value)
+		for _, s := range substrings {
+			if strings.Contains(value, s) {
+				return "contains a forbidden substring", nil
+			}
+		}
+		return "", nil
+	}
+}
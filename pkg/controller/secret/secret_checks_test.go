@@ -0,0 +1,68 @@
+package secret
+
+import (
+	"context"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"regexp"
+	"testing"
+)
+
+func TestValuesMatchPatterns(t *testing.T) {
+	in := newStringTestSecret("password", map[string]string{
+		AnnotationSecretMustMatch:    "^[A-Z]\n[0-9]",
+		AnnotationSecretDrawAttempts: "500",
+	}, "")
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), in))
+	doReconcile(t, in, false)
+
+	out := &corev1.Secret{}
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{Name: in.Name, Namespace: in.Namespace}, out))
+	require.Regexp(t, regexp.MustCompile("^[A-Z]"), string(out.Data["password"]))
+	require.Regexp(t, regexp.MustCompile("[0-9]"), string(out.Data["password"]))
+}
+
+func TestUnmatchablePatternFailsGeneration(t *testing.T) {
+	in := newStringTestSecret("password", map[string]string{
+		AnnotationSecretMustMatch:    "^$",
+		AnnotationSecretDrawAttempts: "3",
+	}, "")
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), in))
+	doReconcile(t, in, true)
+}
+
+func TestDrawAttempts(t *testing.T) {
+	attempts, err := drawAttempts(map[string]string{})
+	require.NoError(t, err)
+	require.Equal(t, maxDrawAttempts, attempts)
+
+	attempts, err = drawAttempts(map[string]string{AnnotationSecretDrawAttempts: "50"})
+	require.NoError(t, err)
+	require.Equal(t, 50, attempts)
+
+	for _, val := range []string{"0", "-1", "abc", "100000"} {
+		_, err := drawAttempts(map[string]string{AnnotationSecretDrawAttempts: val})
+		require.Error(t, err, val)
+	}
+}
+
+func TestForbiddenSubstrings(t *testing.T) {
+	viper.Set("forbidden-substrings", "Mittwald, password")
+	defer viper.Set("forbidden-substrings", "")
+
+	checks, err := valueChecks(&corev1.Secret{})
+	require.NoError(t, err)
+	require.Len(t, checks, 1)
+
+	for _, value := range []string{"xxMITTWALDxx", "myPassword1"} {
+		reason, err := checks[0](value)
+		require.NoError(t, err)
+		require.NotEmpty(t, reason, value)
+		require.NotContains(t, reason, "password")
+	}
+	reason, err := checks[0]("aB3dE5gH7j")
+	require.NoError(t, err)
+	require.Empty(t, reason)
+}
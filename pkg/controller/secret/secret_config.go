@@ -0,0 +1,61 @@
+package secret
+
+import (
+	"github.com/spf13/viper"
+	"io/ioutil"
+	"strings"
+	"sync/atomic"
+)
+
+// configSnapshot is a validated configuration published by PublishConfig. It is never modified after
+// publishing, so workers can read it while a reloaded configuration is being published.
+type configSnapshot struct {
+	options   *viper.Viper
+	forbidden []string
+}
+
+// config holds the current *configSnapshot
+var config atomic.Value
+
+// options returns the options of the published configuration, or the global viper instance if no
+// configuration has been published, e.g. in tests
+func options() *viper.Viper {
+	if s, ok := config.Load().(*configSnapshot); ok {
+		return s.options
+	}
+	return viper.GetViper()
+}
+
+// PublishConfig makes the validated options the configuration of the controller, including the substrings of
+// their forbidden-substrings-file. The options must not be modified afterwards. If the file can't be read, the
+// current configuration is kept.
+func PublishConfig(v *viper.Viper) error {
+	forbidden, err := readForbiddenSubstrings(v.GetString("forbidden-substrings-file"))
+	if err != nil {
+		return err
+	}
+
+	config.Store(&configSnapshot{options: v, forbidden: forbidden})
+	return nil
+}
+
+// readForbiddenSubstrings reads the file containing one forbidden substring per line, if path is set.
+// Empty lines and lines starting with # are ignored.
+func readForbiddenSubstrings(path string) ([]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var substrings []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			substrings = append(substrings, strings.ToLower(line))
+		}
+	}
+	return substrings, nil
+}
@@ -0,0 +1,29 @@
+package secret
+
+import (
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadForbiddenSubstrings(t *testing.T) {
+	dir, err := ioutil.TempDir("", "forbidden")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "forbidden.txt")
+	require.NoError(t, ioutil.WriteFile(path, []byte("# company\nMittwald\n\n  password  \n"), 0600))
+
+	substrings, err := readForbiddenSubstrings(path)
+	require.NoError(t, err)
+	require.Equal(t, []string{"mittwald", "password"}, substrings)
+
+	substrings, err = readForbiddenSubstrings("")
+	require.NoError(t, err)
+	require.Empty(t, substrings)
+
+	_, err = readForbiddenSubstrings(filepath.Join(dir, "missing.txt"))
+	require.Error(t, err)
+}
@@ -2,10 +2,19 @@ package secret
 
 import (
 	"context"
-	"github.com/spf13/viper"
+	"fmt"
+	"github.com/go-logr/logr"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/hook"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/recovery"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/resilience"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/sharding"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/shutdown"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/syncer"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/tracing"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	"reflect"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
@@ -14,45 +23,205 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 	"strconv"
+	"strings"
 	"time"
 )
 
 var log = logf.Log.WithName("controller_secret")
 
 func regenerateInsecure() bool {
-	return viper.GetBool("regenerate-insecure")
+	return options().GetBool("regenerate-insecure")
 }
 
 func secretLength() int {
-	return viper.GetInt("secret-length")
+	return options().GetInt("secret-length")
+}
+
+func secretCharset() string {
+	return options().GetString("secret-charset")
+}
+
+func defaultRotateAfter() time.Duration {
+	return options().GetDuration("default-rotate-after")
 }
 
 func sshKeyLength() int {
-	return viper.GetInt("ssh-key-length")
+	return options().GetInt("ssh-key-length")
+}
+
+func policyMinLength() int {
+	return options().GetInt("policy-min-length")
+}
+
+func policyMaxLength() int {
+	return options().GetInt("policy-max-length")
+}
+
+func workers() int {
+	if w := options().GetInt("workers"); w > 0 {
+		return w
+	}
+	return 1
+}
+
+func mutatingWebhookEnabled() bool {
+	return options().GetBool("enable-mutating-webhook")
+}
+
+func validatingWebhookEnabled() bool {
+	return options().GetBool("enable-validating-webhook")
+}
+
+func preRotationHookURL() string {
+	return options().GetString("pre-rotation-hook-url")
+}
+
+func postRotationHookURLs() []string {
+	var urls []string
+	for _, url := range strings.Split(options().GetString("post-rotation-hook-urls"), ",") {
+		if url = strings.TrimSpace(url); url != "" {
+			urls = append(urls, url)
+		}
+	}
+	return urls
+}
+
+func rotationCooldown() time.Duration {
+	return options().GetDuration("rotation-cooldown")
+}
+
+func maxRegenerationsPerDay() int {
+	return options().GetInt("max-regenerations-per-day")
+}
+
+func rotationHistoryLimit() int {
+	return options().GetInt("rotation-history-limit")
+}
+
+func cacheUnmanagedSecretData() bool {
+	return options().GetBool("cache-unmanaged-secret-data") && !metadataOnlyWatch()
+}
+
+func metadataOnlyWatch() bool {
+	return options().GetBool("metadata-only-watch")
+}
+
+func serverSideApply() bool {
+	return options().GetBool("server-side-apply")
+}
+
+func regenerateTampered() bool {
+	return options().GetBool("regenerate-tampered")
+}
+
+func remediatePolicyDrift() bool {
+	return options().GetBool("remediate-policy-drift")
+}
+
+func expiryWarning() time.Duration {
+	return options().GetDuration("expiry-warning")
+}
+
+func hookTimeout() time.Duration {
+	return options().GetDuration("hook-timeout")
 }
 
 // Add creates a new Secret Controller and adds it to the Manager. The Manager will set fields on the Controller
 // and Start it when the Manager is Started.
 func Add(mgr manager.Manager) error {
-	return add(mgr, newReconciler(mgr))
+	r := newReconciler(mgr)
+
+	if mutatingWebhookEnabled() {
+		mgr.GetWebhookServer().Register(MutatingWebhookPath, &webhook.Admission{Handler: &SecretMutator{reconciler: r}})
+	}
+	if validatingWebhookEnabled() {
+		mgr.GetWebhookServer().Register(ValidatingWebhookPath, &webhook.Admission{Handler: &SecretValidator{}})
+	}
+	if generationAPIBindAddress() != "" {
+		api, err := newGenerationAPI(mgr)
+		if err != nil {
+			return err
+		}
+		if err := mgr.Add(api); err != nil {
+			return err
+		}
+	}
+
+	return add(mgr, r)
 }
 
-// newReconciler returns a new reconcile.Reconciler
-func newReconciler(mgr manager.Manager) reconcile.Reconciler {
-	return &ReconcileSecret{client: mgr.GetClient(), scheme: mgr.GetScheme()}
+// newReconciler returns a new ReconcileSecret
+func newReconciler(mgr manager.Manager) *ReconcileSecret {
+	return &ReconcileSecret{
+		client:   resilience.Client(mgr.GetClient()),
+		reader:   resilience.Reader(mgr.GetAPIReader()),
+		scheme:   mgr.GetScheme(),
+		recorder: mgr.GetEventRecorderFor("secret-generator"),
+		throttle: newStartupThrottle(),
+	}
 }
 
 // add adds a new Controller to mgr with r as the reconcile.Reconciler
 func add(mgr manager.Manager, r reconcile.Reconciler) error {
+	// Report panics of a single reconcile instead of crashing the controller
+	r = recovery.Recover("secret-controller", corev1.SchemeGroupVersion.WithKind("Secret"), mgr.GetEventRecorderFor("secret-generator"), r)
+
 	// Create a new controller
-	c, err := controller.New("secret-controller", mgr, controller.Options{Reconciler: r})
+	// The workqueue never hands the same secret to more than one worker at a time
+	c, err := controller.New("secret-controller", mgr, controller.Options{
+		Reconciler:              shutdown.Track(resilience.Guard(sharding.Filter(r))),
+		MaxConcurrentReconciles: workers(),
+	})
 	if err != nil {
 		return err
 	}
 
 	// Watch for changes to primary resource Secret
-	err = c.Watch(&source.Kind{Type: &corev1.Secret{}}, &handler.EnqueueRequestForObject{})
+	err = c.Watch(&source.Kind{Type: &corev1.Secret{}}, &handler.EnqueueRequestForObject{}, namespacePredicate(), secretTypePredicate())
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to secrets referenced by templates
+	err = c.Watch(&source.Kind{Type: &corev1.Secret{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: referencingSecrets(mgr.GetClient()),
+	}, namespacePredicate())
+	if err != nil {
+		return err
+	}
+
+	// Revert changes to the copies of replicated secrets
+	err = c.Watch(&source.Kind{Type: &corev1.Secret{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(replicaSource),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Replicate secrets to namespaces which are created or labelled after them
+	err = c.Watch(&source.Kind{Type: &corev1.Namespace{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: replicatingSecrets(mgr.GetClient()),
+	}, namespaceCreatedOrRelabeledPredicate())
+	if err != nil {
+		return err
+	}
+
+	// Queue the secrets of forced resyncs
+	err = c.Watch(&source.Channel{Source: resyncChannel(mgr)}, &handler.EnqueueRequestForObject{}, namespacePredicate())
+	if err != nil {
+		return err
+	}
+
+	if _, err := namespaceSelector(); err != nil {
+		return err
+	}
+
+	// Process the secrets of namespaces which are labelled or annotated after their secrets were created
+	err = c.Watch(&source.Kind{Type: &corev1.Namespace{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: secretsInNamespace(mgr.GetClient()),
+	}, namespaceChangedPredicate())
 	if err != nil {
 		return err
 	}
@@ -68,7 +237,23 @@ type ReconcileSecret struct {
 	// This client, initialized using mgr.Client() above, is a split client
 	// that reads objects from the cache and writes to the apiserver
 	client client.Client
-	scheme *runtime.Scheme
+	// reader reads directly from the apiserver, e.g. secrets whose data isn't cached
+	reader   client.Reader
+	scheme   *runtime.Scheme
+	recorder record.EventRecorder
+	throttle *startupThrottle
+}
+
+// NeedsData reports whether the controller needs the data of a secret, i.e. whether it carries any of the
+// secret generator's annotations. With cache-unmanaged-secret-data disabled, the data of other secrets
+// isn't cached. With metadata-only-watch enabled, only these secrets are read in full.
+func NeedsData(instance *corev1.Secret) bool {
+	for key := range instance.Annotations {
+		if strings.HasPrefix(key, AnnotationPrefix) {
+			return true
+		}
+	}
+	return false
 }
 
 // Reconcile reads that state of the cluster for a Secret object and makes changes based on the state read
@@ -77,8 +262,22 @@ type ReconcileSecret struct {
 // The Controller will requeue the Request to be processed again if the returned error is non-nil or
 // Result.Requeue is true, otherwise upon completion it will remove the work from the queue.
 func (r *ReconcileSecret) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	ctx, span := tracing.StartReconcile(shutdown.Context(), "secret-controller", request)
+	res, err := r.withContext(ctx).reconcileSecret(request)
+	tracing.End(span, err)
+	return res, err
+}
+
+// withContext returns a copy of the reconciler whose API calls are traced as children of the span in ctx
+func (r *ReconcileSecret) withContext(ctx context.Context) *ReconcileSecret {
+	traced := *r
+	traced.client = tracing.Client(ctx, r.client)
+	traced.reader = tracing.Reader(ctx, r.reader)
+	return &traced
+}
+
+func (r *ReconcileSecret) reconcileSecret(request reconcile.Request) (reconcile.Result, error) {
 	reqLogger := log.WithValues("Request.Namespace", request.Namespace, "Request.Name", request.Name)
-	reqLogger.Info("Reconciling Secret")
 
 	// Fetch the Secret instance
 	instance := &corev1.Secret{}
@@ -88,19 +287,409 @@ func (r *ReconcileSecret) Reconcile(request reconcile.Request) (reconcile.Result
 			// Request object not found, could have been deleted after reconcile request.
 			// Owned objects are automatically garbage collected. For additional cleanup logic use finalizers.
 			// Return and don't requeue
+			reqLogger.V(1).Info("Secret not found")
 			return reconcile.Result{}, nil
 		}
 		// Error reading the object - requeue the request.
 		return reconcile.Result{}, err
 	}
 
+	reqLogger = debugLogger(reqLogger, instance)
+	reqLogger.V(1).Info("Reconciling Secret")
+
+	if !instance.DeletionTimestamp.IsZero() {
+		if err := r.finalizeExternalSync(instance); err != nil {
+			reqLogger.Error(err, "could not clean up external copies of secret")
+			return reconcile.Result{}, err
+		}
+		if err := r.finalizeReplication(reqLogger, instance); err != nil {
+			reqLogger.Error(err, "could not delete copies of secret")
+			return reconcile.Result{}, err
+		}
+		return reconcile.Result{}, nil
+	}
+
+	paused, err := r.paused()
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if paused {
+		// the watch stays alive, secrets are checked again until the controller is resumed
+		reqLogger.Info("controller is paused, skipping secret")
+		return reconcile.Result{RequeueAfter: pausedRequeueInterval}, nil
+	}
+
+	skip, err := r.skipReason(instance)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if skip != "" {
+		reqLogger.Info("skipping secret", "reason", skip)
+		if IsManaged(instance.Annotations) {
+			r.recorder.Event(instance, corev1.EventTypeNormal, "Skipped", skip)
+		}
+		return reconcile.Result{}, nil
+	}
+
+	if _, ok := instance.Annotations[AnnotationSecretShamirTargets]; ok {
+		return r.reconcileShamir(reqLogger, instance)
+	}
+
+	if deleted, err := r.enforceExpiry(reqLogger, instance); err != nil || deleted {
+		if err != nil {
+			reqLogger.Error(err, "could not delete expired secret")
+		}
+		return reconcile.Result{}, err
+	}
+
+	defaults, err := r.defaults(instance.Namespace)
+	if err != nil {
+		reqLogger.Error(err, "could not get namespace defaults")
+		return reconcile.Result{}, err
+	}
+
 	desired := instance.DeepCopy()
+	reason := rotationReason(instance, defaults.rotateAfter, time.Now())
+
+	managed, res, err := r.generate(reqLogger, desired)
+	if err != nil && len(instance.Data) > 0 && (reason == hook.ReasonRequested || reason == hook.ReasonBulk || reason == hook.ReasonScheduled || reason == hook.ReasonExpired) {
+		r.notifyRotationFailure(reqLogger, instance, reason, err)
+	}
+	if err != nil {
+		// the request is requeued by the rate-limited workqueue with exponential backoff
+		r.recorder.Eventf(instance, corev1.EventTypeWarning, "GenerationFailed", "could not generate values: %s", err)
+		return res, err
+	}
+	if !managed {
+		return res, nil
+	}
+
+	if DryRun() {
+		r.reportDryRun(reqLogger, instance, desired)
+		return res, nil
+	}
+
+	if err := syncExternal(desired); err != nil {
+		reqLogger.Error(err, "could not sync secret to external stores")
+		return reconcile.Result{}, err
+	}
+
+	setReplicationFinalizer(desired)
+
+	if err := r.updateSealed(desired); err != nil {
+		reqLogger.Error(err, "could not seal secret")
+		r.recorder.Eventf(instance, corev1.EventTypeWarning, "SealingFailed", "could not seal secret: %s", err)
+		return reconcile.Result{}, err
+	}
+
+	if err := r.backupSOPS(desired); err != nil {
+		reqLogger.Error(err, "could not back up secret")
+		r.recorder.Eventf(instance, corev1.EventTypeWarning, "BackupFailed", "could not write encrypted backup: %s", err)
+		return reconcile.Result{}, err
+	}
+
+	if isImmutable(desired.Annotations) {
+		if err := r.publishVersion(reqLogger, desired); err != nil {
+			reqLogger.Error(err, "could not publish immutable version of secret")
+			r.recorder.Eventf(instance, corev1.EventTypeWarning, "PublishFailed", "could not publish immutable version of secret: %s", err)
+			return reconcile.Result{}, err
+		}
+	}
+
+	if !reflect.DeepEqual(instance.Annotations, desired.Annotations) ||
+		!reflect.DeepEqual(instance.Data, desired.Data) ||
+		!reflect.DeepEqual(instance.Finalizers, desired.Finalizers) {
+		reqLogger.Info("updating secret")
+
+		if valuesGenerated(instance, desired) {
+			desired.Annotations[AnnotationSecretAutoGeneratedAt] = time.Now().Format(time.RFC3339)
+		}
+		if err := r.backupReplacedValues(reqLogger, instance, desired); err != nil {
+			reqLogger.Error(err, "could not back up replaced values")
+			r.recorder.Eventf(instance, corev1.EventTypeWarning, "BackupFailed", "could not store replaced values in history secret: %s", err)
+			return reconcile.Result{}, err
+		}
+		err := r.updateSecret(instance, desired)
+		if err != nil {
+			reqLogger.Error(err, "could not update secret")
+			r.recorder.Eventf(instance, corev1.EventTypeWarning, "UpdateFailed", "could not update secret: %s", err)
+			return reconcile.Result{Requeue: true}, err
+		}
+		r.reportChanges(instance, desired, reason)
+		auditChanges(reqLogger, instance, desired, reason)
+
+		replaced := replacedKeys(instance.Data, desired.Data)
+		if len(replaced) > 0 {
+			event := rotationEvent(desired, reason)
+			event.Fields = replaced
+			notifyRotation(reqLogger, event, nil)
+		}
+
+		if _, ok := desired.Annotations[AnnotationSecretRestartOnRotation]; ok && len(replaced) > 0 {
+			if err := r.restartWorkloads(reqLogger, desired); err != nil {
+				reqLogger.Error(err, "could not restart workloads using secret")
+				return res, err
+			}
+		}
+	}
+
+	if err := r.updateShadow(desired); err != nil {
+		reqLogger.Error(err, "could not update shadow secret")
+		return res, err
+	}
+
+	if replicates(desired.Annotations) || contains(instance.Finalizers, FinalizerReplication) {
+		if err := r.replicate(reqLogger, desired); err != nil {
+			reqLogger.Error(err, "could not replicate secret")
+			return res, err
+		}
+	}
+
+	if _, ok := desired.Annotations[AnnotationSecretPropagateChecksum]; ok {
+		if err := r.propagateChecksum(reqLogger, desired); err != nil {
+			reqLogger.Error(err, "could not propagate checksum to workloads using secret")
+			return res, err
+		}
+	}
+
+	// requeue for upcoming rotations and expiring values
+	return res, nil
+}
+
+// skipReason returns why the values of instance are not generated, e.g. because its namespace is disabled or it
+// is controlled by another operator, or an empty string if they are. It applies to the controller and the
+// mutating webhook alike.
+func (r *ReconcileSecret) skipReason(instance *corev1.Secret) (string, error) {
+	if IsIgnored(instance.Annotations) {
+		return fmt.Sprintf("secret has the %s annotation", AnnotationSecretIgnore), nil
+	}
+
+	if typeIgnored(instance.Type) {
+		return fmt.Sprintf("secrets of type %s are ignored", instance.Type), nil
+	}
+
+	enabled, err := r.namespaceEnabled(instance.Namespace)
+	if err != nil {
+		return "", err
+	}
+	if !enabled {
+		return fmt.Sprintf("secret generation is disabled for namespace %s", instance.Namespace), nil
+	}
+
+	if owner := foreignController(instance); owner != nil && IsManaged(instance.Annotations) {
+		return fmt.Sprintf("secret is controlled by %s %s, set the %s annotation to generate values anyway",
+			owner.Kind, owner.Name, AnnotationSecretForce), nil
+	}
+	return "", nil
+}
+
+// generate fills in all values of the secret that have to be generated and renders its templates.
+// It returns false if the secret is not managed by the secret generator or must not be changed now.
+func (r *ReconcileSecret) generate(reqLogger logr.Logger, desired *corev1.Secret) (bool, reconcile.Result, error) {
+	if !IsManaged(desired.Annotations) || IsIgnored(desired.Annotations) {
+		reqLogger.V(1).Info("secret has no generator annotations")
+		return false, reconcile.Result{}, nil
+	}
+
+	if _, requested := desired.Annotations[AnnotationSecretRegenerate]; requested && derives(desired.Annotations) {
+		// deriving the values again would result in the same values, so regenerating them would only pretend to.
+		// Regeneration requested below, e.g. to restore tampered values, does change them.
+		reqLogger.Info("dropping regeneration of derived values")
+		r.recorder.Eventf(desired, corev1.EventTypeWarning, "RegenerationIgnored",
+			"derived values don't change when regenerated, change what they are derived from and remove the fields instead")
+		delete(desired.Annotations, AnnotationSecretRegenerate)
+	}
+
+	if err := r.restoreFromShadow(reqLogger, desired); err != nil {
+		reqLogger.Error(err, "could not restore values from shadow secret")
+		return true, reconcile.Result{}, err
+	}
+
+	r.detectTampering(reqLogger, desired)
+	if err := r.remediatePolicyDrift(reqLogger, desired); err != nil {
+		reqLogger.Error(err, "could not check values against the current policy")
+		return true, reconcile.Result{}, err
+	}
+
+	defaults, err := r.defaults(desired.Namespace)
+	if err != nil {
+		reqLogger.Error(err, "could not get namespace defaults")
+		return true, reconcile.Result{}, err
+	}
+
+	now := time.Now()
+
+	rotate, nextRotation, rotationErr := rotationDue(desired, defaults.rotateAfter, now)
+	expired, untilExpiry, expiryErr := expiryDue(desired, now)
+	if expired && expiryPolicy(desired) == ExpiryPolicyRotate {
+		rotate = true
+	}
+	if rotationErr == nil {
+		rotationErr = expiryErr
+	}
+	if derives(desired.Annotations) {
+		// deriving the values again would result in the same values, so rotating them would only pretend to
+		rotate, nextRotation = false, 0
+	}
+	reason := rotationReason(desired, defaults.rotateAfter, now)
+	reqLogger.V(1).Info("checked rotation", "due", rotate, "nextRotation", nextRotation.String(), "expired", expired, "reason", reason)
+
+	if _, requested := desired.Annotations[AnnotationSecretRegenerate]; (rotate || requested) && len(desired.Data) > 0 {
+		wait, err := cooldownRemaining(desired, now)
+		if err != nil {
+			reqLogger.Error(err, "could not determine rotation cooldown")
+			return true, reconcile.Result{}, err
+		}
+		if wait > 0 {
+			reqLogger.Info("rotation is postponed until cooldown has passed", "remaining", wait.String())
+			return false, reconcile.Result{RequeueAfter: wait}, nil
+		}
+
+		if max := maxRegenerationsPerDay(); max > 0 && regenerationsToday(desired, now) >= max {
+			reqLogger.Info("regeneration budget exceeded, postponing rotation until tomorrow")
+			r.recorder.Eventf(desired, corev1.EventTypeWarning, "RegenerationBudgetExceeded",
+				"secret has been regenerated %d times today, postponing regeneration until tomorrow", max)
+			return false, reconcile.Result{RequeueAfter: untilTomorrow(now)}, nil
+		}
+
+		allowed, err := rotationAllowed(desired, reason)
+		if err != nil {
+			reqLogger.Error(err, "could not call pre-rotation hook")
+			return true, reconcile.Result{}, err
+		}
+		if !allowed {
+			reqLogger.Info("rotation vetoed by pre-rotation hook, retrying later")
+			return false, reconcile.Result{RequeueAfter: time.Minute}, nil
+		}
+	}
+
+	if rotate {
+		reqLogger.Info("values are due for rotation")
+		desired.Annotations[AnnotationSecretRegenerate] = "yes"
+	}
+
+	_, regenerate := desired.Annotations[AnnotationSecretRegenerate]
+	previous := make(map[string][]byte, len(desired.Data))
+	for key, value := range desired.Data {
+		previous[key] = value
+	}
+
+	rolledBack, err := r.rollback(reqLogger, desired)
+	if err != nil {
+		reqLogger.Error(err, "could not roll back values")
+		return true, reconcile.Result{}, err
+	}
+
+	managed, res, err := r.generateValues(reqLogger, desired)
+	if err != nil || !managed {
+		return managed, res, err
+	}
+	if _, ok := desired.Annotations[AnnotationSecretRegenerate]; !ok {
+		delete(desired.Annotations, AnnotationSecretRequestedBy)
+	}
+
+	if rotationErr != nil {
+		reqLogger.Error(rotationErr, "could not determine rotation")
+		return true, reconcile.Result{}, rotationErr
+	}
+	requeueAfter(&res, nextRotation)
+	requeueAfter(&res, untilExpiry)
+	requeueAfter(&res, untilExpiry-expiryWarning())
+
+	if regenerate {
+		if err := stageValues(desired, previous, now); err != nil {
+			reqLogger.Error(err, "could not stage values")
+			return true, reconcile.Result{}, err
+		}
+	}
+	promoted, nextPromotion, err := promoteStaged(desired, now)
+	if err != nil {
+		reqLogger.Error(err, "could not promote staged values")
+		return true, reconcile.Result{}, err
+	}
+	requeueAfter(&res, nextPromotion)
+
+	if err := r.copyValues(desired); err != nil {
+		reqLogger.Error(err, "could not copy values")
+		return true, reconcile.Result{}, err
+	}
+
+	if err := r.renderTemplates(desired); err != nil {
+		reqLogger.Error(err, "could not render templates")
+		return true, reconcile.Result{}, err
+	}
+
+	if regenerate || promoted {
+		if err := keepPrevious(desired, previous, now); err != nil {
+			reqLogger.Error(err, "could not keep previous values")
+			return true, reconcile.Result{}, err
+		}
+	}
+	requeueAfter(&res, expirePrevious(desired, now))
+
+	if regenerate && len(previous) > 0 {
+		desired.Annotations[AnnotationSecretRotatedAt] = now.Format(time.RFC3339)
+		if maxRegenerationsPerDay() > 0 {
+			countRegeneration(desired, now)
+		}
+	}
+
+	if pruned := pruneStaleFields(desired); len(pruned) > 0 {
+		reqLogger.Info("removed fields which are no longer generated", "fields", strings.Join(pruned, ","))
+	}
+	if err := escrowValues(desired); err != nil {
+		reqLogger.Error(err, "could not encrypt values for escrow recipients")
+		return true, reconcile.Result{}, err
+	}
+	if len(generatedKeys(previous, desired.Data)) > 0 || len(replacedKeys(previous, desired.Data)) > 0 {
+		desired.Annotations[AnnotationSecretTrigger] = string(reason)
+	}
+	recordFingerprints(desired, previous)
+	desired.Annotations[AnnotationSecretChecksum] = syncer.Checksum(desired.Data)
+
+	if (regenerate && len(previous) > 0) || promoted || rolledBack {
+		if err := recordRotation(desired, reason, now); err != nil {
+			reqLogger.Error(err, "could not record rotation history")
+			return true, reconcile.Result{}, err
+		}
+	}
+	delete(desired.Annotations, AnnotationSecretRotationFailed)
+
+	return true, res, nil
+}
+
+// requeueAfter makes sure the result is requeued after d at the latest, unless d is 0
+func requeueAfter(res *reconcile.Result, d time.Duration) {
+	if d > 0 && (res.RequeueAfter == 0 || d < res.RequeueAfter) {
+		res.RequeueAfter = d
+	}
+}
+
+// IsManaged returns true if the annotations mark a secret to be managed by the secret generator
+func IsManaged(annotations map[string]string) bool {
+	_, spec := annotations[AnnotationSecretSpec]
+	_, autogenerate := annotations[AnnotationSecretAutoGenerate]
+	_, copies := annotations[AnnotationSecretCopyFrom]
+	return spec || autogenerate || copies || annotations[AnnotationSecretType] != ""
+}
+
+// IsIgnored reports whether the secret is to be left untouched, e.g. during migrations
+func IsIgnored(annotations map[string]string) bool {
+	return annotations[AnnotationSecretIgnore] == "true"
+}
+
+// generateValues fills in all randomly generated values of the secret
+func (r *ReconcileSecret) generateValues(reqLogger logr.Logger, desired *corev1.Secret) (bool, reconcile.Result, error) {
+	if _, ok := desired.Annotations[AnnotationSecretSpec]; ok {
+		return r.generateFromSpec(reqLogger, desired)
+	}
 
 	sType := SecretType(desired.Annotations[AnnotationSecretType])
 	if err := sType.Validate(); err != nil {
 		if _, ok := desired.Annotations[AnnotationSecretAutoGenerate]; !ok && sType == "" {
-			// return if secret has no type and no autogenerate annotation
-			return reconcile.Result{}, nil
+			// return if secret has no type and no autogenerate annotation, unless it only copies values
+			_, copies := desired.Annotations[AnnotationSecretCopyFrom]
+			return copies, reconcile.Result{}, nil
 		}
 
 		// keep backwards compatibility by defaulting to string type
@@ -115,36 +704,35 @@ func (r *ReconcileSecret) Reconcile(request reconcile.Request) (reconcile.Result
 		desired.Data = make(map[string][]byte)
 	}
 
+	defaults, err := r.defaults(desired.Namespace)
+	if err != nil {
+		reqLogger.Error(err, "could not get namespace defaults")
+		return true, reconcile.Result{}, err
+	}
+
 	var generator SecretGenerator
 	switch sType {
 	case SecretTypeSSHKeypair:
 		generator = SSHKeypairGenerator{
-			log: reqLogger.WithValues("type", SecretTypeSSHKeypair),
+			log:    reqLogger.WithValues("type", SecretTypeSSHKeypair),
+			length: defaults.sshKeyLength,
 		}
 	case SecretTypeString:
-		generator = StringGenerator{
-			log: reqLogger.WithValues("type", SecretTypeString),
-		}
-	}
-
-	res, err := generator.generateData(desired)
-	if err != nil {
-		return res, err
-	}
-
-	if !reflect.DeepEqual(instance.Annotations, desired.Annotations) ||
-		!reflect.DeepEqual(instance.Data, desired.Data) {
-		reqLogger.Info("updating secret")
-
-		desired.Annotations[AnnotationSecretAutoGeneratedAt] = time.Now().Format(time.RFC3339)
-		err := r.client.Update(context.Background(), desired)
+		pg, err := r.stringGenerator(reqLogger.WithValues("type", SecretTypeString), desired, defaults)
 		if err != nil {
-			reqLogger.Error(err, "could not update secret")
-			return reconcile.Result{Requeue: true}, err
+			reqLogger.Error(err, "could not set up string generator")
+			return true, reconcile.Result{}, err
+		}
+		generator = pg
+	default:
+		generator = PluginGenerator{
+			log:   reqLogger.WithValues("type", sType),
+			sType: sType,
 		}
 	}
 
-	return reconcile.Result{}, nil
+	res, err := generator.generateData(desired)
+	return true, res, err
 }
 
 func secretLengthFromAnnotation(fallback int, annotations map[string]string) (int, error) {
@@ -72,6 +72,14 @@ func setupViper() {
 	viper.Set("secret-length", 40)
 	viper.Set("regenerate-insecure", false)
 	viper.Set("ssh-key-length", 2048)
+	viper.Set("policy-min-length", 0)
+	viper.Set("policy-max-length", 0)
+	viper.Set("rotation-cooldown", 0)
+	viper.Set("max-regenerations-per-day", 0)
+	viper.Set("rotation-history-limit", 0)
+	viper.Set("expiry-warning", 0)
+	viper.Set("secret-charset", "")
+	viper.Set("default-rotate-after", 0)
 }
 
 func reset() {
@@ -95,7 +103,7 @@ func reset() {
 }
 
 func doReconcile(t *testing.T, secret *corev1.Secret, isErr bool) {
-	rec := ReconcileSecret{mgr.GetClient(), mgr.GetScheme()}
+	rec := newReconciler(mgr)
 	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: secret.Name, Namespace: secret.Namespace}}
 
 	res, err := rec.Reconcile(req)
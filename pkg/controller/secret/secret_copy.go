@@ -0,0 +1,123 @@
+package secret
+
+import (
+	"context"
+	"fmt"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"strings"
+)
+
+// copySource is the secret and key the value of a field is copied from
+type copySource struct {
+	secret string
+	key    string
+}
+
+// copySources parses the comma separated entries of the copy-from annotation, each of the form
+// [<field>=]<secret>/<key>, into the sources of the fields. The field defaults to the key.
+func copySources(val string) (map[string]copySource, error) {
+	sources := make(map[string]copySource)
+	for _, entry := range strings.Split(val, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+
+		field, ref := "", entry
+		if i := strings.Index(entry, "="); i >= 0 {
+			field, ref = entry[:i], entry[i+1:]
+		}
+		parts := strings.Split(ref, "/")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid %s entry %q, expected [<field>=]<secret>/<key>", AnnotationSecretCopyFrom, entry)
+		}
+		if field == "" {
+			field = parts[1]
+		}
+
+		if _, ok := sources[field]; ok {
+			return nil, fmt.Errorf("field %s is copied twice", field)
+		}
+		sources[field] = copySource{secret: parts[0], key: parts[1]}
+	}
+	return sources, nil
+}
+
+// copiedFields returns the sources of the fields copied from other secrets, which must neither be generated
+// nor rendered from templates
+func copiedFields(annotations map[string]string) (map[string]copySource, error) {
+	val, ok := annotations[AnnotationSecretCopyFrom]
+	if !ok {
+		return nil, nil
+	}
+
+	sources, err := copySources(val)
+	if err != nil {
+		return nil, err
+	}
+
+	generated := generatedFields(&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Annotations: annotations}})
+	templates, err := secretTemplates(annotations)
+	if err != nil {
+		return nil, err
+	}
+	for field := range sources {
+		if _, ok := templates[field]; ok || contains(generated, field) {
+			return nil, fmt.Errorf("field %s is copied and generated", field)
+		}
+	}
+	return sources, nil
+}
+
+// copyValues sets the fields listed in the copy-from annotation to the values of the referenced secrets in the
+// same namespace. Changes to the referenced secrets trigger copying again, keeping the values in sync.
+func (r *ReconcileSecret) copyValues(instance *corev1.Secret) error {
+	sources, err := copiedFields(instance.Annotations)
+	if err != nil || len(sources) == 0 {
+		return err
+	}
+
+	// referenced secrets usually aren't managed, so they may not be cached
+	var reader client.Reader = r.client
+	if !cachesAllSecrets() {
+		reader = r.reader
+	}
+
+	if instance.Data == nil {
+		instance.Data = make(map[string][]byte)
+	}
+	for field, src := range sources {
+		if src.secret == instance.Name {
+			return fmt.Errorf("field %s cannot be copied from the secret itself", field)
+		}
+
+		ref := &corev1.Secret{}
+		err := reader.Get(context.TODO(), types.NamespacedName{Namespace: instance.Namespace, Name: src.secret}, ref)
+		if err != nil {
+			return fmt.Errorf("could not get secret %s to copy field %s from: %w", src.secret, field, err)
+		}
+
+		value, ok := ref.Data[src.key]
+		if !ok {
+			return fmt.Errorf("secret %s has no key %s", src.secret, src.key)
+		}
+		instance.Data[field] = value
+	}
+	return nil
+}
+
+// copiesFrom reports whether the secret copies values from the named secret
+func copiesFrom(annotations map[string]string, name string) bool {
+	sources, err := copySources(annotations[AnnotationSecretCopyFrom])
+	if err != nil {
+		return false
+	}
+	for _, src := range sources {
+		if src.secret == name {
+			return true
+		}
+	}
+	return false
+}
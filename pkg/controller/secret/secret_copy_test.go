@@ -0,0 +1,51 @@
+package secret
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"testing"
+)
+
+func TestCopyValuesFromOtherSecret(t *testing.T) {
+	source := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: getSecretName(), Namespace: "default"},
+		Data:       map[string][]byte{"password": []byte("s3cr3t")},
+	}
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), source))
+
+	in := newStringTestSecret("token", map[string]string{
+		AnnotationSecretCopyFrom: source.Name + "/password,db-password=" + source.Name + "/password",
+	}, "")
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), in))
+	doReconcile(t, in, false)
+
+	out := &corev1.Secret{}
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{Name: in.Name, Namespace: in.Namespace}, out))
+	require.Equal(t, "s3cr3t", string(out.Data["password"]))
+	require.Equal(t, "s3cr3t", string(out.Data["db-password"]))
+	require.NotEmpty(t, out.Data["token"])
+
+	// changes to the source are copied again
+	source.Data["password"] = []byte("changed")
+	require.NoError(t, mgr.GetClient().Update(context.TODO(), source))
+	doReconcile(t, out, false)
+
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{Name: in.Name, Namespace: in.Namespace}, out))
+	require.Equal(t, "changed", string(out.Data["db-password"]))
+}
+
+func TestCopyValuesFromMissingKey(t *testing.T) {
+	source := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: getSecretName(), Namespace: "default"}}
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), source))
+
+	in := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+		Name:        getSecretName(),
+		Namespace:   "default",
+		Annotations: map[string]string{AnnotationSecretCopyFrom: source.Name + "/password"},
+	}}
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), in))
+	doReconcile(t, in, true)
+}
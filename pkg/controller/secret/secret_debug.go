@@ -0,0 +1,32 @@
+package secret
+
+import (
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// debugLogger returns reqLogger, logging debug messages at info level if the secret has the debug annotation.
+// This allows troubleshooting a single secret without raising the log level of the whole controller.
+func debugLogger(reqLogger logr.Logger, instance *corev1.Secret) logr.Logger {
+	if instance.Annotations[AnnotationSecretDebug] != "true" {
+		return reqLogger
+	}
+	return verboseLogger{reqLogger.WithValues("debug", true)}
+}
+
+// verboseLogger logs messages of all verbosity levels
+type verboseLogger struct {
+	logr.Logger
+}
+
+func (l verboseLogger) V(int) logr.InfoLogger {
+	return l.Logger
+}
+
+func (l verboseLogger) WithValues(keysAndValues ...interface{}) logr.Logger {
+	return verboseLogger{l.Logger.WithValues(keysAndValues...)}
+}
+
+func (l verboseLogger) WithName(name string) logr.Logger {
+	return verboseLogger{l.Logger.WithName(name)}
+}
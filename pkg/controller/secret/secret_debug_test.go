@@ -0,0 +1,20 @@
+package secret
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"testing"
+)
+
+func TestDebugLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+	in := newStringTestSecret("password", nil, "")
+
+	debugLogger(zap.LoggerTo(buf, false), in).V(1).Info("hidden")
+	require.NotContains(t, buf.String(), "hidden")
+
+	in.Annotations[AnnotationSecretDebug] = "true"
+	debugLogger(zap.LoggerTo(buf, false), in).WithName("string").V(1).Info("shown")
+	require.Contains(t, buf.String(), "shown")
+}
@@ -0,0 +1,83 @@
+package secret
+
+import (
+	"context"
+	"fmt"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"strconv"
+	"time"
+)
+
+// NamespaceDefaultsConfigMap is the name of the ConfigMap overriding the controller defaults for the secrets
+// in its namespace
+const NamespaceDefaultsConfigMap = "secret-generator-defaults"
+
+// generatorDefaults are the parameters of generated values not set by annotations or password policies
+type generatorDefaults struct {
+	length       int
+	charset      string
+	sshKeyLength int
+	rotateAfter  time.Duration
+}
+
+// globalDefaults returns the defaults configured for the controller
+func globalDefaults() generatorDefaults {
+	return generatorDefaults{
+		length:       secretLength(),
+		charset:      secretCharset(),
+		sshKeyLength: sshKeyLength(),
+		rotateAfter:  defaultRotateAfter(),
+	}
+}
+
+// defaults returns the controller defaults, overridden by the secret-generator-defaults ConfigMap in
+// namespace if it exists, so that teams can tune them without changing the controller's configuration
+func (r *ReconcileSecret) defaults(namespace string) (generatorDefaults, error) {
+	defaults := globalDefaults()
+
+	cm := &corev1.ConfigMap{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: NamespaceDefaultsConfigMap}, cm)
+	if errors.IsNotFound(err) {
+		return defaults, nil
+	}
+	if err != nil {
+		return defaults, err
+	}
+
+	return parseNamespaceDefaults(defaults, cm.Data)
+}
+
+// parseNamespaceDefaults overrides defaults with the values of the secret-generator-defaults ConfigMap
+func parseNamespaceDefaults(defaults generatorDefaults, data map[string]string) (generatorDefaults, error) {
+	if val, ok := data["length"]; ok {
+		length, err := strconv.Atoi(val)
+		if err != nil || length <= 0 {
+			return defaults, fmt.Errorf("invalid length %q in ConfigMap %s", val, NamespaceDefaultsConfigMap)
+		}
+		defaults.length = length
+	}
+
+	if val, ok := data["charset"]; ok {
+		defaults.charset = val
+	}
+
+	if val, ok := data["ssh-key-length"]; ok {
+		length, err := strconv.Atoi(val)
+		if err != nil || length <= 0 {
+			return defaults, fmt.Errorf("invalid ssh-key-length %q in ConfigMap %s", val, NamespaceDefaultsConfigMap)
+		}
+		defaults.sshKeyLength = length
+	}
+
+	if val, ok := data["rotate-after"]; ok {
+		interval, err := time.ParseDuration(val)
+		if err != nil || interval <= 0 {
+			return defaults, fmt.Errorf("invalid rotate-after %q in ConfigMap %s", val, NamespaceDefaultsConfigMap)
+		}
+		defaults.rotateAfter = interval
+	}
+
+	return defaults, nil
+}
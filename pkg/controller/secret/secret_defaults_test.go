@@ -0,0 +1,56 @@
+package secret
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"testing"
+	"time"
+)
+
+func TestParseNamespaceDefaults(t *testing.T) {
+	defaults, err := parseNamespaceDefaults(generatorDefaults{length: 40, charset: "abc"}, map[string]string{
+		"length":       "12",
+		"rotate-after": "24h",
+	})
+	require.NoError(t, err)
+	require.Equal(t, 12, defaults.length)
+	require.Equal(t, "abc", defaults.charset)
+	require.Equal(t, 24*time.Hour, defaults.rotateAfter)
+
+	_, err = parseNamespaceDefaults(generatorDefaults{}, map[string]string{"length": "-1"})
+	require.Error(t, err)
+
+	_, err = parseNamespaceDefaults(generatorDefaults{}, map[string]string{"rotate-after": "daily"})
+	require.Error(t, err)
+}
+
+func TestNamespaceDefaultsOverrideLength(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      NamespaceDefaultsConfigMap,
+			Namespace: "default",
+		},
+		Data: map[string]string{
+			"length":  "12",
+			"charset": "x",
+		},
+	}
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), cm))
+	defer func() {
+		require.NoError(t, mgr.GetClient().Delete(context.TODO(), cm))
+	}()
+
+	in := newStringTestSecret("testfield", nil, "")
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), in))
+
+	doReconcile(t, in, false)
+
+	out := &corev1.Secret{}
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{
+		Name:      in.Name,
+		Namespace: in.Namespace}, out))
+	require.Equal(t, "xxxxxxxxxxxx", string(out.Data["testfield"]))
+}
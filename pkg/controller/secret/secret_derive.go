@@ -0,0 +1,132 @@
+package secret
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"golang.org/x/crypto/hkdf"
+	"io"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"strings"
+)
+
+// derivation derives the values of generated string fields from a master key using HKDF-SHA256
+// instead of drawing them at random, so that all of them can be derived again from the master key
+// alone, e.g. for disaster recovery
+type derivation struct {
+	master []byte
+	salt   []byte
+	info   string
+}
+
+// reader returns the HKDF output stream for the given field. Every field is derived with its own
+// info string, so that values derived from the same master key are independent of each other.
+func (d *derivation) reader(key string) io.Reader {
+	return hkdf.New(sha256.New, d.master, d.salt, []byte(d.info+"/"+key))
+}
+
+// derives reports whether generated values of the secret are derived rather than drawn at random. Deriving them
+// again results in the same values, so they can't be rotated.
+func derives(annotations map[string]string) bool {
+	_, ok := annotations[AnnotationSecretDeriveFrom]
+	return ok || annotations[AnnotationSecretDeterministic] == "true"
+}
+
+// derivesFromName reports whether the values of the secret are derived with the info <namespace>/<name>
+func derivesFromName(annotations map[string]string) bool {
+	if annotations[AnnotationSecretDeterministic] == "true" {
+		return true
+	}
+	_, derived := annotations[AnnotationSecretDeriveFrom]
+	_, info := annotations[AnnotationSecretDeriveInfo]
+	return derived && !info
+}
+
+// parseDeriveFrom splits the value of the derive-from annotation into the name of the
+// master secret and the key of the master key
+func parseDeriveFrom(val string) (string, string, error) {
+	parts := strings.Split(val, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("invalid %s annotation %q, expected <secret>/<key>", AnnotationSecretDeriveFrom, val)
+	}
+	return parts[0], parts[1], nil
+}
+
+// derivation returns the derivation configured by the deterministic or derive-from annotation of the
+// instance, or nil if values of the instance are generated at random. The master secret has to live in
+// the same namespace as the instance. The info defaults to <namespace>/<name> of the instance.
+func (r *ReconcileSecret) derivation(instance *corev1.Secret) (*derivation, error) {
+	if instance.Annotations[AnnotationSecretDeterministic] == "true" {
+		return r.seedDerivation(instance)
+	}
+
+	val, ok := instance.Annotations[AnnotationSecretDeriveFrom]
+	if !ok {
+		return nil, nil
+	}
+
+	name, key, err := parseDeriveFrom(val)
+	if err != nil {
+		return nil, err
+	}
+	if name == instance.Name {
+		return nil, fmt.Errorf("secret %s cannot derive values from itself", name)
+	}
+
+	master := &corev1.Secret{}
+	err = r.client.Get(context.TODO(), types.NamespacedName{Namespace: instance.Namespace, Name: name}, master)
+	if err != nil {
+		return nil, fmt.Errorf("could not get master secret %s: %w", name, err)
+	}
+	if len(master.Data[key]) == 0 {
+		return nil, fmt.Errorf("master secret %s has no value for key %s", name, key)
+	}
+
+	info, ok := instance.Annotations[AnnotationSecretDeriveInfo]
+	if !ok {
+		info = instance.Namespace + "/" + instance.Name
+	}
+
+	return &derivation{
+		master: master.Data[key],
+		salt:   []byte(instance.Annotations[AnnotationSecretDeriveSalt]),
+		info:   info,
+	}, nil
+}
+
+// deterministicSeedSecret returns the namespace and name of the secret holding the seed of deterministic values
+func deterministicSeedSecret() (types.NamespacedName, error) {
+	val := options().GetString("deterministic-seed-secret")
+	if val == "" {
+		return types.NamespacedName{}, fmt.Errorf("%s requires the deterministic-seed-secret to be configured", AnnotationSecretDeterministic)
+	}
+
+	parts := strings.Split(val, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return types.NamespacedName{}, fmt.Errorf("invalid deterministic-seed-secret %q, expected <namespace>/<name>", val)
+	}
+	return types.NamespacedName{Namespace: parts[0], Name: parts[1]}, nil
+}
+
+// seedDerivation returns the derivation of deterministic values of the instance, derived from the cluster-wide
+// seed with the info <namespace>/<name>, so that each field is derived with <namespace>/<name>/<key>
+func (r *ReconcileSecret) seedDerivation(instance *corev1.Secret) (*derivation, error) {
+	name, err := deterministicSeedSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	seed := &corev1.Secret{}
+	if err := r.client.Get(context.TODO(), name, seed); err != nil {
+		return nil, fmt.Errorf("could not get seed secret %s: %w", name, err)
+	}
+	if len(seed.Data[DeterministicSeedKey]) == 0 {
+		return nil, fmt.Errorf("seed secret %s has no value for key %s", name, DeterministicSeedKey)
+	}
+
+	return &derivation{
+		master: seed.Data[DeterministicSeedKey],
+		info:   instance.Namespace + "/" + instance.Name,
+	}, nil
+}
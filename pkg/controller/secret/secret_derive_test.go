@@ -0,0 +1,130 @@
+package secret
+
+import (
+	"context"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"testing"
+)
+
+func TestDeriveValuesFromMasterKey(t *testing.T) {
+	master := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      getSecretName(),
+			Namespace: "default",
+		},
+		Data: map[string][]byte{"key": []byte("0123456789abcdef0123456789abcdef")},
+	}
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), master))
+
+	derived := func() map[string][]byte {
+		in := newStringTestSecret("password,token", map[string]string{
+			AnnotationSecretDeriveFrom: master.Name + "/key",
+			AnnotationSecretDeriveInfo: "billing",
+		}, "")
+		require.NoError(t, mgr.GetClient().Create(context.TODO(), in))
+		doReconcile(t, in, false)
+
+		out := &corev1.Secret{}
+		require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{Name: in.Name, Namespace: in.Namespace}, out))
+		return out.Data
+	}
+
+	one := derived()
+	require.NotEmpty(t, one["password"])
+	require.NotEqual(t, one["password"], one["token"])
+
+	// values are derived again from the master key, e.g. after losing the secret
+	two := derived()
+	require.Equal(t, one["password"], two["password"])
+	require.Equal(t, one["token"], two["token"])
+}
+
+func TestDerivationInfoSeparatesSecrets(t *testing.T) {
+	d := &derivation{master: []byte("master"), info: "default/a"}
+	a, err := readString(d.reader("password"), 32)
+	require.NoError(t, err)
+
+	d.info = "default/b"
+	b, err := readString(d.reader("password"), 32)
+	require.NoError(t, err)
+	require.NotEqual(t, a, b)
+
+	d.salt = []byte("salt")
+	c, err := readString(d.reader("password"), 32)
+	require.NoError(t, err)
+	require.NotEqual(t, b, c)
+}
+
+func TestDeterministicValuesFromSeed(t *testing.T) {
+	seed := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      getSecretName(),
+			Namespace: "default",
+		},
+		Data: map[string][]byte{DeterministicSeedKey: []byte("cluster seed")},
+	}
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), seed))
+
+	viper.Set("deterministic-seed-secret", seed.Namespace+"/"+seed.Name)
+	defer viper.Set("deterministic-seed-secret", "")
+
+	in := newStringTestSecret("password", map[string]string{
+		AnnotationSecretDeterministic: "true",
+	}, "")
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), in))
+	doReconcile(t, in, false)
+
+	out := &corev1.Secret{}
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{Name: in.Name, Namespace: in.Namespace}, out))
+
+	// the value is reproduced from the seed and the namespace/name/key of the secret alone
+	d := &derivation{master: seed.Data[DeterministicSeedKey], info: in.Namespace + "/" + in.Name}
+	pg := newStringGenerator(nil, globalDefaults(), nil)
+	pg.derivation = d
+	expected, err := pg.generateValue("password", len(out.Data["password"]))
+	require.NoError(t, err)
+	require.Equal(t, expected, string(out.Data["password"]))
+}
+
+func TestDeterministicValuesRequireSeed(t *testing.T) {
+	in := newStringTestSecret("password", map[string]string{
+		AnnotationSecretDeterministic: "true",
+	}, "")
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), in))
+	doReconcile(t, in, true)
+}
+
+func TestRegenerationOfDerivedValuesIsDropped(t *testing.T) {
+	master := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      getSecretName(),
+			Namespace: "default",
+		},
+		Data: map[string][]byte{"key": []byte("0123456789abcdef0123456789abcdef")},
+	}
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), master))
+
+	in := newStringTestSecret("password", map[string]string{
+		AnnotationSecretDeriveFrom: master.Name + "/key",
+	}, "")
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), in))
+	doReconcile(t, in, false)
+
+	out := &corev1.Secret{}
+	key := types.NamespacedName{Name: in.Name, Namespace: in.Namespace}
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), key, out))
+	password := out.Data["password"]
+
+	out.Annotations[AnnotationSecretRegenerate] = "yes"
+	require.NoError(t, mgr.GetClient().Update(context.TODO(), out))
+	doReconcile(t, out, false)
+
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), key, out))
+	require.Equal(t, password, out.Data["password"])
+	require.NotContains(t, out.Annotations, AnnotationSecretRegenerate)
+	require.NotContains(t, out.Annotations, AnnotationSecretRotatedAt)
+}
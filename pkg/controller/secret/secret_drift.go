@@ -0,0 +1,112 @@
+package secret
+
+import (
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// base64Charset contains all characters of values generated without a charset, or with base64 encoding
+	base64Charset = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+	hexCharset    = "0123456789abcdef"
+)
+
+// complies returns true if the value could have been generated by the generator with the given length and encoding
+func (pg StringGenerator) complies(value []byte, length int, encoding Encoding) bool {
+	charset := pg.charset
+	switch {
+	case encoding == EncodingHex:
+		charset = hexCharset
+	case encoding == EncodingBase64 || charset == "":
+		charset = base64Charset
+	}
+
+	if utf8.RuneCount(value) < length {
+		return false
+	}
+	for _, r := range string(value) {
+		if !strings.ContainsRune(charset, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// policyDrift returns the generated string fields of the secret whose values do not comply with the
+// current length and charset settings, e.g. after the global length or a password policy was tightened.
+// Only secrets whose values have all been generated by the controller are checked.
+func (r *ReconcileSecret) policyDrift(instance *corev1.Secret) ([]string, error) {
+	if _, ok := instance.Annotations[AnnotationSecretSecure]; !ok {
+		return nil, nil
+	}
+
+	policy, err := r.passwordPolicy(instance)
+	if err != nil {
+		return nil, err
+	}
+	defaults, err := r.defaults(instance.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	pg := newStringGenerator(nil, defaults, policy)
+
+	var drift []string
+	if val, ok := instance.Annotations[AnnotationSecretSpec]; ok {
+		spec, err := parseSpec(val)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range spec.Fields {
+			if f.Type != SecretTypeString {
+				continue
+			}
+			length := pg.length
+			if f.Length > 0 {
+				length = f.Length
+			}
+			if value, ok := instance.Data[f.Name]; ok && !pg.complies(value, length, f.Encoding) {
+				drift = append(drift, f.Name)
+			}
+		}
+	} else if sType := SecretType(instance.Annotations[AnnotationSecretType]); sType == "" || sType == SecretTypeString {
+		length, err := secretLengthFromAnnotation(pg.length, instance.Annotations)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range autogenerateKeys(instance) {
+			if value, ok := instance.Data[key]; ok && !pg.complies(value, length, "") {
+				drift = append(drift, key)
+			}
+		}
+	}
+
+	sort.Strings(drift)
+	return drift, nil
+}
+
+// remediatePolicyDrift reports values of the secret that do not comply with the current policy. If drift
+// remediation is enabled, these values are queued for regeneration using the regenerate annotation.
+func (r *ReconcileSecret) remediatePolicyDrift(reqLogger logr.Logger, desired *corev1.Secret) error {
+	drift, err := r.policyDrift(desired)
+	if err != nil || len(drift) == 0 {
+		return err
+	}
+
+	fields := strings.Join(drift, ",")
+	if !remediatePolicyDrift() {
+		reqLogger.Info("values do not comply with the current policy", "fields", fields)
+		r.recorder.Eventf(desired, corev1.EventTypeWarning, "PolicyDrift",
+			"fields %s do not comply with the current policy", fields)
+		return nil
+	}
+
+	reqLogger.Info("regenerating values which do not comply with the current policy", "fields", fields)
+	r.recorder.Eventf(desired, corev1.EventTypeNormal, "PolicyDriftRemediated",
+		"regenerating fields %s which did not comply with the current policy", fields)
+
+	requestRegeneration(desired, drift)
+	return nil
+}
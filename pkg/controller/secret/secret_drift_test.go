@@ -0,0 +1,84 @@
+package secret
+
+import (
+	"context"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/apis/secretgenerator/v1alpha1"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"testing"
+)
+
+func TestValueCompliesWithPolicy(t *testing.T) {
+	pg := StringGenerator{}
+	require.True(t, pg.complies([]byte("abcDEF012+/"), 11, ""))
+	require.False(t, pg.complies([]byte("abcDEF012+/"), 12, ""))
+	require.False(t, pg.complies([]byte("abc-def"), 7, ""))
+
+	pg = StringGenerator{charset: "abc"}
+	require.True(t, pg.complies([]byte("abcabc"), 6, ""))
+	require.False(t, pg.complies([]byte("abcabd"), 6, ""))
+	require.True(t, pg.complies([]byte("0a1b2c"), 6, EncodingHex))
+	require.False(t, pg.complies([]byte("0a1b2g"), 6, EncodingHex))
+}
+
+func TestPolicyDriftIsReported(t *testing.T) {
+	in := newStringTestSecret("password", map[string]string{
+		AnnotationSecretSecure: "yes",
+		AnnotationSecretLength: "20",
+	}, "tooShort")
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), in))
+
+	doReconcile(t, in, false)
+
+	out := &corev1.Secret{}
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{
+		Name:      in.Name,
+		Namespace: in.Namespace}, out))
+
+	// values are kept unless remediation is enabled
+	require.Equal(t, "tooShort", string(out.Data["password"]))
+
+	drift, err := newReconciler(mgr).policyDrift(out)
+	require.NoError(t, err)
+	require.Equal(t, []string{"password"}, drift)
+}
+
+func TestPolicyDriftIsRemediated(t *testing.T) {
+	viper.Set("remediate-policy-drift", true)
+	defer viper.Set("remediate-policy-drift", false)
+
+	in := newStringTestSecret("password,token", map[string]string{
+		AnnotationSecretSecure: "yes",
+		AnnotationSecretLength: "20",
+	}, "tooShort,abcdefghijklmnopqrstuvwxyz")
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), in))
+
+	doReconcile(t, in, false)
+
+	out := &corev1.Secret{}
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{
+		Name:      in.Name,
+		Namespace: in.Namespace}, out))
+
+	require.Len(t, out.Data["password"], 20)
+	require.Equal(t, "abcdefghijklmnopqrstuvwxyz", string(out.Data["token"]))
+	require.NotContains(t, out.Annotations, AnnotationSecretRegenerate)
+}
+
+func TestPolicyDriftOfSpecFields(t *testing.T) {
+	in := newSpecTestSecret(`{"fields":[{"name":"key","encoding":"hex","length":8},{"name":"password","length":8}]}`)
+	in.Annotations[AnnotationSecretSecure] = "yes"
+	in.Data = map[string][]byte{
+		"key":      []byte("0123456z"),
+		"password": []byte("abcdefgh"),
+	}
+
+	drift, err := newReconciler(mgr).policyDrift(in)
+	require.NoError(t, err)
+	require.Equal(t, []string{"key"}, drift)
+
+	pg := newStringGenerator(nil, globalDefaults(), &v1alpha1.PasswordPolicySpec{Charset: "abc"})
+	require.False(t, pg.complies(in.Data["password"], 8, ""))
+}
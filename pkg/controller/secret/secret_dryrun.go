@@ -0,0 +1,34 @@
+package secret
+
+import (
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"strings"
+)
+
+// DryRun reports whether the controllers only report the changes they would make instead of writing them
+func DryRun() bool {
+	return options().GetBool("dry-run")
+}
+
+// reportDryRun logs and emits an event describing the changes the controller would have made to instance
+// instead of writing desired
+func (r *ReconcileSecret) reportDryRun(reqLogger logr.Logger, instance, desired *corev1.Secret) {
+	generated := generatedKeys(instance.Data, desired.Data)
+	regenerated := replacedKeys(instance.Data, desired.Data)
+
+	if len(generated) == 0 && len(regenerated) == 0 {
+		return
+	}
+
+	var changes []string
+	if len(generated) > 0 {
+		changes = append(changes, "generate fields "+strings.Join(generated, ","))
+	}
+	if len(regenerated) > 0 {
+		changes = append(changes, "regenerate fields "+strings.Join(regenerated, ","))
+	}
+
+	reqLogger.Info("dry run, not updating secret", "generated", generated, "regenerated", regenerated)
+	r.recorder.Eventf(instance, corev1.EventTypeNormal, "DryRun", "would %s", strings.Join(changes, " and "))
+}
@@ -0,0 +1,32 @@
+package secret
+
+import (
+	"context"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"testing"
+	"time"
+)
+
+func TestDryRunDoesNotUpdateSecret(t *testing.T) {
+	viper.Set("dry-run", true)
+	defer viper.Set("dry-run", false)
+
+	in := newStringTestSecret("testfield,existing", map[string]string{
+		AnnotationSecretRegenerate:      "existing",
+		AnnotationSecretAutoGeneratedAt: time.Now().Format(time.RFC3339),
+	}, ",value")
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), in))
+
+	doReconcile(t, in, false)
+
+	out := &corev1.Secret{}
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{
+		Name:      in.Name,
+		Namespace: in.Namespace}, out))
+	require.Empty(t, out.Data["testfield"])
+	require.Equal(t, "value", string(out.Data["existing"]))
+	require.Contains(t, out.Annotations, AnnotationSecretRegenerate)
+}
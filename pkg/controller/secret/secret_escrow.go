@@ -0,0 +1,59 @@
+package secret
+
+import (
+	"github.com/mittwald/kubernetes-secret-generator/pkg/escrow"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/syncer"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// escrowValues stores the generated values of desired encrypted for the public keys of its escrow-recipients
+// annotation in <key>.enc fields, so that designated people can recover them. Values are only encrypted again
+// when they or the recipients change. Encrypted values are removed along with the annotation.
+func escrowValues(desired *corev1.Secret) error {
+	fields := generatedFields(desired)
+
+	val, ok := desired.Annotations[AnnotationSecretEscrowRecipients]
+	if !ok {
+		if _, escrowed := desired.Annotations[AnnotationSecretEscrowChecksum]; escrowed {
+			for _, key := range fields {
+				delete(desired.Data, key+EscrowFieldSuffix)
+			}
+			delete(desired.Annotations, AnnotationSecretEscrowChecksum)
+		}
+		return nil
+	}
+
+	// the recipients can't collide with a key, as keys must not contain a colon
+	values := map[string][]byte{"recipients:": []byte(val)}
+	for _, key := range fields {
+		if value, ok := desired.Data[key]; ok {
+			values[key] = value
+		}
+	}
+	checksum := syncer.Checksum(values)
+	if desired.Annotations[AnnotationSecretEscrowChecksum] == checksum {
+		return nil
+	}
+
+	if err := escrowRecipientsApproved(val); err != nil {
+		return err
+	}
+	recipients, err := escrow.ParseRecipients(val)
+	if err != nil {
+		return err
+	}
+	for _, key := range fields {
+		value, ok := desired.Data[key]
+		if !ok {
+			continue
+		}
+		encrypted, err := recipients.Encrypt(value)
+		if err != nil {
+			return err
+		}
+		desired.Data[key+EscrowFieldSuffix] = encrypted
+	}
+
+	desired.Annotations[AnnotationSecretEscrowChecksum] = checksum
+	return nil
+}
@@ -0,0 +1,47 @@
+package secret
+
+import (
+	"bytes"
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+)
+
+func TestEscrowValuesEncryptsGeneratedFields(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+
+	desired := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+			AnnotationSecretAutoGenerate:     "password",
+			AnnotationSecretEscrowRecipients: identity.Recipient().String(),
+		}},
+		Data: map[string][]byte{"password": []byte("secret"), "username": []byte("admin")},
+	}
+	require.NoError(t, escrowValues(desired))
+	require.NotContains(t, desired.Data, "username"+EscrowFieldSuffix)
+
+	encrypted := desired.Data["password"+EscrowFieldSuffix]
+	r, err := age.Decrypt(armor.NewReader(bytes.NewReader(encrypted)), identity)
+	require.NoError(t, err)
+	plaintext, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, "secret", string(plaintext))
+
+	// unchanged values are not encrypted again
+	require.NoError(t, escrowValues(desired))
+	require.Equal(t, encrypted, desired.Data["password"+EscrowFieldSuffix])
+
+	desired.Data["password"] = []byte("rotated")
+	require.NoError(t, escrowValues(desired))
+	require.NotEqual(t, encrypted, desired.Data["password"+EscrowFieldSuffix])
+
+	delete(desired.Annotations, AnnotationSecretEscrowRecipients)
+	require.NoError(t, escrowValues(desired))
+	require.NotContains(t, desired.Data, "password"+EscrowFieldSuffix)
+	require.NotContains(t, desired.Annotations, AnnotationSecretEscrowChecksum)
+}
@@ -0,0 +1,37 @@
+package secret
+
+import (
+	"github.com/mittwald/kubernetes-secret-generator/pkg/hook"
+	corev1 "k8s.io/api/core/v1"
+	"sort"
+	"strings"
+)
+
+// generatedKeys returns the sorted keys of all values of the secret that were empty before and have been set
+func generatedKeys(previous, current map[string][]byte) []string {
+	var keys []string
+	for key, value := range current {
+		if len(previous[key]) == 0 && len(value) > 0 {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// reportChanges emits events on the secret describing the values generated and rotated by updating
+// instance to desired, so they show up in kubectl describe without access to the controller logs
+func (r *ReconcileSecret) reportChanges(instance, desired *corev1.Secret, reason hook.Reason) {
+	if generated := generatedKeys(instance.Data, desired.Data); len(generated) > 0 {
+		r.recorder.Eventf(instance, corev1.EventTypeNormal, "Generated", "generated fields %s", strings.Join(generated, ","))
+	}
+	replaced := replacedKeys(instance.Data, desired.Data)
+	if len(replaced) == 0 {
+		return
+	}
+	if reason == hook.ReasonRolledBack {
+		r.recorder.Eventf(instance, corev1.EventTypeNormal, "RolledBack", "restored previous values of fields %s", strings.Join(replaced, ","))
+		return
+	}
+	r.recorder.Eventf(instance, corev1.EventTypeNormal, "Rotated", "rotated fields %s (%s)", strings.Join(replaced, ","), reason)
+}
@@ -0,0 +1,23 @@
+package secret
+
+import (
+	"github.com/mittwald/kubernetes-secret-generator/pkg/hook"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/tools/record"
+	"testing"
+)
+
+func TestReportChanges(t *testing.T) {
+	recorder := record.NewFakeRecorder(2)
+	r := &ReconcileSecret{recorder: recorder}
+
+	instance := newStringTestSecret("new,existing", nil, ",old")
+	desired := instance.DeepCopy()
+	desired.Data["new"] = []byte("generated")
+	desired.Data["existing"] = []byte("rotated")
+
+	r.reportChanges(instance, desired, hook.ReasonScheduled)
+
+	require.Equal(t, "Normal Generated generated fields new", <-recorder.Events)
+	require.Equal(t, "Normal Rotated rotated fields existing (Scheduled)", <-recorder.Events)
+}
@@ -0,0 +1,123 @@
+package secret
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"time"
+)
+
+// ExpiryPolicy defines what happens to a secret once its values have expired
+type ExpiryPolicy string
+
+const (
+	// ExpiryPolicyRotate regenerates the values of the secret
+	ExpiryPolicyRotate ExpiryPolicy = "Rotate"
+	// ExpiryPolicyDelete deletes the secret
+	ExpiryPolicyDelete ExpiryPolicy = "Delete"
+)
+
+func (p ExpiryPolicy) Validate() error {
+	switch p {
+	case ExpiryPolicyRotate,
+		ExpiryPolicyDelete:
+		return nil
+	}
+	return fmt.Errorf("%s is not a valid expiry policy", p)
+}
+
+// expiryPolicy returns the expiry policy of the secret, defaulting to ExpiryPolicyRotate
+func expiryPolicy(instance *corev1.Secret) ExpiryPolicy {
+	if p, ok := instance.Annotations[AnnotationSecretExpiryPolicy]; ok {
+		return ExpiryPolicy(p)
+	}
+	return ExpiryPolicyRotate
+}
+
+// expiryDue checks whether the values of the secret are older than the lifetime given in its expires-after
+// annotation. Values are as old as the last rotation, or the secret if it has never been rotated. It also
+// returns the time until the values expire, which is 0 if they don't expire. Values of pinned secrets
+// don't expire unless they are deleted on expiry.
+func expiryDue(instance *corev1.Secret, now time.Time) (bool, time.Duration, error) {
+	val, ok := instance.Annotations[AnnotationSecretExpiresAfter]
+	if !ok {
+		return false, 0, nil
+	}
+
+	lifetime, err := expiryLifetime(val)
+	if err != nil {
+		return false, 0, err
+	}
+	policy := expiryPolicy(instance)
+	if err := policy.Validate(); err != nil {
+		return false, 0, err
+	}
+	if policy == ExpiryPolicyRotate && IsPinned(instance.Annotations) {
+		return false, 0, nil
+	}
+
+	createdAt := instance.CreationTimestamp.Time
+	if rotatedAt, err := time.Parse(time.RFC3339, instance.Annotations[AnnotationSecretRotatedAt]); err == nil {
+		createdAt = rotatedAt
+	} else if createdAt.IsZero() {
+		// the secret is being created
+		createdAt = now
+	}
+
+	remaining := createdAt.Add(lifetime).Sub(now)
+	if remaining <= 0 {
+		return true, lifetime, nil
+	}
+	return false, remaining, nil
+}
+
+func expiryLifetime(val string) (time.Duration, error) {
+	lifetime, err := time.ParseDuration(val)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s annotation: %w", AnnotationSecretExpiresAfter, err)
+	}
+	if lifetime <= 0 {
+		return 0, fmt.Errorf("%s must be a positive duration, got %q", AnnotationSecretExpiresAfter, val)
+	}
+	return lifetime, nil
+}
+
+// enforceExpiry deletes the secret if its values have expired and its expiry policy is Delete, and
+// warns about values expiring soon. It returns true if the secret has been deleted.
+func (r *ReconcileSecret) enforceExpiry(reqLogger logr.Logger, instance *corev1.Secret) (bool, error) {
+	expired, remaining, err := expiryDue(instance, time.Now())
+	if err != nil || remaining == 0 {
+		// invalid annotations are reported by generate
+		return false, nil
+	}
+
+	policy := expiryPolicy(instance)
+
+	if expired && policy == ExpiryPolicyDelete && DryRun() {
+		reqLogger.Info("dry run, not deleting expired secret")
+		r.recorder.Event(instance, corev1.EventTypeWarning, "DryRun", "secret has expired and would be deleted")
+		return true, nil
+	}
+
+	if expired && policy == ExpiryPolicyDelete {
+		reqLogger.Info("secret has expired, deleting it")
+		r.recorder.Event(instance, corev1.EventTypeWarning, "SecretExpired", "secret has expired and is deleted")
+		if err := r.client.Delete(context.TODO(), instance); err != nil && !errors.IsNotFound(err) {
+			return false, err
+		}
+		return true, nil
+	}
+
+	if !expired && remaining <= expiryWarning() {
+		action := "rotated"
+		if policy == ExpiryPolicyDelete {
+			action = "deleted"
+		}
+		r.recorder.Eventf(instance, corev1.EventTypeWarning, "SecretExpiring",
+			"values expire in %s, the secret will be %s", remaining.Round(time.Second), action)
+	}
+
+	return false, nil
+}
@@ -0,0 +1,76 @@
+package secret
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"testing"
+	"time"
+)
+
+func TestExpiryDue(t *testing.T) {
+	now := time.Now()
+	in := newStringTestSecret("password", map[string]string{
+		AnnotationSecretExpiresAfter: "24h",
+		AnnotationSecretRotatedAt:    now.Add(-12 * time.Hour).Format(time.RFC3339),
+	}, "")
+
+	expired, remaining, err := expiryDue(in, now)
+	require.NoError(t, err)
+	require.False(t, expired)
+	require.InDelta(t, float64(12*time.Hour), float64(remaining), float64(time.Second))
+
+	in.Annotations[AnnotationSecretRotatedAt] = now.Add(-25 * time.Hour).Format(time.RFC3339)
+	expired, _, err = expiryDue(in, now)
+	require.NoError(t, err)
+	require.True(t, expired)
+
+	in.Annotations[AnnotationSecretExpiryPolicy] = "Archive"
+	_, _, err = expiryDue(in, now)
+	require.Error(t, err)
+
+	in.Annotations[AnnotationSecretExpiryPolicy] = string(ExpiryPolicyDelete)
+	in.Annotations[AnnotationSecretExpiresAfter] = "-1h"
+	_, _, err = expiryDue(in, now)
+	require.Error(t, err)
+}
+
+func TestExpiredSecretIsRotated(t *testing.T) {
+	in := newStringTestSecret("password", map[string]string{
+		AnnotationSecretExpiresAfter: "1h",
+		AnnotationSecretSecure:       "yes",
+		AnnotationSecretRotatedAt:    time.Now().Add(-2 * time.Hour).Format(time.RFC3339),
+	}, "password")
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), in))
+
+	doReconcile(t, in, false)
+
+	out := &corev1.Secret{}
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{
+		Name:      in.Name,
+		Namespace: in.Namespace}, out))
+
+	require.NotEqual(t, "password", string(out.Data["password"]))
+	rotatedAt, err := time.Parse(time.RFC3339, out.Annotations[AnnotationSecretRotatedAt])
+	require.NoError(t, err)
+	require.WithinDuration(t, time.Now(), rotatedAt, time.Minute)
+}
+
+func TestExpiredSecretIsDeleted(t *testing.T) {
+	in := newStringTestSecret("password", map[string]string{
+		AnnotationSecretExpiresAfter: "1h",
+		AnnotationSecretExpiryPolicy: string(ExpiryPolicyDelete),
+		AnnotationSecretRotatedAt:    time.Now().Add(-2 * time.Hour).Format(time.RFC3339),
+	}, "password")
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), in))
+
+	doReconcile(t, in, false)
+
+	out := &corev1.Secret{}
+	err := mgr.GetClient().Get(context.TODO(), types.NamespacedName{
+		Name:      in.Name,
+		Namespace: in.Namespace}, out)
+	require.True(t, errors.IsNotFound(err))
+}
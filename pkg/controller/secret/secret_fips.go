@@ -0,0 +1,63 @@
+package secret
+
+import (
+	"github.com/mittwald/kubernetes-secret-generator/pkg/escrow"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/fips"
+	"strconv"
+)
+
+// errPluginNotApproved is the reason generator plugins are rejected in FIPS mode
+const errPluginNotApproved = "plugins can't be verified to use approved algorithms"
+
+// escrowRecipientsApproved returns an error if FIPS mode is enabled and val lists age recipients
+func escrowRecipientsApproved(val string) error {
+	if escrow.IsPGP(val) {
+		return nil
+	}
+	return fips.Disallow("age", "X25519 and ChaCha20-Poly1305 are not approved, use PGP public keys as escrow recipients")
+}
+
+// validateFIPS checks the generator annotations of a secret for algorithms not approved in FIPS mode. Lengths
+// taken from the defaults are checked when the values are generated.
+func validateFIPS(annotations map[string]string) error {
+	if !fips.Enabled() {
+		return nil
+	}
+
+	if val, ok := annotations[AnnotationSecretSpec]; ok {
+		spec, err := parseSpec(val)
+		if err != nil {
+			return err
+		}
+		for _, f := range spec.Fields {
+			if err := typeApproved(f.Type, f.Length); err != nil {
+				return err
+			}
+		}
+	} else if sType := SecretType(annotations[AnnotationSecretType]); sType != "" {
+		length, _ := strconv.Atoi(annotations[AnnotationSecretLength])
+		if err := typeApproved(sType, length); err != nil {
+			return err
+		}
+	}
+
+	if val, ok := annotations[AnnotationSecretEscrowRecipients]; ok {
+		return escrowRecipientsApproved(val)
+	}
+	return nil
+}
+
+// typeApproved returns an error if fields of the given type and length, with 0 being the default length,
+// can't be generated using approved algorithms
+func typeApproved(sType SecretType, length int) error {
+	switch sType {
+	case SecretTypeString:
+		return nil
+	case SecretTypeSSHKeypair:
+		if length == 0 {
+			return nil
+		}
+		return fips.CheckRSAKeyLength(length)
+	}
+	return fips.Disallow("generator plugin type "+string(sType), errPluginNotApproved)
+}
@@ -0,0 +1,50 @@
+package secret
+
+import (
+	"github.com/mittwald/kubernetes-secret-generator/pkg/fips"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestValidateAnnotationsInFIPSMode(t *testing.T) {
+	defer fips.Force(true)()
+
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		valid       bool
+	}{
+		{"string", map[string]string{AnnotationSecretAutoGenerate: "password"}, true},
+		{"ssh-keypair", map[string]string{AnnotationSecretType: string(SecretTypeSSHKeypair), AnnotationSecretLength: "3072"}, true},
+		{"short ssh-keypair", map[string]string{AnnotationSecretType: string(SecretTypeSSHKeypair), AnnotationSecretLength: "1024"}, false},
+		{"plugin", map[string]string{AnnotationSecretAutoGenerate: "token", AnnotationSecretType: "jwt"}, false},
+		{"spec with short ssh-keypair", map[string]string{
+			AnnotationSecretSpec: `{"fields":[{"name":"password"},{"name":"key","type":"ssh-keypair","length":1024}]}`,
+		}, false},
+		{"age escrow recipients", map[string]string{
+			AnnotationSecretAutoGenerate:     "password",
+			AnnotationSecretEscrowRecipients: "age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p",
+		}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateAnnotations(tt.annotations)
+			if tt.valid {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "not approved in FIPS mode")
+			}
+		})
+	}
+}
+
+func TestGenerateSSHKeypairInFIPSMode(t *testing.T) {
+	defer fips.Force(true)()
+
+	_, err := generateSSHKeypair(1024)
+	require.Error(t, err)
+	_, err = generateSSHKeypair(2048)
+	require.NoError(t, err)
+}
@@ -0,0 +1,64 @@
+package secret
+
+import (
+	"encoding/json"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/hook"
+	corev1 "k8s.io/api/core/v1"
+	"time"
+)
+
+// fingerprintLength is the number of hex characters of the data checksum kept as fingerprint
+const fingerprintLength = 16
+
+// RotationRecord describes a single rotation in the rotation history of a secret
+type RotationRecord struct {
+	Time   string      `json:"time"`
+	Reason hook.Reason `json:"reason"`
+	// Fingerprint identifies the values after the rotation without revealing them
+	Fingerprint string `json:"fingerprint"`
+}
+
+// rotationHistory returns the rotation history of the secret, oldest rotation first
+func rotationHistory(instance *corev1.Secret) ([]RotationRecord, error) {
+	val, ok := instance.Annotations[AnnotationSecretRotationHistory]
+	if !ok {
+		return nil, nil
+	}
+
+	var history []RotationRecord
+	if err := json.Unmarshal([]byte(val), &history); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+// recordRotation appends the rotation to the history of the secret, keeping the last rotations up to
+// the configured limit. The history is only recorded if a limit is configured.
+func recordRotation(instance *corev1.Secret, reason hook.Reason, now time.Time) error {
+	limit := rotationHistoryLimit()
+	if limit <= 0 {
+		return nil
+	}
+
+	history, err := rotationHistory(instance)
+	if err != nil {
+		// start over instead of failing every rotation because of a broken annotation
+		history = nil
+	}
+
+	history = append(history, RotationRecord{
+		Time:        now.Format(time.RFC3339),
+		Reason:      reason,
+		Fingerprint: instance.Annotations[AnnotationSecretChecksum][:fingerprintLength],
+	})
+	if len(history) > limit {
+		history = history[len(history)-limit:]
+	}
+
+	encoded, err := json.Marshal(history)
+	if err != nil {
+		return err
+	}
+	instance.Annotations[AnnotationSecretRotationHistory] = string(encoded)
+	return nil
+}
@@ -0,0 +1,41 @@
+package secret
+
+import (
+	"context"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/hook"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"testing"
+	"time"
+)
+
+func TestRotationHistory(t *testing.T) {
+	viper.Set("rotation-history-limit", 2)
+	defer viper.Set("rotation-history-limit", 0)
+
+	in := newStringTestSecret("password", map[string]string{
+		AnnotationSecretSecure:          "yes",
+		AnnotationSecretRegenerate:      "yes",
+		AnnotationSecretRotationHistory: `[{"time":"2020-01-01T00:00:00Z","reason":"Scheduled","fingerprint":"a"},{"time":"2020-02-01T00:00:00Z","reason":"Scheduled","fingerprint":"b"}]`,
+	}, "password")
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), in))
+
+	doReconcile(t, in, false)
+
+	out := &corev1.Secret{}
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{
+		Name:      in.Name,
+		Namespace: in.Namespace}, out))
+
+	history, err := rotationHistory(out)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	require.Equal(t, "b", history[0].Fingerprint)
+	require.Equal(t, hook.ReasonRequested, history[1].Reason)
+	require.Equal(t, out.Annotations[AnnotationSecretChecksum][:fingerprintLength], history[1].Fingerprint)
+
+	_, err = time.Parse(time.RFC3339, history[1].Time)
+	require.NoError(t, err)
+}
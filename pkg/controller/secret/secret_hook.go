@@ -0,0 +1,115 @@
+package secret
+
+import (
+	"context"
+	"github.com/go-logr/logr"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/hook"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sort"
+	"time"
+)
+
+// rotationEvent returns the hook event for the rotation of the secret, which only lists the keys of its values
+func rotationEvent(instance *corev1.Secret, reason hook.Reason) hook.Event {
+	fields := requestedKeys(instance)
+	if fields == nil {
+		fields = make([]string, 0, len(instance.Data))
+		for key := range instance.Data {
+			fields = append(fields, key)
+		}
+	}
+	sort.Strings(fields)
+
+	return hook.Event{
+		Namespace: instance.Namespace,
+		Name:      instance.Name,
+		Labels:    instance.Labels,
+		Reason:    reason,
+		Fields:    fields,
+	}
+}
+
+// rotationReason returns why values of the secret change, based on the state before the change
+func rotationReason(instance *corev1.Secret, defaultRotateAfter time.Duration, now time.Time) hook.Reason {
+	if !hasGeneratedValues(instance) {
+		return hook.ReasonCreated
+	}
+	if _, ok := instance.Annotations[AnnotationSecretRollback]; ok {
+		return hook.ReasonRolledBack
+	}
+	if _, ok := instance.Annotations[AnnotationSecretRegenerate]; ok {
+		if _, bulk := instance.Annotations[AnnotationSecretRequestedBy]; bulk {
+			return hook.ReasonBulk
+		}
+		return hook.ReasonRequested
+	}
+	if rotate, _, _ := rotationDue(instance, defaultRotateAfter, now); rotate {
+		return hook.ReasonScheduled
+	}
+	if expired, _, _ := expiryDue(instance, now); expired {
+		return hook.ReasonExpired
+	}
+	if _, ok := instance.Annotations[AnnotationSecretStagedFields]; ok {
+		return hook.ReasonActivated
+	}
+	return hook.ReasonUpdated
+}
+
+// hasGeneratedValues reports whether any of the values generated for the secret are set,
+// i.e. whether changing them is a rotation rather than their initial generation
+func hasGeneratedValues(instance *corev1.Secret) bool {
+	fields := generatedFields(instance)
+	if len(fields) == 0 {
+		// nothing is generated, e.g. only templates are rendered
+		return true
+	}
+	for _, key := range fields {
+		if len(instance.Data[key]) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// rotationAllowed asks the pre-rotation hook whether the secret may be rotated now.
+// Rotations are always allowed if no hook is configured.
+func rotationAllowed(instance *corev1.Secret, reason hook.Reason) (bool, error) {
+	url := preRotationHookURL()
+	if url == "" {
+		return true, nil
+	}
+
+	h := hook.Hook{URL: url, Timeout: hookTimeout()}
+	return h.Allow(context.TODO(), rotationEvent(instance, reason))
+}
+
+// notifyRotation posts the outcome of a rotation to all post-rotation hooks. Errors are only logged,
+// a failing hook must not prevent the rotation.
+func notifyRotation(reqLogger logr.Logger, event hook.Event, rotationErr error) {
+	n := hook.NewNotification(event, rotationErr)
+
+	for _, url := range postRotationHookURLs() {
+		h := hook.Hook{URL: url, Timeout: hookTimeout()}
+		if err := h.Notify(context.TODO(), n); err != nil {
+			reqLogger.Error(err, "could not call post-rotation hook", "url", url)
+		}
+	}
+}
+
+// notifyRotationFailure notifies the post-rotation hooks about the failed rotation of the secret once, rather
+// than on every retry. The reason is recorded in the rotation-failed annotation until values are generated.
+func (r *ReconcileSecret) notifyRotationFailure(reqLogger logr.Logger, instance *corev1.Secret, reason hook.Reason, rotationErr error) {
+	if instance.Annotations[AnnotationSecretRotationFailed] == string(reason) {
+		return
+	}
+
+	failed := instance.DeepCopy()
+	failed.Annotations[AnnotationSecretRotationFailed] = string(reason)
+	err := r.client.Patch(context.TODO(), failed, mergeFromWithOptimisticLock(instance), client.FieldOwner(FieldManager))
+	if err != nil {
+		// notified again on the next retry
+		reqLogger.Error(err, "could not record failed rotation")
+	}
+	notifyRotation(reqLogger, rotationEvent(instance, reason), rotationErr)
+}
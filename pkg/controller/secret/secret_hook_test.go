@@ -0,0 +1,187 @@
+package secret
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/hook"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPreRotationHookVeto(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+	}))
+	defer srv.Close()
+
+	viper.Set("pre-rotation-hook-url", srv.URL)
+	defer viper.Set("pre-rotation-hook-url", "")
+
+	in := newStringTestSecret("password", map[string]string{
+		AnnotationSecretSecure:     "yes",
+		AnnotationSecretRegenerate: "yes",
+	}, "password")
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), in))
+
+	doReconcile(t, in, false)
+
+	out := &corev1.Secret{}
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{
+		Name:      in.Name,
+		Namespace: in.Namespace}, out))
+
+	require.Equal(t, "password", string(out.Data["password"]))
+	require.Contains(t, out.Annotations, AnnotationSecretRegenerate)
+}
+
+func TestPreRotationHookAllows(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	viper.Set("pre-rotation-hook-url", srv.URL)
+	defer viper.Set("pre-rotation-hook-url", "")
+
+	in := newStringTestSecret("password", map[string]string{
+		AnnotationSecretSecure:     "yes",
+		AnnotationSecretRegenerate: "yes",
+	}, "password")
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), in))
+
+	doReconcile(t, in, false)
+
+	out := &corev1.Secret{}
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{
+		Name:      in.Name,
+		Namespace: in.Namespace}, out))
+
+	require.NotEqual(t, "password", string(out.Data["password"]))
+}
+
+func TestPostRotationNotification(t *testing.T) {
+	var received map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+	}))
+	defer srv.Close()
+
+	viper.Set("post-rotation-hook-urls", srv.URL)
+	defer viper.Set("post-rotation-hook-urls", "")
+
+	in := newStringTestSecret("password", map[string]string{
+		AnnotationSecretSecure:     "yes",
+		AnnotationSecretRegenerate: "yes",
+	}, "password")
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), in))
+
+	doReconcile(t, in, false)
+
+	out := &corev1.Secret{}
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{
+		Name:      in.Name,
+		Namespace: in.Namespace}, out))
+
+	require.Equal(t, in.Name, received["name"])
+	require.Equal(t, string(hook.ReasonRequested), received["reason"])
+	require.Equal(t, true, received["success"])
+	require.NotContains(t, fmt.Sprint(received), string(out.Data["password"]))
+}
+
+func TestFailedRotationIsNotifiedOnce(t *testing.T) {
+	pre := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer pre.Close()
+
+	var notified []map[string]interface{}
+	post := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var received map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		notified = append(notified, received)
+	}))
+	defer post.Close()
+
+	viper.Set("pre-rotation-hook-url", pre.URL)
+	defer viper.Set("pre-rotation-hook-url", "")
+	viper.Set("post-rotation-hook-urls", post.URL)
+	defer viper.Set("post-rotation-hook-urls", "")
+
+	in := newStringTestSecret("password", map[string]string{
+		AnnotationSecretSecure:     "yes",
+		AnnotationSecretRegenerate: "yes",
+	}, "password")
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), in))
+
+	// retries of the failed rotation are not notified again
+	doReconcile(t, in, true)
+	doReconcile(t, in, true)
+
+	require.Len(t, notified, 1)
+	require.Equal(t, false, notified[0]["success"])
+
+	out := &corev1.Secret{}
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{
+		Name:      in.Name,
+		Namespace: in.Namespace}, out))
+	require.Equal(t, string(hook.ReasonRequested), out.Annotations[AnnotationSecretRotationFailed])
+}
+
+func TestPostRotationNotificationListsReplacedFields(t *testing.T) {
+	var received map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+	}))
+	defer srv.Close()
+
+	viper.Set("post-rotation-hook-urls", srv.URL)
+	defer viper.Set("post-rotation-hook-urls", "")
+
+	in := newStringTestSecret("apiKey,dbPassword", map[string]string{
+		AnnotationSecretSecure:     "yes",
+		AnnotationSecretRegenerate: "apiKey",
+	}, "key,password")
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), in))
+
+	doReconcile(t, in, false)
+
+	require.Equal(t, []interface{}{"apiKey"}, received["fields"])
+}
+
+func TestRotationReason(t *testing.T) {
+	in := newStringTestSecret("password", nil, "")
+	require.Equal(t, hook.ReasonCreated, rotationReason(in, 0, time.Now()))
+
+	in.Data["password"] = []byte("password")
+	in.Annotations[AnnotationSecretRegenerate] = "yes"
+	require.Equal(t, hook.ReasonRequested, rotationReason(in, 0, time.Now()))
+
+	in.Annotations[AnnotationSecretRequestedBy] = "RotationRequest/all"
+	require.Equal(t, hook.ReasonBulk, rotationReason(in, 0, time.Now()))
+}
+
+func TestTriggerIsRecorded(t *testing.T) {
+	in := newStringTestSecret("password", map[string]string{
+		AnnotationSecretSecure:      "yes",
+		AnnotationSecretRegenerate:  "yes",
+		AnnotationSecretRequestedBy: "RotationRequest/all",
+	}, "password")
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), in))
+
+	doReconcile(t, in, false)
+
+	out := &corev1.Secret{}
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{
+		Name:      in.Name,
+		Namespace: in.Namespace}, out))
+
+	require.Equal(t, string(hook.ReasonBulk), out.Annotations[AnnotationSecretTrigger])
+	require.NotContains(t, out.Annotations, AnnotationSecretRequestedBy)
+}
@@ -0,0 +1,134 @@
+package secret
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"reflect"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"strconv"
+	"strings"
+)
+
+// LabelVersionOf marks the immutable versions of a secret with the UID of the secret
+const LabelVersionOf = "secret-generator.v1.mittwald.de/version-of"
+
+// isImmutable reports whether the values of the secret are published in immutable versioned secrets
+func isImmutable(annotations map[string]string) bool {
+	return annotations[AnnotationSecretImmutable] == "true"
+}
+
+// versionName returns the name of the n-th immutable version of the named secret
+func versionName(name string, n int) string {
+	return name + "-v" + strconv.Itoa(n)
+}
+
+// versionNumber returns the number of the named version of the secret, or 0 if it isn't one
+func versionNumber(name, version string) int {
+	n, err := strconv.Atoi(strings.TrimPrefix(version, name+"-v"))
+	if err != nil || !strings.HasPrefix(version, name+"-v") || n < 1 {
+		return 0
+	}
+	return n
+}
+
+// publishVersion makes sure the version of the secret named in the current-version annotation holds the data
+// of desired. Since immutable secrets can't be updated, changed data, e.g. after rotation, is published in
+// a new version with the next name suffix, and the annotation is pointed to it. The previous version is kept
+// for workloads still using it, older ones are deleted.
+func (r *ReconcileSecret) publishVersion(reqLogger logr.Logger, desired *corev1.Secret) error {
+	current, ok := desired.Annotations[AnnotationSecretCurrentVersion]
+	if ok {
+		version := &corev1.Secret{}
+		err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: desired.Namespace, Name: current}, version)
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		if err == nil && reflect.DeepEqual(version.Data, desired.Data) {
+			return nil
+		}
+	}
+
+	next := versionName(desired.Name, versionNumber(desired.Name, current)+1)
+	if err := r.createVersion(desired, next); err != nil {
+		return err
+	}
+	reqLogger.Info("published new immutable version of secret", "version", next)
+	desired.Annotations[AnnotationSecretCurrentVersion] = next
+
+	return r.pruneVersions(reqLogger, desired, current)
+}
+
+// createVersion creates the named immutable version of the secret, owned by it. A version left over by a
+// failed update of the secret is replaced, secrets which merely happen to have its name are never touched.
+func (r *ReconcileSecret) createVersion(desired *corev1.Secret, name string) error {
+	existing := &corev1.Secret{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: desired.Namespace, Name: name}, existing)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	if err == nil {
+		if existing.Labels[LabelVersionOf] != string(desired.UID) {
+			return fmt.Errorf("secret %s already exists and is not a version of this secret", name)
+		}
+		if err := r.client.Delete(context.TODO(), existing); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	version := &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   desired.Namespace,
+			Name:        name,
+			Labels:      SelectorLabels(),
+			Annotations: map[string]string{AnnotationSecretOwner: replicaOwner(desired)},
+		},
+		Type: desired.Type,
+		Data: desired.Data,
+	}
+	if version.Labels == nil {
+		version.Labels = make(map[string]string)
+	}
+	version.Labels[LabelVersionOf] = string(desired.UID)
+	if err := controllerutil.SetControllerReference(desired, version, r.scheme); err != nil {
+		return err
+	}
+
+	// the vendored API types predate the immutable field of secrets
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(version)
+	if err != nil {
+		return err
+	}
+	obj["immutable"] = true
+	return r.client.Create(context.TODO(), &unstructured.Unstructured{Object: obj})
+}
+
+// pruneVersions deletes the versions of the secret other than the current and the given previous one
+func (r *ReconcileSecret) pruneVersions(reqLogger logr.Logger, desired *corev1.Secret, previous string) error {
+	list := &corev1.SecretList{}
+	err := r.client.List(context.TODO(), list, client.InNamespace(desired.Namespace), client.MatchingLabels{LabelVersionOf: string(desired.UID)})
+	if err != nil {
+		return err
+	}
+
+	for i := range list.Items {
+		s := &list.Items[i]
+		if s.Name == previous || s.Name == desired.Annotations[AnnotationSecretCurrentVersion] {
+			continue
+		}
+
+		reqLogger.Info("removing outdated version of secret", "version", s.Name)
+		if err := r.client.Delete(context.TODO(), s); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package secret
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"testing"
+)
+
+func TestVersionNumber(t *testing.T) {
+	require.Equal(t, 0, versionNumber("db", ""))
+	require.Equal(t, 1, versionNumber("db", versionName("db", 1)))
+	require.Equal(t, 12, versionNumber("db", "db-v12"))
+	require.Equal(t, 0, versionNumber("db", "other-v2"))
+	require.Equal(t, 0, versionNumber("db", "db-vx"))
+}
+
+func TestImmutableSecretVersions(t *testing.T) {
+	in := newStringTestSecret("password", map[string]string{
+		AnnotationSecretImmutable: "true",
+	}, "")
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), in))
+	doReconcile(t, in, false)
+
+	out := &corev1.Secret{}
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{Name: in.Name, Namespace: in.Namespace}, out))
+	require.Equal(t, versionName(in.Name, 1), out.Annotations[AnnotationSecretCurrentVersion])
+
+	first := &unstructured.Unstructured{}
+	first.SetAPIVersion("v1")
+	first.SetKind("Secret")
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{Name: versionName(in.Name, 1), Namespace: in.Namespace}, first))
+	immutable, _, _ := unstructured.NestedBool(first.Object, "immutable")
+	require.True(t, immutable)
+	require.Equal(t, string(out.UID), first.GetLabels()[LabelVersionOf])
+
+	// reconciling again without changes keeps the version
+	doReconcile(t, out, false)
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{Name: in.Name, Namespace: in.Namespace}, out))
+	require.Equal(t, versionName(in.Name, 1), out.Annotations[AnnotationSecretCurrentVersion])
+
+	// rotations publish new versions, keeping only the previous one
+	for i := 0; i < 2; i++ {
+		out.Annotations[AnnotationSecretRegenerate] = "yes"
+		require.NoError(t, mgr.GetClient().Update(context.TODO(), out))
+		doReconcile(t, out, false)
+		require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{Name: in.Name, Namespace: in.Namespace}, out))
+	}
+	require.Equal(t, versionName(in.Name, 3), out.Annotations[AnnotationSecretCurrentVersion])
+
+	current := &corev1.Secret{}
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{Name: versionName(in.Name, 3), Namespace: in.Namespace}, current))
+	require.Equal(t, out.Data["password"], current.Data["password"])
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{Name: versionName(in.Name, 2), Namespace: in.Namespace}, &corev1.Secret{}))
+
+	err := mgr.GetClient().Get(context.TODO(), types.NamespacedName{Name: versionName(in.Name, 1), Namespace: in.Namespace}, &corev1.Secret{})
+	require.True(t, errors.IsNotFound(err))
+}
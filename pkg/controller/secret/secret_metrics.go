@@ -0,0 +1,30 @@
+package secret
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"time"
+)
+
+// generationDuration tracks how long generating a single value takes per secret type,
+// which differs by orders of magnitude between random strings and RSA keys
+var generationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "secret_generator_generation_duration_seconds",
+	Help:    "Time taken to generate a single value, by secret type",
+	Buckets: prometheus.ExponentialBuckets(0.00001, 4, 12),
+}, []string{"type"})
+
+// selfTestPassing reports whether the last self-test of the entropy source and generators passed
+var selfTestPassing = prometheus.NewGauge(prometheus.GaugeOpts{
+	Name: "secret_generator_self_test_passing",
+	Help: "Whether the last self-test of the entropy source and generators passed (1) or failed (0)",
+})
+
+func init() {
+	metrics.Registry.MustRegister(generationDuration, selfTestPassing)
+}
+
+// observeGeneration records the time since start as the duration of generating a value of the given type
+func observeGeneration(sType SecretType, start time.Time) {
+	generationDuration.WithLabelValues(string(sType)).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,116 @@
+package secret
+
+import (
+	"context"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"reflect"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"strings"
+)
+
+func excludedNamespaces() []string {
+	var namespaces []string
+	for _, ns := range strings.Split(options().GetString("exclude-namespaces"), ",") {
+		if ns = strings.TrimSpace(ns); ns != "" {
+			namespaces = append(namespaces, ns)
+		}
+	}
+	return namespaces
+}
+
+// namespaceAllowed reports whether secrets in the namespace of obj are processed
+func namespaceAllowed(obj metav1.Object) bool {
+	return !contains(excludedNamespaces(), obj.GetNamespace())
+}
+
+// namespacePredicate filters events for secrets in excluded namespaces, e.g. kube-system
+func namespacePredicate() predicate.Predicate {
+	return predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			return namespaceAllowed(e.Meta)
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return namespaceAllowed(e.MetaNew)
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return namespaceAllowed(e.Meta)
+		},
+		GenericFunc: func(e event.GenericEvent) bool {
+			return namespaceAllowed(e.Meta)
+		},
+	}
+}
+
+// namespaceSelector returns the selector namespaces have to match for their secrets to be processed
+func namespaceSelector() (labels.Selector, error) {
+	return labels.Parse(options().GetString("namespace-selector"))
+}
+
+// namespaceEnabled reports whether the secrets of namespace are processed. The enabled annotation of the
+// namespace takes precedence over the namespace selector.
+func (r *ReconcileSecret) namespaceEnabled(namespace string) (bool, error) {
+	selector, err := namespaceSelector()
+	if err != nil {
+		return false, err
+	}
+
+	ns := &corev1.Namespace{}
+	if err := r.client.Get(context.TODO(), types.NamespacedName{Name: namespace}, ns); err != nil {
+		return false, err
+	}
+
+	switch ns.Annotations[AnnotationNamespaceEnabled] {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	return selector.Matches(labels.Set(ns.Labels)), nil
+}
+
+// namespaceChangedPredicate passes updates of namespaces whose labels or annotations changed, which may
+// enable or disable processing of their secrets
+func namespaceChangedPredicate() predicate.Predicate {
+	return predicate.Funcs{
+		CreateFunc: func(event.CreateEvent) bool {
+			return false
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return !reflect.DeepEqual(e.MetaOld.GetLabels(), e.MetaNew.GetLabels()) ||
+				!reflect.DeepEqual(e.MetaOld.GetAnnotations(), e.MetaNew.GetAnnotations())
+		},
+		DeleteFunc: func(event.DeleteEvent) bool {
+			return false
+		},
+		GenericFunc: func(event.GenericEvent) bool {
+			return false
+		},
+	}
+}
+
+// secretsInNamespace maps a namespace to the secrets it contains, so they are processed once the namespace
+// is labelled to match the namespace selector or processing is enabled by its annotation
+func secretsInNamespace(c client.Client) handler.ToRequestsFunc {
+	return func(a handler.MapObject) []reconcile.Request {
+		list := &corev1.SecretList{}
+		if err := c.List(context.TODO(), list, client.InNamespace(a.Meta.GetName())); err != nil {
+			log.Error(err, "could not list secrets")
+			return nil
+		}
+
+		var requests []reconcile.Request
+		for _, s := range list.Items {
+			requests = append(requests, reconcile.Request{
+				NamespacedName: types.NamespacedName{Namespace: s.Namespace, Name: s.Name},
+			})
+		}
+		return requests
+	}
+}
@@ -0,0 +1,99 @@
+package secret
+
+import (
+	"context"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"testing"
+)
+
+func TestNamespacePredicate(t *testing.T) {
+	viper.Set("exclude-namespaces", "kube-system, kube-public")
+	defer viper.Set("exclude-namespaces", "")
+
+	p := namespacePredicate()
+	for ns, expected := range map[string]bool{
+		"default":     true,
+		"kube-system": false,
+		"kube-public": false,
+	} {
+		s := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: ns}}
+		if actual := p.Create(event.CreateEvent{Meta: s, Object: s}); actual != expected {
+			t.Errorf("expected create event in namespace %s to pass: %t, got %t", ns, expected, actual)
+		}
+		if actual := p.Update(event.UpdateEvent{MetaOld: s, ObjectOld: s, MetaNew: s, ObjectNew: s}); actual != expected {
+			t.Errorf("expected update event in namespace %s to pass: %t, got %t", ns, expected, actual)
+		}
+	}
+}
+
+func TestNamespaceSelector(t *testing.T) {
+	viper.Set("namespace-selector", "secret-generator-test=selected")
+	defer viper.Set("namespace-selector", "")
+
+	in := newStringTestSecret("testfield", nil, "")
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), in))
+
+	doReconcile(t, in, false)
+
+	out := &corev1.Secret{}
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{
+		Name:      in.Name,
+		Namespace: in.Namespace}, out))
+	require.Empty(t, out.Data["testfield"], "secret in unselected namespace must not be generated")
+
+	viper.Set("namespace-selector", "")
+	doReconcile(t, in, false)
+
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{
+		Name:      in.Name,
+		Namespace: in.Namespace}, out))
+	require.NotEmpty(t, out.Data["testfield"])
+}
+
+// setNamespaceAnnotation sets the annotation on namespace, or removes it if value is empty
+func setNamespaceAnnotation(t *testing.T, namespace, annotation, value string) {
+	ns := &corev1.Namespace{}
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{Name: namespace}, ns))
+	if ns.Annotations == nil {
+		ns.Annotations = make(map[string]string)
+	}
+	if value == "" {
+		delete(ns.Annotations, annotation)
+	} else {
+		ns.Annotations[annotation] = value
+	}
+	require.NoError(t, mgr.GetClient().Update(context.TODO(), ns))
+}
+
+func TestNamespaceEnabledAnnotation(t *testing.T) {
+	setNamespaceAnnotation(t, "default", AnnotationNamespaceEnabled, "false")
+	defer setNamespaceAnnotation(t, "default", AnnotationNamespaceEnabled, "")
+
+	in := newStringTestSecret("testfield", nil, "")
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), in))
+
+	doReconcile(t, in, false)
+
+	out := &corev1.Secret{}
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{
+		Name:      in.Name,
+		Namespace: in.Namespace}, out))
+	require.Empty(t, out.Data["testfield"], "secret in disabled namespace must not be generated")
+
+	// the annotation takes precedence over the namespace selector
+	viper.Set("namespace-selector", "secret-generator-test=selected")
+	defer viper.Set("namespace-selector", "")
+	setNamespaceAnnotation(t, "default", AnnotationNamespaceEnabled, "true")
+
+	doReconcile(t, in, false)
+
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{
+		Name:      in.Name,
+		Namespace: in.Namespace}, out))
+	require.NotEmpty(t, out.Data["testfield"])
+}
@@ -0,0 +1,45 @@
+package secret
+
+import (
+	"fmt"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"time"
+)
+
+// GenerateOffline fills in the values of the given secrets without a cluster, e.g. to create manifests for
+// air-gapped installs where no controller runs. objects are served in place of the cluster's objects, e.g.
+// password policies or secrets referenced by templates, and scheme must contain their types. Secrets without
+// generator annotations are left unchanged.
+func GenerateOffline(scheme *runtime.Scheme, secrets []*corev1.Secret, objects ...runtime.Object) error {
+	for _, s := range secrets {
+		objects = append(objects, s.DeepCopy())
+	}
+	c := fake.NewFakeClientWithScheme(scheme, objects...)
+
+	// events are dropped, as there is no cluster to record them in
+	r := &ReconcileSecret{client: c, reader: c, scheme: scheme, recorder: &record.FakeRecorder{}}
+	for _, s := range secrets {
+		if !IsManaged(s.Annotations) {
+			continue
+		}
+
+		// the API server converts stringData to data on writes
+		for key, value := range s.StringData {
+			if s.Data == nil {
+				s.Data = map[string][]byte{}
+			}
+			s.Data[key] = []byte(value)
+		}
+		s.StringData = nil
+
+		reqLogger := log.WithValues("Secret.Namespace", s.Namespace, "Secret.Name", s.Name)
+		if _, _, err := r.generate(reqLogger, s); err != nil {
+			return fmt.Errorf("could not generate values of secret %s: %w", s.Name, err)
+		}
+		s.Annotations[AnnotationSecretAutoGeneratedAt] = time.Now().Format(time.RFC3339)
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package secret
+
+import (
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"testing"
+)
+
+func TestGenerateOffline(t *testing.T) {
+	managed := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "database",
+			Namespace: "default",
+			Annotations: map[string]string{
+				AnnotationSecretAutoGenerate: "password",
+				AnnotationSecretLength:       "20",
+			},
+		},
+		StringData: map[string]string{"username": "admin"},
+	}
+	unmanaged := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "default"},
+		Data:       map[string][]byte{"key": []byte("value")},
+	}
+
+	require.NoError(t, GenerateOffline(scheme.Scheme, []*corev1.Secret{managed, unmanaged}))
+
+	require.Len(t, managed.Data["password"], 20)
+	require.Equal(t, "admin", string(managed.Data["username"]))
+	require.NotEmpty(t, managed.Annotations[AnnotationSecretAutoGeneratedAt])
+	require.Equal(t, map[string][]byte{"key": []byte("value")}, unmanaged.Data)
+}
@@ -0,0 +1,73 @@
+package secret
+
+import (
+	"context"
+	"fmt"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/resilience"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// RunOnce reconciles all watched secrets in the given namespaces a single time, generating missing values,
+// e.g. when provisioning an environment from a CI pipeline. The manager is not started, so secrets are read
+// from the API server. It returns the number of reconciled secrets, and an error if any of them failed.
+func RunOnce(mgr manager.Manager, namespaces []string) (int, error) {
+	r, err := newDirectReconciler(mgr)
+	if err != nil {
+		return 0, err
+	}
+	return r.reconcileAll(namespaces)
+}
+
+// newDirectReconciler returns a reconciler reading secrets from the API server instead of the manager's cache,
+// for reconciles that must see the latest state even if the cache has not been started or synced
+func newDirectReconciler(mgr manager.Manager) (*ReconcileSecret, error) {
+	c, err := client.New(mgr.GetConfig(), client.Options{Scheme: mgr.GetScheme(), Mapper: mgr.GetRESTMapper()})
+	if err != nil {
+		return nil, err
+	}
+
+	r := newReconciler(mgr)
+	r.client = resilience.Client(c)
+	return r, nil
+}
+
+// reconcileAll reconciles every watched secret of the namespaces, continuing with the remaining secrets if one fails
+func (r *ReconcileSecret) reconcileAll(namespaces []string) (int, error) {
+	selector, err := labels.Parse(labelSelector())
+	if err != nil {
+		return 0, err
+	}
+
+	reconciled, failed := 0, 0
+	for _, namespace := range namespaces {
+		list := &corev1.SecretList{}
+		if err := r.reader.List(context.TODO(), list, &client.ListOptions{Namespace: namespace, LabelSelector: selector}); err != nil {
+			return reconciled, err
+		}
+
+		for i := range list.Items {
+			s := &list.Items[i]
+			if typeIgnored(s.Type) || !namespaceAllowed(s) {
+				continue
+			}
+
+			request := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: s.Namespace, Name: s.Name}}
+			if _, err := r.reconcileSecret(request); err != nil {
+				log.Error(err, "could not reconcile secret", "Request.Namespace", s.Namespace, "Request.Name", s.Name)
+				failed++
+				continue
+			}
+			reconciled++
+		}
+	}
+
+	if failed > 0 {
+		return reconciled, fmt.Errorf("%d of %d secrets could not be reconciled", failed, reconciled+failed)
+	}
+	return reconciled, nil
+}
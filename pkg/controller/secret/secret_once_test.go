@@ -0,0 +1,29 @@
+package secret
+
+import (
+	"context"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"testing"
+)
+
+func TestRunOnceGeneratesMissingValues(t *testing.T) {
+	in := newStringTestSecret("testfield", nil, "")
+	in.Labels["once"] = in.Name
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), in))
+
+	viper.Set("label-selector", "once="+in.Name)
+	defer viper.Set("label-selector", "")
+
+	reconciled, err := RunOnce(mgr, []string{"default"})
+	require.NoError(t, err)
+	require.Equal(t, 1, reconciled)
+
+	out := &corev1.Secret{}
+	require.NoError(t, mgr.GetAPIReader().Get(context.TODO(), types.NamespacedName{
+		Name:      in.Name,
+		Namespace: in.Namespace}, out))
+	require.NotEmpty(t, out.Data["testfield"])
+}
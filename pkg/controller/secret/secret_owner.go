@@ -0,0 +1,33 @@
+package secret
+
+import (
+	"github.com/mittwald/kubernetes-secret-generator/pkg/apis/secretgenerator/v1alpha1"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/sealing"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// foreignController returns the controller owning instance unless it is one of the secret generator's own
+// resources or the force annotation is set. Values of secrets managed by operators like cert-manager are
+// not to be generated, as both controllers would keep overwriting each other's changes.
+func foreignController(instance *corev1.Secret) *metav1.OwnerReference {
+	if _, force := instance.Annotations[AnnotationSecretForce]; force {
+		return nil
+	}
+
+	owner := metav1.GetControllerOf(instance)
+	if owner == nil {
+		return nil
+	}
+
+	if gv, err := schema.ParseGroupVersion(owner.APIVersion); err == nil && gv.Group == v1alpha1.SchemeGroupVersion.Group {
+		return nil
+	}
+	// the sealed-secrets controller unseals the values the secret generator sealed into a SealedSecret
+	if owner.APIVersion == sealing.APIVersion && owner.Kind == sealing.Kind && owner.Name == instance.Name &&
+		instance.Annotations[AnnotationSecretSealedOutput] == SealedOutputReplace {
+		return nil
+	}
+	return owner
+}
@@ -0,0 +1,61 @@
+package secret
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"testing"
+)
+
+func newControlledTestSecret(t *testing.T, extraAnnotations map[string]string) *corev1.Secret {
+	owner := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      getSecretName(),
+			Namespace: "default",
+			Labels: map[string]string{
+				labelSecretGeneratorTest: "yes",
+			},
+		},
+	}
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), owner))
+
+	controller := true
+	in := newStringTestSecret("testfield", extraAnnotations, "")
+	in.OwnerReferences = []metav1.OwnerReference{{
+		APIVersion: "v1",
+		Kind:       "ConfigMap",
+		Name:       owner.Name,
+		UID:        owner.UID,
+		Controller: &controller,
+	}}
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), in))
+	return in
+}
+
+func TestSkipsSecretsControlledByOtherControllers(t *testing.T) {
+	in := newControlledTestSecret(t, nil)
+
+	doReconcile(t, in, false)
+
+	out := &corev1.Secret{}
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{
+		Name:      in.Name,
+		Namespace: in.Namespace}, out))
+	require.Empty(t, out.Data["testfield"], "secret controlled by another controller must not be generated")
+}
+
+func TestForceGeneratesSecretsControlledByOtherControllers(t *testing.T) {
+	in := newControlledTestSecret(t, map[string]string{
+		AnnotationSecretForce: "yes",
+	})
+
+	doReconcile(t, in, false)
+
+	out := &corev1.Secret{}
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{
+		Name:      in.Name,
+		Namespace: in.Namespace}, out))
+	require.NotEmpty(t, out.Data["testfield"])
+}
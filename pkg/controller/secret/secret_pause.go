@@ -0,0 +1,29 @@
+package secret
+
+import (
+	"context"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"time"
+)
+
+// pausedRequeueInterval is the interval in which secrets are checked again while the controller is paused
+const pausedRequeueInterval = time.Minute
+
+func pauseNamespace() string {
+	return options().GetString("pause-namespace")
+}
+
+// paused reports whether generation and rotation are suspended by the paused annotation on the pause
+// namespace, usually the namespace the controller runs in
+func (r *ReconcileSecret) paused() (bool, error) {
+	if pauseNamespace() == "" {
+		return false, nil
+	}
+
+	ns := &corev1.Namespace{}
+	if err := r.client.Get(context.TODO(), types.NamespacedName{Name: pauseNamespace()}, ns); err != nil {
+		return false, err
+	}
+	return ns.Annotations[AnnotationPaused] == "true", nil
+}
@@ -0,0 +1,36 @@
+package secret
+
+import (
+	"context"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"testing"
+)
+
+func TestPausedControllerDoesNotGenerate(t *testing.T) {
+	viper.Set("pause-namespace", "default")
+	defer viper.Set("pause-namespace", "")
+	setNamespaceAnnotation(t, "default", AnnotationPaused, "true")
+	defer setNamespaceAnnotation(t, "default", AnnotationPaused, "")
+
+	in := newStringTestSecret("testfield", nil, "")
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), in))
+
+	doReconcile(t, in, false)
+
+	out := &corev1.Secret{}
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{
+		Name:      in.Name,
+		Namespace: in.Namespace}, out))
+	require.Empty(t, out.Data["testfield"], "paused controller must not generate values")
+
+	setNamespaceAnnotation(t, "default", AnnotationPaused, "")
+	doReconcile(t, in, false)
+
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{
+		Name:      in.Name,
+		Namespace: in.Namespace}, out))
+	require.NotEmpty(t, out.Data["testfield"])
+}
@@ -0,0 +1,340 @@
+package secret
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/go-logr/logr"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/fips"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/generatorplugin"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/wasmplugin"
+	"io"
+	corev1 "k8s.io/api/core/v1"
+	"os/exec"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"strings"
+	"time"
+)
+
+// PluginAPIVersion is the version of the requests sent to and the responses read from generator plugins
+const PluginAPIVersion = "secret-generator.v1.mittwald.de/plugin"
+
+// maxPluginStderr limits the output of a failed plugin included in errors
+const maxPluginStderr = 1024
+
+func generatorPluginTimeout() time.Duration {
+	return options().GetDuration("generator-plugin-timeout")
+}
+
+// ParseGeneratorPlugins parses a comma-separated list of type=path pairs, mapping custom secret types to
+// the executables generating their values
+func ParseGeneratorPlugins(val string) (map[SecretType]string, error) {
+	plugins := make(map[SecretType]string)
+	for _, entry := range strings.Split(val, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid generator plugin %q, expected type=path", entry)
+		}
+		sType := SecretType(parts[0])
+		switch sType {
+		case SecretTypeString, SecretTypeSSHKeypair:
+			return nil, fmt.Errorf("generator plugin can't replace built-in type %s", sType)
+		}
+		if _, ok := plugins[sType]; ok {
+			return nil, fmt.Errorf("generator plugin for type %s is registered twice", sType)
+		}
+		plugins[sType] = parts[1]
+	}
+	return plugins, nil
+}
+
+// pluginHost runs the gRPC generator plugins, it is nil if none are registered
+var pluginHost *generatorplugin.Host
+
+// SetupGRPCPlugins creates the host of the gRPC generator plugins registered using grpc-generator-plugins,
+// which is used to generate the fields of their types. It returns nil if no plugins are registered.
+func SetupGRPCPlugins() (*generatorplugin.Host, error) {
+	plugins, err := ParseGeneratorPlugins(options().GetString("grpc-generator-plugins"))
+	if err != nil {
+		return nil, err
+	}
+	if len(plugins) == 0 {
+		return nil, nil
+	}
+
+	paths := make(map[string]string, len(plugins))
+	for sType, path := range plugins {
+		if _, ok := generatorPlugin(sType); ok {
+			return nil, fmt.Errorf("type %s is registered as exec and gRPC generator plugin", sType)
+		}
+		paths[string(sType)] = path
+	}
+	pluginHost = generatorplugin.NewHost(paths)
+	return pluginHost, nil
+}
+
+// wasmRuntime runs the WebAssembly generator plugins, it is nil if none are registered
+var wasmRuntime *wasmplugin.Runtime
+
+func wasmPluginMaxMemory() int {
+	return options().GetInt("wasm-plugin-max-memory")
+}
+
+// SetupWASMPlugins compiles the WebAssembly generator plugins registered using wasm-generator-plugins, which
+// are used to generate the fields of their types. It returns nil if no plugins are registered.
+func SetupWASMPlugins() (*wasmplugin.Runtime, error) {
+	plugins, err := ParseGeneratorPlugins(options().GetString("wasm-generator-plugins"))
+	if err != nil {
+		return nil, err
+	}
+	if len(plugins) == 0 {
+		return nil, nil
+	}
+
+	paths := make(map[string]string, len(plugins))
+	for sType, path := range plugins {
+		if isPluginType(sType) {
+			return nil, fmt.Errorf("type %s is registered as WebAssembly and exec or gRPC generator plugin", sType)
+		}
+		paths[string(sType)] = path
+	}
+	wasmRuntime, err = wasmplugin.NewRuntime(context.Background(), paths, wasmPluginMaxMemory())
+	if err != nil {
+		return nil, err
+	}
+	return wasmRuntime, nil
+}
+
+// isPluginType reports whether an exec, gRPC or WebAssembly generator plugin is registered for the type
+func isPluginType(sType SecretType) bool {
+	if _, ok := generatorPlugin(sType); ok {
+		return true
+	}
+	if wasmRuntime != nil && wasmRuntime.Has(string(sType)) {
+		return true
+	}
+	return pluginHost != nil && pluginHost.Has(string(sType))
+}
+
+// generatorPlugin returns the path of the plugin registered for the secret type. The flag is validated
+// on startup, so malformed values only occur if a reloaded config file broke it.
+func generatorPlugin(sType SecretType) (string, bool) {
+	plugins, err := ParseGeneratorPlugins(options().GetString("generator-plugins"))
+	if err != nil {
+		return "", false
+	}
+	path, ok := plugins[sType]
+	return path, ok
+}
+
+// PluginRequest is written to the standard input of a generator plugin
+type PluginRequest struct {
+	APIVersion string     `json:"apiVersion"`
+	Type       SecretType `json:"type"`
+	// Field is the key of the value to generate. Plugins may return additional values under keys prefixed
+	// with the field and a dot, e.g. the certificate of a generated private key.
+	Field     string   `json:"field"`
+	Length    int      `json:"length,omitempty"`
+	Encoding  Encoding `json:"encoding,omitempty"`
+	Namespace string   `json:"namespace"`
+	Secret    string   `json:"secret"`
+}
+
+// PluginResponse is read from the standard output of a generator plugin
+type PluginResponse struct {
+	APIVersion string `json:"apiVersion"`
+	// Values maps keys to the generated values, which are base64 encoded in JSON
+	Values map[string][]byte `json:"values"`
+}
+
+// runPlugin runs the generator plugin at path, returning the values it generated for the request
+func runPlugin(path string, req PluginRequest) (map[string][]byte, error) {
+	req.APIVersion = PluginAPIVersion
+	in, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), generatorPluginTimeout())
+	defer cancel()
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	cmd := exec.CommandContext(ctx, path)
+	cmd.Stdin = bytes.NewReader(in)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err := cmd.Run(); err != nil {
+		msg := stderr.String()
+		if len(msg) > maxPluginStderr {
+			msg = msg[:maxPluginStderr]
+		}
+		return nil, fmt.Errorf("generator plugin %s failed: %w: %s", path, err, strings.TrimSpace(msg))
+	}
+
+	return decodePluginResponse(path, stdout, req.Field)
+}
+
+// runWASMPlugin runs the WebAssembly generator plugin registered for the type of the request
+func runWASMPlugin(req PluginRequest) (map[string][]byte, error) {
+	req.APIVersion = PluginAPIVersion
+	in, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), generatorPluginTimeout())
+	defer cancel()
+
+	out, err := wasmRuntime.Run(ctx, string(req.Type), in)
+	if err != nil {
+		return nil, fmt.Errorf("generator plugin for type %s failed: %w", req.Type, err)
+	}
+	return decodePluginResponse("for type "+string(req.Type), bytes.NewReader(out), req.Field)
+}
+
+// decodePluginResponse decodes the response written by an exec or WebAssembly plugin to its standard output
+func decodePluginResponse(plugin string, stdout io.Reader, field string) (map[string][]byte, error) {
+	res := &PluginResponse{}
+	dec := json.NewDecoder(stdout)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(res); err != nil {
+		return nil, fmt.Errorf("invalid response of generator plugin %s: %w", plugin, err)
+	}
+	if res.APIVersion != PluginAPIVersion {
+		return nil, fmt.Errorf("generator plugin %s responded with unsupported apiVersion %q", plugin, res.APIVersion)
+	}
+	if err := checkPluginValues(res.Values, field); err != nil {
+		return nil, fmt.Errorf("generator plugin %s %w", plugin, err)
+	}
+	return res.Values, nil
+}
+
+// runGRPCPlugin requests the values from the gRPC generator plugin registered for the type of the request
+func runGRPCPlugin(req PluginRequest) (map[string][]byte, error) {
+	g, err := pluginHost.Generator(string(req.Type))
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), generatorPluginTimeout())
+	defer cancel()
+
+	res, err := g.Generate(ctx, &generatorplugin.GenerateRequest{
+		Type:      string(req.Type),
+		Field:     req.Field,
+		Length:    int32(req.Length),
+		Encoding:  string(req.Encoding),
+		Namespace: req.Namespace,
+		Secret:    req.Secret,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("generator plugin for type %s failed: %w", req.Type, err)
+	}
+	if err := checkPluginValues(res.Values, req.Field); err != nil {
+		return nil, fmt.Errorf("generator plugin for type %s %w", req.Type, err)
+	}
+	return res.Values, nil
+}
+
+// checkPluginValues ensures that a plugin generated a value for the field, and no values outside of it
+func checkPluginValues(values map[string][]byte, field string) error {
+	if len(values[field]) == 0 {
+		return fmt.Errorf("returned no value for field %s", field)
+	}
+	for key := range values {
+		if key != field && !strings.HasPrefix(key, field+".") {
+			return fmt.Errorf("returned value for key %s outside of field %s", key, field)
+		}
+	}
+	return nil
+}
+
+// generatePluginField sets the values generated by the plugin of the field's type
+func generatePluginField(instance *corev1.Secret, sType SecretType, field string, length int, encoding Encoding) error {
+	if err := fips.Disallow("generator plugin type "+string(sType), errPluginNotApproved); err != nil {
+		return err
+	}
+
+	req := PluginRequest{
+		Type:      sType,
+		Field:     field,
+		Length:    length,
+		Encoding:  encoding,
+		Namespace: instance.Namespace,
+		Secret:    instance.Name,
+	}
+
+	start := time.Now()
+	var values map[string][]byte
+	var err error
+	if path, ok := generatorPlugin(sType); ok {
+		values, err = runPlugin(path, req)
+	} else if pluginHost != nil && pluginHost.Has(string(sType)) {
+		values, err = runGRPCPlugin(req)
+	} else if wasmRuntime != nil && wasmRuntime.Has(string(sType)) {
+		values, err = runWASMPlugin(req)
+	} else {
+		err = fmt.Errorf("no generator plugin registered for type %s", sType)
+	}
+	observeGeneration(sType, start)
+	if err != nil {
+		return err
+	}
+
+	for key, value := range values {
+		instance.Data[key] = value
+	}
+	return nil
+}
+
+// PluginGenerator generates the fields of the autogenerate annotation using the generator plugin registered
+// for the type annotation
+type PluginGenerator struct {
+	log   logr.Logger
+	sType SecretType
+}
+
+func (pg PluginGenerator) generateData(instance *corev1.Secret) (reconcile.Result, error) {
+	genKeys := autogenerateKeys(instance)
+
+	if err := ensureUniqueness(genKeys); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	regenKeys := keysToRegenerate(pg.log, instance, genKeys)
+
+	// plugins use their own default length
+	length, err := secretLengthFromAnnotation(0, instance.Annotations)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	generatedCount := 0
+	for _, key := range genKeys {
+		if len(instance.Data[key]) != 0 && !contains(regenKeys, key) {
+			pg.log.V(1).Info("field already has a value, keeping it", "field", key)
+			continue
+		}
+		generatedCount++
+
+		if err := generatePluginField(instance, pg.sType, key, length, ""); err != nil {
+			pg.log.Error(err, "could not generate new instance")
+			return reconcile.Result{RequeueAfter: time.Second * 30}, err
+		}
+
+		pg.log.Info("set field of instance to value generated by plugin", "field", key)
+	}
+	pg.log.Info("generated secrets", "count", generatedCount)
+	recordAutogenerated(instance, genKeys)
+
+	if generatedCount == len(genKeys) {
+		instance.Annotations[AnnotationSecretSecure] = "yes"
+	}
+
+	return reconcile.Result{}, nil
+}
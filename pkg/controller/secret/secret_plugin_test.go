@@ -0,0 +1,100 @@
+package secret
+
+import (
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writePlugin writes an executable shell script printing response to a temporary directory
+func writePlugin(t *testing.T, response string) (string, func()) {
+	dir, err := ioutil.TempDir("", "generator-plugin")
+	require.NoError(t, err)
+
+	path := filepath.Join(dir, "plugin")
+	script := "#!/bin/sh\ncat > " + filepath.Join(dir, "request") + "\necho '" + response + "'\n"
+	require.NoError(t, ioutil.WriteFile(path, []byte(script), 0755))
+	return path, func() {
+		_ = os.RemoveAll(dir)
+	}
+}
+
+func registerPlugin(path string) func() {
+	viper.Set("generator-plugins", "totp="+path)
+	viper.Set("generator-plugin-timeout", 5*time.Second)
+	return func() {
+		viper.Set("generator-plugins", "")
+	}
+}
+
+func TestParseGeneratorPlugins(t *testing.T) {
+	plugins, err := ParseGeneratorPlugins("totp=/plugins/totp, x509=/plugins/x509")
+	require.NoError(t, err)
+	require.Equal(t, map[SecretType]string{"totp": "/plugins/totp", "x509": "/plugins/x509"}, plugins)
+
+	_, err = ParseGeneratorPlugins("string=/plugins/string")
+	require.Error(t, err)
+	_, err = ParseGeneratorPlugins("totp")
+	require.Error(t, err)
+}
+
+func TestSpecFieldsUsePluginOfTheirType(t *testing.T) {
+	path, cleanup := writePlugin(t, `{"apiVersion": "`+PluginAPIVersion+`", "values": {"otp": "c2VjcmV0", "otp.uri": "dXJp"}}`)
+	defer cleanup()
+	defer registerPlugin(path)()
+
+	values, err := GenerateFields(&Spec{Fields: []FieldSpec{{Name: "otp", Type: "totp", Length: 20}}})
+	require.NoError(t, err)
+	require.Equal(t, "secret", string(values["otp"]))
+	require.Equal(t, "uri", string(values["otp.uri"]))
+
+	request, err := ioutil.ReadFile(filepath.Join(filepath.Dir(path), "request"))
+	require.NoError(t, err)
+	require.Contains(t, string(request), `"length":20`)
+}
+
+func TestPluginValuesMustBelongToTheField(t *testing.T) {
+	path, cleanup := writePlugin(t, `{"apiVersion": "`+PluginAPIVersion+`", "values": {"otp": "c2VjcmV0", "password": "c2VjcmV0"}}`)
+	defer cleanup()
+	defer registerPlugin(path)()
+
+	instance := &corev1.Secret{Data: map[string][]byte{}}
+	require.Error(t, generatePluginField(instance, "totp", "otp", 0, ""))
+	require.Empty(t, instance.Data)
+}
+
+func TestGeneratedFieldsIncludePluginValues(t *testing.T) {
+	defer registerPlugin("/plugins/totp")()
+
+	instance := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+			AnnotationSecretAutoGenerate: "otp",
+			AnnotationSecretType:         "totp",
+		}},
+		Data: map[string][]byte{
+			"otp":     []byte("secret"),
+			"otp.uri": []byte("uri"),
+			"otp.enc": []byte("ciphertext"),
+			"static":  []byte("static"),
+		},
+	}
+	require.Equal(t, []string{"otp", "otp.uri"}, generatedFields(instance))
+
+	instance.Annotations = map[string]string{
+		AnnotationSecretSpec: `{"fields": [{"name": "otp", "type": "totp"}, {"name": "password"}]}`,
+	}
+	require.Equal(t, []string{"otp", "otp.uri", "password"}, generatedFields(instance))
+}
+
+func TestUnregisteredTypesAreInvalid(t *testing.T) {
+	require.Error(t, SecretType("totp").Validate())
+
+	defer registerPlugin("/plugins/totp")()
+	require.NoError(t, SecretType("totp").Validate())
+}
@@ -0,0 +1,34 @@
+package secret
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"sort"
+)
+
+// pruneStaleFields deletes the values of fields that have been generated before, but are no longer declared
+// by the autogenerate or spec annotation, if the secret has the prune annotation set to true. Generated
+// fields are known from the fingerprints annotation, so values generated by older versions are never pruned.
+func pruneStaleFields(instance *corev1.Secret) []string {
+	if instance.Annotations[AnnotationSecretPrune] != "true" {
+		return nil
+	}
+
+	declared := generatedFields(instance)
+	if len(declared) == 0 {
+		// don't drop all values if the annotations can't be parsed
+		return nil
+	}
+
+	var pruned []string
+	for key := range fingerprints(instance) {
+		if contains(declared, key) {
+			continue
+		}
+		delete(instance.Data, key)
+		delete(instance.Data, key+StagedFieldSuffix)
+		delete(instance.Data, key+EscrowFieldSuffix)
+		pruned = append(pruned, key)
+	}
+	sort.Strings(pruned)
+	return pruned
+}
@@ -0,0 +1,64 @@
+package secret
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"testing"
+)
+
+func TestPruneStaleFields(t *testing.T) {
+	in := newStringTestSecret("password,token", map[string]string{
+		AnnotationSecretPrune: "true",
+	}, "")
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), in))
+
+	doReconcile(t, in, false)
+
+	out := &corev1.Secret{}
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{
+		Name:      in.Name,
+		Namespace: in.Namespace}, out))
+	require.NotEmpty(t, out.Data["token"])
+
+	out.Annotations[AnnotationSecretAutoGenerate] = "password"
+	out.Data["static"] = []byte("static")
+	require.NoError(t, mgr.GetClient().Update(context.TODO(), out))
+
+	doReconcile(t, in, false)
+
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{
+		Name:      in.Name,
+		Namespace: in.Namespace}, out))
+	require.NotContains(t, out.Data, "token")
+	require.NotEmpty(t, out.Data["password"])
+	require.Equal(t, "static", string(out.Data["static"]))
+}
+
+func TestStaleFieldsAreKeptWithoutPrune(t *testing.T) {
+	in := newStringTestSecret("password,token", nil, "")
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), in))
+
+	doReconcile(t, in, false)
+
+	out := &corev1.Secret{}
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{
+		Name:      in.Name,
+		Namespace: in.Namespace}, out))
+
+	out.Annotations[AnnotationSecretAutoGenerate] = "password"
+	require.NoError(t, mgr.GetClient().Update(context.TODO(), out))
+
+	doReconcile(t, in, false)
+
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{
+		Name:      in.Name,
+		Namespace: in.Namespace}, out))
+	require.NotEmpty(t, out.Data["token"])
+
+	// enabling pruning later still removes the field
+	require.Nil(t, pruneStaleFields(out))
+	out.Annotations[AnnotationSecretPrune] = "true"
+	require.Equal(t, []string{"token"}, pruneStaleFields(out))
+}
@@ -0,0 +1,36 @@
+package secret
+
+import (
+	"github.com/mittwald/kubernetes-secret-generator/pkg/hibp"
+)
+
+// pwnedChecker checks values of secrets with the pwned-check annotation, nil if no hibp-source is configured
+var pwnedChecker hibp.Checker
+
+// SetupHIBP sets up the checker configured by the hibp-source flag, either the URL of the Pwned Passwords
+// API or the file:// URL of a bloom filter
+func SetupHIBP() error {
+	source := options().GetString("hibp-source")
+	if source == "" {
+		return nil
+	}
+
+	checker, err := hibp.Open(source)
+	if err != nil {
+		return err
+	}
+	pwnedChecker = checker
+	return nil
+}
+
+// checkPwned rejects values appearing in the Have I Been Pwned corpus
+func checkPwned(value string) (string, error) {
+	pwned, err := pwnedChecker.Pwned([]byte(value))
+	if err != nil {
+		return "", err
+	}
+	if pwned {
+		return "appears in Have I Been Pwned", nil
+	}
+	return "", nil
+}
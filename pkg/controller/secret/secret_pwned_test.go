@@ -0,0 +1,50 @@
+package secret
+
+import (
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+)
+
+// pwnedFirst reports the first n passwords it checks as pwned
+type pwnedFirst struct {
+	n       int
+	checked []string
+}
+
+func (p *pwnedFirst) Pwned(password []byte) (bool, error) {
+	p.checked = append(p.checked, string(password))
+	return len(p.checked) <= p.n, nil
+}
+
+func TestPwnedValuesAreDrawnAgain(t *testing.T) {
+	checker := &pwnedFirst{n: 2}
+	pwnedChecker = checker
+	defer func() { pwnedChecker = nil }()
+
+	instance := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+		AnnotationSecretPwnedCheck: "true",
+	}}}
+	checks, err := valueChecks(instance)
+	require.NoError(t, err)
+
+	pg := newStringGenerator(log, globalDefaults(), nil)
+	pg.checks = checks
+	value, err := pg.generateValue("password", 20)
+	require.NoError(t, err)
+	require.Len(t, checker.checked, 3)
+	require.Equal(t, checker.checked[2], value)
+
+	checker.n = maxDrawAttempts + 10
+	_, err = pg.generateValue("password", 20)
+	require.Error(t, err)
+}
+
+func TestPwnedCheckRequiresSource(t *testing.T) {
+	instance := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+		AnnotationSecretPwnedCheck: "true",
+	}}}
+	_, err := valueChecks(instance)
+	require.Error(t, err)
+}
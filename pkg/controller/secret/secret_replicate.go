@@ -0,0 +1,284 @@
+package secret
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"reflect"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"strings"
+)
+
+// LabelReplicaOf marks the copies of a replicated secret with the UID of the secret, so they are found in all namespaces
+const LabelReplicaOf = "secret-generator.v1.mittwald.de/replica-of"
+
+// replicates reports whether the secret is copied to other namespaces
+func replicates(annotations map[string]string) bool {
+	_, list := annotations[AnnotationSecretReplicateTo]
+	_, selector := annotations[AnnotationSecretReplicateToSelector]
+	return list || selector
+}
+
+// replicaOwner returns the value of the owner annotation of the copies of the secret
+func replicaOwner(instance *corev1.Secret) string {
+	return "Secret/" + instance.Namespace + "/" + instance.Name
+}
+
+// parseReplicateTo returns the namespaces listed in the comma separated replicate-to annotation
+func parseReplicateTo(val string) ([]string, error) {
+	var namespaces []string
+	for _, ns := range strings.Split(val, ",") {
+		if ns = strings.TrimSpace(ns); ns == "" {
+			continue
+		}
+		if errs := validation.IsDNS1123Label(ns); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid namespace %q in %s annotation: %s", ns, AnnotationSecretReplicateTo, strings.Join(errs, ", "))
+		}
+		namespaces = append(namespaces, ns)
+	}
+	return namespaces, nil
+}
+
+// replicationNamespaces returns the namespaces the secret is copied to, listed by its replicate-to annotation
+// or selected by its replicate-to-selector annotation. The namespace of the secret itself is never included.
+func (r *ReconcileSecret) replicationNamespaces(instance *corev1.Secret) ([]string, error) {
+	namespaces, err := parseReplicateTo(instance.Annotations[AnnotationSecretReplicateTo])
+	if err != nil {
+		return nil, err
+	}
+
+	if val, ok := instance.Annotations[AnnotationSecretReplicateToSelector]; ok {
+		selector, err := labels.Parse(val)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s annotation: %w", AnnotationSecretReplicateToSelector, err)
+		}
+
+		list := &corev1.NamespaceList{}
+		if err := r.client.List(context.TODO(), list, &client.ListOptions{LabelSelector: selector}); err != nil {
+			return nil, err
+		}
+		for _, ns := range list.Items {
+			if ns.DeletionTimestamp.IsZero() && !contains(namespaces, ns.Name) {
+				namespaces = append(namespaces, ns.Name)
+			}
+		}
+	}
+
+	var targets []string
+	for _, ns := range namespaces {
+		if ns != instance.Namespace {
+			targets = append(targets, ns)
+		}
+	}
+	return targets, nil
+}
+
+// setReplicationFinalizer adds the finalizer deleting the copies of a replicated secret along with it,
+// or removes it once the secret is no longer replicated
+func setReplicationFinalizer(desired *corev1.Secret) {
+	if replicates(desired.Annotations) {
+		if !contains(desired.Finalizers, FinalizerReplication) {
+			desired.Finalizers = append(desired.Finalizers, FinalizerReplication)
+		}
+		return
+	}
+
+	var finalizers []string
+	for _, f := range desired.Finalizers {
+		if f != FinalizerReplication {
+			finalizers = append(finalizers, f)
+		}
+	}
+	desired.Finalizers = finalizers
+}
+
+// replicate copies the secret to all namespaces it is replicated to, updates copies whose data differs, e.g.
+// after rotation, and deletes copies in namespaces it is no longer replicated to. A namespace failing doesn't
+// keep the secret from being copied to the others.
+func (r *ReconcileSecret) replicate(reqLogger logr.Logger, instance *corev1.Secret) error {
+	namespaces, err := r.replicationNamespaces(instance)
+	if err != nil {
+		r.recorder.Eventf(instance, corev1.EventTypeWarning, "ReplicationFailed", "could not replicate secret: %s", err)
+		return err
+	}
+
+	var failed []string
+	for _, ns := range namespaces {
+		if err := r.replicateTo(reqLogger, instance, ns); err != nil {
+			reqLogger.Error(err, "could not replicate secret", "namespace", ns)
+			r.recorder.Eventf(instance, corev1.EventTypeWarning, "ReplicationFailed", "could not replicate secret to namespace %s: %s", ns, err)
+			failed = append(failed, ns)
+		}
+	}
+
+	if err := r.pruneReplicas(reqLogger, instance, namespaces); err != nil {
+		return err
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("could not replicate secret to namespaces %s", strings.Join(failed, ","))
+	}
+	return nil
+}
+
+// replicateTo creates or updates the copy of the secret in the namespace. Secrets of the same name which
+// are not a copy of the secret are never overwritten.
+func (r *ReconcileSecret) replicateTo(reqLogger logr.Logger, instance *corev1.Secret, namespace string) error {
+	allowed, err := TargetNamespaceAllowed(r.client, instance.Namespace, namespace)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return fmt.Errorf("secrets in namespace %s may not create secrets in namespace %s", instance.Namespace, namespace)
+	}
+
+	replica := &corev1.Secret{}
+	err = r.client.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: instance.Name}, replica)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	if errors.IsNotFound(err) {
+		replica = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   namespace,
+				Name:        instance.Name,
+				Labels:      SelectorLabels(),
+				Annotations: map[string]string{AnnotationSecretOwner: replicaOwner(instance)},
+			},
+			Type: instance.Type,
+			Data: instance.Data,
+		}
+		if replica.Labels == nil {
+			replica.Labels = make(map[string]string)
+		}
+		replica.Labels[LabelReplicaOf] = string(instance.UID)
+
+		reqLogger.Info("replicating secret", "namespace", namespace)
+		return r.client.Create(context.TODO(), replica)
+	}
+
+	if replica.Annotations[AnnotationSecretOwner] != replicaOwner(instance) {
+		return fmt.Errorf("secret %s/%s already exists and is not a copy of this secret", namespace, instance.Name)
+	}
+	if reflect.DeepEqual(replica.Data, instance.Data) && replica.Labels[LabelReplicaOf] == string(instance.UID) {
+		return nil
+	}
+
+	if replica.Labels == nil {
+		replica.Labels = make(map[string]string)
+	}
+	replica.Labels[LabelReplicaOf] = string(instance.UID)
+	replica.Data = instance.Data
+
+	reqLogger.Info("updating copy of secret", "namespace", namespace)
+	return r.client.Update(context.TODO(), replica)
+}
+
+// pruneReplicas deletes the copies of the secret in namespaces other than the given ones
+func (r *ReconcileSecret) pruneReplicas(reqLogger logr.Logger, instance *corev1.Secret, namespaces []string) error {
+	list := &corev1.SecretList{}
+	if err := r.client.List(context.TODO(), list, client.MatchingLabels{LabelReplicaOf: string(instance.UID)}); err != nil {
+		return err
+	}
+
+	for i := range list.Items {
+		s := &list.Items[i]
+		if contains(namespaces, s.Namespace) || s.Annotations[AnnotationSecretOwner] != replicaOwner(instance) {
+			continue
+		}
+
+		reqLogger.Info("removing copy of secret", "namespace", s.Namespace)
+		if err := r.client.Delete(context.TODO(), s); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// finalizeReplication deletes the copies of a deleted secret and removes the finalizer afterwards
+func (r *ReconcileSecret) finalizeReplication(reqLogger logr.Logger, instance *corev1.Secret) error {
+	if !contains(instance.Finalizers, FinalizerReplication) {
+		return nil
+	}
+
+	if err := r.pruneReplicas(reqLogger, instance, nil); err != nil {
+		return err
+	}
+
+	var finalizers []string
+	for _, f := range instance.Finalizers {
+		if f != FinalizerReplication {
+			finalizers = append(finalizers, f)
+		}
+	}
+	instance.Finalizers = finalizers
+
+	return r.client.Update(context.TODO(), instance)
+}
+
+// replicaSource maps a copy of a replicated secret to the secret, so that changes to the copy are reverted
+func replicaSource(a handler.MapObject) []reconcile.Request {
+	if _, ok := a.Meta.GetLabels()[LabelReplicaOf]; !ok {
+		return nil
+	}
+
+	parts := strings.Split(a.Meta.GetAnnotations()[AnnotationSecretOwner], "/")
+	if len(parts) != 3 || parts[0] != "Secret" {
+		return nil
+	}
+
+	return []reconcile.Request{
+		{NamespacedName: types.NamespacedName{Namespace: parts[1], Name: parts[2]}},
+	}
+}
+
+// replicatingSecrets maps a namespace to all replicated secrets, so they are copied to namespaces which are
+// created or labelled after them
+func replicatingSecrets(c client.Client) handler.ToRequestsFunc {
+	return func(_ handler.MapObject) []reconcile.Request {
+		list := &corev1.SecretList{}
+		if err := c.List(context.TODO(), list); err != nil {
+			log.Error(err, "could not list secrets")
+			return nil
+		}
+
+		var requests []reconcile.Request
+		for _, s := range list.Items {
+			if replicates(s.Annotations) {
+				requests = append(requests, reconcile.Request{
+					NamespacedName: types.NamespacedName{Namespace: s.Namespace, Name: s.Name},
+				})
+			}
+		}
+		return requests
+	}
+}
+
+// namespaceCreatedOrRelabeledPredicate filters events for namespaces which are created or whose labels change
+func namespaceCreatedOrRelabeledPredicate() predicate.Predicate {
+	return predicate.Funcs{
+		CreateFunc: func(event.CreateEvent) bool {
+			return true
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return !reflect.DeepEqual(e.MetaOld.GetLabels(), e.MetaNew.GetLabels())
+		},
+		DeleteFunc: func(event.DeleteEvent) bool {
+			return false
+		},
+		GenericFunc: func(event.GenericEvent) bool {
+			return false
+		},
+	}
+}
@@ -0,0 +1,68 @@
+package secret
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"testing"
+)
+
+func TestReplicateSecret(t *testing.T) {
+	target := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:   getSecretName(),
+		Labels: map[string]string{LabelNamespaceAllowFrom: "default"},
+	}}
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), target))
+
+	in := newStringTestSecret("password", map[string]string{
+		AnnotationSecretReplicateTo: target.Name,
+	}, "")
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), in))
+	doReconcile(t, in, false)
+
+	out := &corev1.Secret{}
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{Name: in.Name, Namespace: in.Namespace}, out))
+	require.Contains(t, out.Finalizers, FinalizerReplication)
+
+	replica := &corev1.Secret{}
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{Name: in.Name, Namespace: target.Name}, replica))
+	require.Equal(t, out.Data["password"], replica.Data["password"])
+	require.Equal(t, "Secret/default/"+in.Name, replica.Annotations[AnnotationSecretOwner])
+	require.False(t, IsManaged(replica.Annotations))
+
+	// the copy follows rotation
+	out.Annotations[AnnotationSecretRegenerate] = "yes"
+	require.NoError(t, mgr.GetClient().Update(context.TODO(), out))
+	doReconcile(t, out, false)
+
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{Name: in.Name, Namespace: in.Namespace}, out))
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{Name: in.Name, Namespace: target.Name}, replica))
+	require.Equal(t, out.Data["password"], replica.Data["password"])
+
+	// the copy is deleted once the namespace is no longer listed
+	delete(out.Annotations, AnnotationSecretReplicateTo)
+	require.NoError(t, mgr.GetClient().Update(context.TODO(), out))
+	doReconcile(t, out, false)
+
+	err := mgr.GetClient().Get(context.TODO(), types.NamespacedName{Name: in.Name, Namespace: target.Name}, replica)
+	require.True(t, errors.IsNotFound(err))
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{Name: in.Name, Namespace: in.Namespace}, out))
+	require.NotContains(t, out.Finalizers, FinalizerReplication)
+}
+
+func TestReplicateSecretToForbiddenNamespace(t *testing.T) {
+	target := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: getSecretName()}}
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), target))
+
+	in := newStringTestSecret("password", map[string]string{
+		AnnotationSecretReplicateTo: target.Name,
+	}, "")
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), in))
+	doReconcile(t, in, true)
+
+	err := mgr.GetClient().Get(context.TODO(), types.NamespacedName{Name: in.Name, Namespace: target.Name}, &corev1.Secret{})
+	require.True(t, errors.IsNotFound(err))
+}
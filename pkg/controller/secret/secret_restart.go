@@ -0,0 +1,173 @@
+package secret
+
+import (
+	"bytes"
+	"context"
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sort"
+	"strings"
+	"time"
+)
+
+// replacedKeys returns the sorted keys of all existing values of the secret that have been changed
+func replacedKeys(previous, current map[string][]byte) []string {
+	var keys []string
+	for key, value := range previous {
+		if len(value) == 0 {
+			continue
+		}
+		if newValue, ok := current[key]; ok && !bytes.Equal(value, newValue) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// restartWorkloads triggers a rolling restart of all deployments and stateful sets in the namespace of
+// the secret which mount it or read it into environment variables, by annotating their pod templates
+func (r *ReconcileSecret) restartWorkloads(reqLogger logr.Logger, instance *corev1.Secret) error {
+	return r.annotateWorkloads(reqLogger, instance, AnnotationRestartedAt, time.Now().Format(time.RFC3339))
+}
+
+// annotateWorkloads sets the annotation on the pod templates of all deployments and stateful sets in the
+// namespace of the secret which mount it or read it into environment variables
+func (r *ReconcileSecret) annotateWorkloads(reqLogger logr.Logger, instance *corev1.Secret, key, value string) error {
+	return r.patchWorkloads(reqLogger, instance, key, func(_ *metav1.ObjectMeta, template *corev1.PodTemplateSpec) bool {
+		if template.Annotations[key] == value {
+			return false
+		}
+		setPodTemplateAnnotation(template, key, value)
+		return true
+	})
+}
+
+// patchWorkloads applies change to the metadata and pod template of all deployments and stateful sets in the
+// namespace of the secret which mount it or read it into environment variables, and patches those it changed
+func (r *ReconcileSecret) patchWorkloads(reqLogger logr.Logger, instance *corev1.Secret, key string,
+	change func(meta *metav1.ObjectMeta, template *corev1.PodTemplateSpec) bool) error {
+	deployments := &appsv1.DeploymentList{}
+	if err := r.client.List(context.TODO(), deployments, client.InNamespace(instance.Namespace)); err != nil {
+		return err
+	}
+	for i := range deployments.Items {
+		d := &deployments.Items[i]
+		if !podUsesSecret(&d.Spec.Template.Spec, instance.Name) {
+			continue
+		}
+
+		patch := client.MergeFrom(d.DeepCopy())
+		if !change(&d.ObjectMeta, &d.Spec.Template) {
+			continue
+		}
+		if err := r.client.Patch(context.TODO(), d, patch); err != nil {
+			return err
+		}
+		reqLogger.Info("annotated deployment", "deployment", d.Name, "annotation", key)
+	}
+
+	statefulSets := &appsv1.StatefulSetList{}
+	if err := r.client.List(context.TODO(), statefulSets, client.InNamespace(instance.Namespace)); err != nil {
+		return err
+	}
+	for i := range statefulSets.Items {
+		s := &statefulSets.Items[i]
+		if !podUsesSecret(&s.Spec.Template.Spec, instance.Name) {
+			continue
+		}
+
+		patch := client.MergeFrom(s.DeepCopy())
+		if !change(&s.ObjectMeta, &s.Spec.Template) {
+			continue
+		}
+		if err := r.client.Patch(context.TODO(), s, patch); err != nil {
+			return err
+		}
+		reqLogger.Info("annotated stateful set", "statefulSet", s.Name, "annotation", key)
+	}
+
+	return nil
+}
+
+func setPodTemplateAnnotation(template *corev1.PodTemplateSpec, key, value string) {
+	if template.Annotations == nil {
+		template.Annotations = make(map[string]string)
+	}
+	template.Annotations[key] = value
+}
+
+// podUsesSecret returns true if the pod mounts the named secret or reads it into environment variables
+func podUsesSecret(spec *corev1.PodSpec, name string) bool {
+	for _, v := range spec.Volumes {
+		if v.Secret != nil && v.Secret.SecretName == name {
+			return true
+		}
+		if v.Projected != nil {
+			for _, s := range v.Projected.Sources {
+				if s.Secret != nil && s.Secret.Name == name {
+					return true
+				}
+			}
+		}
+	}
+
+	containers := append(append([]corev1.Container{}, spec.InitContainers...), spec.Containers...)
+	for _, c := range containers {
+		for _, e := range c.EnvFrom {
+			if e.SecretRef != nil && e.SecretRef.Name == name {
+				return true
+			}
+		}
+		for _, e := range c.Env {
+			if e.ValueFrom != nil && e.ValueFrom.SecretKeyRef != nil && e.ValueFrom.SecretKeyRef.Name == name {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// propagateChecksum copies the checksum of the secret to the pod templates of the workloads using it,
+// which triggers a rolling restart whenever the data of the secret changes. With the value reloader, the secret
+// is added to the reload annotation of the workloads instead, leaving the restarts to Reloader.
+func (r *ReconcileSecret) propagateChecksum(reqLogger logr.Logger, instance *corev1.Secret) error {
+	if instance.Annotations[AnnotationSecretPropagateChecksum] == PropagateChecksumReloader {
+		return r.registerWithReloader(reqLogger, instance)
+	}
+
+	key := AnnotationChecksumPrefix + instance.Name
+	if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+		reqLogger.Info("secret name is too long to propagate checksum", "annotation", key)
+		return nil
+	}
+
+	return r.annotateWorkloads(reqLogger, instance, key, instance.Annotations[AnnotationSecretChecksum])
+}
+
+// registerWithReloader adds the secret to the list of secrets in the reload annotation of the workloads using it,
+// so that Reloader restarts them whenever the data of the secret changes
+func (r *ReconcileSecret) registerWithReloader(reqLogger logr.Logger, instance *corev1.Secret) error {
+	return r.patchWorkloads(reqLogger, instance, AnnotationReloaderSecretReload, func(meta *metav1.ObjectMeta, _ *corev1.PodTemplateSpec) bool {
+		secrets := meta.Annotations[AnnotationReloaderSecretReload]
+		for _, name := range strings.Split(secrets, ",") {
+			if strings.TrimSpace(name) == instance.Name {
+				return false
+			}
+		}
+
+		if meta.Annotations == nil {
+			meta.Annotations = make(map[string]string)
+		}
+		if secrets != "" {
+			secrets += ","
+		}
+		meta.Annotations[AnnotationReloaderSecretReload] = secrets + instance.Name
+		return true
+	})
+}
@@ -0,0 +1,141 @@
+package secret
+
+import (
+	"context"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/syncer"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"testing"
+)
+
+func newTestDeployment(secretName string) *appsv1.Deployment {
+	labels := map[string]string{
+		labelSecretGeneratorTest: "yes",
+	}
+
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      getSecretName(),
+			Namespace: "default",
+			Labels:    labels,
+		},
+		Spec: appsv1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:  "app",
+							Image: "busybox",
+							EnvFrom: []corev1.EnvFromSource{
+								{SecretRef: &corev1.SecretEnvSource{
+									LocalObjectReference: corev1.LocalObjectReference{Name: secretName},
+								}},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestRestartOnRotation(t *testing.T) {
+	in := newStringTestSecret("password", map[string]string{
+		AnnotationSecretRestartOnRotation: "yes",
+		AnnotationSecretSecure:            "yes",
+		AnnotationSecretRegenerate:        "yes",
+	}, "password")
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), in))
+
+	using := newTestDeployment(in.Name)
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), using))
+	other := newTestDeployment("other")
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), other))
+
+	doReconcile(t, in, false)
+
+	out := &appsv1.Deployment{}
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{
+		Name:      using.Name,
+		Namespace: using.Namespace}, out))
+	require.Contains(t, out.Spec.Template.Annotations, AnnotationRestartedAt)
+
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{
+		Name:      other.Name,
+		Namespace: other.Namespace}, out))
+	require.NotContains(t, out.Spec.Template.Annotations, AnnotationRestartedAt)
+}
+
+func TestPodUsesSecret(t *testing.T) {
+	spec := &corev1.PodSpec{
+		Volumes: []corev1.Volume{
+			{Name: "secret", VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{SecretName: "mounted"},
+			}},
+		},
+		InitContainers: []corev1.Container{
+			{Env: []corev1.EnvVar{
+				{Name: "PASSWORD", ValueFrom: &corev1.EnvVarSource{
+					SecretKeyRef: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "env"},
+						Key:                  "password",
+					},
+				}},
+			}},
+		},
+	}
+
+	require.True(t, podUsesSecret(spec, "mounted"))
+	require.True(t, podUsesSecret(spec, "env"))
+	require.False(t, podUsesSecret(spec, "other"))
+}
+
+func TestChecksumIsPropagated(t *testing.T) {
+	in := newStringTestSecret("password", map[string]string{
+		AnnotationSecretPropagateChecksum: "yes",
+	}, "")
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), in))
+
+	using := newTestDeployment(in.Name)
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), using))
+
+	doReconcile(t, in, false)
+
+	secret := &corev1.Secret{}
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{
+		Name:      in.Name,
+		Namespace: in.Namespace}, secret))
+	require.Equal(t, syncer.Checksum(secret.Data), secret.Annotations[AnnotationSecretChecksum])
+
+	out := &appsv1.Deployment{}
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{
+		Name:      using.Name,
+		Namespace: using.Namespace}, out))
+	require.Equal(t, secret.Annotations[AnnotationSecretChecksum], out.Spec.Template.Annotations[AnnotationChecksumPrefix+in.Name])
+}
+
+func TestSecretIsRegisteredWithReloader(t *testing.T) {
+	in := newStringTestSecret("password", map[string]string{
+		AnnotationSecretPropagateChecksum: PropagateChecksumReloader,
+	}, "")
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), in))
+
+	using := newTestDeployment(in.Name)
+	using.Annotations = map[string]string{AnnotationReloaderSecretReload: "tls"}
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), using))
+
+	doReconcile(t, in, false)
+	doReconcile(t, in, false)
+
+	out := &appsv1.Deployment{}
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{
+		Name:      using.Name,
+		Namespace: using.Namespace}, out))
+	require.Equal(t, "tls,"+in.Name, out.Annotations[AnnotationReloaderSecretReload])
+	require.NotContains(t, out.Spec.Template.Annotations, AnnotationChecksumPrefix+in.Name)
+}
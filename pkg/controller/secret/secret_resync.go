@@ -0,0 +1,66 @@
+package secret
+
+import (
+	"context"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sync"
+)
+
+var (
+	resyncMu sync.Mutex
+	// resyncEvents queues the secrets of a forced resync for reconciliation by the controller of each manager
+	resyncEvents = map[manager.Manager]chan event.GenericEvent{}
+)
+
+// resyncChannel returns the channel the secrets of forced resyncs of the cluster of mgr are queued on
+func resyncChannel(mgr manager.Manager) chan event.GenericEvent {
+	resyncMu.Lock()
+	defer resyncMu.Unlock()
+
+	ch, ok := resyncEvents[mgr]
+	if !ok {
+		ch = make(chan event.GenericEvent)
+		resyncEvents[mgr] = ch
+	}
+	return ch
+}
+
+// ForgetResync releases the resync channel of mgr once the manager has stopped
+func ForgetResync(mgr manager.Manager) {
+	resyncMu.Lock()
+	defer resyncMu.Unlock()
+	delete(resyncEvents, mgr)
+}
+
+// Resync lists all watched secrets in the given namespaces of the cluster of mgr from the API server and queues
+// them for reconciliation, e.g. after the cluster has been restored from a backup. It returns the number of
+// queued secrets.
+func Resync(mgr manager.Manager, namespaces []string) (int, error) {
+	selector, err := labels.Parse(labelSelector())
+	if err != nil {
+		return 0, err
+	}
+
+	events := resyncChannel(mgr)
+	queued := 0
+	for _, namespace := range namespaces {
+		list := &corev1.SecretList{}
+		if err := mgr.GetAPIReader().List(context.TODO(), list, &client.ListOptions{Namespace: namespace, LabelSelector: selector}); err != nil {
+			return queued, err
+		}
+
+		for i := range list.Items {
+			s := &list.Items[i]
+			if typeIgnored(s.Type) {
+				continue
+			}
+			events <- event.GenericEvent{Meta: s, Object: s}
+			queued++
+		}
+	}
+	return queued, nil
+}
@@ -0,0 +1,87 @@
+package secret
+
+import (
+	"fmt"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"strings"
+)
+
+// rollbackFields returns the fields listed in the rollback annotation, or nil if all fields are to be rolled back
+func rollbackFields(val string) []string {
+	if val == "yes" || val == "true" {
+		return nil
+	}
+
+	fields := make([]string, 0)
+	for _, key := range strings.Split(val, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			fields = append(fields, key)
+		}
+	}
+	return fields
+}
+
+// rollback restores the fields listed in the rollback annotation of desired to the value they had before they
+// were last replaced, from its history secret, and removes the annotation. It reports whether values were
+// restored. Rollbacks which can't succeed, e.g. because no previous value is kept, are dropped with a
+// RollbackFailed event instead of being retried.
+func (r *ReconcileSecret) rollback(reqLogger logr.Logger, desired *corev1.Secret) (bool, error) {
+	val, ok := desired.Annotations[AnnotationSecretRollback]
+	if !ok {
+		return false, nil
+	}
+
+	history, err := r.valueHistory(desired)
+	if err != nil {
+		return false, err
+	}
+
+	delete(desired.Annotations, AnnotationSecretRollback)
+	values, err := previousValues(desired, history, rollbackFields(val))
+	if err != nil {
+		reqLogger.Info("dropping rollback", "reason", err.Error())
+		r.recorder.Eventf(desired, corev1.EventTypeWarning, "RollbackFailed", "could not roll back values: %s", err)
+		return false, nil
+	}
+
+	times := replacedAt(history)
+	for key, value := range values {
+		reqLogger.Info("rolling back value", "field", key, "replacedAt", times[historyKey(key, 1)])
+		desired.Data[key] = value
+	}
+	return true, nil
+}
+
+// previousValues returns the previous values of the given fields kept in the history secret, or of all
+// generated fields which have a previous value if fields is nil
+func previousValues(instance, history *corev1.Secret, fields []string) (map[string][]byte, error) {
+	if history == nil {
+		return nil, fmt.Errorf("secret keeps no value history, set the %s annotation", AnnotationSecretValueHistory)
+	}
+
+	generated := generatedFields(instance)
+	if fields == nil {
+		for _, key := range generated {
+			if _, ok := history.Data[historyKey(key, 1)]; ok {
+				fields = append(fields, key)
+			}
+		}
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("no previous values are kept")
+		}
+	}
+
+	values := make(map[string][]byte, len(fields))
+	for _, key := range fields {
+		if !contains(generated, key) {
+			return nil, fmt.Errorf("field %s is not generated", key)
+		}
+		value, ok := history.Data[historyKey(key, 1)]
+		if !ok {
+			return nil, fmt.Errorf("no previous value of field %s is kept", key)
+		}
+		values[key] = value
+	}
+	return values, nil
+}
@@ -0,0 +1,65 @@
+package secret
+
+import (
+	"context"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/hook"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"testing"
+)
+
+func TestRollbackRestoresPreviousValue(t *testing.T) {
+	in := newStringTestSecret("password,token", map[string]string{
+		AnnotationSecretValueHistory: "1",
+	}, "")
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), in))
+	doReconcile(t, in, false)
+
+	out := &corev1.Secret{}
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{Name: in.Name, Namespace: in.Namespace}, out))
+	original := out.Data["password"]
+	token := out.Data["token"]
+
+	out.Annotations[AnnotationSecretRegenerate] = "password"
+	require.NoError(t, mgr.GetClient().Update(context.TODO(), out))
+	doReconcile(t, out, false)
+
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{Name: in.Name, Namespace: in.Namespace}, out))
+	rotated := out.Data["password"]
+	require.NotEqual(t, original, rotated)
+
+	out.Annotations[AnnotationSecretRollback] = "true"
+	require.NoError(t, mgr.GetClient().Update(context.TODO(), out))
+	doReconcile(t, out, false)
+
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{Name: in.Name, Namespace: in.Namespace}, out))
+	require.Equal(t, original, out.Data["password"])
+	require.Equal(t, token, out.Data["token"])
+	require.NotContains(t, out.Annotations, AnnotationSecretRollback)
+	require.Equal(t, string(hook.ReasonRolledBack), out.Annotations[AnnotationSecretTrigger])
+	require.Empty(t, tamperedFields(out))
+
+	// the rolled back value is kept in turn, so the rollback can be undone
+	history := &corev1.Secret{}
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{Name: historyName(in.Name), Namespace: in.Namespace}, history))
+	require.Equal(t, rotated, history.Data["password.1"])
+}
+
+func TestRollbackWithoutHistoryIsDropped(t *testing.T) {
+	in := newStringTestSecret("password", nil, "")
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), in))
+	doReconcile(t, in, false)
+
+	out := &corev1.Secret{}
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{Name: in.Name, Namespace: in.Namespace}, out))
+	value := out.Data["password"]
+
+	out.Annotations[AnnotationSecretRollback] = "password"
+	require.NoError(t, mgr.GetClient().Update(context.TODO(), out))
+	doReconcile(t, out, false)
+
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{Name: in.Name, Namespace: in.Namespace}, out))
+	require.Equal(t, value, out.Data["password"])
+	require.NotContains(t, out.Annotations, AnnotationSecretRollback)
+}
@@ -0,0 +1,361 @@
+package secret
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/robfig/cron/v3"
+	corev1 "k8s.io/api/core/v1"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const dateFormat = "2006-01-02"
+
+// rotationDue checks whether the values of the secret are older than the duration given in its
+// rotate-after annotation, or a rotation was scheduled by its rotate-schedule annotation since they
+// were generated. Secrets without either annotation are rotated after defaultRotateAfter, if it is
+// positive. It also returns the time until the next rotation, which is 0 if the secret is not
+// rotated automatically. Pinned secrets are never due for rotation.
+func rotationDue(instance *corev1.Secret, defaultRotateAfter time.Duration, now time.Time) (bool, time.Duration, error) {
+	var schedules []cron.Schedule
+
+	if val, ok := instance.Annotations[AnnotationSecretRotateAfter]; ok {
+		interval, err := rotationInterval(val)
+		if err != nil {
+			return false, 0, err
+		}
+		schedules = append(schedules, cron.Every(interval))
+	}
+
+	if val, ok := instance.Annotations[AnnotationSecretRotateSchedule]; ok {
+		schedule, err := rotationSchedule(val)
+		if err != nil {
+			return false, 0, err
+		}
+		schedules = append(schedules, schedule)
+	}
+
+	if len(schedules) == 0 && defaultRotateAfter > 0 {
+		schedules = append(schedules, cron.Every(defaultRotateAfter))
+	}
+
+	if len(schedules) == 0 || IsPinned(instance.Annotations) {
+		return false, 0, nil
+	}
+
+	generatedAt, err := time.Parse(time.RFC3339, instance.Annotations[AnnotationSecretAutoGeneratedAt])
+	if err != nil {
+		// values have not been generated yet, the next rotation is relative to the generation
+		generatedAt = now
+	}
+
+	rotate := false
+	var next time.Duration
+	for _, schedule := range schedules {
+		due := schedule.Next(generatedAt)
+		if !due.After(now) {
+			// values are rotated now, so the next rotation is relative to now
+			rotate = true
+			due = schedule.Next(now)
+		}
+		if d := due.Sub(now); next == 0 || d < next {
+			next = d
+		}
+	}
+
+	return rotate, next, nil
+}
+
+// valuesGenerated reports whether desired holds newly generated values, as opposed to other changes like
+// re-rendered templates, copied values, expired previous values or promoted staged values, which must not
+// postpone the next rotation. Values are also considered generated if the secret has no generation time yet.
+func valuesGenerated(instance, desired *corev1.Secret) bool {
+	if _, ok := instance.Annotations[AnnotationSecretAutoGeneratedAt]; !ok {
+		return true
+	}
+
+	for _, key := range generatedFields(desired) {
+		if value := desired.Data[key]; len(value) > 0 && !bytes.Equal(value, instance.Data[key]) &&
+			!bytes.Equal(value, instance.Data[key+StagedFieldSuffix]) {
+			return true
+		}
+		if value := desired.Data[key+StagedFieldSuffix]; len(value) > 0 && !bytes.Equal(value, instance.Data[key+StagedFieldSuffix]) {
+			return true
+		}
+	}
+	return false
+}
+
+// NextRotation returns the time until the values of the secret are rotated according to its rotate-after and
+// rotate-schedule annotations, which is 0 if the rotation is overdue. scheduled is false if the annotations don't
+// schedule any rotation.
+func NextRotation(instance *corev1.Secret, now time.Time) (next time.Duration, scheduled bool, err error) {
+	rotate, next, err := rotationDue(instance, 0, now)
+	if err != nil || rotate {
+		return 0, rotate, err
+	}
+	return next, next > 0, nil
+}
+
+// IsPinned returns true if the annotations exempt a secret from scheduled and bulk rotations
+func IsPinned(annotations map[string]string) bool {
+	_, ok := annotations[AnnotationSecretPin]
+	return ok
+}
+
+func rotationInterval(val string) (time.Duration, error) {
+	interval, err := time.ParseDuration(val)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s annotation: %w", AnnotationSecretRotateAfter, err)
+	}
+	if interval <= 0 {
+		return 0, fmt.Errorf("%s must be a positive duration, got %q", AnnotationSecretRotateAfter, val)
+	}
+	return interval, nil
+}
+
+// cooldownRemaining returns the time until the values of the secret may be regenerated again,
+// or 0 if they may be regenerated now
+func cooldownRemaining(instance *corev1.Secret, now time.Time) (time.Duration, error) {
+	cooldown := rotationCooldown()
+	if val, ok := instance.Annotations[AnnotationSecretRotationCooldown]; ok {
+		var err error
+		if cooldown, err = cooldownDuration(val); err != nil {
+			return 0, err
+		}
+	}
+
+	rotatedAt, err := time.Parse(time.RFC3339, instance.Annotations[AnnotationSecretRotatedAt])
+	if cooldown == 0 || err != nil {
+		return 0, nil
+	}
+
+	if wait := rotatedAt.Add(cooldown).Sub(now); wait > 0 {
+		return wait, nil
+	}
+	return 0, nil
+}
+
+func cooldownDuration(val string) (time.Duration, error) {
+	cooldown, err := time.ParseDuration(val)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s annotation: %w", AnnotationSecretRotationCooldown, err)
+	}
+	if cooldown < 0 {
+		return 0, fmt.Errorf("%s must not be negative, got %q", AnnotationSecretRotationCooldown, val)
+	}
+	return cooldown, nil
+}
+
+// regenerationsToday returns the number of regenerations of the secret on the day of now (UTC)
+func regenerationsToday(instance *corev1.Secret, now time.Time) int {
+	parts := strings.SplitN(instance.Annotations[AnnotationSecretRegenerations], "/", 2)
+	if len(parts) != 2 || parts[0] != now.UTC().Format(dateFormat) {
+		return 0
+	}
+
+	count, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0
+	}
+	return count
+}
+
+// countRegeneration increments the number of regenerations of the secret on the day of now (UTC)
+func countRegeneration(instance *corev1.Secret, now time.Time) {
+	count := regenerationsToday(instance, now) + 1
+	instance.Annotations[AnnotationSecretRegenerations] = fmt.Sprintf("%s/%d", now.UTC().Format(dateFormat), count)
+}
+
+// untilTomorrow returns the time until the next day (UTC) begins
+func untilTomorrow(now time.Time) time.Duration {
+	return now.UTC().Truncate(24 * time.Hour).Add(24 * time.Hour).Sub(now)
+}
+
+func rotationSchedule(val string) (cron.Schedule, error) {
+	schedule, err := cron.ParseStandard(val)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s annotation: %w", AnnotationSecretRotateSchedule, err)
+	}
+	return schedule, nil
+}
+
+// keepPrevious stores the values replaced by a regeneration in <key>-previous fields, which are
+// removed by expirePrevious after the grace period given in the keep-previous annotation
+func keepPrevious(instance *corev1.Secret, previous map[string][]byte, now time.Time) error {
+	val, ok := instance.Annotations[AnnotationSecretKeepPrevious]
+	if !ok {
+		return nil
+	}
+
+	grace, err := gracePeriod(val)
+	if err != nil {
+		return err
+	}
+
+	var fields []string
+	if val, ok := instance.Annotations[AnnotationSecretPreviousFields]; ok {
+		fields = strings.Split(val, ",")
+	}
+
+	kept := fields
+	staged := strings.Split(instance.Annotations[AnnotationSecretStagedFields], ",")
+	changed := false
+	for key, value := range previous {
+		if strings.HasSuffix(key, PreviousFieldSuffix) && contains(kept, strings.TrimSuffix(key, PreviousFieldSuffix)) {
+			// value kept during an earlier rotation
+			continue
+		}
+		if strings.HasSuffix(key, StagedFieldSuffix) && contains(staged, strings.TrimSuffix(key, StagedFieldSuffix)) {
+			// staged values are not in use yet
+			continue
+		}
+		current, ok := instance.Data[key]
+		if !ok || len(value) == 0 || bytes.Equal(value, current) {
+			continue
+		}
+
+		instance.Data[key+PreviousFieldSuffix] = value
+		if !contains(fields, key) {
+			fields = append(fields, key)
+		}
+		changed = true
+	}
+
+	if !changed {
+		return nil
+	}
+
+	sort.Strings(fields)
+	instance.Annotations[AnnotationSecretPreviousFields] = strings.Join(fields, ",")
+	instance.Annotations[AnnotationSecretPreviousExpiresAt] = now.Add(grace).Format(time.RFC3339)
+	return nil
+}
+
+// expirePrevious removes the previous values stored by keepPrevious once their grace period is over.
+// It returns the time until they expire, or 0 if there are none left.
+func expirePrevious(instance *corev1.Secret, now time.Time) time.Duration {
+	val, ok := instance.Annotations[AnnotationSecretPreviousFields]
+	if !ok {
+		return 0
+	}
+
+	expiresAt, err := time.Parse(time.RFC3339, instance.Annotations[AnnotationSecretPreviousExpiresAt])
+	if err == nil && expiresAt.After(now) {
+		return expiresAt.Sub(now)
+	}
+
+	for _, key := range strings.Split(val, ",") {
+		delete(instance.Data, key+PreviousFieldSuffix)
+	}
+	delete(instance.Annotations, AnnotationSecretPreviousFields)
+	delete(instance.Annotations, AnnotationSecretPreviousExpiresAt)
+	return 0
+}
+
+func gracePeriod(val string) (time.Duration, error) {
+	grace, err := time.ParseDuration(val)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s annotation: %w", AnnotationSecretKeepPrevious, err)
+	}
+	if grace <= 0 {
+		return 0, fmt.Errorf("%s must be a positive duration, got %q", AnnotationSecretKeepPrevious, val)
+	}
+	return grace, nil
+}
+
+// stageValues moves the values replaced by a regeneration to <key>-staged fields and restores the
+// current values, if staged rotation is enabled. Staged values are activated by promoteStaged.
+func stageValues(instance *corev1.Secret, previous map[string][]byte, now time.Time) error {
+	val, ok := instance.Annotations[AnnotationSecretStagedRotation]
+	if !ok {
+		return nil
+	}
+
+	if _, err := promotionDelay(val); err != nil {
+		return err
+	}
+
+	var fields []string
+	if val, ok := instance.Annotations[AnnotationSecretStagedFields]; ok {
+		fields = strings.Split(val, ",")
+	}
+
+	changed := false
+	for key, value := range previous {
+		if len(value) == 0 || bytes.Equal(value, instance.Data[key]) {
+			// fields without value are generated directly
+			continue
+		}
+
+		instance.Data[key+StagedFieldSuffix] = instance.Data[key]
+		instance.Data[key] = value
+		if !contains(fields, key) {
+			fields = append(fields, key)
+		}
+		changed = true
+	}
+
+	if !changed {
+		return nil
+	}
+
+	sort.Strings(fields)
+	instance.Annotations[AnnotationSecretStagedFields] = strings.Join(fields, ",")
+	instance.Annotations[AnnotationSecretStagedAt] = now.Format(time.RFC3339)
+	return nil
+}
+
+// promoteStaged moves staged values to their fields once the activate annotation is set or the
+// delay of the staged-rotation annotation has passed. It returns whether values were promoted and
+// the time until they will be.
+func promoteStaged(instance *corev1.Secret, now time.Time) (bool, time.Duration, error) {
+	_, activate := instance.Annotations[AnnotationSecretActivate]
+	delete(instance.Annotations, AnnotationSecretActivate)
+
+	val, ok := instance.Annotations[AnnotationSecretStagedFields]
+	if !ok {
+		return false, 0, nil
+	}
+
+	if !activate {
+		delay, err := promotionDelay(instance.Annotations[AnnotationSecretStagedRotation])
+		if err != nil || delay == 0 {
+			return false, 0, err
+		}
+
+		stagedAt, err := time.Parse(time.RFC3339, instance.Annotations[AnnotationSecretStagedAt])
+		if err == nil && stagedAt.Add(delay).After(now) {
+			return false, stagedAt.Add(delay).Sub(now), nil
+		}
+	}
+
+	for _, key := range strings.Split(val, ",") {
+		if value, ok := instance.Data[key+StagedFieldSuffix]; ok {
+			instance.Data[key] = value
+			delete(instance.Data, key+StagedFieldSuffix)
+		}
+	}
+	delete(instance.Annotations, AnnotationSecretStagedFields)
+	delete(instance.Annotations, AnnotationSecretStagedAt)
+	return true, 0, nil
+}
+
+// promotionDelay returns the delay after which staged values are activated automatically,
+// or 0 if they are activated manually
+func promotionDelay(val string) (time.Duration, error) {
+	if val == StagedRotationManual || val == "" {
+		return 0, nil
+	}
+
+	delay, err := time.ParseDuration(val)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be %s or a duration: %w", AnnotationSecretStagedRotation, StagedRotationManual, err)
+	}
+	if delay <= 0 {
+		return 0, fmt.Errorf("%s must be a positive duration, got %q", AnnotationSecretStagedRotation, val)
+	}
+	return delay, nil
+}
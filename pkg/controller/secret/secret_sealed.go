@@ -0,0 +1,177 @@
+package secret
+
+import (
+	"context"
+	"crypto/rsa"
+	"fmt"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/sealing"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/syncer"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/yaml"
+)
+
+// SealedManifestKey is the key of the ConfigMap holding the SealedSecret manifest of a secret
+const SealedManifestKey = "sealedsecret.yaml"
+
+func sealedSecretsNamespace() string {
+	return options().GetString("sealed-secrets-namespace")
+}
+
+// sealedName returns the name of the ConfigMap holding the SealedSecret manifest of the named secret
+func sealedName(name string) string {
+	return name + "-sealed"
+}
+
+// sealingKey returns the public key of the newest active sealing key of the sealed-secrets controller
+func (r *ReconcileSecret) sealingKey() (*rsa.PublicKey, error) {
+	keys := &corev1.SecretList{}
+	err := r.reader.List(context.TODO(), keys, client.InNamespace(sealedSecretsNamespace()),
+		client.MatchingLabels{sealing.LabelSealingKey: "active"})
+	if err != nil {
+		return nil, err
+	}
+
+	var newest *corev1.Secret
+	for i := range keys.Items {
+		if newest == nil || newest.CreationTimestamp.Before(&keys.Items[i].CreationTimestamp) {
+			newest = &keys.Items[i]
+		}
+	}
+	if newest == nil {
+		return nil, fmt.Errorf("no active sealing key found in namespace %s", sealedSecretsNamespace())
+	}
+
+	key, err := sealing.PublicKey(newest.Data[corev1.TLSCertKey])
+	if err != nil {
+		return nil, fmt.Errorf("invalid sealing key %s: %w", newest.Name, err)
+	}
+	return key, nil
+}
+
+// updateSealed seals the values of desired for the sealed-secrets controller if its sealed-output annotation
+// is set and its data changed since it was last sealed. With copy, the SealedSecret manifest is stored in a
+// ConfigMap owned by the secret, to be committed to Git. With replace, a SealedSecret is created, which the
+// sealed-secrets controller is allowed to unseal into the secret.
+func (r *ReconcileSecret) updateSealed(desired *corev1.Secret) error {
+	mode, ok := desired.Annotations[AnnotationSecretSealedOutput]
+	if !ok {
+		return nil
+	}
+	if mode != SealedOutputCopy && mode != SealedOutputReplace {
+		return fmt.Errorf("invalid %s annotation %q, must be %s or %s", AnnotationSecretSealedOutput, mode, SealedOutputCopy, SealedOutputReplace)
+	}
+	if mode == SealedOutputReplace {
+		desired.Annotations[sealing.AnnotationManaged] = "true"
+	}
+
+	var obj runtime.Object
+	if mode == SealedOutputCopy {
+		obj = &corev1.ConfigMap{}
+	} else {
+		u := &unstructured.Unstructured{}
+		u.SetAPIVersion(sealing.APIVersion)
+		u.SetKind(sealing.Kind)
+		obj = u
+	}
+
+	name := desired.Name
+	if mode == SealedOutputCopy {
+		name = sealedName(desired.Name)
+	}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: desired.Namespace, Name: name}, obj)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	exists := err == nil
+
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+	checksum := syncer.Checksum(desired.Data)
+	if exists {
+		if accessor.GetAnnotations()[AnnotationSecretSealedOf] != desired.Name {
+			return fmt.Errorf("%s %s already exists and was not sealed from this secret", obj.GetObjectKind().GroupVersionKind().Kind, name)
+		}
+		if accessor.GetAnnotations()[AnnotationSecretSealedChecksum] == checksum {
+			return nil
+		}
+	}
+
+	key, err := r.sealingKey()
+	if err != nil {
+		return err
+	}
+	sealed, err := sealing.Seal(desired, key)
+	if err != nil {
+		return err
+	}
+	sealed.Annotations = map[string]string{
+		AnnotationSecretSealedOf:       desired.Name,
+		AnnotationSecretSealedChecksum: checksum,
+	}
+
+	if mode == SealedOutputCopy {
+		obj, err = sealedConfigMap(sealed, obj.(*corev1.ConfigMap))
+		if err == nil && !exists {
+			err = controllerutil.SetControllerReference(desired, obj.(*corev1.ConfigMap), r.scheme)
+		}
+	} else {
+		obj, err = sealedObject(sealed, obj.(*unstructured.Unstructured))
+	}
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		return r.client.Update(context.TODO(), obj)
+	}
+	return r.client.Create(context.TODO(), obj)
+}
+
+// sealedConfigMap stores the manifest of sealed in cm
+func sealedConfigMap(sealed *sealing.SealedSecret, cm *corev1.ConfigMap) (*corev1.ConfigMap, error) {
+	manifest, err := yaml.Marshal(sealed)
+	if err != nil {
+		return nil, err
+	}
+
+	cm.Namespace = sealed.Namespace
+	cm.Name = sealedName(sealed.Name)
+	if cm.Annotations == nil {
+		cm.Annotations = make(map[string]string)
+	}
+	for k, v := range sealed.Annotations {
+		cm.Annotations[k] = v
+	}
+	cm.Data = map[string]string{SealedManifestKey: string(manifest)}
+	return cm, nil
+}
+
+// sealedObject replaces the spec of u with the one of sealed, keeping the metadata of an existing SealedSecret
+func sealedObject(sealed *sealing.SealedSecret, u *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(sealed)
+	if err != nil {
+		return nil, err
+	}
+
+	u.SetNamespace(sealed.Namespace)
+	u.SetName(sealed.Name)
+	annotations := u.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	for k, v := range sealed.Annotations {
+		annotations[k] = v
+	}
+	u.SetAnnotations(annotations)
+	u.Object["spec"] = content["spec"]
+	return u, nil
+}
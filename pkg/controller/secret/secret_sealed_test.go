@@ -0,0 +1,82 @@
+package secret
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/sealing"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"math/big"
+	"sigs.k8s.io/yaml"
+	"testing"
+	"time"
+)
+
+// newSealingKeySecret returns an active sealing key of the sealed-secrets controller
+func newSealingKeySecret(t *testing.T, namespace string) *corev1.Secret {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "sealed-secret"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      getSecretName(),
+			Labels:    map[string]string{sealing.LabelSealingKey: "active"},
+		},
+		Type: corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+			corev1.TLSPrivateKeyKey: pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}),
+		},
+	}
+}
+
+func TestSealedOutputCopyStoresManifestInConfigMap(t *testing.T) {
+	in := newStringTestSecret("testfield", map[string]string{
+		AnnotationSecretSealedOutput: SealedOutputCopy,
+	}, "")
+
+	sealingKey := newSealingKeySecret(t, in.Namespace)
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), sealingKey))
+	defer mgr.GetClient().Delete(context.TODO(), sealingKey)
+	viper.Set("sealed-secrets-namespace", in.Namespace)
+	defer viper.Set("sealed-secrets-namespace", "")
+
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), in))
+	doReconcile(t, in, false)
+
+	cm := &corev1.ConfigMap{}
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{
+		Name:      sealedName(in.Name),
+		Namespace: in.Namespace}, cm))
+	require.Equal(t, in.Name, cm.Annotations[AnnotationSecretSealedOf])
+
+	sealed := &sealing.SealedSecret{}
+	require.NoError(t, yaml.Unmarshal([]byte(cm.Data[SealedManifestKey]), sealed))
+	require.Equal(t, sealing.Kind, sealed.Kind)
+	require.Equal(t, in.Name, sealed.Name)
+	require.NotEmpty(t, sealed.Spec.EncryptedData["testfield"])
+
+	// unchanged values are not sealed again
+	doReconcile(t, in, false)
+	resealed := &corev1.ConfigMap{}
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{
+		Name:      sealedName(in.Name),
+		Namespace: in.Namespace}, resealed))
+	require.Equal(t, cm.ResourceVersion, resealed.ResourceVersion)
+}
@@ -0,0 +1,69 @@
+package secret
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"strings"
+)
+
+func labelSelector() string {
+	return options().GetString("label-selector")
+}
+
+// cachesAllSecrets reports whether all secrets, including their data, are cached. Otherwise, secrets
+// the controller doesn't manage, e.g. those referenced by templates, are read from the apiserver.
+func cachesAllSecrets() bool {
+	return cacheUnmanagedSecretData() && labelSelector() == ""
+}
+
+// SelectorLabels returns the labels secrets created by the controllers need to carry to be watched with the
+// label selector. Only equality-based requirements can be satisfied this way.
+func SelectorLabels() map[string]string {
+	set, err := labels.ConvertSelectorToLabelsMap(labelSelector())
+	if err != nil {
+		return nil
+	}
+	return set
+}
+
+func ignoredSecretTypes() []string {
+	var types []string
+	for _, t := range strings.Split(options().GetString("ignore-secret-types"), ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			types = append(types, t)
+		}
+	}
+	return types
+}
+
+// typeIgnored reports whether secrets of the given type are left alone, e.g. service account tokens
+// or Helm release secrets
+func typeIgnored(secretType corev1.SecretType) bool {
+	return contains(ignoredSecretTypes(), string(secretType))
+}
+
+func typeAllowed(obj runtime.Object) bool {
+	s, ok := obj.(*corev1.Secret)
+	return !ok || !typeIgnored(s.Type)
+}
+
+// secretTypePredicate filters events for secrets of ignored types
+func secretTypePredicate() predicate.Predicate {
+	return predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool {
+			return typeAllowed(e.Object)
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return typeAllowed(e.ObjectNew)
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return typeAllowed(e.Object)
+		},
+		GenericFunc: func(e event.GenericEvent) bool {
+			return typeAllowed(e.Object)
+		},
+	}
+}
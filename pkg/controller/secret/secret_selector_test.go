@@ -0,0 +1,49 @@
+package secret
+
+import (
+	"context"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"testing"
+)
+
+func TestIgnoredSecretType(t *testing.T) {
+	viper.Set("ignore-secret-types", "kubernetes.io/service-account-token,helm.sh/release.v1")
+	defer viper.Set("ignore-secret-types", "")
+
+	in := newStringTestSecret("testfield", nil, "")
+	in.Type = "helm.sh/release.v1"
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), in))
+
+	doReconcile(t, in, false)
+
+	out := &corev1.Secret{}
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{
+		Name:      in.Name,
+		Namespace: in.Namespace}, out))
+	require.Empty(t, out.Data["testfield"], "secret of ignored type must not be generated")
+
+	p := secretTypePredicate()
+	require.False(t, p.Create(event.CreateEvent{Meta: in, Object: in}))
+	in.Type = corev1.SecretTypeOpaque
+	require.True(t, p.Create(event.CreateEvent{Meta: in, Object: in}))
+}
+
+func TestIgnoreAnnotation(t *testing.T) {
+	in := newStringTestSecret("testfield", map[string]string{
+		AnnotationSecretIgnore: "true",
+	}, "")
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), in))
+
+	doReconcile(t, in, false)
+
+	out := &corev1.Secret{}
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{
+		Name:      in.Name,
+		Namespace: in.Namespace}, out))
+	require.Empty(t, out.Data["testfield"], "ignored secret must not be generated")
+	require.NotContains(t, out.Annotations, AnnotationSecretAutoGeneratedAt)
+}
@@ -0,0 +1,83 @@
+package secret
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/entropy"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/shamir"
+	"io"
+)
+
+// selfTestKeyLength is the length of the RSA key generated by the self-test, the smallest length approved in FIPS mode
+const selfTestKeyLength = 2048
+
+// SelfTest checks the entropy source and runs a generation round-trip for each built-in generator. While the
+// entropy source fails, no values are generated at all.
+func SelfTest() error {
+	err := selfTest()
+	if err != nil {
+		selfTestPassing.Set(0)
+	} else {
+		selfTestPassing.Set(1)
+	}
+	return err
+}
+
+func selfTest() error {
+	if err := entropy.SelfTest(); err != nil {
+		return err
+	}
+
+	pg := newStringGenerator(log, globalDefaults(), nil)
+	for _, encoding := range []Encoding{"", EncodingBase64, EncodingHex} {
+		one, err := pg.generateEncodedValue("self-test", 32, encoding)
+		if err != nil {
+			return fmt.Errorf("string generator failed self-test: %w", err)
+		}
+		two, err := pg.generateEncodedValue("self-test", 32, encoding)
+		if err != nil {
+			return fmt.Errorf("string generator failed self-test: %w", err)
+		}
+		if !pg.complies([]byte(one), 32, encoding) || one == two {
+			return fmt.Errorf("string generator failed self-test with encoding %q", encoding)
+		}
+	}
+
+	d := &derivation{master: []byte("self-test"), info: "self-test"}
+	one, err := readString(d.reader("key"), 32)
+	if err != nil {
+		return fmt.Errorf("derivation failed self-test: %w", err)
+	}
+	two, err := readString(d.reader("key"), 32)
+	if err != nil || one != two {
+		return fmt.Errorf("derivation failed self-test: values differ")
+	}
+
+	key := make([]byte, shamirKeyLength)
+	if _, err := io.ReadFull(entropy.Reader, key); err != nil {
+		return err
+	}
+	shares, err := shamir.Split(key, 3, 2)
+	if err != nil {
+		return fmt.Errorf("shamir failed self-test: %w", err)
+	}
+	combined, err := shamir.Combine(shares[1:])
+	if err != nil || !bytes.Equal(combined, key) {
+		return fmt.Errorf("shamir failed self-test: shares don't combine into the key")
+	}
+
+	keyPair, err := generateSSHKeypair(selfTestKeyLength)
+	if err != nil {
+		return fmt.Errorf("ssh-keypair generator failed self-test: %w", err)
+	}
+	privateKey, err := privateKeyFromPEM(keyPair.PrivateKey)
+	if err != nil {
+		return fmt.Errorf("ssh-keypair generator failed self-test: %w", err)
+	}
+	publicKey, err := sshPublicKeyForPrivateKey(privateKey)
+	if err != nil || !bytes.Equal(publicKey, keyPair.PublicKey) {
+		return fmt.Errorf("ssh-keypair generator failed self-test: public key doesn't match private key")
+	}
+
+	return nil
+}
@@ -0,0 +1,12 @@
+package secret
+
+import (
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestSelfTestPasses(t *testing.T) {
+	require.NoError(t, SelfTest())
+	require.Equal(t, float64(1), testutil.ToFloat64(selfTestPassing))
+}
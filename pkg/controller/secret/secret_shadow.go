@@ -0,0 +1,106 @@
+package secret
+
+import (
+	"context"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"reflect"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"strings"
+)
+
+// shadowName returns the name of the shadow secret keeping a copy of the generated values of the named secret
+func shadowName(name string) string {
+	return name + "-shadow"
+}
+
+// restoreFromShadow fills in generated fields of desired which have been emptied, e.g. by a GitOps tool
+// re-applying the secret without its values, from its shadow secret instead of regenerating them
+func (r *ReconcileSecret) restoreFromShadow(reqLogger logr.Logger, desired *corev1.Secret) error {
+	if _, ok := desired.Annotations[AnnotationSecretShadow]; !ok {
+		return nil
+	}
+	if _, ok := desired.Annotations[AnnotationSecretRegenerate]; ok {
+		// emptied values are regenerated along with the requested ones
+		return nil
+	}
+
+	shadow := &corev1.Secret{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: desired.Namespace, Name: shadowName(desired.Name)}, shadow)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var restored []string
+	for _, key := range generatedFields(desired) {
+		if len(desired.Data[key]) > 0 || len(shadow.Data[key]) == 0 {
+			continue
+		}
+		if desired.Data == nil {
+			desired.Data = make(map[string][]byte)
+		}
+		desired.Data[key] = shadow.Data[key]
+		restored = append(restored, key)
+	}
+
+	if len(restored) > 0 {
+		reqLogger.Info("restored emptied values from shadow secret", "fields", restored)
+		r.recorder.Eventf(desired, corev1.EventTypeNormal, "ValuesRestored",
+			"restored emptied values of fields %s from shadow secret", strings.Join(restored, ","))
+	}
+	return nil
+}
+
+// updateShadow copies the generated values of instance to its shadow secret, which is owned by instance
+// and thus deleted along with it
+func (r *ReconcileSecret) updateShadow(instance *corev1.Secret) error {
+	if _, ok := instance.Annotations[AnnotationSecretShadow]; !ok {
+		return nil
+	}
+
+	data := make(map[string][]byte)
+	for _, key := range generatedFields(instance) {
+		if value := instance.Data[key]; len(value) > 0 {
+			data[key] = value
+		}
+	}
+
+	shadow := &corev1.Secret{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: instance.Namespace, Name: shadowName(instance.Name)}, shadow)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	if errors.IsNotFound(err) {
+		shadow = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   instance.Namespace,
+				Name:        shadowName(instance.Name),
+				Labels:      SelectorLabels(),
+				Annotations: map[string]string{AnnotationSecretShadowOf: instance.Name},
+			},
+			Type: corev1.SecretTypeOpaque,
+			Data: data,
+		}
+		if err := controllerutil.SetControllerReference(instance, shadow, r.scheme); err != nil {
+			return err
+		}
+		return r.client.Create(context.TODO(), shadow)
+	}
+
+	if shadow.Annotations[AnnotationSecretShadowOf] != instance.Name {
+		// don't overwrite secrets that merely happen to have the shadow's name
+		return nil
+	}
+	if reflect.DeepEqual(shadow.Data, data) {
+		return nil
+	}
+	shadow.Data = data
+	return r.client.Update(context.TODO(), shadow)
+}
@@ -0,0 +1,43 @@
+package secret
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"testing"
+)
+
+func TestRestoreEmptiedValuesFromShadow(t *testing.T) {
+	in := newStringTestSecret("testfield", map[string]string{
+		AnnotationSecretShadow: "yes",
+	}, "")
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), in))
+
+	doReconcile(t, in, false)
+
+	out := &corev1.Secret{}
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{
+		Name:      in.Name,
+		Namespace: in.Namespace}, out))
+	generated := string(out.Data["testfield"])
+	require.NotEmpty(t, generated)
+
+	shadow := &corev1.Secret{}
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{
+		Name:      shadowName(in.Name),
+		Namespace: in.Namespace}, shadow))
+	require.Equal(t, generated, string(shadow.Data["testfield"]))
+	require.Equal(t, in.Name, shadow.Annotations[AnnotationSecretShadowOf])
+
+	// re-apply the secret without its value, like a GitOps tool would
+	out.Data["testfield"] = []byte{}
+	require.NoError(t, mgr.GetClient().Update(context.TODO(), out))
+
+	doReconcile(t, in, false)
+
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{
+		Name:      in.Name,
+		Namespace: in.Namespace}, out))
+	require.Equal(t, generated, string(out.Data["testfield"]))
+}
@@ -0,0 +1,148 @@
+package secret
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-logr/logr"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/entropy"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/shamir"
+	"io"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// ShamirShareKey is the key of the share in the secrets the shares of a master key are stored in
+	ShamirShareKey = "share"
+	// shamirKeyLength is the length of split master keys in bytes
+	shamirKeyLength = 32
+)
+
+// shamirConfig returns the targets and threshold of a secret whose master key is split into shares
+func shamirConfig(annotations map[string]string) ([]types.NamespacedName, int, error) {
+	var targets []types.NamespacedName
+	for _, t := range strings.Split(annotations[AnnotationSecretShamirTargets], ",") {
+		parts := strings.Split(strings.TrimSpace(t), "/")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, 0, fmt.Errorf("invalid %s annotation, expected namespace/name, got %q", AnnotationSecretShamirTargets, t)
+		}
+		target := types.NamespacedName{Namespace: parts[0], Name: parts[1]}
+		for _, other := range targets {
+			if other == target {
+				return nil, 0, fmt.Errorf("duplicate target %s in %s annotation", target, AnnotationSecretShamirTargets)
+			}
+		}
+		targets = append(targets, target)
+	}
+
+	threshold, err := strconv.Atoi(annotations[AnnotationSecretShamirThreshold])
+	if err != nil || threshold < 2 || threshold > len(targets) {
+		return nil, 0, fmt.Errorf("%s must be between 2 and the number of targets, got %q", AnnotationSecretShamirThreshold, annotations[AnnotationSecretShamirThreshold])
+	}
+	return targets, threshold, nil
+}
+
+// reconcileShamir generates a master key and stores its shares in the target secrets, so that no single
+// team holds the entire key. The master key itself is never stored. Shares are only generated once, or again
+// if the regenerate annotation is set, as replacing a single share would invalidate all others.
+func (r *ReconcileSecret) reconcileShamir(reqLogger logr.Logger, instance *corev1.Secret) (reconcile.Result, error) {
+	targets, threshold, err := shamirConfig(instance.Annotations)
+	if err != nil {
+		r.recorder.Eventf(instance, corev1.EventTypeWarning, "GenerationFailed", "could not split master key: %s", err)
+		return reconcile.Result{}, err
+	}
+
+	owner := "Secret/" + instance.Namespace + "/" + instance.Name
+	_, regenerate := instance.Annotations[AnnotationSecretRegenerate]
+	if _, split := instance.Annotations[AnnotationSecretShamirSplitAt]; split && !regenerate {
+		for _, target := range targets {
+			err := r.client.Get(context.TODO(), target, &corev1.Secret{})
+			if errors.IsNotFound(err) {
+				reqLogger.Info("share of split master key is missing", "target", target.String())
+				r.recorder.Eventf(instance, corev1.EventTypeWarning, "ShareMissing",
+					"share %s is missing, set the %s annotation to split a new master key", target, AnnotationSecretRegenerate)
+			} else if err != nil {
+				return reconcile.Result{}, err
+			}
+		}
+		return reconcile.Result{}, nil
+	}
+
+	existing := make([]*corev1.Secret, len(targets))
+	for i, target := range targets {
+		allowed, err := TargetNamespaceAllowed(r.client, instance.Namespace, target.Namespace)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		if !allowed {
+			err := fmt.Errorf("secrets in namespace %s may not create shares in namespace %s", instance.Namespace, target.Namespace)
+			r.recorder.Eventf(instance, corev1.EventTypeWarning, "GenerationFailed", "could not split master key: %s", err)
+			return reconcile.Result{}, err
+		}
+
+		s := &corev1.Secret{}
+		err = r.client.Get(context.TODO(), target, s)
+		if err != nil && !errors.IsNotFound(err) {
+			return reconcile.Result{}, err
+		}
+		if err == nil {
+			if s.Annotations[AnnotationSecretOwner] != owner {
+				err := fmt.Errorf("secret %s already exists and does not hold a share of this secret", target)
+				r.recorder.Eventf(instance, corev1.EventTypeWarning, "GenerationFailed", "could not split master key: %s", err)
+				return reconcile.Result{}, err
+			}
+			existing[i] = s
+		}
+	}
+
+	key := make([]byte, shamirKeyLength)
+	if _, err := io.ReadFull(entropy.Reader, key); err != nil {
+		return reconcile.Result{}, err
+	}
+	shares, err := shamir.Split(key, len(targets), threshold)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	for i, target := range targets {
+		s := existing[i]
+		if s == nil {
+			s = &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: target.Namespace, Name: target.Name, Labels: SelectorLabels()}}
+		}
+		if s.Annotations == nil {
+			s.Annotations = make(map[string]string)
+		}
+		s.Annotations[AnnotationSecretOwner] = owner
+		s.Annotations[AnnotationSecretShamirThreshold] = strconv.Itoa(threshold)
+		s.Data = map[string][]byte{ShamirShareKey: shares[i]}
+
+		if existing[i] == nil {
+			err = r.client.Create(context.TODO(), s)
+		} else {
+			err = r.client.Update(context.TODO(), s)
+		}
+		if err != nil {
+			// shares written so far are useless without the others, the next attempt splits a new key
+			reqLogger.Error(err, "could not store share of master key", "target", target.String())
+			return reconcile.Result{}, err
+		}
+	}
+
+	desired := instance.DeepCopy()
+	delete(desired.Annotations, AnnotationSecretRegenerate)
+	desired.Annotations[AnnotationSecretShamirSplitAt] = time.Now().Format(time.RFC3339)
+	if err := r.updateSecret(instance, desired); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	reqLogger.Info("split master key into shares", "shares", len(targets), "threshold", threshold)
+	r.recorder.Eventf(instance, corev1.EventTypeNormal, "MasterKeySplit",
+		"split new master key into %d shares, %d of which reconstruct it", len(targets), threshold)
+	return reconcile.Result{}, nil
+}
@@ -0,0 +1,55 @@
+package secret
+
+import (
+	"context"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/shamir"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"strings"
+	"testing"
+)
+
+func TestSplitMasterKeyIntoShares(t *testing.T) {
+	targets := []string{getSecretName(), getSecretName(), getSecretName()}
+	in := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      getSecretName(),
+			Namespace: "default",
+			Annotations: map[string]string{
+				AnnotationSecretShamirTargets:   "default/" + strings.Join(targets, ",default/"),
+				AnnotationSecretShamirThreshold: "2",
+			},
+		},
+	}
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), in))
+
+	doReconcile(t, in, false)
+
+	shares := make([][]byte, len(targets))
+	for i, name := range targets {
+		share := &corev1.Secret{}
+		require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{Name: name, Namespace: "default"}, share))
+		require.Equal(t, "Secret/default/"+in.Name, share.Annotations[AnnotationSecretOwner])
+		shares[i] = share.Data[ShamirShareKey]
+	}
+
+	key, err := shamir.Combine(shares[:2])
+	require.NoError(t, err)
+	require.Len(t, key, shamirKeyLength)
+	other, err := shamir.Combine(shares[1:])
+	require.NoError(t, err)
+	require.Equal(t, key, other)
+
+	out := &corev1.Secret{}
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{Name: in.Name, Namespace: "default"}, out))
+	require.Empty(t, out.Data)
+	require.NotEmpty(t, out.Annotations[AnnotationSecretShamirSplitAt])
+
+	// shares are not replaced without the regenerate annotation
+	doReconcile(t, in, false)
+	share := &corev1.Secret{}
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{Name: targets[0], Namespace: "default"}, share))
+	require.Equal(t, shares[0], share.Data[ShamirShareKey])
+}
@@ -0,0 +1,240 @@
+package secret
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"strings"
+	"time"
+)
+
+// MaxFieldLength is the maximum length of a field, or bits of an ssh-keypair, limiting the memory and time
+// its generation takes. The length of other fields is limited by policy-max-length instead, if it is set.
+const MaxFieldLength = 8192
+
+// Spec describes the fields of a secret in a single annotation, replacing the
+// individual v1 annotations
+type Spec struct {
+	Fields    []FieldSpec       `json:"fields"`
+	Templates map[string]string `json:"templates,omitempty"`
+}
+
+// FieldSpec describes a single generated field. Fields of type ssh-keypair store the
+// private key in the named field and the public key in the field suffixed with .pub
+type FieldSpec struct {
+	Name     string     `json:"name"`
+	Type     SecretType `json:"type,omitempty"`
+	Length   int        `json:"length,omitempty"`
+	Encoding Encoding   `json:"encoding,omitempty"`
+}
+
+// parseSpec decodes and validates the value of the spec annotation
+func parseSpec(val string) (*Spec, error) {
+	spec := &Spec{}
+
+	dec := json.NewDecoder(strings.NewReader(val))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(spec); err != nil {
+		return nil, fmt.Errorf("invalid %s annotation: %w", AnnotationSecretSpec, err)
+	}
+
+	for i := range spec.Fields {
+		if spec.Fields[i].Type == "" {
+			spec.Fields[i].Type = SecretTypeString
+		}
+	}
+
+	if err := spec.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid %s annotation: %w", AnnotationSecretSpec, err)
+	}
+	return spec, nil
+}
+
+// maxLength returns the maximum length of the field
+func (f FieldSpec) maxLength() int {
+	if max := policyMaxLength(); max > 0 && f.Type != SecretTypeSSHKeypair {
+		return max
+	}
+	return MaxFieldLength
+}
+
+func (s *Spec) Validate() error {
+	if len(s.Fields) == 0 {
+		return fmt.Errorf("no fields specified")
+	}
+
+	var keys []string
+	for _, f := range s.Fields {
+		if f.Name == "" {
+			return fmt.Errorf("field name must not be empty")
+		}
+		if err := f.Type.Validate(); err != nil {
+			return err
+		}
+		if err := f.Encoding.Validate(); err != nil {
+			return err
+		}
+		if f.Length < 0 {
+			return fmt.Errorf("length of field %s must not be negative", f.Name)
+		}
+		if max := f.maxLength(); f.Length > max {
+			return fmt.Errorf("length of field %s must not exceed %d", f.Name, max)
+		}
+		if f.Type == SecretTypeSSHKeypair && f.Encoding != "" {
+			return fmt.Errorf("encoding of field %s is only supported for type %s", f.Name, SecretTypeString)
+		}
+		keys = append(keys, f.keys()...)
+	}
+	for key, text := range s.Templates {
+		if _, err := parseTemplate(key, text); err != nil {
+			return err
+		}
+		keys = append(keys, key)
+	}
+	return ensureUniqueness(keys)
+}
+
+// keys returns the keys of the secret data written for the field
+func (f FieldSpec) keys() []string {
+	if f.Type == SecretTypeSSHKeypair {
+		return []string{f.Name, f.Name + ".pub"}
+	}
+	return []string{f.Name}
+}
+
+type SpecGenerator struct {
+	log          logr.Logger
+	spec         *Spec
+	string       StringGenerator
+	sshKeyLength int
+}
+
+func (sg SpecGenerator) generateData(instance *corev1.Secret) (reconcile.Result, error) {
+	var genKeys []string
+	for _, f := range sg.spec.Fields {
+		genKeys = append(genKeys, f.Name)
+	}
+
+	regenKeys := keysToRegenerate(sg.log, instance, genKeys)
+
+	generatedCount := 0
+	for _, f := range sg.spec.Fields {
+		if len(instance.Data[f.Name]) != 0 && !contains(regenKeys, f.Name) {
+			// dont generate field if it already has a value
+			// and is not queued for regeneration
+			continue
+		}
+		generatedCount++
+
+		switch f.Type {
+		case SecretTypeString:
+			length := sg.string.length
+			if f.Length > 0 {
+				length = f.Length
+			}
+
+			start := time.Now()
+			value, err := sg.string.generateEncodedValue(f.Name, length, f.Encoding)
+			observeGeneration(SecretTypeString, start)
+			if err != nil {
+				sg.log.Error(err, "could not generate new instance")
+				return reconcile.Result{RequeueAfter: time.Second * 30}, err
+			}
+			instance.Data[f.Name] = []byte(value)
+		case SecretTypeSSHKeypair:
+			length := sg.sshKeyLength
+			if f.Length > 0 {
+				length = f.Length
+			}
+
+			start := time.Now()
+			keyPair, err := generateSSHKeypair(length)
+			observeGeneration(SecretTypeSSHKeypair, start)
+			if err != nil {
+				sg.log.Error(err, "could not generate new instance")
+				return reconcile.Result{RequeueAfter: time.Second * 30}, err
+			}
+			instance.Data[f.Name] = keyPair.PrivateKey
+			instance.Data[f.Name+".pub"] = keyPair.PublicKey
+		default:
+			if err := generatePluginField(instance, f.Type, f.Name, f.Length, f.Encoding); err != nil {
+				sg.log.Error(err, "could not generate new instance")
+				return reconcile.Result{RequeueAfter: time.Second * 30}, err
+			}
+		}
+
+		sg.log.Info("set field of instance to new randomly generated instance", "type", f.Type, "field", f.Name)
+	}
+	sg.log.Info("generated secrets", "count", generatedCount)
+
+	if generatedCount == len(genKeys) {
+		// all keys have been generated by this instance
+		instance.Annotations[AnnotationSecretSecure] = "yes"
+	}
+
+	return reconcile.Result{}, nil
+}
+
+// GenerateFields returns the values of the fields of spec, generated using the controller defaults.
+// It allows generating values outside of secrets, e.g. on behalf of other services.
+func GenerateFields(spec *Spec) (map[string][]byte, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+
+	defaults := globalDefaults()
+	instance := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{}},
+		Data:       map[string][]byte{},
+	}
+	generator := SpecGenerator{
+		log:          log,
+		spec:         spec,
+		string:       newStringGenerator(log, defaults, nil),
+		sshKeyLength: defaults.sshKeyLength,
+	}
+	if _, err := generator.generateData(instance); err != nil {
+		return nil, err
+	}
+	return instance.Data, nil
+}
+
+// generateFromSpec fills in the values described by the spec annotation
+func (r *ReconcileSecret) generateFromSpec(reqLogger logr.Logger, desired *corev1.Secret) (bool, reconcile.Result, error) {
+	reqLogger = reqLogger.WithValues("spec", "v2")
+
+	spec, err := parseSpec(desired.Annotations[AnnotationSecretSpec])
+	if err != nil {
+		reqLogger.Error(err, "could not parse spec")
+		return true, reconcile.Result{}, err
+	}
+
+	defaults, err := r.defaults(desired.Namespace)
+	if err != nil {
+		reqLogger.Error(err, "could not get namespace defaults")
+		return true, reconcile.Result{}, err
+	}
+
+	pg, err := r.stringGenerator(reqLogger, desired, defaults)
+	if err != nil {
+		reqLogger.Error(err, "could not set up string generator")
+		return true, reconcile.Result{}, err
+	}
+
+	if desired.Data == nil {
+		desired.Data = make(map[string][]byte)
+	}
+
+	generator := SpecGenerator{
+		log:          reqLogger,
+		spec:         spec,
+		string:       pg,
+		sshKeyLength: defaults.sshKeyLength,
+	}
+
+	res, err := generator.generateData(desired)
+	return true, res, err
+}
@@ -0,0 +1,110 @@
+package secret
+
+import (
+	"context"
+	"encoding/hex"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"testing"
+)
+
+func newSpecTestSecret(spec string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      getSecretName(),
+			Namespace: "default",
+			Labels: map[string]string{
+				labelSecretGeneratorTest: "yes",
+			},
+			Annotations: map[string]string{
+				AnnotationSecretSpec: spec,
+			},
+		},
+		Type: corev1.SecretTypeOpaque,
+	}
+}
+
+func TestParseSpec(t *testing.T) {
+	spec, err := parseSpec(`{"fields":[{"name":"password"},{"name":"key","type":"ssh-keypair"}]}`)
+	require.NoError(t, err)
+	require.Equal(t, SecretTypeString, spec.Fields[0].Type)
+	require.Equal(t, SecretTypeSSHKeypair, spec.Fields[1].Type)
+
+	_, err = parseSpec(`{"fields":[]}`)
+	require.Error(t, err)
+	_, err = parseSpec(`{"fields":[{"name":"password","type":"foo"}]}`)
+	require.Error(t, err)
+	_, err = parseSpec(`{"fields":[{"name":"password","encoding":"foo"}]}`)
+	require.Error(t, err)
+	_, err = parseSpec(`{"fields":[{"name":"key","type":"ssh-keypair","encoding":"hex"}]}`)
+	require.Error(t, err)
+	_, err = parseSpec(`{"fields":[{"name":"key","type":"ssh-keypair"},{"name":"key.pub"}]}`)
+	require.Error(t, err)
+	_, err = parseSpec(`{"fields":[{"name":"password","size":10}]}`)
+	require.Error(t, err)
+}
+
+func TestSpecRejectsOversizedFields(t *testing.T) {
+	require.Error(t, (&Spec{Fields: []FieldSpec{{Name: "password", Type: SecretTypeString, Length: 1 << 31}}}).Validate())
+	require.NoError(t, (&Spec{Fields: []FieldSpec{{Name: "key", Type: SecretTypeSSHKeypair, Length: 4096}}}).Validate())
+
+	viper.Set("policy-max-length", 64)
+	defer viper.Set("policy-max-length", 0)
+	require.Error(t, (&Spec{Fields: []FieldSpec{{Name: "password", Type: SecretTypeString, Length: 128}}}).Validate())
+	require.NoError(t, (&Spec{Fields: []FieldSpec{{Name: "password", Type: SecretTypeString, Length: 64}}}).Validate())
+	require.NoError(t, (&Spec{Fields: []FieldSpec{{Name: "key", Type: SecretTypeSSHKeypair, Length: 4096}}}).Validate())
+}
+
+func TestGenerateFromSpec(t *testing.T) {
+	in := newSpecTestSecret(`{"fields":[
+		{"name":"password","length":20},
+		{"name":"token","length":32,"encoding":"hex"},
+		{"name":"key","type":"ssh-keypair"}
+	]}`)
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), in))
+
+	doReconcile(t, in, false)
+
+	out := &corev1.Secret{}
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{
+		Name:      in.Name,
+		Namespace: in.Namespace}, out))
+
+	require.Len(t, out.Data["password"], 20)
+	require.Len(t, out.Data["token"], 32)
+	_, err := hex.DecodeString(string(out.Data["token"]))
+	require.NoError(t, err)
+
+	key, err := privateKeyFromPEM(out.Data["key"])
+	require.NoError(t, err)
+	pub, err := sshPublicKeyForPrivateKey(key)
+	require.NoError(t, err)
+	require.Equal(t, pub, out.Data["key.pub"])
+
+	require.Equal(t, "yes", out.Annotations[AnnotationSecretSecure])
+}
+
+func TestRegenerateFromSpec(t *testing.T) {
+	in := newSpecTestSecret(`{"fields":[{"name":"password"},{"name":"token"}]}`)
+	in.Data = map[string][]byte{
+		"password": []byte("password"),
+		"token":    []byte("token"),
+	}
+	in.Annotations[AnnotationSecretSecure] = "yes"
+	in.Annotations[AnnotationSecretRegenerate] = "token"
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), in))
+
+	doReconcile(t, in, false)
+
+	out := &corev1.Secret{}
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{
+		Name:      in.Name,
+		Namespace: in.Namespace}, out))
+
+	require.Equal(t, "password", string(out.Data["password"]))
+	require.NotEqual(t, "token", string(out.Data["token"]))
+	require.NotContains(t, out.Annotations, AnnotationSecretRegenerate)
+}
@@ -2,12 +2,13 @@ package secret
 
 import (
 	"bytes"
-	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/pem"
 	"errors"
 	"github.com/go-logr/logr"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/entropy"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/fips"
 	"golang.org/x/crypto/ssh"
 	corev1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
@@ -20,7 +21,8 @@ const (
 )
 
 type SSHKeypairGenerator struct {
-	log logr.Logger
+	log    logr.Logger
+	length int
 }
 
 type SSHKeypair struct {
@@ -59,12 +61,14 @@ func (sg SSHKeypairGenerator) generateData(instance *corev1.Secret) (reconcile.R
 		delete(instance.Annotations, AnnotationSecretRegenerate)
 	}
 
-	length, err := secretLengthFromAnnotation(sshKeyLength(), instance.Annotations)
+	length, err := secretLengthFromAnnotation(sg.length, instance.Annotations)
 	if err != nil {
 		return reconcile.Result{}, err
 	}
 
+	start := time.Now()
 	keyPair, err := generateSSHKeypair(length)
+	observeGeneration(SecretTypeSSHKeypair, start)
 	if err != nil {
 		return reconcile.Result{RequeueAfter: time.Second * 30}, err
 	}
@@ -79,7 +83,11 @@ func (sg SSHKeypairGenerator) generateData(instance *corev1.Secret) (reconcile.R
 // the returned public key is in authorized-keys format
 // the private key is PEM encoded
 func generateSSHKeypair(length int) (SSHKeypair, error) {
-	key, err := rsa.GenerateKey(rand.Reader, length)
+	if err := fips.CheckRSAKeyLength(length); err != nil {
+		return SSHKeypair{}, err
+	}
+
+	key, err := rsa.GenerateKey(entropy.Reader, length)
 	if err != nil {
 		return SSHKeypair{}, err
 	}
@@ -3,43 +3,66 @@ package secret
 import (
 	"crypto/rand"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"github.com/go-logr/logr"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/apis/secretgenerator/v1alpha1"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/entropy"
+	"io"
 	corev1 "k8s.io/api/core/v1"
+	"math/big"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"strings"
 	"time"
 )
 
 type StringGenerator struct {
-	log logr.Logger
+	log     logr.Logger
+	length  int
+	charset string
+	// derivation derives values from a master key instead of generating them at random if set
+	derivation *derivation
+	// checks reject generated values, which are then drawn again up to attempts times
+	checks   []valueCheck
+	attempts int
 }
 
-func (pg StringGenerator) generateData(instance *corev1.Secret) (reconcile.Result, error) {
-	toGenerate := instance.Annotations[AnnotationSecretAutoGenerate] // won't generate anything if annotation is not set
+// maxDrawAttempts is the default number of values drawn for a field until one passes the checks of the generator
+const maxDrawAttempts = 10
+
+// valueCheck returns why a generated value is rejected, or an empty string if it is accepted
+type valueCheck func(value string) (string, error)
+
+// newStringGenerator returns a StringGenerator using the given defaults,
+// overridden by the given PasswordPolicy if it is not nil
+func newStringGenerator(log logr.Logger, defaults generatorDefaults, policy *v1alpha1.PasswordPolicySpec) StringGenerator {
+	pg := StringGenerator{
+		log:      log,
+		length:   defaults.length,
+		charset:  defaults.charset,
+		attempts: maxDrawAttempts,
+	}
+	if policy != nil {
+		if policy.Length > 0 {
+			pg.length = policy.Length
+		}
+		if policy.Charset != "" {
+			pg.charset = policy.Charset
+		}
+	}
+	return pg
+}
 
-	genKeys := strings.Split(toGenerate, ",")
+func (pg StringGenerator) generateData(instance *corev1.Secret) (reconcile.Result, error) {
+	genKeys := autogenerateKeys(instance) // won't generate anything if annotation is not set
 
 	if err := ensureUniqueness(genKeys); err != nil {
 		return reconcile.Result{}, err
 	}
 
-	var regenKeys []string
-	if _, ok := instance.Annotations[AnnotationSecretSecure]; !ok && regenerateInsecure() {
-		pg.log.Info("instance was generated by a cryptographically insecure PRNG")
-		regenKeys = genKeys // regenerate all keys
-	} else if regenerate, ok := instance.Annotations[AnnotationSecretRegenerate]; ok {
-		pg.log.Info("removing regenerate annotation from instance")
-		delete(instance.Annotations, AnnotationSecretRegenerate)
-
-		if regenerate == "yes" {
-			regenKeys = genKeys
-		} else {
-			regenKeys = strings.Split(regenerate, ",") // regenerate requested keys
-		}
-	}
+	regenKeys := keysToRegenerate(pg.log, instance, genKeys)
 
-	length, err := secretLengthFromAnnotation(secretLength(), instance.Annotations)
+	length, err := secretLengthFromAnnotation(pg.length, instance.Annotations)
 	if err != nil {
 		return reconcile.Result{}, err
 	}
@@ -49,11 +72,14 @@ func (pg StringGenerator) generateData(instance *corev1.Secret) (reconcile.Resul
 		if len(instance.Data[key]) != 0 && !contains(regenKeys, key) {
 			// dont generate key if it already has a value
 			// and is not queued for regeneration
+			pg.log.V(1).Info("field already has a value, keeping it", "field", key)
 			continue
 		}
 		generatedCount++
 
-		value, err := generateRandomString(length)
+		start := time.Now()
+		value, err := pg.generateValue(key, length)
+		observeGeneration(SecretTypeString, start)
 		if err != nil {
 			pg.log.Error(err, "could not generate new instance")
 			return reconcile.Result{RequeueAfter: time.Second * 30}, err
@@ -61,9 +87,10 @@ func (pg StringGenerator) generateData(instance *corev1.Secret) (reconcile.Resul
 
 		instance.Data[key] = []byte(value)
 
-		pg.log.Info("set field of instance to new randomly generated instance", "bytes", len(value), "field", key)
+		pg.log.Info("set field of instance to new randomly generated instance", "bytes", len(value), "field", key, "derived", pg.derivation != nil)
 	}
 	pg.log.Info("generated secrets", "count", generatedCount)
+	recordAutogenerated(instance, genKeys)
 
 	if generatedCount == len(genKeys) {
 		// all keys have been generated by this instance
@@ -73,9 +100,150 @@ func (pg StringGenerator) generateData(instance *corev1.Secret) (reconcile.Resul
 	return reconcile.Result{}, nil
 }
 
+// keysToRegenerate returns the keys of the instance that have to be regenerated even though
+// they already have a value, and removes the regenerate annotation from the instance
+func keysToRegenerate(log logr.Logger, instance *corev1.Secret, genKeys []string) []string {
+	if _, ok := instance.Annotations[AnnotationSecretSecure]; !ok && regenerateInsecure() {
+		log.Info("instance was generated by a cryptographically insecure PRNG")
+		return genKeys // regenerate all keys
+	}
+
+	regenerate, ok := instance.Annotations[AnnotationSecretRegenerate]
+	if !ok {
+		return nil
+	}
+
+	keys := requestedKeys(instance)
+
+	log.Info("removing regenerate annotation from instance", "value", regenerate)
+	delete(instance.Annotations, AnnotationSecretRegenerate)
+
+	if keys == nil {
+		return genKeys
+	}
+	return keys // regenerate requested keys
+}
+
+// requestedKeys returns the keys listed in the regenerate annotation of the instance,
+// or nil if the annotation is missing or requests all keys to be regenerated
+func requestedKeys(instance *corev1.Secret) []string {
+	regenerate, ok := instance.Annotations[AnnotationSecretRegenerate]
+	if !ok || regenerate == "yes" || regenerate == "true" {
+		return nil
+	}
+
+	keys := make([]string, 0)
+	for _, key := range strings.Split(regenerate, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// requestRegeneration adds the given keys to the regenerate annotation of the instance,
+// unless all keys are requested to be regenerated anyway
+func requestRegeneration(instance *corev1.Secret, keys []string) {
+	regenerate, ok := instance.Annotations[AnnotationSecretRegenerate]
+	if !ok {
+		instance.Annotations[AnnotationSecretRegenerate] = strings.Join(keys, ",")
+		return
+	}
+
+	requested := requestedKeys(instance)
+	if requested == nil {
+		return
+	}
+	for _, key := range keys {
+		if !contains(requested, key) {
+			regenerate += "," + key
+		}
+	}
+	instance.Annotations[AnnotationSecretRegenerate] = regenerate
+}
+
+// source returns the source the value of the given field is drawn from
+func (pg StringGenerator) source(key string) io.Reader {
+	if pg.derivation != nil {
+		return pg.derivation.reader(key)
+	}
+	return entropy.Reader
+}
+
+func (pg StringGenerator) generateValue(key string, length int) (string, error) {
+	return pg.generateEncodedValue(key, length, "")
+}
+
+// generateEncodedValue returns a random string of the given length using the given encoding, or the
+// charset of the generator if no encoding is set. Values rejected by the checks of the generator are
+// drawn again from the same source, up to the number of attempts of the generator.
+func (pg StringGenerator) generateEncodedValue(key string, length int, encoding Encoding) (string, error) {
+	r := pg.source(key)
+
+	var reason string
+	for attempt := 0; attempt < pg.attempts; attempt++ {
+		value, err := pg.read(r, length, encoding)
+		if err != nil {
+			return "", err
+		}
+
+		reason, err = pg.check(value)
+		if err != nil {
+			return "", err
+		}
+		if reason == "" {
+			return value, nil
+		}
+		if pg.log != nil {
+			pg.log.V(1).Info("drawing value again", "field", key, "reason", reason)
+		}
+	}
+	return "", fmt.Errorf("no value for field %s passed the checks in %d attempts, the last one %s", key, pg.attempts, reason)
+}
+
+// read reads a string of the given length from r, using the given encoding or the charset of the generator
+func (pg StringGenerator) read(r io.Reader, length int, encoding Encoding) (string, error) {
+	switch encoding {
+	case EncodingBase64:
+		return readString(r, length)
+	case EncodingHex:
+		return readHexString(r, length)
+	}
+	if pg.charset != "" {
+		return readStringFromCharset(r, length, pg.charset)
+	}
+	return readString(r, length)
+}
+
+// check returns why the value is rejected by the checks of the generator, or an empty string if it is accepted
+func (pg StringGenerator) check(value string) (string, error) {
+	for _, c := range pg.checks {
+		reason, err := c(value)
+		if err != nil || reason != "" {
+			return reason, err
+		}
+	}
+	return "", nil
+}
+
 func generateRandomString(length int) (string, error) {
+	return readString(entropy.Reader, length)
+}
+
+func generateRandomHexString(length int) (string, error) {
+	return readHexString(entropy.Reader, length)
+}
+
+// generateRandomStringFromCharset returns a random string of the given length
+// with characters drawn uniformly from charset
+func generateRandomStringFromCharset(length int, charset string) (string, error) {
+	return readStringFromCharset(entropy.Reader, length, charset)
+}
+
+// readString returns a base64 string of the given length read from r
+func readString(r io.Reader, length int) (string, error) {
 	b := make([]byte, length)
-	_, err := rand.Read(b)
+	_, err := io.ReadFull(r, b)
 	if err != nil {
 		return "", err
 	}
@@ -83,6 +251,35 @@ func generateRandomString(length int) (string, error) {
 	return base64.StdEncoding.EncodeToString(b)[0:length], nil
 }
 
+// readHexString returns a hex string of the given length read from r
+func readHexString(r io.Reader, length int) (string, error) {
+	b := make([]byte, (length+1)/2)
+	_, err := io.ReadFull(r, b)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b)[0:length], nil
+}
+
+// readStringFromCharset returns a string of the given length with characters
+// drawn uniformly from charset using r
+func readStringFromCharset(r io.Reader, length int, charset string) (string, error) {
+	chars := []rune(charset)
+	max := big.NewInt(int64(len(chars)))
+
+	b := make([]rune, length)
+	for i := range b {
+		n, err := rand.Int(r, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = chars[n.Int64()]
+	}
+
+	return string(b), nil
+}
+
 // ensure elements in input array are unique
 func ensureUniqueness(a []string) error {
 	set := map[string]bool{}
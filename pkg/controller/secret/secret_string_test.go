@@ -186,6 +186,27 @@ func TestRegenerateSingleField(t *testing.T) {
 	verifyStringRegen(t, in, out)
 }
 
+func TestRegenerateSelectedFieldsOnly(t *testing.T) {
+	in := newStringTestSecret("apiKey,dbPassword,token", map[string]string{
+		AnnotationSecretSecure:          "yes",
+		AnnotationSecretRegenerate:      "apiKey, token",
+		AnnotationSecretAutoGeneratedAt: time.Now().Format(time.RFC3339),
+	}, "key,password,token")
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), in))
+
+	doReconcile(t, in, false)
+
+	out := &corev1.Secret{}
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{
+		Name:      in.Name,
+		Namespace: in.Namespace}, out))
+
+	require.NotContains(t, out.Annotations, AnnotationSecretRegenerate)
+	require.NotEqual(t, "key", string(out.Data["apiKey"]))
+	require.NotEqual(t, "token", string(out.Data["token"]))
+	require.Equal(t, "password", string(out.Data["dbPassword"]))
+}
+
 func TestRegenerateAllSingleField(t *testing.T) {
 	in := newStringTestSecret("testfield", map[string]string{
 		AnnotationSecretRegenerate:      "yes",
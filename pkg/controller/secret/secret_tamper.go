@@ -0,0 +1,161 @@
+package secret
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"sort"
+	"strings"
+)
+
+// generatedFields returns the keys of all values the secret generator generates for the secret
+func generatedFields(instance *corev1.Secret) []string {
+	if val, ok := instance.Annotations[AnnotationSecretSpec]; ok {
+		spec, err := parseSpec(val)
+		if err != nil {
+			return nil
+		}
+		var keys []string
+		for _, f := range spec.Fields {
+			keys = append(keys, f.Name)
+			switch f.Type {
+			case SecretTypeSSHKeypair:
+				keys = append(keys, f.Name+".pub")
+			case SecretTypeString:
+			default:
+				keys = append(keys, pluginExtraKeys(instance, f.Name)...)
+			}
+		}
+		return keys
+	}
+
+	sType := SecretType(instance.Annotations[AnnotationSecretType])
+	if sType == SecretTypeSSHKeypair {
+		return []string{SecretFieldPrivateKey, SecretFieldPublicKey}
+	}
+
+	var keys []string
+	for _, key := range autogenerateKeys(instance) {
+		if key == "" {
+			continue
+		}
+		keys = append(keys, key)
+		if sType != "" && sType != SecretTypeString {
+			keys = append(keys, pluginExtraKeys(instance, key)...)
+		}
+	}
+	return keys
+}
+
+// pluginExtraKeys returns the keys of the additional values a generator plugin returned for field, e.g.
+// field.crt, which are stored under keys prefixed with the field name
+func pluginExtraKeys(instance *corev1.Secret, field string) []string {
+	var keys []string
+	for key := range instance.Data {
+		if strings.HasPrefix(key, field+".") && !strings.HasSuffix(key, EscrowFieldSuffix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Fingerprint returns a short hash identifying a value without revealing it
+func Fingerprint(value []byte) string {
+	sum := sha256.Sum256(value)
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+// fingerprints parses the fingerprints annotation of the secret, mapping each generated field to the
+// fingerprint of the value last written by the controller
+func fingerprints(instance *corev1.Secret) map[string]string {
+	recorded := make(map[string]string)
+	val, ok := instance.Annotations[AnnotationSecretFingerprints]
+	if !ok {
+		return recorded
+	}
+
+	for _, entry := range strings.Split(val, ",") {
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) == 2 {
+			recorded[parts[0]] = parts[1]
+		}
+	}
+	return recorded
+}
+
+// tamperedFields returns the generated fields whose values no longer match their recorded fingerprint
+func tamperedFields(instance *corev1.Secret) []string {
+	recorded := fingerprints(instance)
+
+	var tampered []string
+	for _, key := range generatedFields(instance) {
+		fp, ok := recorded[key]
+		value := instance.Data[key]
+		if ok && len(value) > 0 && Fingerprint(value) != fp {
+			tampered = append(tampered, key)
+		}
+	}
+	sort.Strings(tampered)
+	return tampered
+}
+
+// detectTampering warns about generated values that have been modified by something other than the
+// controller. If enabled, these values are queued for regeneration using the regenerate annotation.
+func (r *ReconcileSecret) detectTampering(reqLogger logr.Logger, desired *corev1.Secret) {
+	tampered := tamperedFields(desired)
+	if len(tampered) == 0 {
+		return
+	}
+
+	fields := strings.Join(tampered, ",")
+	if !regenerateTampered() {
+		reqLogger.Info("generated values have been modified", "fields", fields)
+		r.recorder.Eventf(desired, corev1.EventTypeWarning, "ValueTampered",
+			"fields %s have been modified outside of the secret generator", fields)
+		return
+	}
+
+	reqLogger.Info("regenerating modified values", "fields", fields)
+	r.recorder.Eventf(desired, corev1.EventTypeWarning, "ValueTampered",
+		"fields %s have been modified outside of the secret generator and are regenerated", fields)
+	requestRegeneration(desired, tampered)
+}
+
+// recordFingerprints records the fingerprints of all generated values of the secret. Fingerprints of values
+// that have not been changed by the controller are kept, so that modified values keep being reported, as well
+// as those of values which are no longer declared.
+func recordFingerprints(instance *corev1.Secret, previous map[string][]byte) {
+	recorded := fingerprints(instance)
+
+	declared := generatedFields(instance)
+
+	var entries []string
+	for _, key := range declared {
+		value := instance.Data[key]
+		if len(value) == 0 {
+			continue
+		}
+
+		fp, ok := recorded[key]
+		if !ok || !bytes.Equal(previous[key], value) {
+			fp = Fingerprint(value)
+		}
+		entries = append(entries, key+"="+fp)
+	}
+	for key, fp := range recorded {
+		if _, ok := instance.Data[key]; ok && !contains(declared, key) {
+			// keep fields that are no longer declared, so that they can be pruned
+			entries = append(entries, key+"="+fp)
+		}
+	}
+
+	if len(entries) == 0 {
+		delete(instance.Annotations, AnnotationSecretFingerprints)
+		return
+	}
+	sort.Strings(entries)
+	instance.Annotations[AnnotationSecretFingerprints] = strings.Join(entries, ",")
+}
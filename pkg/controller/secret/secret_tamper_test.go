@@ -0,0 +1,57 @@
+package secret
+
+import (
+	"context"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"testing"
+)
+
+func TestTamperedFields(t *testing.T) {
+	in := newStringTestSecret("password,token", nil, "password,token")
+
+	recordFingerprints(in, map[string][]byte{})
+	require.Empty(t, tamperedFields(in))
+
+	in.Data["password"] = []byte("changed")
+	require.Equal(t, []string{"password"}, tamperedFields(in))
+
+	// fingerprints of modified values are kept
+	recordFingerprints(in, map[string][]byte{"password": []byte("changed"), "token": []byte("token")})
+	require.Equal(t, []string{"password"}, tamperedFields(in))
+
+	// values changed by the controller are fingerprinted again
+	recordFingerprints(in, map[string][]byte{"password": []byte("password"), "token": []byte("token")})
+	require.Empty(t, tamperedFields(in))
+}
+
+func TestTamperedValueIsRegenerated(t *testing.T) {
+	viper.Set("regenerate-tampered", true)
+	defer viper.Set("regenerate-tampered", false)
+
+	in := newStringTestSecret("password,token", nil, "")
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), in))
+
+	doReconcile(t, in, false)
+
+	out := &corev1.Secret{}
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{
+		Name:      in.Name,
+		Namespace: in.Namespace}, out))
+	require.NotEmpty(t, out.Annotations[AnnotationSecretFingerprints])
+	token := string(out.Data["token"])
+
+	out.Data["password"] = []byte("temporary")
+	require.NoError(t, mgr.GetClient().Update(context.TODO(), out))
+
+	doReconcile(t, in, false)
+
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{
+		Name:      in.Name,
+		Namespace: in.Namespace}, out))
+	require.NotEqual(t, "temporary", string(out.Data["password"]))
+	require.Equal(t, token, string(out.Data["token"]))
+	require.Empty(t, tamperedFields(out))
+}
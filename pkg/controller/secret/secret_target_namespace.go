@@ -0,0 +1,51 @@
+package secret
+
+import (
+	"context"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"strings"
+)
+
+// LabelNamespaceAllowFrom allows StringSecrets and split secrets in the namespace given as label value
+// to create their secrets in the labeled namespace
+const LabelNamespaceAllowFrom = "secret-generator.v1.mittwald.de/allow-from"
+
+func targetNamespaceAllowlist() string {
+	return options().GetString("target-namespace-allowlist")
+}
+
+// TargetNamespaceAllowed checks whether resources in the source namespace may create secrets in the target
+// namespace, either because the controller allowlist permits it or the target namespace opted in via label
+func TargetNamespaceAllowed(c client.Reader, source, target string) (bool, error) {
+	if source == target || NamespaceAllowlisted(targetNamespaceAllowlist(), source, target) {
+		return true, nil
+	}
+
+	ns := &corev1.Namespace{}
+	if err := c.Get(context.TODO(), types.NamespacedName{Name: target}, ns); err != nil {
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return ns.Labels[LabelNamespaceAllowFrom] == source, nil
+}
+
+// NamespaceAllowlisted checks whether the comma separated allowlist contains a source:target entry
+// matching the given namespaces. A source of * matches all namespaces.
+func NamespaceAllowlisted(allowlist, source, target string) bool {
+	for _, entry := range strings.Split(allowlist, ",") {
+		parts := strings.SplitN(strings.TrimSpace(entry), ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		if (parts[0] == "*" || parts[0] == source) && parts[1] == target {
+			return true
+		}
+	}
+	return false
+}
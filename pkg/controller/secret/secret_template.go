@@ -0,0 +1,155 @@
+package secret
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// secretTemplates returns the templates of the secret, keyed by the field they are rendered into.
+// Templates are taken from template annotations and the templates of the spec annotation.
+func secretTemplates(annotations map[string]string) (map[string]string, error) {
+	templates := make(map[string]string)
+	for name, text := range annotations {
+		if strings.HasPrefix(name, AnnotationSecretTemplatePrefix) {
+			templates[strings.TrimPrefix(name, AnnotationSecretTemplatePrefix)] = text
+		}
+	}
+
+	if val, ok := annotations[AnnotationSecretSpec]; ok {
+		spec, err := parseSpec(val)
+		if err != nil {
+			return nil, err
+		}
+		for key, text := range spec.Templates {
+			if _, ok := templates[key]; ok {
+				return nil, fmt.Errorf("template for field %s is defined twice", key)
+			}
+			templates[key] = text
+		}
+	}
+
+	for key, text := range templates {
+		if _, err := parseTemplate(key, text); err != nil {
+			return nil, err
+		}
+	}
+
+	return templates, nil
+}
+
+func parseTemplate(key, text string) (*template.Template, error) {
+	if key == "" {
+		return nil, fmt.Errorf("template field name must not be empty")
+	}
+
+	// functions have to be known at parse time, they are replaced before execution
+	funcs := template.FuncMap{
+		"secret": func(string, string) (string, error) { return "", nil },
+	}
+
+	t, err := template.New(key).Option("missingkey=error").Funcs(funcs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template for field %s: %w", key, err)
+	}
+	return t, nil
+}
+
+// renderTemplates renders the templates of the secret over its values. Templates only see the
+// values the secret had before rendering, so they can't reference each other. Values of other
+// secrets in the same namespace are read using the secret function, e.g. {{ secret "db" "host" }}.
+func (r *ReconcileSecret) renderTemplates(instance *corev1.Secret) error {
+	templates, err := secretTemplates(instance.Annotations)
+	if err != nil {
+		return err
+	}
+	if len(templates) == 0 {
+		delete(instance.Annotations, AnnotationSecretTemplateReferences)
+		return nil
+	}
+
+	values := make(map[string]string, len(instance.Data))
+	for key, value := range instance.Data {
+		if _, ok := templates[key]; !ok {
+			values[key] = string(value)
+		}
+	}
+
+	var references []string
+	lookup := func(name, key string) (string, error) {
+		if !contains(references, name) {
+			references = append(references, name)
+		}
+
+		// referenced secrets usually aren't managed, so they may not be cached
+		var reader client.Reader = r.client
+		if !cachesAllSecrets() {
+			reader = r.reader
+		}
+
+		ref := &corev1.Secret{}
+		err := reader.Get(context.TODO(), types.NamespacedName{Namespace: instance.Namespace, Name: name}, ref)
+		if err != nil {
+			return "", err
+		}
+
+		value, ok := ref.Data[key]
+		if !ok {
+			return "", fmt.Errorf("secret %s has no key %s", name, key)
+		}
+		return string(value), nil
+	}
+
+	for key, text := range templates {
+		t, err := parseTemplate(key, text)
+		if err != nil {
+			return err
+		}
+
+		buf := &bytes.Buffer{}
+		if err := t.Funcs(template.FuncMap{"secret": lookup}).Execute(buf, values); err != nil {
+			return fmt.Errorf("could not render template for field %s: %w", key, err)
+		}
+		instance.Data[key] = buf.Bytes()
+	}
+
+	if len(references) > 0 {
+		sort.Strings(references)
+		instance.Annotations[AnnotationSecretTemplateReferences] = strings.Join(references, ",")
+	} else {
+		delete(instance.Annotations, AnnotationSecretTemplateReferences)
+	}
+
+	return nil
+}
+
+// referencingSecrets maps a secret to the secrets in the same namespace whose templates reference it or which
+// copy values from it
+func referencingSecrets(c client.Client) handler.ToRequestsFunc {
+	return func(a handler.MapObject) []reconcile.Request {
+		list := &corev1.SecretList{}
+		if err := c.List(context.TODO(), list, client.InNamespace(a.Meta.GetNamespace())); err != nil {
+			log.Error(err, "could not list secrets")
+			return nil
+		}
+
+		var requests []reconcile.Request
+		for _, s := range list.Items {
+			references := strings.Split(s.Annotations[AnnotationSecretTemplateReferences], ",")
+			if contains(references, a.Meta.GetName()) || copiesFrom(s.Annotations, a.Meta.GetName()) {
+				requests = append(requests, reconcile.Request{
+					NamespacedName: types.NamespacedName{Namespace: s.Namespace, Name: s.Name},
+				})
+			}
+		}
+		return requests
+	}
+}
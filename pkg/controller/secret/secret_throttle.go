@@ -0,0 +1,50 @@
+package secret
+
+import (
+	"k8s.io/client-go/util/flowcontrol"
+	"time"
+)
+
+func startupSyncQPS() float64 {
+	return options().GetFloat64("startup-sync-qps")
+}
+
+func startupSyncBurst() int {
+	return options().GetInt("startup-sync-burst")
+}
+
+func startupSyncPeriod() time.Duration {
+	return options().GetDuration("startup-sync-period")
+}
+
+// startupThrottle rate-limits the writes to secrets during the initial sync after the controller started,
+// when every existing secret is reconciled at once, e.g. to regenerate insecure values or migrate annotations.
+type startupThrottle struct {
+	until   time.Time
+	limiter flowcontrol.RateLimiter
+}
+
+// newStartupThrottle returns a throttle for the configured startup sync period, or nil if throttling is disabled
+func newStartupThrottle() *startupThrottle {
+	if startupSyncQPS() <= 0 || startupSyncPeriod() <= 0 {
+		return nil
+	}
+
+	burst := startupSyncBurst()
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &startupThrottle{
+		until:   time.Now().Add(startupSyncPeriod()),
+		limiter: flowcontrol.NewTokenBucketRateLimiter(float32(startupSyncQPS()), burst),
+	}
+}
+
+// wait blocks until the next write is allowed. Writes after the startup sync period are not throttled.
+func (t *startupThrottle) wait() {
+	if t == nil || time.Now().After(t.until) {
+		return
+	}
+	t.limiter.Accept()
+}
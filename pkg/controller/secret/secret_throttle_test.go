@@ -0,0 +1,54 @@
+package secret
+
+import (
+	"github.com/spf13/viper"
+	"testing"
+	"time"
+)
+
+func TestStartupThrottleDisabled(t *testing.T) {
+	viper.Set("startup-sync-qps", 0)
+	viper.Set("startup-sync-period", time.Minute)
+	defer viper.Set("startup-sync-period", 0)
+
+	if throttle := newStartupThrottle(); throttle != nil {
+		t.Errorf("expected no throttle without startup-sync-qps")
+	}
+}
+
+func TestStartupThrottleLimitsWrites(t *testing.T) {
+	viper.Set("startup-sync-qps", 10)
+	viper.Set("startup-sync-burst", 1)
+	viper.Set("startup-sync-period", time.Minute)
+	defer func() {
+		viper.Set("startup-sync-qps", 0)
+		viper.Set("startup-sync-burst", 0)
+		viper.Set("startup-sync-period", 0)
+	}()
+
+	throttle := newStartupThrottle()
+	if throttle == nil {
+		t.Fatal("expected throttle")
+	}
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		throttle.wait()
+	}
+	if elapsed := time.Since(start); elapsed < 250*time.Millisecond {
+		t.Errorf("expected writes to be throttled, took %s", elapsed)
+	}
+}
+
+func TestStartupThrottleEndsAfterPeriod(t *testing.T) {
+	throttle := &startupThrottle{until: time.Now().Add(-time.Second)}
+
+	start := time.Now()
+	for i := 0; i < 100; i++ {
+		// limiter is nil, so waiting would panic if the period was not over
+		throttle.wait()
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("expected writes after the startup period not to be throttled, took %s", elapsed)
+	}
+}
@@ -0,0 +1,266 @@
+package secret
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"strings"
+)
+
+// FieldManager is the name the controller uses to identify its changes to secrets
+const FieldManager = "secret-generator"
+
+// controllerAnnotations are the annotations written by the controller itself, as opposed to those
+// configuring it. The controller owns these when using server-side apply.
+var controllerAnnotations = []string{
+	AnnotationSecretAutoGeneratedAt,
+	AnnotationSecretSecure,
+	AnnotationSecretRotatedAt,
+	AnnotationSecretRegenerations,
+	AnnotationSecretRotationHistory,
+	AnnotationSecretTrigger,
+	AnnotationSecretFingerprints,
+	AnnotationSecretChecksum,
+	AnnotationSecretPreviousFields,
+	AnnotationSecretPreviousExpiresAt,
+	AnnotationSecretStagedFields,
+	AnnotationSecretStagedAt,
+	AnnotationSecretTemplateReferences,
+	AnnotationSecretSyncChecksum,
+	AnnotationSecretSealedChecksum,
+	AnnotationSecretEscrowChecksum,
+	AnnotationSecretBackupChecksum,
+	AnnotationSecretShamirSplitAt,
+	AnnotationSecretCurrentVersion,
+	AnnotationSecretGeneratedFields,
+	AnnotationSecretRotationFailed,
+}
+
+// updateSecret writes the changes made to desired since instance was read using a JSON merge patch.
+// The patch only contains the data keys and annotations changed by the controller, so that concurrent changes
+// to other keys or labels, e.g. by Helm, are not clobbered. Lists like the finalizers are replaced as a whole
+// by a merge patch though, so the patch carries the resourceVersion of instance and fails with a conflict if the
+// secret has been modified in the meantime. The changes are then applied to its latest version and the patch
+// is retried. With server-side apply enabled, the fields owned by the controller are applied instead.
+// During the startup sync, writes are throttled. On success, desired holds the patched secret.
+func (r *ReconcileSecret) updateSecret(instance, desired *corev1.Secret) error {
+	r.throttle.wait()
+
+	if !serverSideApply() {
+		key := types.NamespacedName{Namespace: instance.Namespace, Name: instance.Name}
+		base, target := instance, desired.DeepCopy()
+
+		err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+			if target == nil {
+				latest := &corev1.Secret{}
+				if err := r.reader.Get(context.TODO(), key, latest); err != nil {
+					return err
+				}
+				base, target = latest, applyChanges(instance, desired, latest)
+			}
+
+			err := r.client.Patch(context.TODO(), target, mergeFromWithOptimisticLock(base), client.FieldOwner(FieldManager))
+			if err != nil {
+				target = nil
+			}
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		target.DeepCopyInto(desired)
+		return nil
+	}
+
+	// removals and changes to fields the controller doesn't own, e.g. removing the regenerate
+	// annotation, can't be applied and are patched instead
+	unowned := instance.DeepCopy()
+	changed := false
+	for key := range instance.Annotations {
+		if _, ok := desired.Annotations[key]; !ok {
+			delete(unowned.Annotations, key)
+			changed = true
+		}
+	}
+	for key, value := range desired.Annotations {
+		if !contains(controllerAnnotations, key) && instance.Annotations[key] != value {
+			if unowned.Annotations == nil {
+				unowned.Annotations = make(map[string]string)
+			}
+			unowned.Annotations[key] = value
+			changed = true
+		}
+	}
+	for key := range instance.Data {
+		if _, ok := desired.Data[key]; !ok {
+			delete(unowned.Data, key)
+			changed = true
+		}
+	}
+	if changed {
+		err := r.client.Patch(context.TODO(), unowned, client.MergeFrom(instance), client.FieldOwner(FieldManager))
+		if err != nil {
+			return err
+		}
+	}
+
+	applied := ownedFields(desired)
+	if err := r.client.Patch(context.TODO(), applied, client.Apply, client.FieldOwner(FieldManager)); err != nil {
+		return err
+	}
+	applied.DeepCopyInto(desired)
+	return nil
+}
+
+// applyChanges applies the data, annotations and finalizers changed from instance to desired onto latest.
+// Changes made by the controller take precedence over concurrent changes of the same keys.
+func applyChanges(instance, desired, latest *corev1.Secret) *corev1.Secret {
+	target := latest.DeepCopy()
+
+	if target.Data == nil {
+		target.Data = make(map[string][]byte)
+	}
+	for key, value := range desired.Data {
+		if old, ok := instance.Data[key]; !ok || !bytes.Equal(old, value) {
+			target.Data[key] = value
+		}
+	}
+	for key := range instance.Data {
+		if _, ok := desired.Data[key]; !ok {
+			delete(target.Data, key)
+		}
+	}
+
+	if target.Annotations == nil {
+		target.Annotations = make(map[string]string)
+	}
+	for key, value := range desired.Annotations {
+		if old, ok := instance.Annotations[key]; !ok || old != value {
+			target.Annotations[key] = value
+		}
+	}
+	for key := range instance.Annotations {
+		if _, ok := desired.Annotations[key]; !ok {
+			delete(target.Annotations, key)
+		}
+	}
+
+	for _, f := range desired.Finalizers {
+		if !contains(instance.Finalizers, f) && !contains(target.Finalizers, f) {
+			target.Finalizers = append(target.Finalizers, f)
+		}
+	}
+	finalizers := target.Finalizers[:0]
+	for _, f := range target.Finalizers {
+		if contains(instance.Finalizers, f) && !contains(desired.Finalizers, f) {
+			continue
+		}
+		finalizers = append(finalizers, f)
+	}
+	target.Finalizers = finalizers
+
+	return target
+}
+
+// optimisticLockPatch is a JSON merge patch from an object which also contains its resourceVersion, so that it
+// fails with a conflict if the object has been modified since. It mirrors client.MergeFromWithOptimisticLock of
+// later controller-runtime versions.
+type optimisticLockPatch struct {
+	from runtime.Object
+}
+
+// mergeFromWithOptimisticLock returns a merge patch from obj that is only applied to the same version of obj
+func mergeFromWithOptimisticLock(obj runtime.Object) client.Patch {
+	return optimisticLockPatch{from: obj}
+}
+
+func (p optimisticLockPatch) Type() types.PatchType {
+	return types.MergePatchType
+}
+
+func (p optimisticLockPatch) Data(obj runtime.Object) ([]byte, error) {
+	data, err := client.MergeFrom(p.from).Data(obj)
+	if err != nil {
+		return nil, err
+	}
+	from, err := meta.Accessor(p.from)
+	if err != nil {
+		return nil, err
+	}
+
+	patch := make(map[string]interface{})
+	if err := json.Unmarshal(data, &patch); err != nil {
+		return nil, err
+	}
+	metadata, ok := patch["metadata"].(map[string]interface{})
+	if !ok {
+		metadata = make(map[string]interface{})
+		patch["metadata"] = metadata
+	}
+	metadata["resourceVersion"] = from.GetResourceVersion()
+	return json.Marshal(patch)
+}
+
+// ownsData returns true if the value stored under key is written by the controller
+func ownsData(instance *corev1.Secret, key string) bool {
+	if contains(generatedFields(instance), key) {
+		return true
+	}
+	if templates, err := secretTemplates(instance.Annotations); err == nil {
+		if _, ok := templates[key]; ok {
+			return true
+		}
+	}
+	if sources, err := copiedFields(instance.Annotations); err == nil {
+		if _, ok := sources[key]; ok {
+			return true
+		}
+	}
+	for _, suffix := range []string{PreviousFieldSuffix, StagedFieldSuffix, EscrowFieldSuffix} {
+		if strings.HasSuffix(key, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// ownedFields returns the apply configuration of all fields of the secret owned by the controller
+func ownedFields(desired *corev1.Secret) *corev1.Secret {
+	applied := &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "v1",
+			Kind:       "Secret",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        desired.Name,
+			Namespace:   desired.Namespace,
+			Annotations: make(map[string]string),
+		},
+		Data: make(map[string][]byte),
+	}
+
+	for key, value := range desired.Annotations {
+		if contains(controllerAnnotations, key) {
+			applied.Annotations[key] = value
+		}
+	}
+	for key, value := range desired.Data {
+		if ownsData(desired, key) {
+			applied.Data[key] = value
+		}
+	}
+	for _, f := range []string{FinalizerExternalSync, FinalizerReplication} {
+		if contains(desired.Finalizers, f) {
+			applied.Finalizers = append(applied.Finalizers, f)
+		}
+	}
+	return applied
+}
@@ -0,0 +1,146 @@
+package secret
+
+import (
+	"context"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"testing"
+)
+
+func TestApplyChanges(t *testing.T) {
+	instance := newStringTestSecret("password", map[string]string{
+		AnnotationSecretRegenerate: "yes",
+	}, "old")
+	desired := instance.DeepCopy()
+	desired.Data["password"] = []byte("new")
+	delete(desired.Annotations, AnnotationSecretRegenerate)
+	desired.Annotations[AnnotationSecretSecure] = "yes"
+	desired.Finalizers = []string{FinalizerReplication}
+
+	latest := instance.DeepCopy()
+	latest.Data["static"] = []byte("helm")
+	latest.Annotations["meta.helm.sh/release-name"] = "app"
+	latest.Finalizers = []string{"example.com/cleanup"}
+
+	target := applyChanges(instance, desired, latest)
+	require.Equal(t, "new", string(target.Data["password"]))
+	require.Equal(t, "helm", string(target.Data["static"]))
+	require.Equal(t, "yes", target.Annotations[AnnotationSecretSecure])
+	require.Equal(t, "app", target.Annotations["meta.helm.sh/release-name"])
+	require.NotContains(t, target.Annotations, AnnotationSecretRegenerate)
+	require.Equal(t, []string{"example.com/cleanup", FinalizerReplication}, target.Finalizers)
+}
+
+func TestMergePatchCarriesResourceVersion(t *testing.T) {
+	instance := newStringTestSecret("password", nil, "old")
+	instance.ResourceVersion = "42"
+	desired := instance.DeepCopy()
+	desired.Data["password"] = []byte("new")
+
+	data, err := mergeFromWithOptimisticLock(instance).Data(desired)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"metadata":{"resourceVersion":"42"},"data":{"password":"bmV3"}}`, string(data))
+}
+
+func TestUpdateKeepsConcurrentChanges(t *testing.T) {
+	in := newStringTestSecret("password", nil, "")
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), in))
+
+	stale := &corev1.Secret{}
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{
+		Name:      in.Name,
+		Namespace: in.Namespace}, stale))
+
+	// concurrent change by another tool
+	other := stale.DeepCopy()
+	other.Data["static"] = []byte("helm")
+	require.NoError(t, mgr.GetClient().Update(context.TODO(), other))
+
+	desired := stale.DeepCopy()
+	desired.Data["password"] = []byte("generated")
+	require.NoError(t, newReconciler(mgr).updateSecret(stale, desired))
+
+	out := &corev1.Secret{}
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{
+		Name:      in.Name,
+		Namespace: in.Namespace}, out))
+	require.Equal(t, "generated", string(out.Data["password"]))
+	require.Equal(t, "helm", string(out.Data["static"]))
+}
+
+func TestServerSideApply(t *testing.T) {
+	viper.Set("server-side-apply", true)
+	defer viper.Set("server-side-apply", false)
+
+	in := newStringTestSecret("password", map[string]string{
+		AnnotationSecretRegenerate: "yes",
+	}, "password")
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), in))
+
+	doReconcile(t, in, false)
+
+	out := &corev1.Secret{}
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{
+		Name:      in.Name,
+		Namespace: in.Namespace}, out))
+	require.NotEqual(t, "password", string(out.Data["password"]))
+	require.NotContains(t, out.Annotations, AnnotationSecretRegenerate)
+	require.Equal(t, "yes", out.Annotations[AnnotationSecretSecure])
+
+	managers := make([]string, 0, len(out.ManagedFields))
+	for _, f := range out.ManagedFields {
+		managers = append(managers, f.Manager)
+	}
+	require.Contains(t, managers, FieldManager)
+}
+
+func TestOwnedFields(t *testing.T) {
+	in := newStringTestSecret("password", map[string]string{
+		AnnotationSecretSecure:                 "yes",
+		AnnotationSecretTemplatePrefix + "url": "postgres://{{ .password }}@db",
+		AnnotationSecretPreviousFields:         "password",
+		AnnotationSecretEscrowChecksum:         "checksum",
+		"meta.helm.sh/release-name":            "app",
+	}, "password")
+	in.Data["url"] = []byte("postgres://password@db")
+	in.Data["password-previous"] = []byte("old")
+	in.Data["password.enc"] = []byte("ciphertext")
+	in.Data["static"] = []byte("static")
+
+	applied := ownedFields(in)
+	require.Equal(t, map[string]string{
+		AnnotationSecretSecure:         "yes",
+		AnnotationSecretPreviousFields: "password",
+		AnnotationSecretEscrowChecksum: "checksum",
+	}, applied.Annotations)
+	require.Contains(t, applied.Data, "password")
+	require.Contains(t, applied.Data, "url")
+	require.Contains(t, applied.Data, "password-previous")
+	require.Contains(t, applied.Data, "password.enc")
+	require.NotContains(t, applied.Data, "static")
+}
+
+func TestServerSideApplyWritesPluginValues(t *testing.T) {
+	viper.Set("server-side-apply", true)
+	defer viper.Set("server-side-apply", false)
+
+	in := newStringTestSecret("otp", map[string]string{
+		AnnotationSecretType: "totp",
+	}, "")
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), in))
+
+	desired := in.DeepCopy()
+	desired.Data["otp"] = []byte("secret")
+	desired.Data["otp.uri"] = []byte("otpauth://totp/app")
+	require.ElementsMatch(t, []string{"otp", "otp.uri"}, generatedFields(desired))
+	require.NoError(t, newReconciler(mgr).updateSecret(in, desired))
+
+	out := &corev1.Secret{}
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{
+		Name:      in.Name,
+		Namespace: in.Namespace}, out))
+	require.Equal(t, "secret", string(out.Data["otp"]))
+	require.Equal(t, "otpauth://totp/app", string(out.Data["otp.uri"]))
+}
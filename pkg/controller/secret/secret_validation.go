@@ -0,0 +1,255 @@
+package secret
+
+import (
+	"context"
+	"fmt"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/escrow"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"net/http"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	"strconv"
+	"strings"
+)
+
+// ValidatingWebhookPath is the path the validating webhook for secrets is served at
+const ValidatingWebhookPath = "/validate-v1-secret"
+
+// stringAnnotations only apply to string values and can't be used with other types
+var stringAnnotations = []string{
+	AnnotationSecretPasswordPolicy,
+	AnnotationSecretDeriveFrom,
+	AnnotationSecretDeterministic,
+	AnnotationSecretPwnedCheck,
+	AnnotationSecretMustMatch,
+	AnnotationSecretDrawAttempts,
+}
+
+// SecretValidator rejects secrets with malformed generator annotations, instead of
+// leaving the error to be found in the controller logs
+type SecretValidator struct {
+	decoder *admission.Decoder
+}
+
+// blank assignment to verify that SecretValidator implements admission.Handler
+var _ admission.Handler = &SecretValidator{}
+
+// Handle denies the request if the generator annotations of the secret are invalid
+func (v *SecretValidator) Handle(_ context.Context, req admission.Request) admission.Response {
+	instance := &corev1.Secret{}
+	if err := v.decoder.Decode(req, instance); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	if err := validateAnnotations(instance.Annotations); err != nil {
+		return admission.Denied(err.Error())
+	}
+
+	return admission.Allowed("")
+}
+
+// InjectDecoder injects the decoder into the SecretValidator
+func (v *SecretValidator) InjectDecoder(d *admission.Decoder) error {
+	v.decoder = d
+	return nil
+}
+
+// validateAnnotations checks the generator annotations of a secret. Secrets without
+// spec, autogenerate, type or shamir-targets annotation are not managed and always valid,
+// unless they request algorithms that are not approved in FIPS mode.
+func validateAnnotations(annotations map[string]string) error {
+	if err := validateFIPS(annotations); err != nil {
+		return err
+	}
+
+	if _, ok := annotations[AnnotationSecretShamirTargets]; ok {
+		if IsManaged(annotations) {
+			return fmt.Errorf("%s cannot be combined with generated fields", AnnotationSecretShamirTargets)
+		}
+		_, _, err := shamirConfig(annotations)
+		return err
+	}
+
+	if _, ok := annotations[AnnotationSecretSpec]; ok {
+		for _, a := range []string{AnnotationSecretAutoGenerate, AnnotationSecretType, AnnotationSecretLength} {
+			if _, ok := annotations[a]; ok {
+				return fmt.Errorf("%s cannot be combined with %s", a, AnnotationSecretSpec)
+			}
+		}
+		for _, a := range []string{AnnotationSecretDeriveFrom, AnnotationSecretDeterministic} {
+			if _, ok := annotations[a]; !ok {
+				continue
+			}
+			spec, err := parseSpec(annotations[AnnotationSecretSpec])
+			if err != nil {
+				return err
+			}
+			for _, f := range spec.Fields {
+				if f.Type != SecretTypeString {
+					return fmt.Errorf("%s cannot be used with fields of type %s", a, f.Type)
+				}
+			}
+		}
+		return validateCommonAnnotations(annotations)
+	}
+
+	fields, autogenerate := annotations[AnnotationSecretAutoGenerate]
+	sType := SecretType(annotations[AnnotationSecretType])
+	if !autogenerate && sType == "" {
+		// secrets only copying values from other secrets
+		_, err := copiedFields(annotations)
+		return err
+	}
+
+	if sType == "" {
+		sType = SecretTypeString
+	}
+	if err := sType.Validate(); err != nil {
+		return err
+	}
+
+	if val, ok := annotations[AnnotationSecretLength]; ok {
+		length, err := strconv.Atoi(val)
+		if err != nil || length <= 0 {
+			return fmt.Errorf("%s must be a positive integer, got %q", AnnotationSecretLength, val)
+		}
+	}
+
+	switch sType {
+	case SecretTypeString:
+		if err := ensureUniqueness(strings.Split(fields, ",")); err != nil {
+			return fmt.Errorf("invalid %s annotation: %w", AnnotationSecretAutoGenerate, err)
+		}
+	case SecretTypeSSHKeypair:
+		for _, a := range append([]string{AnnotationSecretAutoGenerate}, stringAnnotations...) {
+			if _, ok := annotations[a]; ok {
+				return fmt.Errorf("%s cannot be used with type %s", a, SecretTypeSSHKeypair)
+			}
+		}
+	default:
+		// generator plugins
+		for _, a := range stringAnnotations {
+			if _, ok := annotations[a]; ok {
+				return fmt.Errorf("%s cannot be used with type %s", a, sType)
+			}
+		}
+		if err := ensureUniqueness(strings.Split(fields, ",")); err != nil {
+			return fmt.Errorf("invalid %s annotation: %w", AnnotationSecretAutoGenerate, err)
+		}
+	}
+
+	return validateCommonAnnotations(annotations)
+}
+
+// validateCommonAnnotations checks the annotations shared by v1 and spec annotated secrets
+func validateCommonAnnotations(annotations map[string]string) error {
+	if _, err := secretTemplates(annotations); err != nil {
+		return err
+	}
+
+	if val, ok := annotations[AnnotationSecretRotateAfter]; ok {
+		if _, err := rotationInterval(val); err != nil {
+			return err
+		}
+	}
+
+	if val, ok := annotations[AnnotationSecretRotateSchedule]; ok {
+		if _, err := rotationSchedule(val); err != nil {
+			return err
+		}
+	}
+
+	if val, ok := annotations[AnnotationSecretExpiresAfter]; ok {
+		if _, err := expiryLifetime(val); err != nil {
+			return err
+		}
+	}
+
+	if val, ok := annotations[AnnotationSecretExpiryPolicy]; ok {
+		if err := ExpiryPolicy(val).Validate(); err != nil {
+			return err
+		}
+	}
+
+	if val, ok := annotations[AnnotationSecretRotationCooldown]; ok {
+		if _, err := cooldownDuration(val); err != nil {
+			return err
+		}
+	}
+
+	if val, ok := annotations[AnnotationSecretStagedRotation]; ok {
+		if _, err := promotionDelay(val); err != nil {
+			return err
+		}
+	}
+
+	if val, ok := annotations[AnnotationSecretKeepPrevious]; ok {
+		if _, err := gracePeriod(val); err != nil {
+			return err
+		}
+	}
+
+	if val, ok := annotations[AnnotationSecretDeriveFrom]; ok {
+		if _, _, err := parseDeriveFrom(val); err != nil {
+			return err
+		}
+		if _, ok := annotations[AnnotationSecretDeterministic]; ok {
+			return fmt.Errorf("%s cannot be combined with %s", AnnotationSecretDeterministic, AnnotationSecretDeriveFrom)
+		}
+	}
+
+	if derives(annotations) {
+		for _, a := range []string{AnnotationSecretRotateAfter, AnnotationSecretRotateSchedule} {
+			if _, ok := annotations[a]; ok {
+				return fmt.Errorf("%s cannot be used with derived values, which don't change when regenerated", a)
+			}
+		}
+		if ExpiryPolicy(annotations[AnnotationSecretExpiryPolicy]) == ExpiryPolicyRotate {
+			return fmt.Errorf("expiry policy %s cannot be used with derived values, which don't change when regenerated", ExpiryPolicyRotate)
+		}
+	}
+
+	for _, a := range []string{AnnotationSecretDeterministic, AnnotationSecretPwnedCheck, AnnotationSecretImmutable} {
+		if val, ok := annotations[a]; ok && val != "true" {
+			return fmt.Errorf("%s must be \"true\", got %q", a, val)
+		}
+	}
+
+	if val, ok := annotations[AnnotationSecretMustMatch]; ok {
+		if _, err := mustMatchPatterns(val); err != nil {
+			return err
+		}
+	}
+
+	if _, err := drawAttempts(annotations); err != nil {
+		return err
+	}
+
+	if _, err := valueHistoryLimit(annotations); err != nil {
+		return err
+	}
+
+	if _, err := copiedFields(annotations); err != nil {
+		return err
+	}
+
+	if val, ok := annotations[AnnotationSecretReplicateTo]; ok {
+		if _, err := parseReplicateTo(val); err != nil {
+			return err
+		}
+	}
+
+	if val, ok := annotations[AnnotationSecretReplicateToSelector]; ok {
+		if _, err := labels.Parse(val); err != nil {
+			return fmt.Errorf("invalid %s annotation: %w", AnnotationSecretReplicateToSelector, err)
+		}
+	}
+
+	if val, ok := annotations[AnnotationSecretEscrowRecipients]; ok {
+		if _, err := escrow.ParseRecipients(val); err != nil {
+			return fmt.Errorf("invalid %s annotation: %w", AnnotationSecretEscrowRecipients, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,166 @@
+package secret
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	"testing"
+)
+
+func TestValidateAnnotations(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		valid       bool
+	}{
+		{"unmanaged", map[string]string{AnnotationSecretLength: "abc"}, true},
+		{"string", map[string]string{AnnotationSecretAutoGenerate: "password", AnnotationSecretLength: "20"}, true},
+		{"ssh-keypair", map[string]string{AnnotationSecretType: string(SecretTypeSSHKeypair)}, true},
+		{"unknown type", map[string]string{AnnotationSecretType: "foo"}, false},
+		{"non-numeric length", map[string]string{AnnotationSecretAutoGenerate: "password", AnnotationSecretLength: "abc"}, false},
+		{"negative length", map[string]string{AnnotationSecretAutoGenerate: "password", AnnotationSecretLength: "-1"}, false},
+		{"duplicate fields", map[string]string{AnnotationSecretAutoGenerate: "password,password"}, false},
+		{"ssh-keypair with fields", map[string]string{
+			AnnotationSecretType:         string(SecretTypeSSHKeypair),
+			AnnotationSecretAutoGenerate: "password",
+		}, false},
+		{"ssh-keypair with policy", map[string]string{
+			AnnotationSecretType:           string(SecretTypeSSHKeypair),
+			AnnotationSecretPasswordPolicy: "policy",
+		}, false},
+		{"shamir", map[string]string{
+			AnnotationSecretShamirTargets:   "team-a/root-share,team-b/root-share,team-c/root-share",
+			AnnotationSecretShamirThreshold: "2",
+		}, true},
+		{"shamir threshold above targets", map[string]string{
+			AnnotationSecretShamirTargets:   "team-a/root-share,team-b/root-share",
+			AnnotationSecretShamirThreshold: "3",
+		}, false},
+		{"shamir with fields", map[string]string{
+			AnnotationSecretShamirTargets:   "team-a/root-share,team-b/root-share",
+			AnnotationSecretShamirThreshold: "2",
+			AnnotationSecretAutoGenerate:    "password",
+		}, false},
+		{"invalid escrow recipients", map[string]string{
+			AnnotationSecretAutoGenerate:     "password",
+			AnnotationSecretEscrowRecipients: "ssh-ed25519 AAAA",
+		}, false},
+		{"derive from master key", map[string]string{
+			AnnotationSecretAutoGenerate: "password",
+			AnnotationSecretDeriveFrom:   "master/key",
+		}, true},
+		{"rotated derived values", map[string]string{
+			AnnotationSecretAutoGenerate: "password",
+			AnnotationSecretDeriveFrom:   "master/key",
+			AnnotationSecretRotateAfter:  "720h",
+		}, false},
+		{"rotated deterministic values", map[string]string{
+			AnnotationSecretAutoGenerate:   "password",
+			AnnotationSecretDeterministic:  "true",
+			AnnotationSecretRotateSchedule: "0 3 * * 0",
+		}, false},
+		{"derived values rotated on expiry", map[string]string{
+			AnnotationSecretAutoGenerate: "password",
+			AnnotationSecretDeriveFrom:   "master/key",
+			AnnotationSecretExpiresAfter: "720h",
+			AnnotationSecretExpiryPolicy: string(ExpiryPolicyRotate),
+		}, false},
+		{"derive from without key", map[string]string{
+			AnnotationSecretAutoGenerate: "password",
+			AnnotationSecretDeriveFrom:   "master",
+		}, false},
+		{"pwned check", map[string]string{
+			AnnotationSecretAutoGenerate: "password",
+			AnnotationSecretPwnedCheck:   "true",
+		}, true},
+		{"ssh-keypair with pwned check", map[string]string{
+			AnnotationSecretType:       string(SecretTypeSSHKeypair),
+			AnnotationSecretPwnedCheck: "true",
+		}, false},
+		{"invalid must-match pattern", map[string]string{
+			AnnotationSecretAutoGenerate: "password",
+			AnnotationSecretMustMatch:    "[0-9",
+		}, false},
+		{"too many draw attempts", map[string]string{
+			AnnotationSecretAutoGenerate: "password",
+			AnnotationSecretDrawAttempts: "5000",
+		}, false},
+		{"copied fields", map[string]string{
+			AnnotationSecretCopyFrom: "db/password, admin-password=admin/password",
+		}, true},
+		{"invalid copy-from entry", map[string]string{
+			AnnotationSecretCopyFrom: "password",
+		}, false},
+		{"field both copied and generated", map[string]string{
+			AnnotationSecretAutoGenerate: "password",
+			AnnotationSecretCopyFrom:     "db/password",
+		}, false},
+		{"value history", map[string]string{
+			AnnotationSecretAutoGenerate: "password",
+			AnnotationSecretValueHistory: "3",
+		}, true},
+		{"too long value history", map[string]string{
+			AnnotationSecretAutoGenerate: "password",
+			AnnotationSecretValueHistory: "100",
+		}, false},
+		{"replicated to namespaces", map[string]string{
+			AnnotationSecretAutoGenerate:        "password",
+			AnnotationSecretReplicateTo:         "team-a, team-b",
+			AnnotationSecretReplicateToSelector: "registry-access=true",
+		}, true},
+		{"replicated to invalid namespace", map[string]string{
+			AnnotationSecretAutoGenerate: "password",
+			AnnotationSecretReplicateTo:  "Team_A",
+		}, false},
+		{"invalid replication selector", map[string]string{
+			AnnotationSecretAutoGenerate:        "password",
+			AnnotationSecretReplicateToSelector: "registry-access in (",
+		}, false},
+		{"immutable", map[string]string{
+			AnnotationSecretAutoGenerate: "password",
+			AnnotationSecretImmutable:    "true",
+		}, true},
+		{"invalid immutable", map[string]string{
+			AnnotationSecretAutoGenerate: "password",
+			AnnotationSecretImmutable:    "yes",
+		}, false},
+		{"ssh-keypair derived from master key", map[string]string{
+			AnnotationSecretType:       string(SecretTypeSSHKeypair),
+			AnnotationSecretDeriveFrom: "master/key",
+		}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateAnnotations(tt.annotations)
+			if tt.valid {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestValidatingWebhookDeniesInvalidSecret(t *testing.T) {
+	decoder, err := admission.NewDecoder(mgr.GetScheme())
+	require.NoError(t, err)
+
+	v := &SecretValidator{}
+	require.NoError(t, v.InjectDecoder(decoder))
+
+	in := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      getSecretName(),
+			Namespace: "default",
+			Annotations: map[string]string{
+				AnnotationSecretType: "foo",
+			},
+		},
+	}
+
+	res := v.Handle(context.TODO(), newCreateRequest(t, in))
+	require.False(t, res.Allowed)
+}
@@ -0,0 +1,153 @@
+package secret
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxValueHistory bounds the value-history annotation
+const maxValueHistory = 10
+
+// historyName returns the name of the history secret keeping the replaced values of the named secret
+func historyName(name string) string {
+	return name + "-history"
+}
+
+// historyKey returns the key of the n-th most recently replaced value of the field in the history secret
+func historyKey(key string, n int) string {
+	return key + "." + strconv.Itoa(n)
+}
+
+// valueHistoryLimit returns the number of replaced values kept per field, or 0 if none are kept
+func valueHistoryLimit(annotations map[string]string) (int, error) {
+	val, ok := annotations[AnnotationSecretValueHistory]
+	if !ok {
+		return 0, nil
+	}
+
+	limit, err := strconv.Atoi(val)
+	if err != nil || limit < 1 || limit > maxValueHistory {
+		return 0, fmt.Errorf("%s must be between 1 and %d, got %q", AnnotationSecretValueHistory, maxValueHistory, val)
+	}
+	return limit, nil
+}
+
+// valueHistory returns the history secret of the instance, or nil if it doesn't exist yet. Secrets which
+// merely happen to have its name are never used.
+func (r *ReconcileSecret) valueHistory(instance *corev1.Secret) (*corev1.Secret, error) {
+	history := &corev1.Secret{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: instance.Namespace, Name: historyName(instance.Name)}, history)
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if history.Annotations[AnnotationSecretHistoryOf] != instance.Name {
+		return nil, fmt.Errorf("secret %s already exists and does not hold the value history of this secret", history.Name)
+	}
+	return history, nil
+}
+
+// replacedAt returns the times the values in the history secret were replaced, by their key
+func replacedAt(history *corev1.Secret) map[string]string {
+	times := make(map[string]string)
+	if val, ok := history.Annotations[AnnotationSecretReplacedAt]; ok {
+		// start over instead of failing every rotation because of a broken annotation
+		_ = json.Unmarshal([]byte(val), &times)
+	}
+	return times
+}
+
+// backupReplacedValues stores the values of generated fields of instance which are replaced in desired in
+// the history secret before the secret is updated, keeping the number of values given in the value-history
+// annotation per field. The history secret is owned by the instance and thus deleted along with it.
+func (r *ReconcileSecret) backupReplacedValues(reqLogger logr.Logger, instance, desired *corev1.Secret) error {
+	limit, err := valueHistoryLimit(desired.Annotations)
+	if err != nil || limit == 0 {
+		return err
+	}
+
+	generated := generatedFields(instance)
+	var replaced []string
+	for _, key := range replacedKeys(instance.Data, desired.Data) {
+		if contains(generated, key) {
+			replaced = append(replaced, key)
+		}
+	}
+	if len(replaced) == 0 {
+		return nil
+	}
+
+	history, err := r.valueHistory(instance)
+	if err != nil {
+		return err
+	}
+	create := history == nil
+	if create {
+		history = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   instance.Namespace,
+				Name:        historyName(instance.Name),
+				Labels:      SelectorLabels(),
+				Annotations: map[string]string{AnnotationSecretHistoryOf: instance.Name},
+			},
+			Type: corev1.SecretTypeOpaque,
+		}
+		if err := controllerutil.SetControllerReference(instance, history, r.scheme); err != nil {
+			return err
+		}
+	}
+	if history.Data == nil {
+		history.Data = make(map[string][]byte)
+	}
+
+	times := replacedAt(history)
+	now := time.Now().Format(time.RFC3339)
+	for _, key := range replaced {
+		for n := limit; n > 1; n-- {
+			older, newer := historyKey(key, n), historyKey(key, n-1)
+			if value, ok := history.Data[newer]; ok {
+				history.Data[older] = value
+				times[older] = times[newer]
+			} else {
+				delete(history.Data, older)
+				delete(times, older)
+			}
+		}
+		history.Data[historyKey(key, 1)] = instance.Data[key]
+		times[historyKey(key, 1)] = now
+	}
+
+	// drop values beyond the limit, e.g. after it was lowered
+	for key := range history.Data {
+		i := strings.LastIndex(key, ".")
+		if n, err := strconv.Atoi(key[i+1:]); i < 0 || err != nil || n > limit {
+			delete(history.Data, key)
+			delete(times, key)
+		}
+	}
+
+	encoded, err := json.Marshal(times)
+	if err != nil {
+		return err
+	}
+	history.Annotations[AnnotationSecretReplacedAt] = string(encoded)
+
+	reqLogger.Info("storing replaced values in history secret", "fields", replaced)
+	if create {
+		return r.client.Create(context.TODO(), history)
+	}
+	return r.client.Update(context.TODO(), history)
+}
@@ -0,0 +1,40 @@
+package secret
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"testing"
+)
+
+func TestBackupReplacedValues(t *testing.T) {
+	in := newStringTestSecret("password", map[string]string{
+		AnnotationSecretValueHistory: "2",
+	}, "")
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), in))
+	doReconcile(t, in, false)
+
+	// the initial generation replaces no value
+	err := mgr.GetClient().Get(context.TODO(), types.NamespacedName{Name: historyName(in.Name), Namespace: in.Namespace}, &corev1.Secret{})
+	require.Error(t, err)
+
+	var values [][]byte
+	out := &corev1.Secret{}
+	for i := 0; i < 3; i++ {
+		require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{Name: in.Name, Namespace: in.Namespace}, out))
+		values = append(values, out.Data["password"])
+
+		out.Annotations[AnnotationSecretRegenerate] = "yes"
+		require.NoError(t, mgr.GetClient().Update(context.TODO(), out))
+		doReconcile(t, out, false)
+	}
+
+	history := &corev1.Secret{}
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{Name: historyName(in.Name), Namespace: in.Namespace}, history))
+	require.Equal(t, in.Name, history.Annotations[AnnotationSecretHistoryOf])
+	require.Len(t, history.Data, 2)
+	require.Equal(t, values[2], history.Data["password.1"])
+	require.Equal(t, values[1], history.Data["password.2"])
+	require.Contains(t, replacedAt(history), "password.2")
+}
@@ -0,0 +1,91 @@
+package secret
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	corev1 "k8s.io/api/core/v1"
+	"net/http"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	"time"
+)
+
+// MutatingWebhookPath is the path the mutating webhook for secrets is served at
+const MutatingWebhookPath = "/mutate-v1-secret"
+
+// SecretMutator fills in generated values when an annotated secret is created,
+// so there is no window in which the secret exists without its values
+type SecretMutator struct {
+	reconciler *ReconcileSecret
+	decoder    *admission.Decoder
+}
+
+// blank assignment to verify that SecretMutator implements admission.Handler
+var _ admission.Handler = &SecretMutator{}
+
+// Handle generates the values of annotated secrets and returns them as patch
+func (m *SecretMutator) Handle(_ context.Context, req admission.Request) admission.Response {
+	instance := &corev1.Secret{}
+	if err := m.decoder.Decode(req, instance); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	reqLogger := log.WithValues("Request.Namespace", req.Namespace, "Request.Name", req.Name, "webhook", "mutating")
+
+	if instance.Name == "" && IsManaged(instance.Annotations) && derivesFromName(instance.Annotations) {
+		// the name of secrets using generateName is only set after admission
+		return admission.Denied(fmt.Sprintf("values derived from the name of the secret require it to be named, "+
+			"set its name instead of generateName or the %s annotation", AnnotationSecretDeriveInfo))
+	}
+
+	if paused, err := m.reconciler.paused(); err != nil || paused {
+		return admission.Allowed("generation deferred to controller")
+	}
+
+	desired := instance.DeepCopy()
+	if desired.Namespace == "" {
+		// the namespace is required to look up referenced resources
+		desired.Namespace = req.Namespace
+	}
+
+	skip, err := m.reconciler.skipReason(desired)
+	if err != nil {
+		reqLogger.Error(err, "could not check whether secret is skipped, deferring to controller")
+		return admission.Allowed("generation deferred to controller")
+	}
+	if skip != "" {
+		return admission.Allowed(skip)
+	}
+
+	managed, _, err := m.reconciler.generate(reqLogger, desired)
+	if err != nil {
+		// never block the creation of a secret, the controller will retry generation
+		reqLogger.Error(err, "could not generate values, deferring to controller")
+		return admission.Allowed("generation deferred to controller")
+	}
+	if !managed {
+		return admission.Allowed("secret is not managed by the secret generator")
+	}
+
+	if DryRun() {
+		reqLogger.Info("dry run, not generating values at admission")
+		return admission.Allowed("dry run")
+	}
+
+	desired.Namespace = instance.Namespace
+	desired.Annotations[AnnotationSecretAutoGeneratedAt] = time.Now().Format(time.RFC3339)
+
+	marshaled, err := json.Marshal(desired)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	reqLogger.Info("generated values at admission")
+	return admission.PatchResponseFromRaw(req.Object.Raw, marshaled)
+}
+
+// InjectDecoder injects the decoder into the SecretMutator
+func (m *SecretMutator) InjectDecoder(d *admission.Decoder) error {
+	m.decoder = d
+	return nil
+}
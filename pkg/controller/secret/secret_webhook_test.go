@@ -0,0 +1,98 @@
+package secret
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	"testing"
+)
+
+func newTestMutator(t *testing.T) *SecretMutator {
+	decoder, err := admission.NewDecoder(mgr.GetScheme())
+	require.NoError(t, err)
+
+	m := &SecretMutator{reconciler: newReconciler(mgr)}
+	require.NoError(t, m.InjectDecoder(decoder))
+	return m
+}
+
+func newCreateRequest(t *testing.T, secret *corev1.Secret) admission.Request {
+	raw, err := json.Marshal(secret)
+	require.NoError(t, err)
+
+	return admission.Request{AdmissionRequest: admissionv1beta1.AdmissionRequest{
+		Operation: admissionv1beta1.Create,
+		Namespace: secret.Namespace,
+		Name:      secret.Name,
+		Object:    runtime.RawExtension{Raw: raw},
+	}}
+}
+
+func TestMutatingWebhookGeneratesValues(t *testing.T) {
+	in := newStringTestSecret("testfield", nil, "")
+
+	res := newTestMutator(t).Handle(context.TODO(), newCreateRequest(t, in))
+	require.True(t, res.Allowed)
+
+	paths := make(map[string]bool)
+	for _, p := range res.Patches {
+		paths[p.Path] = true
+	}
+	require.True(t, paths["/data/testfield"], "generated value is missing from patch")
+}
+
+func TestMutatingWebhookIgnoresOtherSecrets(t *testing.T) {
+	in := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      getSecretName(),
+			Namespace: "default",
+		},
+		Data: map[string][]byte{
+			"testkey": []byte("test"),
+		},
+	}
+
+	res := newTestMutator(t).Handle(context.TODO(), newCreateRequest(t, in))
+	require.True(t, res.Allowed)
+	require.Empty(t, res.Patches)
+}
+
+func TestMutatingWebhookDeniesDerivedValuesWithoutName(t *testing.T) {
+	in := newStringTestSecret("testfield", map[string]string{AnnotationSecretDeterministic: "true"}, "")
+	in.GenerateName, in.Name = "app-", ""
+
+	res := newTestMutator(t).Handle(context.TODO(), newCreateRequest(t, in))
+	require.False(t, res.Allowed)
+
+	in.Annotations = map[string]string{
+		AnnotationSecretAutoGenerate: "testfield",
+		AnnotationSecretDeriveFrom:   "master/key",
+		AnnotationSecretDeriveInfo:   "app",
+	}
+	res = newTestMutator(t).Handle(context.TODO(), newCreateRequest(t, in))
+	require.True(t, res.Allowed)
+}
+
+func TestMutatingWebhookSkipsDisabledNamespaces(t *testing.T) {
+	setNamespaceAnnotation(t, "default", AnnotationNamespaceEnabled, "false")
+	defer setNamespaceAnnotation(t, "default", AnnotationNamespaceEnabled, "")
+
+	in := newStringTestSecret("testfield", nil, "")
+
+	res := newTestMutator(t).Handle(context.TODO(), newCreateRequest(t, in))
+	require.True(t, res.Allowed)
+	require.Empty(t, res.Patches)
+}
+
+func TestMutatingWebhookSkipsSecretsControlledByOtherControllers(t *testing.T) {
+	in := newControlledTestSecret(t, nil)
+
+	res := newTestMutator(t).Handle(context.TODO(), newCreateRequest(t, in))
+	require.True(t, res.Allowed)
+	require.Empty(t, res.Patches)
+}
@@ -7,12 +7,203 @@ import (
 )
 
 const (
+	// AnnotationPrefix is the prefix of all annotations read or written by the secret generator
+	AnnotationPrefix = "secret-generator.v1.mittwald.de/"
+
 	AnnotationSecretAutoGenerate    = "secret-generator.v1.mittwald.de/autogenerate"
 	AnnotationSecretAutoGeneratedAt = "secret-generator.v1.mittwald.de/autogenerate-generated-at"
+	// AnnotationSecretGeneratedFields records the keys generated for an autogenerate annotation of AutoGenerateAll
+	AnnotationSecretGeneratedFields = "secret-generator.v1.mittwald.de/generated-fields"
 	AnnotationSecretRegenerate      = "secret-generator.v1.mittwald.de/regenerate"
 	AnnotationSecretSecure          = "secret-generator.v1.mittwald.de/secure"
 	AnnotationSecretType            = "secret-generator.v1.mittwald.de/type"
 	AnnotationSecretLength          = "secret-generator.v1.mittwald.de/length"
+	AnnotationSecretPasswordPolicy  = "secret-generator.v1.mittwald.de/password-policy"
+	AnnotationSecretOwner           = "secret-generator.v1.mittwald.de/owner"
+	AnnotationSecretRotateAfter     = "secret-generator.v1.mittwald.de/rotate-after"
+	AnnotationSecretRotateSchedule  = "secret-generator.v1.mittwald.de/rotate-schedule"
+	AnnotationSecretRotatedAt       = "secret-generator.v1.mittwald.de/rotated-at"
+	// AnnotationSecretRegenerations counts the regenerations of a secret on a day, formatted as <date>/<count>
+	AnnotationSecretRegenerations = "secret-generator.v1.mittwald.de/regenerations"
+	// AnnotationSecretRotationHistory holds the JSON encoded RotationRecords of the last rotations
+	AnnotationSecretRotationHistory = "secret-generator.v1.mittwald.de/rotation-history"
+	// AnnotationSecretRotationRequest records the RotationRequest which last requested regeneration of a secret
+	AnnotationSecretRotationRequest = "secret-generator.v1.mittwald.de/rotation-request"
+	// AnnotationSecretRequestedBy is set together with the regenerate annotation by bulk triggers, e.g. RotationRequests,
+	// and removed with it once the values have been regenerated
+	AnnotationSecretRequestedBy = "secret-generator.v1.mittwald.de/requested-by"
+	// AnnotationSecretTrigger records what caused the last generation or rotation of the values of a secret
+	AnnotationSecretTrigger = "secret-generator.v1.mittwald.de/trigger"
+	// AnnotationSecretRotationFailed records the reason of a failed rotation post-rotation hooks have been
+	// notified about, until values are generated successfully
+	AnnotationSecretRotationFailed = "secret-generator.v1.mittwald.de/rotation-failed"
+	// AnnotationSecretExpiresAfter is the lifetime of the values of a secret, enforced according to its expiry policy
+	AnnotationSecretExpiresAfter = "secret-generator.v1.mittwald.de/expires-after"
+	AnnotationSecretExpiryPolicy = "secret-generator.v1.mittwald.de/expiry-policy"
+	// AnnotationSecretRotationCooldown overrides the minimum interval between regenerations of a secret
+	AnnotationSecretRotationCooldown = "secret-generator.v1.mittwald.de/rotation-cooldown"
+
+	// AnnotationSecretFingerprints records the fingerprints of the generated values of a secret to detect modifications
+	AnnotationSecretFingerprints = "secret-generator.v1.mittwald.de/fingerprints"
+
+	// AnnotationSecretPrune enables the deletion of generated fields that are no longer declared
+	AnnotationSecretPrune = "secret-generator.v1.mittwald.de/prune"
+
+	// AnnotationSecretPin exempts a secret from scheduled rotation, expiry rotation and RotationRequests
+	AnnotationSecretPin = "secret-generator.v1.mittwald.de/pin"
+
+	// AnnotationNamespaceEnabled is set to true or false on a namespace to enable or disable processing its secrets
+	AnnotationNamespaceEnabled = "secret-generator.v1.mittwald.de/enabled"
+
+	// AnnotationPaused is set to true on the controller's namespace to suspend all generation and rotation
+	AnnotationPaused = "secret-generator.v1.mittwald.de/paused"
+
+	// AnnotationSecretDebug is set to true on a secret to log its reconciles at debug level
+	AnnotationSecretDebug = "secret-generator.v1.mittwald.de/debug"
+
+	// AnnotationSecretIgnore makes the controller leave a secret untouched despite other generator annotations
+	AnnotationSecretIgnore = "secret-generator.v1.mittwald.de/ignore"
+
+	// AnnotationSecretShadow enables keeping a copy of the generated values in a shadow secret, from which
+	// values emptied by re-applying the secret are restored
+	AnnotationSecretShadow = "secret-generator.v1.mittwald.de/shadow"
+	// AnnotationSecretShadowOf marks a shadow secret with the name of the secret it belongs to
+	AnnotationSecretShadowOf = "secret-generator.v1.mittwald.de/shadow-of"
+
+	// AnnotationSecretValueHistory is the number of replaced values per field kept in the history secret
+	AnnotationSecretValueHistory = "secret-generator.v1.mittwald.de/value-history"
+	// AnnotationSecretHistoryOf marks a history secret with the name of the secret it belongs to
+	AnnotationSecretHistoryOf = "secret-generator.v1.mittwald.de/history-of"
+	// AnnotationSecretReplacedAt holds the JSON encoded times the values in a history secret were replaced
+	AnnotationSecretReplacedAt = "secret-generator.v1.mittwald.de/replaced-at"
+	// AnnotationSecretRollback restores the listed fields, or all fields if set to true, to their previous value
+	// from the history secret
+	AnnotationSecretRollback = "secret-generator.v1.mittwald.de/rollback"
+
+	// AnnotationSecretForce enables generating values of secrets controlled by other controllers
+	AnnotationSecretForce = "secret-generator.v1.mittwald.de/force"
+
+	// AnnotationSecretRestartOnRotation enables rolling restarts of the workloads using a secret after rotation
+	AnnotationSecretRestartOnRotation = "secret-generator.v1.mittwald.de/restart-on-rotation"
+	// AnnotationRestartedAt is set on the pod template of workloads to trigger a rolling restart
+	AnnotationRestartedAt = "secret-generator.v1.mittwald.de/restarted-at"
+
+	// AnnotationSecretChecksum holds the checksum of the secret's data
+	AnnotationSecretChecksum = "secret-generator.v1.mittwald.de/checksum"
+	// AnnotationSecretPropagateChecksum enables copying the checksum to the workloads using a secret
+	AnnotationSecretPropagateChecksum = "secret-generator.v1.mittwald.de/propagate-checksum"
+	// AnnotationChecksumPrefix is followed by the name of a secret on the pod templates its checksum is copied to
+	AnnotationChecksumPrefix = "checksum.secret-generator.v1.mittwald.de/"
+	// AnnotationReloaderSecretReload is the annotation of workloads listing the secrets stakater/Reloader restarts
+	// them on changes of
+	AnnotationReloaderSecretReload = "secret.reloader.stakater.com/reload"
+
+	// AnnotationSecretKeepPrevious is the grace period regenerated values are kept in <key>-previous fields for
+	AnnotationSecretKeepPrevious      = "secret-generator.v1.mittwald.de/keep-previous"
+	AnnotationSecretPreviousFields    = "secret-generator.v1.mittwald.de/previous-fields"
+	AnnotationSecretPreviousExpiresAt = "secret-generator.v1.mittwald.de/previous-expires-at"
+
+	// AnnotationSecretStagedRotation is either manual or the delay after which staged values are activated
+	AnnotationSecretStagedRotation = "secret-generator.v1.mittwald.de/staged-rotation"
+	AnnotationSecretStagedFields   = "secret-generator.v1.mittwald.de/staged-fields"
+	AnnotationSecretStagedAt       = "secret-generator.v1.mittwald.de/staged-at"
+	AnnotationSecretActivate       = "secret-generator.v1.mittwald.de/activate"
+
+	// AnnotationSecretTemplatePrefix is followed by the key a Go template is rendered into
+	AnnotationSecretTemplatePrefix = "secret-generator.v1.mittwald.de/template."
+	// AnnotationSecretTemplateReferences lists the secrets referenced by templates, so changes to them trigger rendering
+	AnnotationSecretTemplateReferences = "secret-generator.v1.mittwald.de/template-references"
+
+	// AnnotationSecretCopyFrom lists the fields copied from other secrets in the same namespace, as comma
+	// separated [<field>=]<secret>/<key> entries
+	AnnotationSecretCopyFrom = "secret-generator.v1.mittwald.de/copy-from"
+
+	// AnnotationSecretSpec holds a JSON encoded Spec describing all fields to generate
+	AnnotationSecretSpec = "secret-generator.v2.mittwald.de/spec"
+
+	AnnotationSecretSyncChecksum       = "secret-generator.v1.mittwald.de/sync-checksum"
+	AnnotationSecretSyncDeletionPolicy = "secret-generator.v1.mittwald.de/sync-deletion-policy"
+
+	// AnnotationSecretSealedOutput is copy or replace, writing the values into a SealedSecret manifest
+	AnnotationSecretSealedOutput = "secret-generator.v1.mittwald.de/sealed-output"
+	// AnnotationSecretSealedOf marks a ConfigMap or SealedSecret with the name of the secret it was sealed from
+	AnnotationSecretSealedOf = "secret-generator.v1.mittwald.de/sealed-of"
+	// AnnotationSecretSealedChecksum holds the checksum of the data that was sealed
+	AnnotationSecretSealedChecksum = "secret-generator.v1.mittwald.de/sealed-checksum"
+
+	// AnnotationSecretEscrowRecipients lists the age or PGP public keys generated values are encrypted for
+	AnnotationSecretEscrowRecipients = "secret-generator.v1.mittwald.de/escrow-recipients"
+	// AnnotationSecretEscrowChecksum holds the checksum of the recipients and values that were encrypted
+	AnnotationSecretEscrowChecksum = "secret-generator.v1.mittwald.de/escrow-checksum"
+
+	// AnnotationSecretShamirTargets lists the namespace/name of the secrets the shares of a split master key
+	// are stored in, AnnotationSecretShamirThreshold is the number of shares required to reconstruct it
+	AnnotationSecretShamirTargets   = "secret-generator.v1.mittwald.de/shamir-targets"
+	AnnotationSecretShamirThreshold = "secret-generator.v1.mittwald.de/shamir-threshold"
+	// AnnotationSecretShamirSplitAt records when the master key was last split
+	AnnotationSecretShamirSplitAt = "secret-generator.v1.mittwald.de/shamir-split-at"
+
+	// AnnotationSecretBackupChecksum holds the checksum of the data of the last SOPS encrypted backup
+	AnnotationSecretBackupChecksum = "secret-generator.v1.mittwald.de/backup-checksum"
+	// AnnotationSecretBackupOf marks a ConfigMap with the name of the secret it holds the backup of
+	AnnotationSecretBackupOf = "secret-generator.v1.mittwald.de/backup-of"
+
+	// AnnotationSecretDeriveFrom references the <secret>/<key> of the master key generated string values
+	// are derived from using HKDF, AnnotationSecretDeriveSalt and AnnotationSecretDeriveInfo are the salt
+	// and the info prefix of the derivation
+	AnnotationSecretDeriveFrom = "secret-generator.v1.mittwald.de/derive-from"
+	AnnotationSecretDeriveSalt = "secret-generator.v1.mittwald.de/derive-salt"
+	AnnotationSecretDeriveInfo = "secret-generator.v1.mittwald.de/derive-info"
+	// AnnotationSecretDeterministic opts in to deriving generated string values from the cluster-wide seed,
+	// the namespace, name and key of the secret, so that they are reproduced when rebuilding a cluster
+	AnnotationSecretDeterministic = "secret-generator.v1.mittwald.de/deterministic"
+
+	// AnnotationSecretPwnedCheck enables drawing generated string values again if they appear in Have I Been Pwned
+	AnnotationSecretPwnedCheck = "secret-generator.v1.mittwald.de/pwned-check"
+	// AnnotationSecretMustMatch lists regular expressions, one per line, generated string values have to match
+	AnnotationSecretMustMatch = "secret-generator.v1.mittwald.de/must-match"
+	// AnnotationSecretDrawAttempts is the number of values drawn for a field until one passes its checks
+	AnnotationSecretDrawAttempts = "secret-generator.v1.mittwald.de/draw-attempts"
+
+	// AnnotationSecretReplicateTo lists the namespaces a secret is copied to, AnnotationSecretReplicateToSelector
+	// selects them by label. The copies are kept in sync with the secret and deleted along with it.
+	AnnotationSecretReplicateTo         = "secret-generator.v1.mittwald.de/replicate-to"
+	AnnotationSecretReplicateToSelector = "secret-generator.v1.mittwald.de/replicate-to-selector"
+
+	// AnnotationSecretImmutable publishes the values of a secret in immutable versions of it, whose current one
+	// is named in AnnotationSecretCurrentVersion
+	AnnotationSecretImmutable      = "secret-generator.v1.mittwald.de/immutable"
+	AnnotationSecretCurrentVersion = "secret-generator.v1.mittwald.de/current-version"
+)
+
+const (
+	// PreviousFieldSuffix is appended to the key of a field to store its previous value during rotation
+	PreviousFieldSuffix = "-previous"
+	// StagedFieldSuffix is appended to the key of a field to store its next value during staged rotation
+	StagedFieldSuffix = "-staged"
+	// EscrowFieldSuffix is appended to the key of a field to store its value encrypted for the escrow recipients
+	EscrowFieldSuffix = ".enc"
+)
+
+// DeterministicSeedKey is the key of the seed in the secret configured by the deterministic-seed-secret flag
+const DeterministicSeedKey = "seed"
+
+// StagedRotationManual stages values until the activate annotation is set
+const StagedRotationManual = "manual"
+
+// PropagateChecksumReloader leaves restarts on changes to stakater/Reloader instead of propagating the checksum
+const PropagateChecksumReloader = "reloader"
+
+const (
+	// SealedOutputCopy keeps the secret and stores its SealedSecret manifest in a ConfigMap
+	SealedOutputCopy = "copy"
+	// SealedOutputReplace creates a SealedSecret the sealed-secrets controller unseals into the secret
+	SealedOutputReplace = "replace"
+)
+
+const (
+	FinalizerExternalSync = "secret-generator.v1.mittwald.de/external-sync"
+	FinalizerReplication  = "secret-generator.v1.mittwald.de/replication"
 )
 
 type SecretType string
@@ -28,9 +219,30 @@ func (st SecretType) Validate() error {
 		SecretTypeSSHKeypair:
 		return nil
 	}
+	if isPluginType(st) {
+		return nil
+	}
 	return fmt.Errorf("%s is not a valid secret type", st)
 }
 
+// Encoding selects the characters of a generated string value
+type Encoding string
+
+const (
+	EncodingBase64 Encoding = "base64"
+	EncodingHex    Encoding = "hex"
+)
+
+func (e Encoding) Validate() error {
+	switch e {
+	case "",
+		EncodingBase64,
+		EncodingHex:
+		return nil
+	}
+	return fmt.Errorf("%s is not a valid encoding", e)
+}
+
 type SecretGenerator interface {
 	generateData(*corev1.Secret) (reconcile.Result, error)
 }
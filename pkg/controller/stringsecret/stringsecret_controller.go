@@ -0,0 +1,320 @@
+package stringsecret
+
+import (
+	"context"
+	"fmt"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/apis/secretgenerator/v1alpha1"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/controller/secret"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/recovery"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/resilience"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/sharding"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/shutdown"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"reflect"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var log = logf.Log.WithName("controller_stringsecret")
+
+const ownerKind = "StringSecret"
+
+// Add creates a new StringSecret Controller and adds it to the Manager. The Manager will set fields on the Controller
+// and Start it when the Manager is Started.
+func Add(mgr manager.Manager) error {
+	return add(mgr, newReconciler(mgr))
+}
+
+// newReconciler returns a new reconcile.Reconciler
+func newReconciler(mgr manager.Manager) reconcile.Reconciler {
+	return &ReconcileStringSecret{client: resilience.Client(mgr.GetClient()), scheme: mgr.GetScheme()}
+}
+
+// add adds a new Controller to mgr with r as the reconcile.Reconciler
+func add(mgr manager.Manager, r reconcile.Reconciler) error {
+	// Report panics of a single reconcile instead of crashing the controller
+	r = recovery.Recover("stringsecret-controller", v1alpha1.SchemeGroupVersion.WithKind("StringSecret"), mgr.GetEventRecorderFor("secret-generator"), r)
+
+	// Create a new controller
+	c, err := controller.New("stringsecret-controller", mgr, controller.Options{Reconciler: shutdown.Track(resilience.Guard(sharding.Filter(r)))})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to primary resource StringSecret
+	err = c.Watch(&source.Kind{Type: &v1alpha1.StringSecret{}}, &handler.EnqueueRequestForObject{})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to the generated secrets. Secrets kept on deletion carry no owner reference,
+	// so they are mapped back to their StringSecret using the owner annotation.
+	err = c.Watch(&source.Kind{Type: &corev1.Secret{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(mapSecretToOwner),
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ownerAnnotation returns the value of the owner annotation for secrets created from the given StringSecret
+func ownerAnnotation(instance *v1alpha1.StringSecret) string {
+	return ownerKind + "/" + instance.Namespace + "/" + instance.Name
+}
+
+func mapSecretToOwner(a handler.MapObject) []reconcile.Request {
+	owner := a.Meta.GetAnnotations()[secret.AnnotationSecretOwner]
+	parts := strings.Split(owner, "/")
+	if len(parts) != 3 || parts[0] != ownerKind {
+		return nil
+	}
+
+	return []reconcile.Request{
+		{NamespacedName: types.NamespacedName{Namespace: parts[1], Name: parts[2]}},
+	}
+}
+
+// blank assignment to verify that ReconcileStringSecret implements reconcile.Reconciler
+var _ reconcile.Reconciler = &ReconcileStringSecret{}
+
+// ReconcileStringSecret reconciles a StringSecret object
+type ReconcileStringSecret struct {
+	// This client, initialized using mgr.Client() above, is a split client
+	// that reads objects from the cache and writes to the apiserver
+	client client.Client
+	scheme *runtime.Scheme
+}
+
+// Reconcile ensures a Secret with generator annotations matching the StringSecret exists.
+// Generating the actual values is left to the secret controller.
+func (r *ReconcileStringSecret) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	reqLogger := log.WithValues("Request.Namespace", request.Namespace, "Request.Name", request.Name)
+	reqLogger.V(1).Info("Reconciling StringSecret")
+
+	instance := &v1alpha1.StringSecret{}
+	err := r.client.Get(context.TODO(), request.NamespacedName, instance)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			// Request object not found, could have been deleted after reconcile request.
+			// Owned objects are automatically garbage collected.
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, err
+	}
+
+	if !instance.DeletionTimestamp.IsZero() {
+		return reconcile.Result{}, r.finalize(instance)
+	}
+
+	status := instance.Status.DeepCopy()
+	status.ObservedGeneration = instance.Generation
+
+	allowed, err := r.targetNamespaceAllowed(instance)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if !allowed {
+		reqLogger.Info("target namespace is not allowed", "targetNamespace", targetNamespace(instance))
+		status.Conditions.MarkError("TargetNamespaceNotAllowed",
+			fmt.Errorf("creating secrets in namespace %s is not allowed", targetNamespace(instance)))
+		if err := r.updateStatus(instance, status); err != nil {
+			return reconcile.Result{}, err
+		}
+		// the target namespace might opt in later on
+		return reconcile.Result{RequeueAfter: time.Minute}, nil
+	}
+
+	if err := r.moveSecret(instance, status); err != nil {
+		reqLogger.Error(err, "could not delete secret in previous target namespace")
+		return reconcile.Result{}, err
+	}
+
+	if isCrossNamespace(instance) && !containsString(instance.Finalizers, finalizerTargetSecret) {
+		instance.Finalizers = append(instance.Finalizers, finalizerTargetSecret)
+		if err := r.client.Update(context.TODO(), instance); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+
+	target := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      instance.Name,
+			Namespace: targetNamespace(instance),
+		},
+	}
+
+	op, err := controllerutil.CreateOrUpdate(context.TODO(), r.client, target, func() error {
+		return r.mutateSecret(instance, target)
+	})
+	if err != nil {
+		reqLogger.Error(err, "could not create or update secret")
+		status.Conditions.MarkError("SecretUpdateFailed", err)
+		if statusErr := r.updateStatus(instance, status); statusErr != nil {
+			reqLogger.Error(statusErr, "could not update status")
+		}
+		return reconcile.Result{}, err
+	}
+	if op != controllerutil.OperationResultNone {
+		reqLogger.Info("secret reconciled", "operation", op)
+	}
+
+	status.SecretNamespace = target.Namespace
+	updateSecretStatus(status, instance, target)
+
+	if err := r.updateStatus(instance, status); err != nil {
+		reqLogger.Error(err, "could not update status")
+		return reconcile.Result{}, err
+	}
+
+	return reconcile.Result{}, nil
+}
+
+// mutateSecret applies the StringSecret's spec to the given secret
+func (r *ReconcileStringSecret) mutateSecret(instance *v1alpha1.StringSecret, target *corev1.Secret) error {
+	if target.Annotations == nil {
+		target.Annotations = make(map[string]string)
+	}
+
+	owner := ownerAnnotation(instance)
+	if !target.CreationTimestamp.IsZero() && target.Annotations[secret.AnnotationSecretOwner] != owner {
+		return fmt.Errorf("secret %s/%s already exists and is not managed by this StringSecret", target.Namespace, target.Name)
+	}
+
+	target.Annotations[secret.AnnotationSecretOwner] = owner
+	ApplyTemplate(instance.Spec.SecretTemplate, target)
+
+	// owner references can't point to other namespaces, these secrets are cleaned up using a finalizer
+	if instance.Spec.KeepOnDelete || isCrossNamespace(instance) {
+		removeOwnerReference(target, instance.UID)
+		return nil
+	}
+	return controllerutil.SetControllerReference(instance, target, r.scheme)
+}
+
+// ApplyTemplate sets the generator annotations and static data described by the template on the given secret.
+// Generated values are filled in by the secret controller afterwards.
+func ApplyTemplate(template v1alpha1.SecretTemplate, target *corev1.Secret) {
+	if target.Annotations == nil {
+		target.Annotations = make(map[string]string)
+	}
+	if target.Data == nil {
+		target.Data = make(map[string][]byte)
+	}
+
+	// secrets not matching the label selector would not be seen by the controllers
+	for key, value := range secret.SelectorLabels() {
+		if target.Labels == nil {
+			target.Labels = make(map[string]string)
+		}
+		target.Labels[key] = value
+	}
+
+	if target.CreationTimestamp.IsZero() {
+		// the type of existing secrets is immutable
+		target.Type = template.Type
+		if target.Type == "" {
+			target.Type = corev1.SecretTypeOpaque
+		}
+	}
+
+	target.Annotations[secret.AnnotationSecretType] = string(secret.SecretTypeString)
+	target.Annotations[secret.AnnotationSecretAutoGenerate] = strings.Join(template.Fields, ",")
+	setOrDeleteAnnotation(target.Annotations, secret.AnnotationSecretPasswordPolicy, template.PasswordPolicy)
+	if template.Length > 0 {
+		target.Annotations[secret.AnnotationSecretLength] = strconv.Itoa(template.Length)
+	} else {
+		delete(target.Annotations, secret.AnnotationSecretLength)
+	}
+
+	if template.Prune {
+		target.Annotations[secret.AnnotationSecretPrune] = "true"
+	} else {
+		delete(target.Annotations, secret.AnnotationSecretPrune)
+	}
+
+	for name := range target.Annotations {
+		key := strings.TrimPrefix(name, secret.AnnotationSecretTemplatePrefix)
+		if _, ok := template.Templates[key]; !ok && key != name {
+			delete(target.Annotations, name)
+		}
+	}
+	for key, text := range template.Templates {
+		target.Annotations[secret.AnnotationSecretTemplatePrefix+key] = text
+	}
+
+	for key, value := range template.Data {
+		target.Data[key] = []byte(value)
+	}
+}
+
+// MissingFields returns the fields of the template which have not been generated yet
+func MissingFields(template v1alpha1.SecretTemplate, target *corev1.Secret) []string {
+	var missing []string
+	for _, field := range template.Fields {
+		if len(target.Data[field]) == 0 {
+			missing = append(missing, field)
+		}
+	}
+	return missing
+}
+
+func (r *ReconcileStringSecret) updateStatus(instance *v1alpha1.StringSecret, status *v1alpha1.StringSecretStatus) error {
+	if reflect.DeepEqual(instance.Status, *status) {
+		return nil
+	}
+
+	instance.Status = *status
+	return r.client.Status().Update(context.TODO(), instance)
+}
+
+// updateSecretStatus sets the Ready and LastRotation conditions according to the state of the generated secret
+func updateSecretStatus(status *v1alpha1.StringSecretStatus, instance *v1alpha1.StringSecret, target *corev1.Secret) {
+	if missing := MissingFields(instance.Spec.SecretTemplate, target); len(missing) > 0 {
+		status.Conditions.MarkNotReady("Pending", "waiting for generation of fields "+strings.Join(missing, ","))
+	} else {
+		status.Conditions.MarkReady("Generated", "")
+	}
+
+	generatedAt, err := time.Parse(time.RFC3339, target.Annotations[secret.AnnotationSecretAutoGeneratedAt])
+	if err == nil {
+		status.Conditions.SetCondition(v1alpha1.Condition{
+			Type:               v1alpha1.ConditionLastRotation,
+			Status:             corev1.ConditionTrue,
+			LastTransitionTime: metav1.NewTime(generatedAt.Local()),
+			Reason:             "Generated",
+		})
+	}
+}
+
+func setOrDeleteAnnotation(annotations map[string]string, key, value string) {
+	if value == "" {
+		delete(annotations, key)
+		return
+	}
+	annotations[key] = value
+}
+
+func removeOwnerReference(target *corev1.Secret, uid types.UID) {
+	refs := target.OwnerReferences[:0]
+	for _, ref := range target.OwnerReferences {
+		if ref.UID != uid {
+			refs = append(refs, ref)
+		}
+	}
+	target.OwnerReferences = refs
+}
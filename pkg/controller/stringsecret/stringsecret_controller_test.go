@@ -0,0 +1,232 @@
+package stringsecret
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/apis"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/apis/secretgenerator/v1alpha1"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/controller/secret"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/util/flowcontrol"
+	"os"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"testing"
+)
+
+var mgr manager.Manager
+
+func TestMain(m *testing.M) {
+	cfgPath := os.Getenv("KUBECONFIG")
+	cfg, err := clientcmd.BuildConfigFromFlags("", cfgPath)
+
+	if err != nil {
+		panic(err)
+	}
+
+	restMapper := func(cfg *rest.Config) (meta.RESTMapper, error) {
+		return apiutil.NewDynamicRESTMapper(cfg)
+	}
+
+	mgrOpts := manager.Options{
+		MapperProvider: restMapper,
+		NewClient: func(_ cache.Cache, config *rest.Config, options client.Options) (client.Client, error) {
+			config.RateLimiter = flowcontrol.NewFakeAlwaysRateLimiter()
+			return client.New(config, options)
+		},
+	}
+
+	mgr, err = manager.New(cfg, mgrOpts)
+	if err != nil {
+		panic(err)
+	}
+
+	if err := apis.AddToScheme(mgr.GetScheme()); err != nil {
+		panic(err)
+	}
+
+	code := m.Run()
+
+	os.Exit(code)
+}
+
+func newStringSecret(spec v1alpha1.StringSecretSpec) *v1alpha1.StringSecret {
+	return &v1alpha1.StringSecret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      uuid.New().String(),
+			Namespace: "default",
+		},
+		Spec: spec,
+	}
+}
+
+func doReconcile(t *testing.T, instance *v1alpha1.StringSecret, isErr bool) *corev1.Secret {
+	rec := ReconcileStringSecret{mgr.GetClient(), mgr.GetScheme()}
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: instance.Name, Namespace: instance.Namespace}}
+
+	_, err := rec.Reconcile(req)
+	if isErr {
+		require.Error(t, err)
+		return nil
+	}
+	require.NoError(t, err)
+
+	out := &corev1.Secret{}
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), req.NamespacedName, out))
+	return out
+}
+
+func TestSecretIsCreatedWithOwnerReference(t *testing.T) {
+	in := newStringSecret(v1alpha1.StringSecretSpec{
+		SecretTemplate: v1alpha1.SecretTemplate{
+			Fields: []string{"password", "token"},
+			Length: 20,
+			Data: map[string]string{
+				"username": "admin",
+			},
+		},
+	})
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), in))
+
+	out := doReconcile(t, in, false)
+
+	require.Equal(t, "password,token", out.Annotations[secret.AnnotationSecretAutoGenerate])
+	require.Equal(t, "20", out.Annotations[secret.AnnotationSecretLength])
+	require.Equal(t, []byte("admin"), out.Data["username"])
+
+	owner := metav1.GetControllerOf(out)
+	require.NotNil(t, owner)
+	require.Equal(t, in.UID, owner.UID)
+}
+
+func TestApplyTemplateReplacesTemplates(t *testing.T) {
+	target := &corev1.Secret{}
+	ApplyTemplate(v1alpha1.SecretTemplate{
+		Fields: []string{"password"},
+		Templates: map[string]string{
+			"DATABASE_URL": "postgres://user:{{ .password }}@host/db",
+			"REDIS_URL":    "redis://:{{ .password }}@host",
+		},
+	}, target)
+
+	ApplyTemplate(v1alpha1.SecretTemplate{
+		Fields: []string{"password"},
+		Templates: map[string]string{
+			"DATABASE_URL": "postgres://admin:{{ .password }}@host/db",
+		},
+	}, target)
+
+	require.Equal(t, "postgres://admin:{{ .password }}@host/db", target.Annotations[secret.AnnotationSecretTemplatePrefix+"DATABASE_URL"])
+	require.NotContains(t, target.Annotations, secret.AnnotationSecretTemplatePrefix+"REDIS_URL")
+}
+
+func TestKeepOnDeleteOmitsOwnerReference(t *testing.T) {
+	in := newStringSecret(v1alpha1.StringSecretSpec{
+		SecretTemplate: v1alpha1.SecretTemplate{
+			Fields: []string{"password"},
+		},
+		KeepOnDelete: true,
+	})
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), in))
+
+	out := doReconcile(t, in, false)
+
+	require.Nil(t, metav1.GetControllerOf(out))
+	require.Equal(t, ownerAnnotation(in), out.Annotations[secret.AnnotationSecretOwner])
+}
+
+func TestExistingSecretIsNotAdopted(t *testing.T) {
+	in := newStringSecret(v1alpha1.StringSecretSpec{
+		SecretTemplate: v1alpha1.SecretTemplate{
+			Fields: []string{"password"},
+		},
+	})
+	existing := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      in.Name,
+			Namespace: in.Namespace,
+		},
+	}
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), existing))
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), in))
+
+	doReconcile(t, in, true)
+}
+
+func TestTargetNamespaceNotAllowed(t *testing.T) {
+	in := newStringSecret(v1alpha1.StringSecretSpec{
+		SecretTemplate: v1alpha1.SecretTemplate{
+			Fields: []string{"password"},
+		},
+		TargetNamespace: "kube-system",
+	})
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), in))
+
+	rec := ReconcileStringSecret{mgr.GetClient(), mgr.GetScheme()}
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: in.Name, Namespace: in.Namespace}}
+	_, err := rec.Reconcile(req)
+	require.NoError(t, err)
+
+	out := &v1alpha1.StringSecret{}
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), req.NamespacedName, out))
+	require.True(t, out.Status.Conditions.IsTrue(v1alpha1.ConditionError))
+
+	err = mgr.GetClient().Get(context.TODO(), types.NamespacedName{Name: in.Name, Namespace: "kube-system"}, &corev1.Secret{})
+	require.True(t, errors.IsNotFound(err))
+}
+
+func TestSecretIsMovedWithTargetNamespace(t *testing.T) {
+	viper.Set("target-namespace-allowlist", "default:kube-public")
+	defer viper.Set("target-namespace-allowlist", "")
+
+	in := newStringSecret(v1alpha1.StringSecretSpec{
+		SecretTemplate: v1alpha1.SecretTemplate{
+			Fields: []string{"password"},
+		},
+		TargetNamespace: "kube-public",
+	})
+	require.NoError(t, mgr.GetClient().Create(context.TODO(), in))
+
+	rec := ReconcileStringSecret{mgr.GetClient(), mgr.GetScheme()}
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: in.Name, Namespace: in.Namespace}}
+	_, err := rec.Reconcile(req)
+	require.NoError(t, err)
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), types.NamespacedName{Name: in.Name, Namespace: "kube-public"}, &corev1.Secret{}))
+
+	moved := &v1alpha1.StringSecret{}
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), req.NamespacedName, moved))
+	require.Equal(t, "kube-public", moved.Status.SecretNamespace)
+	require.Contains(t, moved.Finalizers, finalizerTargetSecret)
+
+	moved.Spec.TargetNamespace = ""
+	require.NoError(t, mgr.GetClient().Update(context.TODO(), moved))
+	doReconcile(t, in, false)
+
+	err = mgr.GetClient().Get(context.TODO(), types.NamespacedName{Name: in.Name, Namespace: "kube-public"}, &corev1.Secret{})
+	require.True(t, errors.IsNotFound(err), "secret in previous target namespace must be deleted")
+
+	out := &v1alpha1.StringSecret{}
+	require.NoError(t, mgr.GetClient().Get(context.TODO(), req.NamespacedName, out))
+	require.Equal(t, in.Namespace, out.Status.SecretNamespace)
+	require.NotContains(t, out.Finalizers, finalizerTargetSecret)
+}
+
+func TestNamespaceAllowlisted(t *testing.T) {
+	allowlist := "team-a:shared, *:public"
+
+	require.True(t, secret.NamespaceAllowlisted(allowlist, "team-a", "shared"))
+	require.False(t, secret.NamespaceAllowlisted(allowlist, "team-b", "shared"))
+	require.True(t, secret.NamespaceAllowlisted(allowlist, "team-b", "public"))
+	require.False(t, secret.NamespaceAllowlisted("", "team-a", "shared"))
+}
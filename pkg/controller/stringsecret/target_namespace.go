@@ -0,0 +1,116 @@
+package stringsecret
+
+import (
+	"context"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/apis/secretgenerator/v1alpha1"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/controller/secret"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// LabelNamespaceAllowFrom allows StringSecrets in the namespace given as label value
+	// to create their secrets in the labeled namespace
+	LabelNamespaceAllowFrom = secret.LabelNamespaceAllowFrom
+
+	// finalizerTargetSecret is used to clean up secrets in other namespaces,
+	// as these can't be garbage collected using owner references
+	finalizerTargetSecret = "secret-generator.v1.mittwald.de/target-secret"
+)
+
+// targetNamespace returns the namespace the secret of the given StringSecret is created in
+func targetNamespace(instance *v1alpha1.StringSecret) string {
+	if instance.Spec.TargetNamespace == "" {
+		return instance.Namespace
+	}
+	return instance.Spec.TargetNamespace
+}
+
+func isCrossNamespace(instance *v1alpha1.StringSecret) bool {
+	return targetNamespace(instance) != instance.Namespace
+}
+
+// targetNamespaceAllowed checks whether the StringSecret may create its secret in its target namespace
+func (r *ReconcileStringSecret) targetNamespaceAllowed(instance *v1alpha1.StringSecret) (bool, error) {
+	return secret.TargetNamespaceAllowed(r.client, instance.Namespace, targetNamespace(instance))
+}
+
+// finalize deletes the secret of a StringSecret in another namespace, unless it is to be kept,
+// and removes the finalizer afterwards
+func (r *ReconcileStringSecret) finalize(instance *v1alpha1.StringSecret) error {
+	if !containsString(instance.Finalizers, finalizerTargetSecret) {
+		return nil
+	}
+
+	if !instance.Spec.KeepOnDelete {
+		if err := r.deleteSecret(instance, targetNamespace(instance)); err != nil {
+			return err
+		}
+		// the target namespace may have changed since the secret was created
+		if ns := instance.Status.SecretNamespace; ns != "" && ns != targetNamespace(instance) {
+			if err := r.deleteSecret(instance, ns); err != nil {
+				return err
+			}
+		}
+	}
+
+	instance.Finalizers = removeString(instance.Finalizers, finalizerTargetSecret)
+	return r.client.Update(context.TODO(), instance)
+}
+
+// moveSecret deletes the secret created in a previous target namespace of the StringSecret and removes the
+// finalizer once its secret is no longer in another namespace
+func (r *ReconcileStringSecret) moveSecret(instance *v1alpha1.StringSecret, status *v1alpha1.StringSecretStatus) error {
+	if ns := status.SecretNamespace; ns != "" && ns != targetNamespace(instance) {
+		if err := r.deleteSecret(instance, ns); err != nil {
+			return err
+		}
+		status.SecretNamespace = ""
+	}
+
+	if !isCrossNamespace(instance) && containsString(instance.Finalizers, finalizerTargetSecret) {
+		instance.Finalizers = removeString(instance.Finalizers, finalizerTargetSecret)
+		return r.client.Update(context.TODO(), instance)
+	}
+	return nil
+}
+
+// deleteSecret deletes the secret of the StringSecret in the given namespace, if it is owned by it
+func (r *ReconcileStringSecret) deleteSecret(instance *v1alpha1.StringSecret, namespace string) error {
+	target := &corev1.Secret{}
+	err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: instance.Name}, target)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if target.Annotations[secret.AnnotationSecretOwner] != ownerAnnotation(instance) {
+		return nil
+	}
+	if err := r.client.Delete(context.TODO(), target); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+func containsString(s []string, e string) bool {
+	for _, a := range s {
+		if a == e {
+			return true
+		}
+	}
+	return false
+}
+
+func removeString(s []string, e string) []string {
+	var res []string
+	for _, a := range s {
+		if a != e {
+			res = append(res, a)
+		}
+	}
+	return res
+}
@@ -0,0 +1,70 @@
+package entropy
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	// egdReadBlocking is the EGD command reading the given number of bytes, blocking until they are available
+	egdReadBlocking = 0x02
+	// egdMaxRead is the maximum number of bytes of a single EGD read
+	egdMaxRead = 255
+
+	egdTimeout = 10 * time.Second
+)
+
+// egdReader reads from an entropy gathering daemon, e.g. egd or prngd, reconnecting after errors
+type egdReader struct {
+	mu      sync.Mutex
+	network string
+	addr    string
+	conn    net.Conn
+}
+
+func (r *egdReader) Read(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.conn == nil {
+		conn, err := net.DialTimeout(r.network, r.addr, egdTimeout)
+		if err != nil {
+			return 0, fmt.Errorf("could not connect to entropy gathering daemon: %w", err)
+		}
+		r.conn = conn
+	}
+
+	n := len(p)
+	if n > egdMaxRead {
+		n = egdMaxRead
+	}
+
+	err := r.conn.SetDeadline(time.Now().Add(egdTimeout))
+	if err == nil {
+		_, err = r.conn.Write([]byte{egdReadBlocking, byte(n)})
+	}
+	if err == nil {
+		_, err = io.ReadFull(r.conn, p[:n])
+	}
+	if err != nil {
+		r.conn.Close()
+		r.conn = nil
+		return 0, fmt.Errorf("could not read from entropy gathering daemon: %w", err)
+	}
+	return n, nil
+}
+
+func (r *egdReader) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.conn == nil {
+		return nil
+	}
+	err := r.conn.Close()
+	r.conn = nil
+	return err
+}
@@ -0,0 +1,102 @@
+// Package entropy provides the source of randomness used for generated values and keys, which is crypto/rand
+// unless replaced by an external source, e.g. a PKCS#11 HSM or an entropy gathering daemon
+package entropy
+
+import (
+	"crypto/rand"
+	"fmt"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/fips"
+	"io"
+	"net/url"
+	"os"
+	"sync"
+)
+
+// Reader is the source of randomness for generated values and keys. Reads fail while the last self-test of
+// the source failed, see SelfTest.
+var Reader io.Reader = guardedReader{}
+
+// source is the source configured by Setup
+var source io.Reader = rand.Reader
+
+// Setup replaces the source of Reader with the source described by uri:
+//
+//	crypto                                      crypto/rand, the default
+//	file:///dev/hwrng                           a character device or file, e.g. a hardware RNG
+//	egd:///var/run/egd-pool, egd://host:port    an entropy gathering daemon on a UNIX or TCP socket
+//	pkcs11:///usr/lib/module.so?slot=0&pin-file=/etc/hsm/pin
+//	                                            C_GenerateRandom of a PKCS#11 token, requires the pkcs11 build tag
+//
+// The source stays open for the lifetime of the process.
+func Setup(uri string) error {
+	if uri == "" || uri == "crypto" {
+		source = rand.Reader
+		return nil
+	}
+
+	r, err := Open(uri)
+	if err != nil {
+		return err
+	}
+	source = r
+	return nil
+}
+
+// Open opens the source described by uri, see Setup
+func Open(uri string) (io.ReadCloser, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("invalid entropy source %q: %w", uri, err)
+	}
+
+	switch u.Scheme {
+	case "file", "egd":
+		if err := fips.Disallow("entropy source "+u.Scheme, "only crypto/rand and PKCS#11 tokens provide an approved DRBG"); err != nil {
+			return nil, err
+		}
+	}
+
+	switch u.Scheme {
+	case "file":
+		return openFile(u.Path)
+	case "egd":
+		if u.Host != "" {
+			return &egdReader{network: "tcp", addr: u.Host}, nil
+		}
+		return &egdReader{network: "unix", addr: u.Path}, nil
+	case "pkcs11":
+		return openPKCS11(u)
+	}
+	return nil, fmt.Errorf("unsupported entropy source %q, expected crypto, file, egd or pkcs11", uri)
+}
+
+// fileReader reads from a character device or file, serializing reads as the device may not support
+// concurrent readers
+type fileReader struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func openFile(path string) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open entropy source: %w", err)
+	}
+	return &fileReader{file: f}, nil
+}
+
+func (r *fileReader) Read(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n, err := r.file.Read(p)
+	if err == io.EOF {
+		// a file running out of data is exhausted rather than at the end of a stream
+		return n, io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+func (r *fileReader) Close() error {
+	return r.file.Close()
+}
@@ -0,0 +1,114 @@
+package entropy
+
+import (
+	"bytes"
+	"crypto/rand"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/fips"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSetupDefaultsToCryptoRand(t *testing.T) {
+	if err := Setup(""); err != nil {
+		t.Fatal(err)
+	}
+	if source != rand.Reader {
+		t.Error("expected crypto/rand to be used by default")
+	}
+}
+
+func TestFileSource(t *testing.T) {
+	dir, err := ioutil.TempDir("", "entropy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "pool")
+	if err := ioutil.WriteFile(path, []byte("0123456789"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := Open("file://" + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	b := make([]byte, 4)
+	if _, err := io.ReadFull(r, b); err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "0123" {
+		t.Errorf("unexpected bytes %q", b)
+	}
+
+	// an exhausted source must not be mistaken for a complete read
+	if _, err := io.ReadFull(r, make([]byte, 10)); err == nil {
+		t.Error("expected an error reading past the end of the file")
+	}
+}
+
+func TestEGDSource(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			cmd := make([]byte, 2)
+			if _, err := io.ReadFull(conn, cmd); err != nil {
+				return
+			}
+			if cmd[0] != egdReadBlocking {
+				return
+			}
+			if _, err := conn.Write(bytes.Repeat([]byte{0xab}, int(cmd[1]))); err != nil {
+				return
+			}
+		}
+	}()
+
+	r, err := Open("egd://" + l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	// reads larger than a single EGD request are split up
+	b := make([]byte, 300)
+	if _, err := io.ReadFull(r, b); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, bytes.Repeat([]byte{0xab}, 300)) {
+		t.Error("unexpected bytes read from entropy gathering daemon")
+	}
+}
+
+func TestUnsupportedSource(t *testing.T) {
+	if _, err := Open("http://example.com"); err == nil {
+		t.Error("expected an error for an unsupported source")
+	}
+}
+
+func TestFIPSModeRejectsUnapprovedSources(t *testing.T) {
+	defer fips.Force(true)()
+
+	if _, err := Open("file:///dev/hwrng"); err == nil {
+		t.Error("expected file sources to be rejected in FIPS mode")
+	}
+	if _, err := Open("egd:///var/run/egd-pool"); err == nil {
+		t.Error("expected EGD sources to be rejected in FIPS mode")
+	}
+}
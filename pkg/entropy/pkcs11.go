@@ -0,0 +1,87 @@
+//go:build pkcs11
+// +build pkcs11
+
+package entropy
+
+import (
+	"fmt"
+	"github.com/miekg/pkcs11"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// pkcs11Reader reads random bytes generated by a PKCS#11 token, e.g. an HSM
+type pkcs11Reader struct {
+	mu      sync.Mutex
+	ctx     *pkcs11.Ctx
+	session pkcs11.SessionHandle
+}
+
+// openPKCS11 loads the module at the path of u and opens a session on the slot given by the slot
+// query parameter, logging in with the PIN read from the pin-file query parameter if set
+func openPKCS11(u *url.URL) (io.ReadCloser, error) {
+	slot, err := strconv.ParseUint(u.Query().Get("slot"), 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid slot of PKCS#11 entropy source: %w", err)
+	}
+
+	ctx := pkcs11.New(u.Path)
+	if ctx == nil {
+		return nil, fmt.Errorf("could not load PKCS#11 module %s", u.Path)
+	}
+	if err := ctx.Initialize(); err != nil {
+		ctx.Destroy()
+		return nil, fmt.Errorf("could not initialize PKCS#11 module: %w", err)
+	}
+
+	r := &pkcs11Reader{ctx: ctx}
+	r.session, err = ctx.OpenSession(uint(slot), pkcs11.CKF_SERIAL_SESSION)
+	if err != nil {
+		r.finalize()
+		return nil, fmt.Errorf("could not open PKCS#11 session: %w", err)
+	}
+
+	if pinFile := u.Query().Get("pin-file"); pinFile != "" {
+		pin, err := ioutil.ReadFile(pinFile)
+		if err != nil {
+			r.Close()
+			return nil, fmt.Errorf("could not read PKCS#11 PIN: %w", err)
+		}
+		if err := ctx.Login(r.session, pkcs11.CKU_USER, strings.TrimSpace(string(pin))); err != nil {
+			r.Close()
+			return nil, fmt.Errorf("could not log in to PKCS#11 token: %w", err)
+		}
+	}
+
+	return r, nil
+}
+
+func (r *pkcs11Reader) Read(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	b, err := r.ctx.GenerateRandom(r.session, len(p))
+	if err != nil {
+		return 0, fmt.Errorf("could not generate random bytes on PKCS#11 token: %w", err)
+	}
+	return copy(p, b), nil
+}
+
+func (r *pkcs11Reader) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_ = r.ctx.Logout(r.session)
+	err := r.ctx.CloseSession(r.session)
+	r.finalize()
+	return err
+}
+
+func (r *pkcs11Reader) finalize() {
+	_ = r.ctx.Finalize()
+	r.ctx.Destroy()
+}
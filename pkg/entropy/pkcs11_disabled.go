@@ -0,0 +1,14 @@
+//go:build !pkcs11
+// +build !pkcs11
+
+package entropy
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+)
+
+func openPKCS11(*url.URL) (io.ReadCloser, error) {
+	return nil, fmt.Errorf("PKCS#11 entropy sources require building with the pkcs11 tag")
+}
@@ -0,0 +1,95 @@
+package entropy
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/bits"
+	"sync"
+)
+
+const (
+	// selfTestBytes is the size of the sample checked by SelfTest, 20000 bits as in FIPS 140-2
+	selfTestBytes = 2500
+	// monobitMin and monobitMax bound the number of ones in the sample, as in the FIPS 140-2 monobit test
+	monobitMin = 9725
+	monobitMax = 10275
+	// repetitionCutoff is the number of identical consecutive bytes failing the repetition count test of
+	// NIST SP 800-90B for full entropy bytes, at a false positive probability of 2^-40
+	repetitionCutoff = 6
+	// blockSize is the size of the blocks compared by the continuous test, which fails on identical
+	// consecutive blocks, i.e. a source that got stuck
+	blockSize = 16
+)
+
+var health struct {
+	sync.RWMutex
+	err error
+}
+
+// guardedReader reads from the configured source unless the last self-test failed
+type guardedReader struct{}
+
+func (guardedReader) Read(p []byte) (int, error) {
+	if err := Healthy(); err != nil {
+		return 0, err
+	}
+	return source.Read(p)
+}
+
+// Healthy returns the error of the last self-test, or nil if it passed
+func Healthy() error {
+	health.RLock()
+	defer health.RUnlock()
+	return health.err
+}
+
+// SelfTest checks a sample of the configured source. Reader fails until SelfTest passes again if it fails,
+// so that a broken source never silently produces weak values.
+func SelfTest() error {
+	err := CheckSample(source)
+
+	health.Lock()
+	defer health.Unlock()
+	if err != nil {
+		health.err = fmt.Errorf("entropy source failed self-test: %w", err)
+	} else {
+		health.err = nil
+	}
+	return health.err
+}
+
+// CheckSample reads a sample from r and runs the monobit, repetition count and continuous tests on it
+func CheckSample(r io.Reader) error {
+	sample := make([]byte, selfTestBytes)
+	if _, err := io.ReadFull(r, sample); err != nil {
+		return err
+	}
+
+	ones := 0
+	for _, b := range sample {
+		ones += bits.OnesCount8(b)
+	}
+	if ones <= monobitMin || ones >= monobitMax {
+		return fmt.Errorf("monobit test failed with %d of %d bits set", ones, len(sample)*8)
+	}
+
+	run := 1
+	for i := 1; i < len(sample); i++ {
+		if sample[i] != sample[i-1] {
+			run = 1
+			continue
+		}
+		if run++; run >= repetitionCutoff {
+			return fmt.Errorf("repetition count test failed with byte %#x repeated %d times", sample[i], run)
+		}
+	}
+
+	for i := blockSize; i+blockSize <= len(sample); i += blockSize {
+		if bytes.Equal(sample[i-blockSize:i], sample[i:i+blockSize]) {
+			return fmt.Errorf("continuous test failed with identical consecutive blocks at offset %d", i)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,54 @@
+package entropy
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"testing"
+)
+
+func TestCheckSample(t *testing.T) {
+	if err := CheckSample(rand.Reader); err != nil {
+		t.Errorf("expected crypto/rand to pass, got %v", err)
+	}
+
+	random := make([]byte, selfTestBytes)
+	if _, err := rand.Read(random); err != nil {
+		t.Fatal(err)
+	}
+	repeated := append([]byte{}, random...)
+	copy(repeated[100:], bytes.Repeat([]byte{0x5a}, repetitionCutoff))
+
+	tests := map[string]io.Reader{
+		"zeros":    bytes.NewReader(make([]byte, selfTestBytes)),
+		"biased":   bytes.NewReader(bytes.Repeat([]byte{0xff, 0xfe, 0x7f}, selfTestBytes)),
+		"stuck":    bytes.NewReader(bytes.Repeat([]byte("0123456789abcdef"), selfTestBytes)),
+		"repeated": bytes.NewReader(repeated),
+		"short":    bytes.NewReader(make([]byte, 10)),
+	}
+	for name, r := range tests {
+		if err := CheckSample(r); err == nil {
+			t.Errorf("expected %s sample to fail", name)
+		}
+	}
+}
+
+func TestReaderFailsAfterFailedSelfTest(t *testing.T) {
+	defer func() { source = rand.Reader }()
+
+	source = bytes.NewReader(make([]byte, selfTestBytes))
+	if err := SelfTest(); err == nil {
+		t.Fatal("expected the self-test to fail")
+	}
+	if _, err := Reader.Read(make([]byte, 8)); err == nil {
+		t.Error("expected reads to fail after a failed self-test")
+	}
+
+	source = rand.Reader
+	if err := SelfTest(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadFull(Reader, make([]byte, 8)); err != nil {
+		t.Errorf("expected reads to succeed after a passing self-test, got %v", err)
+	}
+}
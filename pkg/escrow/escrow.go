@@ -0,0 +1,95 @@
+// Package escrow encrypts generated values for the public keys of the people allowed to recover them
+package escrow
+
+import (
+	"bytes"
+	"errors"
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"fmt"
+	"golang.org/x/crypto/openpgp"
+	pgparmor "golang.org/x/crypto/openpgp/armor"
+	"io"
+	"strings"
+)
+
+// Recipients encrypts values for age or PGP public keys
+type Recipients interface {
+	Encrypt(plaintext []byte) ([]byte, error)
+}
+
+// ParseRecipients parses either a list of age X25519 public keys, separated by commas or whitespace, or an
+// armored block of PGP public keys. Values are encrypted so that any of the keys can decrypt them.
+func ParseRecipients(val string) (Recipients, error) {
+	if IsPGP(val) {
+		entities, err := openpgp.ReadArmoredKeyRing(strings.NewReader(val))
+		if err != nil {
+			return nil, fmt.Errorf("invalid PGP public keys: %w", err)
+		}
+		return pgpRecipients(entities), nil
+	}
+
+	var recipients ageRecipients
+	for _, r := range strings.FieldsFunc(val, func(c rune) bool { return c == ',' || c == ' ' || c == '\n' || c == '\t' }) {
+		key, err := age.ParseX25519Recipient(r)
+		if err != nil {
+			return nil, fmt.Errorf("invalid age recipient %q: %w", r, err)
+		}
+		recipients = append(recipients, key)
+	}
+	if len(recipients) == 0 {
+		return nil, errors.New("no age or PGP public keys given")
+	}
+	return recipients, nil
+}
+
+// IsPGP reports whether val holds PGP public keys rather than age public keys
+func IsPGP(val string) bool {
+	return strings.Contains(val, "-----BEGIN PGP PUBLIC KEY BLOCK-----")
+}
+
+type ageRecipients []age.Recipient
+
+// Encrypt returns the plaintext as armored age file, which is decrypted using "age -d -i <identity>"
+func (r ageRecipients) Encrypt(plaintext []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	armored := armor.NewWriter(&buf)
+	w, err := age.Encrypt(armored, r...)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeAndClose(w, armored, plaintext); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+type pgpRecipients openpgp.EntityList
+
+// Encrypt returns the plaintext as armored PGP message, which is decrypted using "gpg -d"
+func (r pgpRecipients) Encrypt(plaintext []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	armored, err := pgparmor.Encode(&buf, "PGP MESSAGE", nil)
+	if err != nil {
+		return nil, err
+	}
+	w, err := openpgp.Encrypt(armored, r, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeAndClose(w, armored, plaintext); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeAndClose writes the plaintext to the encrypting writer and flushes it and the armor
+func writeAndClose(w, armored io.WriteCloser, plaintext []byte) error {
+	if _, err := w.Write(plaintext); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return armored.Close()
+}
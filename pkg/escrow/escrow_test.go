@@ -0,0 +1,105 @@
+package escrow
+
+import (
+	"bytes"
+	"crypto"
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"golang.org/x/crypto/openpgp"
+	pgparmor "golang.org/x/crypto/openpgp/armor"
+	"golang.org/x/crypto/openpgp/packet"
+	"io/ioutil"
+	"testing"
+)
+
+func TestEncryptForAgeRecipients(t *testing.T) {
+	alice, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recipients, err := ParseRecipients(alice.Recipient().String() + ", " + bob.Recipient().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext, err := recipients.Encrypt([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, identity := range []*age.X25519Identity{alice, bob} {
+		r, err := age.Decrypt(armor.NewReader(bytes.NewReader(ciphertext)), identity)
+		if err != nil {
+			t.Fatal(err)
+		}
+		plaintext, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(plaintext) != "secret" {
+			t.Errorf("expected the value to decrypt to secret, got %q", plaintext)
+		}
+	}
+}
+
+func TestEncryptForPGPRecipients(t *testing.T) {
+	// keys without hash preferences would require RIPEMD160, which isn't compiled in
+	entity, err := openpgp.NewEntity("Recovery", "", "recovery@example.com", &packet.Config{DefaultHash: crypto.SHA256})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// NewEntity only sets the hash preference after signing the identity, so it has to be signed again
+	for _, id := range entity.Identities {
+		if err := id.SelfSignature.SignUserId(id.UserId.Id, entity.PrimaryKey, entity.PrivateKey, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var keys bytes.Buffer
+	w, err := pgparmor.Encode(&keys, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := entity.Serialize(w); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	recipients, err := ParseRecipients(keys.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext, err := recipients.Encrypt([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block, err := pgparmor.Decode(bytes.NewReader(ciphertext))
+	if err != nil {
+		t.Fatal(err)
+	}
+	md, err := openpgp.ReadMessage(block.Body, openpgp.EntityList{entity}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext, err := ioutil.ReadAll(md.UnverifiedBody)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(plaintext) != "secret" {
+		t.Errorf("expected the value to decrypt to secret, got %q", plaintext)
+	}
+}
+
+func TestParseRecipientsRejectsInvalidKeys(t *testing.T) {
+	for _, val := range []string{"", "age1invalid", "ssh-ed25519 AAAA"} {
+		if _, err := ParseRecipients(val); err == nil {
+			t.Errorf("expected %q to be rejected", val)
+		}
+	}
+}
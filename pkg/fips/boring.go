@@ -0,0 +1,17 @@
+//go:build boringcrypto
+// +build boringcrypto
+
+package fips
+
+import (
+	"crypto/boring"
+	// restrict TLS to FIPS approved settings
+	_ "crypto/tls/fipsonly"
+)
+
+// required enables FIPS mode regardless of the fips flag in builds using BoringCrypto
+const required = true
+
+func moduleEnabled() bool {
+	return boring.Enabled()
+}
@@ -0,0 +1,68 @@
+// Package fips restricts the controller to algorithms approved by FIPS 140. FIPS mode requires the controller
+// to be built with GOEXPERIMENT=boringcrypto, which replaces the standard library cryptography with the
+// validated BoringCrypto module, and is always enabled in such builds.
+package fips
+
+import (
+	"errors"
+	"fmt"
+)
+
+var enabled bool
+
+// Error is returned for requests that require an algorithm that is not approved in FIPS mode
+type Error struct {
+	Algorithm string
+	Reason    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s is not approved in FIPS mode: %s", e.Algorithm, e.Reason)
+}
+
+// Setup enables FIPS mode if requested or required by the build, failing if the crypto module in use is
+// not the validated BoringCrypto module
+func Setup(enable bool) error {
+	if !enable && !required {
+		enabled = false
+		return nil
+	}
+	if !moduleEnabled() {
+		return errors.New("FIPS mode requires the controller to be built with GOEXPERIMENT=boringcrypto")
+	}
+	enabled = true
+	return nil
+}
+
+// Enabled reports whether FIPS mode is enabled
+func Enabled() bool {
+	return enabled
+}
+
+// Disallow returns an *Error for the given algorithm if FIPS mode is enabled
+func Disallow(algorithm, reason string) error {
+	if !enabled {
+		return nil
+	}
+	return &Error{Algorithm: algorithm, Reason: reason}
+}
+
+// CheckRSAKeyLength returns an *Error if FIPS mode is enabled and length is not an approved RSA modulus length
+func CheckRSAKeyLength(length int) error {
+	if !enabled {
+		return nil
+	}
+	switch length {
+	case 2048, 3072, 4096:
+		return nil
+	}
+	return &Error{Algorithm: fmt.Sprintf("RSA with %d bit keys", length), Reason: "use 2048, 3072 or 4096 bits"}
+}
+
+// Force enables or disables FIPS mode without verifying the crypto module, so that the checks of FIPS mode
+// can be tested in builds without BoringCrypto. It returns a function restoring the previous state.
+func Force(enable bool) func() {
+	previous := enabled
+	enabled = enable
+	return func() { enabled = previous }
+}
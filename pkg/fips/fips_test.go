@@ -0,0 +1,51 @@
+package fips
+
+import (
+	"testing"
+)
+
+func TestSetupWithoutBoringCrypto(t *testing.T) {
+	if required {
+		t.Skip("FIPS mode can't be disabled in builds using BoringCrypto")
+	}
+
+	if err := Setup(true); err == nil {
+		t.Error("expected FIPS mode to require BoringCrypto")
+	}
+	if Enabled() {
+		t.Error("expected FIPS mode to stay disabled")
+	}
+
+	if err := Setup(false); err != nil {
+		t.Fatal(err)
+	}
+	if err := Disallow("age", "X25519 is not approved"); err != nil {
+		t.Errorf("expected no error with FIPS mode disabled, got %v", err)
+	}
+	if err := CheckRSAKeyLength(1024); err != nil {
+		t.Errorf("expected no error with FIPS mode disabled, got %v", err)
+	}
+}
+
+func TestChecksInFIPSMode(t *testing.T) {
+	defer Force(true)()
+
+	err := Disallow("age", "X25519 is not approved")
+	if _, ok := err.(*Error); !ok {
+		t.Fatalf("expected *Error, got %v", err)
+	}
+	if err.Error() != "age is not approved in FIPS mode: X25519 is not approved" {
+		t.Errorf("unexpected error message %q", err)
+	}
+
+	for _, length := range []int{2048, 3072, 4096} {
+		if err := CheckRSAKeyLength(length); err != nil {
+			t.Errorf("expected %d bit keys to be approved, got %v", length, err)
+		}
+	}
+	for _, length := range []int{1024, 2047, 8192} {
+		if err := CheckRSAKeyLength(length); err == nil {
+			t.Errorf("expected %d bit keys not to be approved", length)
+		}
+	}
+}
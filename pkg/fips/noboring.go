@@ -0,0 +1,10 @@
+//go:build !boringcrypto
+// +build !boringcrypto
+
+package fips
+
+const required = false
+
+func moduleEnabled() bool {
+	return false
+}
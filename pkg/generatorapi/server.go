@@ -0,0 +1,158 @@
+// Package generatorapi serves the generators of the controller over gRPC, so that platform components written in
+// other languages can delegate generating credentials to a single audited service. Clients authenticate using
+// certificates signed by a trusted CA (mTLS).
+package generatorapi
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative generator.proto
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/audit"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/controller/secret"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/hook"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"time"
+)
+
+var log = logf.Log.WithName("generatorapi")
+
+// Server serves the Generator service. It is added to the manager as a runnable.
+type Server struct {
+	addr   string
+	config *tls.Config
+}
+
+// NewServer returns a server listening on addr, using the tls.crt and tls.key in certDir as its certificate and
+// accepting clients with certificates signed by the ca.crt in certDir
+func NewServer(addr, certDir string) (*Server, error) {
+	cert, err := tls.LoadX509KeyPair(filepath.Join(certDir, "tls.crt"), filepath.Join(certDir, "tls.key"))
+	if err != nil {
+		return nil, err
+	}
+	ca, err := ioutil.ReadFile(filepath.Join(certDir, "ca.crt"))
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("no certificates found in %s", filepath.Join(certDir, "ca.crt"))
+	}
+
+	return &Server{
+		addr: addr,
+		config: &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			ClientAuth:   tls.RequireAndVerifyClientCert,
+			ClientCAs:    pool,
+			MinVersion:   tls.VersionTLS12,
+		},
+	}, nil
+}
+
+// NeedLeaderElection makes the manager serve the API on all replicas
+func (s *Server) NeedLeaderElection() bool {
+	return false
+}
+
+// Start serves the API until stop is closed
+func (s *Server) Start(stop <-chan struct{}) error {
+	lis, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	return s.serve(lis, stop)
+}
+
+func (s *Server) serve(lis net.Listener, stop <-chan struct{}) error {
+	server := grpc.NewServer(grpc.Creds(credentials.NewTLS(s.config)))
+	RegisterGeneratorServer(server, generator{})
+	go func() {
+		<-stop
+		server.GracefulStop()
+	}()
+
+	log.Info("serving generator API", "address", lis.Addr().String())
+	return server.Serve(lis)
+}
+
+// generator implements the Generator service
+type generator struct {
+	UnimplementedGeneratorServer
+}
+
+func (generator) Generate(ctx context.Context, req *GenerateRequest) (*GenerateResponse, error) {
+	client, err := clientName(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	if req.Name == "" {
+		return nil, status.Error(codes.InvalidArgument, "name must be set")
+	}
+
+	spec := &secret.Spec{}
+	for _, f := range req.Fields {
+		field := secret.FieldSpec{
+			Name:     f.Name,
+			Type:     secret.SecretType(f.Type),
+			Length:   int(f.Length),
+			Encoding: secret.Encoding(f.Encoding),
+		}
+		if field.Type == "" {
+			field.Type = secret.SecretTypeString
+		}
+		spec.Fields = append(spec.Fields, field)
+	}
+	if err := spec.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	values, err := secret.GenerateFields(spec)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	res := &GenerateResponse{Values: values, Fingerprints: map[string]string{}}
+	for key, value := range values {
+		res.Fingerprints[key] = secret.Fingerprint(value)
+	}
+
+	// The values must not be handed out without a record of them
+	record := audit.Record{
+		Time:      time.Now().UTC(),
+		Actor:     client,
+		Name:      req.Name,
+		Reason:    string(hook.ReasonCreated),
+		Generated: res.Fingerprints,
+	}
+	if err := audit.Write(ctx, record); err != nil {
+		log.Error(err, "could not write audit record", "client", client, "name", req.Name)
+		return nil, status.Error(codes.Unavailable, "could not write audit record")
+	}
+
+	log.Info("generated values", "client", client, "name", req.Name, "count", len(values))
+	return res, nil
+}
+
+// clientName returns the common name of the verified client certificate of the request
+func clientName(ctx context.Context) (string, error) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return "", fmt.Errorf("no peer information")
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.VerifiedChains) == 0 || len(info.State.VerifiedChains[0]) == 0 {
+		return "", fmt.Errorf("no verified client certificate")
+	}
+	return info.State.VerifiedChains[0][0].Subject.CommonName, nil
+}
@@ -0,0 +1,154 @@
+package generatorapi
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+)
+
+// issue returns a certificate with the given common name signed by parent, or self-signed if parent is nil
+func issue(t *testing.T, cn string, parent *tls.Certificate) tls.Certificate {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  parent == nil,
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent.Leaf, parent.PrivateKey.(*rsa.PrivateKey)
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+// serve starts a server trusting clients signed by ca and returns a client using the given certificate
+func serve(t *testing.T, ca tls.Certificate, client tls.Certificate) (GeneratorClient, func()) {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.Leaf)
+	s := &Server{config: &tls.Config{
+		Certificates: []tls.Certificate{issue(t, "server", &ca)},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pool,
+	}}
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stop := make(chan struct{})
+	go func() {
+		_ = s.serve(lis, stop)
+	}()
+
+	creds := credentials.NewTLS(&tls.Config{Certificates: []tls.Certificate{client}, RootCAs: pool})
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithTransportCredentials(creds))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewGeneratorClient(conn), func() {
+		_ = conn.Close()
+		close(stop)
+	}
+}
+
+func TestGenerateReturnsValuesToAuthenticatedClients(t *testing.T) {
+	viper.Set("secret-length", 40)
+	defer viper.Set("secret-length", nil)
+
+	ca := issue(t, "ca", nil)
+	client, stop := serve(t, ca, issue(t, "billing", &ca))
+	defer stop()
+
+	res, err := client.Generate(context.Background(), &GenerateRequest{
+		Name: "billing-db",
+		Fields: []*Field{
+			{Name: "password"},
+			{Name: "token", Length: 16, Encoding: "hex"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Values["password"]) != 40 {
+		t.Errorf("expected password of default length 40, got %q", res.Values["password"])
+	}
+	if len(res.Values["token"]) != 16 {
+		t.Errorf("expected token of length 16, got %q", res.Values["token"])
+	}
+	if len(res.Fingerprints) != 2 {
+		t.Errorf("expected fingerprints of both values, got %v", res.Fingerprints)
+	}
+}
+
+func TestGenerateRejectsInvalidFields(t *testing.T) {
+	ca := issue(t, "ca", nil)
+	client, stop := serve(t, ca, issue(t, "billing", &ca))
+	defer stop()
+
+	_, err := client.Generate(context.Background(), &GenerateRequest{
+		Name:   "billing-db",
+		Fields: []*Field{{Name: "password", Type: "certificate"}},
+	})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument, got %v", err)
+	}
+}
+
+func TestGenerateRejectsOversizedFields(t *testing.T) {
+	ca := issue(t, "ca", nil)
+	client, stop := serve(t, ca, issue(t, "billing", &ca))
+	defer stop()
+
+	_, err := client.Generate(context.Background(), &GenerateRequest{
+		Name:   "billing-db",
+		Fields: []*Field{{Name: "password", Length: 1 << 30}},
+	})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument, got %v", err)
+	}
+}
+
+func TestServerRejectsClientsOfOtherCAs(t *testing.T) {
+	ca := issue(t, "ca", nil)
+	other := issue(t, "other", nil)
+	client, stop := serve(t, ca, issue(t, "billing", &other))
+	defer stop()
+
+	_, err := client.Generate(context.Background(), &GenerateRequest{
+		Name:   "billing-db",
+		Fields: []*Field{{Name: "password"}},
+	})
+	if err == nil {
+		t.Error("expected client with certificate of another CA to be rejected")
+	}
+}
@@ -0,0 +1,99 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+
+package generatorplugin
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// GeneratorClient is the client API for Generator service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type GeneratorClient interface {
+	Generate(ctx context.Context, in *GenerateRequest, opts ...grpc.CallOption) (*GenerateResponse, error)
+}
+
+type generatorClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewGeneratorClient(cc grpc.ClientConnInterface) GeneratorClient {
+	return &generatorClient{cc}
+}
+
+func (c *generatorClient) Generate(ctx context.Context, in *GenerateRequest, opts ...grpc.CallOption) (*GenerateResponse, error) {
+	out := new(GenerateResponse)
+	err := c.cc.Invoke(ctx, "/secretgenerator.plugin.v1.Generator/Generate", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GeneratorServer is the server API for Generator service.
+// All implementations should embed UnimplementedGeneratorServer
+// for forward compatibility
+type GeneratorServer interface {
+	Generate(context.Context, *GenerateRequest) (*GenerateResponse, error)
+}
+
+// UnimplementedGeneratorServer should be embedded to have forward compatible implementations.
+type UnimplementedGeneratorServer struct {
+}
+
+func (UnimplementedGeneratorServer) Generate(context.Context, *GenerateRequest) (*GenerateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Generate not implemented")
+}
+
+// UnsafeGeneratorServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to GeneratorServer will
+// result in compilation errors.
+type UnsafeGeneratorServer interface {
+	mustEmbedUnimplementedGeneratorServer()
+}
+
+func RegisterGeneratorServer(s grpc.ServiceRegistrar, srv GeneratorServer) {
+	s.RegisterService(&Generator_ServiceDesc, srv)
+}
+
+func _Generator_Generate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GenerateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GeneratorServer).Generate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/secretgenerator.plugin.v1.Generator/Generate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GeneratorServer).Generate(ctx, req.(*GenerateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Generator_ServiceDesc is the grpc.ServiceDesc for Generator service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Generator_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "secretgenerator.plugin.v1.Generator",
+	HandlerType: (*GeneratorServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Generate",
+			Handler:    _Generator_Generate_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "generatorplugin/generator.proto",
+}
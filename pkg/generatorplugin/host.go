@@ -0,0 +1,130 @@
+package generatorplugin
+
+import (
+	"fmt"
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/go-plugin"
+	"github.com/prometheus/client_golang/prometheus"
+	"os"
+	"os/exec"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sync"
+)
+
+var (
+	pluginUp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "secret_generator_plugin_up",
+		Help: "Whether the generator plugin of a type is running",
+	}, []string{"type"})
+	pluginStarts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "secret_generator_plugin_starts_total",
+		Help: "Number of times the generator plugin of a type has been started, including restarts after it exited",
+	}, []string{"type"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(pluginUp, pluginStarts)
+}
+
+// Host runs the generator plugins registered for custom types. Plugins are started along with the manager,
+// restarted on the next request if they exited, and killed when the manager stops.
+type Host struct {
+	paths map[string]string
+
+	mu      sync.Mutex
+	clients map[string]*plugin.Client
+	stopped bool
+}
+
+// NewHost returns a host for the plugins at paths, by type
+func NewHost(paths map[string]string) *Host {
+	return &Host{paths: paths, clients: make(map[string]*plugin.Client)}
+}
+
+// Has reports whether a plugin is registered for the type
+func (h *Host) Has(sType string) bool {
+	_, ok := h.paths[sType]
+	return ok
+}
+
+// NeedLeaderElection makes the manager start the plugins on all replicas, as the mutating webhook and
+// the generator API use them as well
+func (h *Host) NeedLeaderElection() bool {
+	return false
+}
+
+// Start starts all plugins and kills them once stop is closed. Plugins failing to start are only logged,
+// starting them is retried on their first request.
+func (h *Host) Start(stop <-chan struct{}) error {
+	for sType := range h.paths {
+		if _, err := h.Generator(sType); err != nil {
+			log.Error(err, "could not start generator plugin", "type", sType)
+		}
+	}
+
+	<-stop
+	h.Kill()
+	return nil
+}
+
+// Kill kills all running plugins. Plugins are not started again afterwards.
+func (h *Host) Kill() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.stopped = true
+	for sType, c := range h.clients {
+		c.Kill()
+		pluginUp.WithLabelValues(sType).Set(0)
+	}
+}
+
+// Generator returns the generator of the plugin registered for the type, starting the plugin if it is not
+// running
+func (h *Host) Generator(sType string) (Generator, error) {
+	path, ok := h.paths[sType]
+	if !ok {
+		return nil, fmt.Errorf("no generator plugin registered for type %s", sType)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.stopped {
+		return nil, fmt.Errorf("generator plugins have been stopped")
+	}
+
+	c, ok := h.clients[sType]
+	if ok && c.Exited() {
+		log.Info("generator plugin exited, restarting it", "type", sType, "path", path)
+		c.Kill()
+		ok = false
+	}
+	if !ok {
+		c = plugin.NewClient(&plugin.ClientConfig{
+			HandshakeConfig:  Handshake,
+			Plugins:          plugin.PluginSet{pluginName: &grpcPlugin{}},
+			Cmd:              exec.Command(path),
+			AllowedProtocols: []plugin.Protocol{plugin.ProtocolGRPC},
+			Logger: hclog.New(&hclog.LoggerOptions{
+				Name:       "plugin." + sType,
+				Output:     os.Stderr,
+				JSONFormat: true,
+			}),
+		})
+		h.clients[sType] = c
+		pluginStarts.WithLabelValues(sType).Inc()
+	}
+
+	rpc, err := c.Client()
+	if err != nil {
+		c.Kill()
+		delete(h.clients, sType)
+		pluginUp.WithLabelValues(sType).Set(0)
+		return nil, fmt.Errorf("could not start generator plugin %s: %w", path, err)
+	}
+	raw, err := rpc.Dispense(pluginName)
+	if err != nil {
+		return nil, err
+	}
+	pluginUp.WithLabelValues(sType).Set(1)
+	return raw.(Generator), nil
+}
@@ -0,0 +1,65 @@
+// Package generatorplugin runs long-running generator plugins using hashicorp/go-plugin. Unlike exec plugins,
+// which are started for every value, they keep their state between requests, e.g. a session with an HSM or a
+// remote CA, and are restarted by the controller if they exit.
+package generatorplugin
+
+// The proto file is registered as generatorplugin/generator.proto, as the generator API has a generator.proto too
+//go:generate protoc -I .. --go_out=.. --go_opt=paths=source_relative --go-grpc_out=.. --go-grpc_opt=paths=source_relative,require_unimplemented_servers=false generatorplugin/generator.proto
+
+import (
+	"context"
+	"github.com/hashicorp/go-plugin"
+	"google.golang.org/grpc"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+var log = logf.Log.WithName("generatorplugin")
+
+// pluginName is the name the generator is dispensed as
+const pluginName = "generator"
+
+// Handshake is shared by the controller and its plugins. It prevents running plugins directly or plugins
+// implementing a different protocol version.
+var Handshake = plugin.HandshakeConfig{
+	ProtocolVersion:  1,
+	MagicCookieKey:   "SECRET_GENERATOR_PLUGIN",
+	MagicCookieValue: "8d3c2f7e-generator",
+}
+
+// Generator generates the values of a field. It is the GeneratorServer of generator.proto.
+type Generator interface {
+	Generate(ctx context.Context, req *GenerateRequest) (*GenerateResponse, error)
+}
+
+// Serve serves the generator as a plugin. It is called from the main function of plugins written in Go.
+func Serve(g Generator) {
+	plugin.Serve(&plugin.ServeConfig{
+		HandshakeConfig: Handshake,
+		Plugins:         plugin.PluginSet{pluginName: &grpcPlugin{impl: g}},
+		GRPCServer:      plugin.DefaultGRPCServer,
+	})
+}
+
+// grpcPlugin implements plugin.GRPCPlugin for the Generator service
+type grpcPlugin struct {
+	plugin.NetRPCUnsupportedPlugin
+	impl Generator
+}
+
+func (p *grpcPlugin) GRPCServer(_ *plugin.GRPCBroker, s *grpc.Server) error {
+	RegisterGeneratorServer(s, p.impl)
+	return nil
+}
+
+func (p *grpcPlugin) GRPCClient(_ context.Context, _ *plugin.GRPCBroker, cc *grpc.ClientConn) (interface{}, error) {
+	return &client{GeneratorClient: NewGeneratorClient(cc)}, nil
+}
+
+// client calls the Generator service of a plugin
+type client struct {
+	GeneratorClient
+}
+
+func (c *client) Generate(ctx context.Context, in *GenerateRequest) (*GenerateResponse, error) {
+	return c.GeneratorClient.Generate(ctx, in)
+}
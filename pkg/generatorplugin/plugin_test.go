@@ -0,0 +1,54 @@
+package generatorplugin
+
+import (
+	"context"
+	"github.com/hashicorp/go-plugin"
+	"testing"
+)
+
+type staticGenerator struct{}
+
+func (staticGenerator) Generate(_ context.Context, req *GenerateRequest) (*GenerateResponse, error) {
+	return &GenerateResponse{Values: map[string][]byte{
+		req.Field:          []byte(req.Namespace + "/" + req.Secret),
+		req.Field + ".pub": []byte(req.Type),
+	}}, nil
+}
+
+func TestGeneratorIsServedOverGRPC(t *testing.T) {
+	c, server := plugin.TestPluginGRPCConn(t, plugin.PluginSet{pluginName: &grpcPlugin{impl: staticGenerator{}}})
+	defer server.Stop()
+	defer c.Close()
+
+	raw, err := c.Dispense(pluginName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := raw.(Generator).Generate(context.Background(), &GenerateRequest{
+		Type:      "hsm",
+		Field:     "key",
+		Namespace: "default",
+		Secret:    "app",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(res.Values["key"]) != "default/app" || string(res.Values["key.pub"]) != "hsm" {
+		t.Errorf("unexpected values %v", res.Values)
+	}
+}
+
+func TestHostRejectsUnregisteredTypes(t *testing.T) {
+	h := NewHost(map[string]string{"hsm": "/plugins/hsm"})
+	if !h.Has("hsm") || h.Has("totp") {
+		t.Error("expected only hsm to be registered")
+	}
+	if _, err := h.Generator("totp"); err == nil {
+		t.Error("expected error for unregistered type")
+	}
+
+	h.Kill()
+	if _, err := h.Generator("hsm"); err == nil {
+		t.Error("expected error after plugins have been killed")
+	}
+}
@@ -0,0 +1,125 @@
+package hibp
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"strings"
+)
+
+// bloomMagic starts every bloom filter file, followed by the number of hash functions as uint32,
+// the number of bits as uint64, both big endian, and the bits
+var bloomMagic = []byte("KSGBLM1\n")
+
+// BloomFilter is a bloom filter of the SHA-1 hashes of breached passwords. It never misses a breached
+// password, but reports a small fraction of other passwords as breached, which are then drawn again.
+type BloomFilter struct {
+	k    uint32
+	m    uint64
+	bits []byte
+}
+
+// NewBloomFilter returns an empty filter for n hashes with a false positive rate of p
+func NewBloomFilter(n uint64, p float64) (*BloomFilter, error) {
+	if n == 0 || p <= 0 || p >= 1 {
+		return nil, errors.New("bloom filter requires at least one hash and a false positive rate between 0 and 1")
+	}
+
+	m := uint64(math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2)))
+	k := uint32(math.Max(1, math.Round(float64(m)/float64(n)*math.Ln2)))
+	return &BloomFilter{k: k, m: m, bits: make([]byte, (m+7)/8)}, nil
+}
+
+// LoadBloomFilter reads a filter written by WriteTo from the file at path
+func LoadBloomFilter(path string) (*BloomFilter, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	header := len(bloomMagic) + 12
+	if len(data) < header || !bytes.Equal(data[:len(bloomMagic)], bloomMagic) {
+		return nil, fmt.Errorf("%s is not a bloom filter", path)
+	}
+	f := &BloomFilter{
+		k:    binary.BigEndian.Uint32(data[len(bloomMagic):]),
+		m:    binary.BigEndian.Uint64(data[len(bloomMagic)+4:]),
+		bits: data[header:],
+	}
+	if f.k == 0 || f.m == 0 || uint64(len(f.bits)) != (f.m+7)/8 {
+		return nil, fmt.Errorf("bloom filter %s is corrupt", path)
+	}
+	return f, nil
+}
+
+// Add adds the SHA-1 hash of a password
+func (f *BloomFilter) Add(hash [sha1.Size]byte) {
+	h1, h2 := f.hashes(hash)
+	for i := uint64(0); i < uint64(f.k); i++ {
+		bit := (h1 + i*h2) % f.m
+		f.bits[bit/8] |= 1 << (bit % 8)
+	}
+}
+
+// Pwned reports whether the hash of the password has been added to the filter
+func (f *BloomFilter) Pwned(password []byte) (bool, error) {
+	h1, h2 := f.hashes(sha1.Sum(password))
+	for i := uint64(0); i < uint64(f.k); i++ {
+		bit := (h1 + i*h2) % f.m
+		if f.bits[bit/8]&(1<<(bit%8)) == 0 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// hashes derives the two hashes of double hashing from the SHA-1 hash, which is uniformly distributed already
+func (f *BloomFilter) hashes(hash [sha1.Size]byte) (uint64, uint64) {
+	return binary.BigEndian.Uint64(hash[0:8]), binary.BigEndian.Uint64(hash[8:16]) | 1
+}
+
+// WriteTo writes the filter in the format read by LoadBloomFilter
+func (f *BloomFilter) WriteTo(w io.Writer) (int64, error) {
+	header := make([]byte, 12)
+	binary.BigEndian.PutUint32(header, f.k)
+	binary.BigEndian.PutUint64(header[4:], f.m)
+
+	var written int64
+	for _, b := range [][]byte{bloomMagic, header, f.bits} {
+		n, err := w.Write(b)
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// AddHashes adds the hashes listed in r, one per line in the format of the downloadable Pwned Passwords
+// files, i.e. the hex encoded SHA-1 hash followed by a colon and the number of occurrences
+func (f *BloomFilter) AddHashes(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if i := strings.IndexByte(line, ':'); i >= 0 {
+			line = line[:i]
+		}
+
+		var hash [sha1.Size]byte
+		if n, err := hex.Decode(hash[:], []byte(line)); err != nil || n != sha1.Size {
+			return fmt.Errorf("invalid SHA-1 hash %q", line)
+		}
+		f.Add(hash)
+	}
+	return scanner.Err()
+}
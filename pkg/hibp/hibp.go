@@ -0,0 +1,88 @@
+// Package hibp checks passwords against the Have I Been Pwned corpus of breached passwords, either using the
+// k-anonymity range API, which only learns the first five characters of the SHA-1 hash of a password, or a
+// bloom filter built from the downloaded hashes, for clusters without internet access
+package hibp
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultURL is the URL of the Pwned Passwords API
+const DefaultURL = "https://api.pwnedpasswords.com"
+
+// Checker reports whether a password appears in the breach corpus
+type Checker interface {
+	Pwned(password []byte) (bool, error)
+}
+
+// Open returns the checker described by source, either the URL of a Pwned Passwords API or the
+// file:// URL of a bloom filter
+func Open(source string) (Checker, error) {
+	u, err := url.Parse(source)
+	if err != nil {
+		return nil, fmt.Errorf("invalid HIBP source %q: %w", source, err)
+	}
+
+	switch u.Scheme {
+	case "https", "http":
+		return NewRangeAPI(strings.TrimSuffix(source, "/")), nil
+	case "file":
+		return LoadBloomFilter(u.Path)
+	}
+	return nil, fmt.Errorf("unsupported HIBP source %q, expected an http(s) or file URL", source)
+}
+
+// RangeAPI checks passwords using the range endpoint of the Pwned Passwords API
+type RangeAPI struct {
+	url    string
+	client *http.Client
+}
+
+// NewRangeAPI returns a RangeAPI for the API at url, e.g. DefaultURL
+func NewRangeAPI(url string) *RangeAPI {
+	return &RangeAPI{url: url, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Pwned requests the suffixes of all hashes sharing the first five characters of the hash of the password
+// and looks up the suffix of the hash. Responses are padded with fake suffixes, so that their size doesn't
+// reveal the prefix either.
+func (a *RangeAPI) Pwned(password []byte) (bool, error) {
+	sum := sha1.Sum(password)
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	req, err := http.NewRequest(http.MethodGet, a.url+"/range/"+hash[:5], nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Add-Padding", "true")
+	req.Header.Set("User-Agent", "kubernetes-secret-generator")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("could not query Pwned Passwords API: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("Pwned Passwords API returned %s", resp.Status)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		parts := strings.SplitN(strings.TrimSpace(scanner.Text()), ":", 2)
+		if len(parts) != 2 || parts[0] != hash[5:] {
+			continue
+		}
+		// padding entries have a count of 0
+		count, err := strconv.Atoi(parts[1])
+		return err == nil && count > 0, nil
+	}
+	return false, scanner.Err()
+}
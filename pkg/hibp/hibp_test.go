@@ -0,0 +1,113 @@
+package hibp
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRangeAPI(t *testing.T) {
+	// SHA-1 of "password" is 5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Add-Padding") != "true" {
+			t.Error("expected padded responses to be requested")
+		}
+		fmt.Fprint(w, "003D68EB55068C33ACE09247EE4C639306B:0\r\n")
+		if r.URL.Path == "/range/5BAA6" {
+			fmt.Fprint(w, "1E4C9B93F3F0682250B6CF8331B7EE68FD8:3861493\r\n")
+		}
+	}))
+	defer server.Close()
+
+	api := NewRangeAPI(server.URL)
+	pwned, err := api.Pwned([]byte("password"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pwned {
+		t.Error("expected password to be pwned")
+	}
+
+	pwned, err = api.Pwned([]byte("5dL4iZ8q2rWx7Tm"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pwned {
+		t.Error("expected random password not to be pwned")
+	}
+}
+
+func TestBloomFilter(t *testing.T) {
+	var hashes bytes.Buffer
+	for i := 0; i < 1000; i++ {
+		sum := sha1.Sum([]byte(fmt.Sprintf("password%d", i)))
+		fmt.Fprintf(&hashes, "%s:%d\n", strings.ToUpper(hex.EncodeToString(sum[:])), i+1)
+	}
+
+	f, err := NewBloomFilter(1000, 0.001)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.AddHashes(&hashes); err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "hibp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "filter")
+
+	var buf bytes.Buffer
+	if _, err := f.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, buf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := Open("file://" + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 1000; i++ {
+		if pwned, _ := loaded.Pwned([]byte(fmt.Sprintf("password%d", i))); !pwned {
+			t.Fatalf("expected password%d to be pwned", i)
+		}
+	}
+
+	falsePositives := 0
+	for i := 0; i < 10000; i++ {
+		if pwned, _ := loaded.Pwned([]byte(fmt.Sprintf("other%d", i))); pwned {
+			falsePositives++
+		}
+	}
+	if falsePositives > 50 {
+		t.Errorf("expected a false positive rate around 0.1%%, got %d of 10000", falsePositives)
+	}
+}
+
+func TestLoadBloomFilterRejectsOtherFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hibp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "filter")
+	if err := ioutil.WriteFile(path, []byte("5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:1\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadBloomFilter(path); err == nil {
+		t.Error("expected an error loading a file that is not a bloom filter")
+	}
+}
@@ -0,0 +1,131 @@
+// Package hook notifies external HTTP endpoints about the rotation of generated secrets
+package hook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Reason describes why a secret is rotated
+type Reason string
+
+const (
+	// ReasonCreated is used when values are generated for the first time
+	ReasonCreated Reason = "Created"
+	// ReasonScheduled is used for rotations triggered by the rotate-after or rotate-schedule annotations
+	ReasonScheduled Reason = "Scheduled"
+	// ReasonRequested is used for rotations triggered by the regenerate annotation
+	ReasonRequested Reason = "Requested"
+	// ReasonBulk is used for rotations requested for many secrets at once, e.g. by a RotationRequest
+	ReasonBulk Reason = "Bulk"
+	// ReasonExpired is used for rotations triggered by the expires-after annotation
+	ReasonExpired Reason = "Expired"
+	// ReasonActivated is used when staged values are activated
+	ReasonActivated Reason = "Activated"
+	// ReasonRolledBack is used when values are restored from the value history by the rollback annotation
+	ReasonRolledBack Reason = "RolledBack"
+	// ReasonUpdated is used when values change for other reasons, e.g. rendered templates
+	ReasonUpdated Reason = "Updated"
+)
+
+// Event is the payload sent to hooks. It must never contain the values of the secret.
+type Event struct {
+	Namespace string            `json:"namespace"`
+	Name      string            `json:"name"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Reason    Reason            `json:"reason"`
+	// Fields lists the keys of the secret, without their values
+	Fields []string `json:"fields,omitempty"`
+}
+
+// Notification is the payload sent to hooks after a rotation
+type Notification struct {
+	Event
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+	// Text summarizes the notification, so it can be posted to Slack incoming webhooks as it is
+	Text string `json:"text"`
+}
+
+// NewNotification returns the notification about the outcome of the rotation described by the event
+func NewNotification(event Event, err error) Notification {
+	n := Notification{
+		Event:   event,
+		Success: err == nil,
+	}
+
+	if err != nil {
+		n.Error = err.Error()
+		n.Text = fmt.Sprintf("Rotation of secret %s/%s failed: %s", event.Namespace, event.Name, n.Error)
+	} else {
+		n.Text = fmt.Sprintf("Secret %s/%s has been rotated (%s)", event.Namespace, event.Name, event.Reason)
+	}
+	return n
+}
+
+// Hook is an HTTP endpoint events are posted to
+type Hook struct {
+	URL     string
+	Timeout time.Duration
+}
+
+// Allow asks the hook whether the secret of the event may be rotated now. Any 2xx response allows the
+// rotation, a 4xx response vetoes it. Other responses are returned as error.
+func (h Hook) Allow(ctx context.Context, event Event) (bool, error) {
+	status, err := h.post(ctx, event)
+	if err != nil {
+		return false, err
+	}
+
+	switch {
+	case status >= 200 && status < 300:
+		return true, nil
+	case status >= 400 && status < 500:
+		return false, nil
+	}
+	return false, fmt.Errorf("pre-rotation hook returned unexpected status %d", status)
+}
+
+// Notify posts the notification to the hook. Any 2xx response is considered successful.
+func (h Hook) Notify(ctx context.Context, n Notification) error {
+	status, err := h.post(ctx, n)
+	if err != nil {
+		return err
+	}
+
+	if status < 200 || status >= 300 {
+		return fmt.Errorf("post-rotation hook returned unexpected status %d", status)
+	}
+	return nil
+}
+
+func (h Hook) post(ctx context.Context, payload interface{}) (int, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return 0, err
+	}
+
+	if h.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.Timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequest(http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	return res.StatusCode, nil
+}
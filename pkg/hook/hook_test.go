@@ -0,0 +1,70 @@
+package hook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newTestServer(t *testing.T, status int, received *Event) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(received))
+		w.WriteHeader(status)
+	}))
+}
+
+func TestAllow(t *testing.T) {
+	event := Event{Namespace: "default", Name: "db", Reason: ReasonScheduled, Fields: []string{"password"}}
+
+	received := Event{}
+	srv := newTestServer(t, http.StatusOK, &received)
+	defer srv.Close()
+
+	allowed, err := Hook{URL: srv.URL}.Allow(context.TODO(), event)
+	require.NoError(t, err)
+	require.True(t, allowed)
+	require.Equal(t, event, received)
+}
+
+func TestVeto(t *testing.T) {
+	received := Event{}
+	srv := newTestServer(t, http.StatusConflict, &received)
+	defer srv.Close()
+
+	allowed, err := Hook{URL: srv.URL}.Allow(context.TODO(), Event{Name: "db"})
+	require.NoError(t, err)
+	require.False(t, allowed)
+}
+
+func TestUnexpectedStatus(t *testing.T) {
+	received := Event{}
+	srv := newTestServer(t, http.StatusInternalServerError, &received)
+	defer srv.Close()
+
+	_, err := Hook{URL: srv.URL}.Allow(context.TODO(), Event{Name: "db"})
+	require.Error(t, err)
+}
+
+func TestNotify(t *testing.T) {
+	event := Event{Namespace: "default", Name: "db", Reason: ReasonRequested}
+
+	var received map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+	}))
+	defer srv.Close()
+
+	require.NoError(t, Hook{URL: srv.URL}.Notify(context.TODO(), NewNotification(event, nil)))
+	require.Equal(t, "db", received["name"])
+	require.Equal(t, true, received["success"])
+	require.Equal(t, "Secret default/db has been rotated (Requested)", received["text"])
+
+	require.NoError(t, Hook{URL: srv.URL}.Notify(context.TODO(), NewNotification(event, errors.New("boom"))))
+	require.Equal(t, false, received["success"])
+	require.Equal(t, "boom", received["error"])
+}
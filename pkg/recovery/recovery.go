@@ -0,0 +1,55 @@
+// Package recovery keeps a panic while reconciling a single object from crashing the whole controller
+package recovery
+
+import (
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/record"
+	"runtime/debug"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var log = logf.Log.WithName("recovery")
+
+var panics = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "secret_generator_reconcile_panics_total",
+	Help: "Number of reconciles that panicked, by controller",
+}, []string{"controller"})
+
+func init() {
+	metrics.Registry.MustRegister(panics)
+}
+
+// Recover wraps the reconciler of the named controller, turning panics into reconcile errors. The
+// panic is counted, logged with its stack and reported as a warning event on the reconciled object of
+// the given kind. As with any other error, the request is requeued with backoff.
+func Recover(controller string, kind schema.GroupVersionKind, recorder record.EventRecorder, r reconcile.Reconciler) reconcile.Reconciler {
+	return reconcile.Func(func(request reconcile.Request) (result reconcile.Result, err error) {
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+
+			panics.WithLabelValues(controller).Inc()
+			err = fmt.Errorf("reconcile panicked: %v", p)
+			log.Error(err, "recovered from panic", "controller", controller,
+				"Request.Namespace", request.Namespace, "Request.Name", request.Name, "stack", string(debug.Stack()))
+
+			// The object may not be fetchable, so the event refers to it by name only
+			ref := &corev1.ObjectReference{
+				APIVersion: kind.GroupVersion().String(),
+				Kind:       kind.Kind,
+				Namespace:  request.Namespace,
+				Name:       request.Name,
+			}
+			recorder.Eventf(ref, corev1.EventTypeWarning, "ReconcilePanic", "%s: %v", controller, p)
+			result = reconcile.Result{}
+		}()
+		return r.Reconcile(request)
+	})
+}
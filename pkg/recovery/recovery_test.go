@@ -0,0 +1,52 @@
+package recovery
+
+import (
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"strings"
+	"testing"
+)
+
+func TestRecoverTurnsPanicsIntoErrors(t *testing.T) {
+	recorder := record.NewFakeRecorder(1)
+	r := Recover("test-controller", corev1.SchemeGroupVersion.WithKind("Secret"), recorder,
+		reconcile.Func(func(reconcile.Request) (reconcile.Result, error) {
+			var data map[string][]byte
+			data["boom"] = nil
+			return reconcile.Result{}, nil
+		}))
+
+	before := testutil.ToFloat64(panics.WithLabelValues("test-controller"))
+	_, err := r.Reconcile(reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "broken"}})
+	if err == nil {
+		t.Fatal("expected the panic to be returned as an error")
+	}
+
+	if got := testutil.ToFloat64(panics.WithLabelValues("test-controller")); got != before+1 {
+		t.Errorf("expected the panic to be counted, got %v", got-before)
+	}
+
+	select {
+	case event := <-recorder.Events:
+		if !strings.Contains(event, "ReconcilePanic") {
+			t.Errorf("unexpected event %q", event)
+		}
+	default:
+		t.Error("expected a warning event")
+	}
+}
+
+func TestRecoverPassesResults(t *testing.T) {
+	r := Recover("test-controller", corev1.SchemeGroupVersion.WithKind("Secret"), record.NewFakeRecorder(1),
+		reconcile.Func(func(reconcile.Request) (reconcile.Result, error) {
+			return reconcile.Result{Requeue: true}, nil
+		}))
+
+	res, err := r.Reconcile(reconcile.Request{})
+	if err != nil || !res.Requeue {
+		t.Error("expected the result of the reconciler to be passed through")
+	}
+}
@@ -0,0 +1,96 @@
+package resilience
+
+import (
+	"context"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Client returns a client retrying calls failing with transient errors and failing fast while the
+// API server is overloaded
+func Client(c client.Client) client.Client {
+	return &resilientClient{Client: c}
+}
+
+// Reader returns a reader retrying calls like Client
+func Reader(r client.Reader) client.Reader {
+	return &resilientReader{Reader: r}
+}
+
+type resilientReader struct {
+	client.Reader
+}
+
+func (r *resilientReader) Get(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+	return do(ctx, func() error {
+		return r.Reader.Get(ctx, key, obj)
+	})
+}
+
+func (r *resilientReader) List(ctx context.Context, list runtime.Object, opts ...client.ListOption) error {
+	return do(ctx, func() error {
+		return r.Reader.List(ctx, list, opts...)
+	})
+}
+
+type resilientClient struct {
+	client.Client
+}
+
+func (c *resilientClient) Get(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+	return (&resilientReader{Reader: c.Client}).Get(ctx, key, obj)
+}
+
+func (c *resilientClient) List(ctx context.Context, list runtime.Object, opts ...client.ListOption) error {
+	return (&resilientReader{Reader: c.Client}).List(ctx, list, opts...)
+}
+
+func (c *resilientClient) Create(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+	return do(ctx, func() error {
+		return c.Client.Create(ctx, obj, opts...)
+	})
+}
+
+func (c *resilientClient) Delete(ctx context.Context, obj runtime.Object, opts ...client.DeleteOption) error {
+	return do(ctx, func() error {
+		return c.Client.Delete(ctx, obj, opts...)
+	})
+}
+
+func (c *resilientClient) Update(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+	return do(ctx, func() error {
+		return c.Client.Update(ctx, obj, opts...)
+	})
+}
+
+func (c *resilientClient) Patch(ctx context.Context, obj runtime.Object, patch client.Patch, opts ...client.PatchOption) error {
+	return do(ctx, func() error {
+		return c.Client.Patch(ctx, obj, patch, opts...)
+	})
+}
+
+func (c *resilientClient) DeleteAllOf(ctx context.Context, obj runtime.Object, opts ...client.DeleteAllOfOption) error {
+	return do(ctx, func() error {
+		return c.Client.DeleteAllOf(ctx, obj, opts...)
+	})
+}
+
+func (c *resilientClient) Status() client.StatusWriter {
+	return &resilientStatusWriter{StatusWriter: c.Client.Status()}
+}
+
+type resilientStatusWriter struct {
+	client.StatusWriter
+}
+
+func (w *resilientStatusWriter) Update(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+	return do(ctx, func() error {
+		return w.StatusWriter.Update(ctx, obj, opts...)
+	})
+}
+
+func (w *resilientStatusWriter) Patch(ctx context.Context, obj runtime.Object, patch client.Patch, opts ...client.PatchOption) error {
+	return do(ctx, func() error {
+		return w.StatusWriter.Patch(ctx, obj, patch, opts...)
+	})
+}
@@ -0,0 +1,139 @@
+// Package resilience retries transient API errors and backs off cluster-wide while the API server is overloaded
+package resilience
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sync"
+	"time"
+)
+
+var log = logf.Log.WithName("resilience")
+
+const (
+	// attempts is the number of times a call failing with a transient error is made
+	attempts = 3
+	// retryDelay is the delay before the first retry, doubled for every further retry
+	retryDelay = 200 * time.Millisecond
+)
+
+var (
+	mu        sync.Mutex
+	threshold = 5
+	cooldown  = 30 * time.Second
+	failures  int
+	openUntil time.Time
+
+	now = time.Now
+)
+
+var breakerOpen = prometheus.NewGauge(prometheus.GaugeOpts{
+	Name: "secret_generator_circuit_breaker_open",
+	Help: "Whether API calls are suspended because the API server is overloaded",
+})
+
+func init() {
+	metrics.Registry.MustRegister(breakerOpen)
+}
+
+// Configure sets the number of consecutive calls failing due to an overloaded API server after which
+// all calls are suspended for cooldown. A threshold of 0 disables the circuit breaker.
+func Configure(consecutiveFailures int, cooldownPeriod time.Duration) {
+	mu.Lock()
+	defer mu.Unlock()
+	threshold = consecutiveFailures
+	cooldown = cooldownPeriod
+}
+
+// Guard wraps the reconciler, requeueing reconciles until the circuit breaker closes instead of
+// letting them fail on their first API call
+func Guard(r reconcile.Reconciler) reconcile.Reconciler {
+	return reconcile.Func(func(request reconcile.Request) (reconcile.Result, error) {
+		if remaining := remainingCooldown(); remaining > 0 {
+			return reconcile.Result{RequeueAfter: jitter(remaining)}, nil
+		}
+		return r.Reconcile(request)
+	})
+}
+
+// errCircuitOpen is returned by calls made while the circuit breaker is open
+var errCircuitOpen = errors.NewServiceUnavailable("API calls are suspended while the API server is overloaded")
+
+// remainingCooldown returns the time until the circuit breaker closes again, or 0 if it is closed
+func remainingCooldown() time.Duration {
+	mu.Lock()
+	defer mu.Unlock()
+	return openUntil.Sub(now())
+}
+
+// record updates the circuit breaker with the result of a call. Once open, the breaker lets calls pass
+// again after the cooldown, but reopens on the first call still failing.
+func record(err error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if !overloaded(err) {
+		failures = 0
+		breakerOpen.Set(0)
+		return
+	}
+
+	failures++
+	if threshold > 0 && failures >= threshold {
+		if !openUntil.After(now()) {
+			log.Info("API server is overloaded, suspending API calls", "failures", failures, "cooldown", cooldown)
+		}
+		openUntil = now().Add(cooldown)
+		breakerOpen.Set(1)
+	}
+}
+
+// overloaded returns whether err indicates that the API server is overloaded
+func overloaded(err error) bool {
+	return errors.IsTooManyRequests(err) || errors.IsServiceUnavailable(err) ||
+		errors.IsServerTimeout(err) || errors.IsTimeout(err)
+}
+
+// transient returns whether a call failing with err may succeed when retried
+func transient(err error) bool {
+	return overloaded(err) || errors.IsInternalError(err)
+}
+
+// jitter spreads d by up to its own length, so that requests backing off at the same time don't
+// hit the API server at the same time again
+func jitter(d time.Duration) time.Duration {
+	return wait.Jitter(d, 1.0)
+}
+
+// do makes the call, retrying transient errors with jittered exponential backoff. Calls fail immediately
+// while the circuit breaker is open.
+func do(ctx context.Context, call func() error) error {
+	delay := retryDelay
+	for attempt := 1; ; attempt++ {
+		if remainingCooldown() > 0 {
+			return errCircuitOpen
+		}
+
+		err := call()
+		record(err)
+		if err == nil || !transient(err) || attempt == attempts {
+			return err
+		}
+
+		backoff := jitter(delay)
+		if seconds, ok := errors.SuggestsClientDelay(err); ok && time.Duration(seconds)*time.Second > backoff {
+			backoff = time.Duration(seconds) * time.Second
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		delay *= 2
+	}
+}
@@ -0,0 +1,94 @@
+package resilience
+
+import (
+	"context"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"testing"
+	"time"
+)
+
+var secrets = schema.GroupResource{Resource: "secrets"}
+
+// reset closes the circuit breaker and configures it for a test, returning a func restoring the defaults
+func reset(consecutiveFailures int) func() {
+	Configure(consecutiveFailures, time.Minute)
+	failures = 0
+	openUntil = time.Time{}
+	return func() {
+		Configure(5, 30*time.Second)
+		failures = 0
+		openUntil = time.Time{}
+	}
+}
+
+func TestDoRetriesTransientErrors(t *testing.T) {
+	defer reset(0)()
+
+	calls := 0
+	err := do(context.Background(), func() error {
+		calls++
+		if calls == 1 {
+			return errors.NewInternalError(context.DeadlineExceeded)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("expected the retry to succeed, got %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("expected 2 calls, got %d", calls)
+	}
+}
+
+func TestDoDoesNotRetryPermanentErrors(t *testing.T) {
+	defer reset(0)()
+
+	calls := 0
+	err := do(context.Background(), func() error {
+		calls++
+		return errors.NewNotFound(secrets, "missing")
+	})
+	if !errors.IsNotFound(err) {
+		t.Errorf("expected the error of the call, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected a single call, got %d", calls)
+	}
+}
+
+func TestBreakerOpensWhenOverloaded(t *testing.T) {
+	defer reset(2)()
+
+	calls := 0
+	err := do(context.Background(), func() error {
+		calls++
+		return errors.NewTooManyRequests("overloaded", 0)
+	})
+	if err != errCircuitOpen {
+		t.Errorf("expected the breaker to open, got %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("expected 2 calls before the breaker opened, got %d", calls)
+	}
+
+	reconciled := false
+	res, err := Guard(reconcile.Func(func(reconcile.Request) (reconcile.Result, error) {
+		reconciled = true
+		return reconcile.Result{}, nil
+	})).Reconcile(reconcile.Request{})
+	if err != nil || reconciled || res.RequeueAfter < time.Minute-time.Second {
+		t.Error("expected reconciles to be requeued until the cooldown has passed")
+	}
+
+	// Once the cooldown has passed, a single failing call reopens the breaker
+	now = func() time.Time { return time.Now().Add(time.Minute) }
+	defer func() { now = time.Now }()
+	err = do(context.Background(), func() error {
+		return errors.NewServiceUnavailable("still overloaded")
+	})
+	if err != errCircuitOpen || remainingCooldown() <= 0 {
+		t.Errorf("expected the breaker to reopen, got %v", err)
+	}
+}
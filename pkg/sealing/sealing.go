@@ -0,0 +1,125 @@
+// Package sealing encrypts secrets into SealedSecrets of the sealed-secrets controller
+package sealing
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"io"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// APIVersion and Kind of SealedSecrets
+	APIVersion = "bitnami.com/v1alpha1"
+	Kind       = "SealedSecret"
+
+	// AnnotationManaged allows the sealed-secrets controller to take over an existing secret
+	AnnotationManaged = "sealedsecrets.bitnami.com/managed"
+	// LabelSealingKey is set to active on the secrets holding the current sealing keys of the cluster
+	LabelSealingKey = "sealedsecrets.bitnami.com/sealed-secrets-key"
+)
+
+// SealedSecret is a SealedSecret of the sealed-secrets controller, which decrypts it into a secret in the cluster
+type SealedSecret struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+	Spec              SealedSecretSpec `json:"spec"`
+}
+
+type SealedSecretSpec struct {
+	Template      SealedSecretTemplate `json:"template"`
+	EncryptedData map[string]string    `json:"encryptedData"`
+}
+
+type SealedSecretTemplate struct {
+	metav1.ObjectMeta `json:"metadata"`
+	Type              corev1.SecretType `json:"type,omitempty"`
+}
+
+// PublicKey returns the public key of the sealed-secrets controller from its PEM encoded certificate,
+// as printed by "kubeseal --fetch-cert"
+func PublicKey(data []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM encoded certificate found")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	key, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("certificate does not contain an RSA public key")
+	}
+	return key, nil
+}
+
+// Seal encrypts the values of the secret for the sealed-secrets controller, scoped to the namespace and name
+// of the secret
+func Seal(s *corev1.Secret, key *rsa.PublicKey) (*SealedSecret, error) {
+	sealed := &SealedSecret{
+		TypeMeta:   metav1.TypeMeta{APIVersion: APIVersion, Kind: Kind},
+		ObjectMeta: metav1.ObjectMeta{Name: s.Name, Namespace: s.Namespace},
+		Spec: SealedSecretSpec{
+			Template: SealedSecretTemplate{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        s.Name,
+					Namespace:   s.Namespace,
+					Labels:      s.Labels,
+					Annotations: s.Annotations,
+				},
+				Type: s.Type,
+			},
+			EncryptedData: map[string]string{},
+		},
+	}
+
+	label := []byte(s.Namespace + "/" + s.Name)
+	for k, v := range s.Data {
+		ciphertext, err := hybridEncrypt(rand.Reader, key, v, label)
+		if err != nil {
+			return nil, err
+		}
+		sealed.Spec.EncryptedData[k] = base64.StdEncoding.EncodeToString(ciphertext)
+	}
+	return sealed, nil
+}
+
+// hybridEncrypt encrypts plaintext the way the sealed-secrets controller expects: a random AES-256 session key,
+// encrypted using RSA-OAEP with the label, is prepended by its length to the AES-GCM encrypted plaintext.
+// The session key is never reused, so a zero nonce is safe.
+func hybridEncrypt(rnd io.Reader, key *rsa.PublicKey, plaintext, label []byte) ([]byte, error) {
+	sessionKey := make([]byte, 32)
+	if _, err := io.ReadFull(rnd, sessionKey); err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(sessionKey)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	encryptedKey, err := rsa.EncryptOAEP(sha256.New(), rnd, key, sessionKey, label)
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertext := make([]byte, 2, 2+len(encryptedKey)+len(plaintext)+aead.Overhead())
+	binary.BigEndian.PutUint16(ciphertext, uint16(len(encryptedKey)))
+	ciphertext = append(ciphertext, encryptedKey...)
+	return aead.Seal(ciphertext, make([]byte, aead.NonceSize()), plaintext, nil), nil
+}
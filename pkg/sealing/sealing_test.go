@@ -0,0 +1,56 @@
+package sealing
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+)
+
+func TestSealEncryptsValuesForTheSecret(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "my-app", Name: "database"},
+		Data:       map[string][]byte{"password": []byte("secret")},
+	}
+
+	sealed, err := Seal(s, &key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(sealed.Spec.EncryptedData["password"])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// decrypt the way the sealed-secrets controller does
+	keyLength := int(binary.BigEndian.Uint16(ciphertext))
+	sessionKey, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, key, ciphertext[2:2+keyLength], []byte("my-app/database"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := aes.NewCipher(sessionKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext, err := aead.Open(nil, make([]byte, aead.NonceSize()), ciphertext[2+keyLength:], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(plaintext) != "secret" {
+		t.Errorf("expected the sealed value to decrypt to the secret value, got %q", plaintext)
+	}
+}
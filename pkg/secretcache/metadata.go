@@ -0,0 +1,75 @@
+package secretcache
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/rest"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"time"
+)
+
+// NewMetadataCacheFunc wraps newCache like NewCacheFunc, but watches only the metadata of secrets. Getting a
+// secret fetch returns true for reads the full secret from the apiserver, other secrets are returned without
+// their data. Neither the data of secrets is kept in memory, nor is it transferred by the watch.
+func NewMetadataCacheFunc(newCache cache.NewCacheFunc, opts Options, fetch KeepFunc) cache.NewCacheFunc {
+	return wrap(newCache, func(config *rest.Config, resync time.Duration) (*secretCache, error) {
+		metadataClient, err := metadata.NewForConfig(config)
+		if err != nil {
+			return nil, err
+		}
+
+		clientset, err := kubernetes.NewForConfig(config)
+		if err != nil {
+			return nil, err
+		}
+
+		c := newSecretCache(opts.Namespaces, func(namespace string) toolscache.SharedIndexInformer {
+			return newMetadataInformer(metadataClient, namespace, opts.LabelSelector, resync)
+		})
+		c.clientset = clientset
+		c.fetch = fetch
+		return c, nil
+	})
+}
+
+// newMetadataInformer returns an informer watching the secrets in namespace matching selector as
+// PartialObjectMetadata, storing them as secrets without data
+func newMetadataInformer(metadataClient metadata.Interface, namespace, selector string, resync time.Duration) toolscache.SharedIndexInformer {
+	secrets := metadataClient.Resource(corev1.SchemeGroupVersion.WithResource("secrets")).Namespace(namespace)
+
+	lw := &toolscache.ListWatch{
+		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			opts.LabelSelector = selector
+			list, err := secrets.List(opts)
+			if err != nil {
+				return nil, err
+			}
+
+			secretList := &corev1.SecretList{ListMeta: list.ListMeta}
+			for i := range list.Items {
+				secretList.Items = append(secretList.Items, corev1.Secret{ObjectMeta: list.Items[i].ObjectMeta})
+			}
+			return secretList, nil
+		},
+		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			opts.LabelSelector = selector
+			w, err := secrets.Watch(opts)
+			if err != nil {
+				return nil, err
+			}
+			return watch.Filter(w, func(event watch.Event) (watch.Event, bool) {
+				if partial, ok := event.Object.(*metav1.PartialObjectMetadata); ok {
+					event.Object = &corev1.Secret{ObjectMeta: partial.ObjectMeta}
+				}
+				return event, true
+			}), nil
+		},
+	}
+
+	return newInstrumentedInformer(lw, namespace, resync)
+}
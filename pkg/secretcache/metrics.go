@@ -0,0 +1,91 @@
+package secretcache
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"time"
+)
+
+var (
+	watchRestarts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "secret_generator_watch_restarts_total",
+		Help: "Number of times the watch on secrets was re-established after the initial one",
+	}, []string{"namespace"})
+
+	listDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "secret_generator_list_duration_seconds",
+		Help:    "Time taken to list secrets from the apiserver",
+		Buckets: prometheus.ExponentialBuckets(0.01, 2, 12),
+	}, []string{"namespace"})
+
+	cachedSecrets = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "secret_generator_cached_secrets",
+		Help: "Number of secrets in the cache",
+	}, []string{"namespace"})
+
+	lastSync = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "secret_generator_last_sync_timestamp_seconds",
+		Help: "Unix time of the last successful list or watch event of secrets",
+	}, []string{"namespace"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(watchRestarts, listDuration, cachedSecrets, lastSync)
+}
+
+// instrument wraps the list and watch functions of lw, recording the health of the watch on namespace
+func instrument(lw *toolscache.ListWatch, namespace string) *toolscache.ListWatch {
+	list, watchFunc := lw.ListFunc, lw.WatchFunc
+	watching := false
+
+	return &toolscache.ListWatch{
+		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			start := time.Now()
+			obj, err := list(opts)
+			if err != nil {
+				return nil, err
+			}
+			listDuration.WithLabelValues(namespace).Observe(time.Since(start).Seconds())
+			lastSync.WithLabelValues(namespace).SetToCurrentTime()
+			return obj, nil
+		},
+		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			// the reflector calls this sequentially, no synchronization needed
+			if watching {
+				watchRestarts.WithLabelValues(namespace).Inc()
+			}
+			watching = true
+
+			w, err := watchFunc(opts)
+			if err != nil {
+				return nil, err
+			}
+			return watch.Filter(w, func(event watch.Event) (watch.Event, bool) {
+				if event.Type != watch.Error {
+					lastSync.WithLabelValues(namespace).SetToCurrentTime()
+				}
+				return event, true
+			}), nil
+		},
+	}
+}
+
+// newInstrumentedInformer returns an informer for the secrets in namespace listed and watched by lw,
+// exporting metrics about its watch and store
+func newInstrumentedInformer(lw *toolscache.ListWatch, namespace string, resync time.Duration) toolscache.SharedIndexInformer {
+	informer := toolscache.NewSharedIndexInformer(instrument(lw, namespace), &corev1.Secret{}, resync, toolscache.Indexers{
+		toolscache.NamespaceIndex: toolscache.MetaNamespaceIndexFunc,
+	})
+
+	size := cachedSecrets.WithLabelValues(namespace)
+	informer.AddEventHandler(toolscache.ResourceEventHandlerFuncs{
+		AddFunc:    func(interface{}) { size.Inc() },
+		DeleteFunc: func(interface{}) { size.Dec() },
+	})
+	return informer
+}
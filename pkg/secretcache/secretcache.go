@@ -0,0 +1,317 @@
+// Package secretcache provides a cache for the manager which doesn't keep the data of secrets the controllers
+// don't need, reducing the memory footprint on clusters with many or large secrets
+package secretcache
+
+import (
+	"context"
+	"fmt"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"time"
+)
+
+// defaultResync matches the resync period of the controller-runtime cache
+const defaultResync = 10 * time.Hour
+
+var secretGVK = corev1.SchemeGroupVersion.WithKind("Secret")
+
+// KeepFunc reports whether the data of a secret is to be kept in the cache
+type KeepFunc func(secret *corev1.Secret) bool
+
+// Options configures which secrets are watched
+type Options struct {
+	// Namespaces are the namespaces secrets are watched in, with the empty string watching all namespaces
+	Namespaces []string
+	// LabelSelector restricts the watch to secrets matching it, so other secrets are never delivered
+	LabelSelector string
+}
+
+// KeepAll keeps the data of all secrets
+func KeepAll(*corev1.Secret) bool {
+	return true
+}
+
+// NewCacheFunc wraps newCache, serving secrets from informers which drop the data of secrets keep returns
+// false for before they are stored. All other types are served by the cache returned by newCache.
+func NewCacheFunc(newCache cache.NewCacheFunc, opts Options, keep KeepFunc) cache.NewCacheFunc {
+	return wrap(newCache, func(config *rest.Config, resync time.Duration) (*secretCache, error) {
+		clientset, err := kubernetes.NewForConfig(config)
+		if err != nil {
+			return nil, err
+		}
+
+		return newSecretCache(opts.Namespaces, func(namespace string) toolscache.SharedIndexInformer {
+			return newSecretInformer(clientset, namespace, opts.LabelSelector, resync, keep)
+		}), nil
+	})
+}
+
+// wrap returns a NewCacheFunc serving secrets from the cache returned by newSecrets and everything else
+// from the cache returned by newCache
+func wrap(newCache cache.NewCacheFunc, newSecrets func(config *rest.Config, resync time.Duration) (*secretCache, error)) cache.NewCacheFunc {
+	if newCache == nil {
+		newCache = cache.New
+	}
+
+	return func(config *rest.Config, opts cache.Options) (cache.Cache, error) {
+		delegate, err := newCache(config, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		resync := defaultResync
+		if opts.Resync != nil {
+			resync = *opts.Resync
+		}
+
+		c, err := newSecrets(config, resync)
+		if err != nil {
+			return nil, err
+		}
+		c.Cache = delegate
+		return c, nil
+	}
+}
+
+type secretCache struct {
+	cache.Cache
+	informers map[string]toolscache.SharedIndexInformer
+
+	// clientset is used to get the full secret from the apiserver if fetch returns true for the cached one
+	clientset kubernetes.Interface
+	fetch     KeepFunc
+}
+
+func newSecretCache(namespaces []string, newInformer func(namespace string) toolscache.SharedIndexInformer) *secretCache {
+	if len(namespaces) == 0 {
+		namespaces = []string{metav1.NamespaceAll}
+	}
+
+	c := &secretCache{
+		informers: make(map[string]toolscache.SharedIndexInformer),
+	}
+	for _, namespace := range namespaces {
+		c.informers[namespace] = newInformer(namespace)
+	}
+	return c
+}
+
+// newSecretInformer returns an informer for the secrets in namespace matching selector which strips the
+// data of listed and watched secrets before they reach its store
+func newSecretInformer(clientset kubernetes.Interface, namespace, selector string, resync time.Duration, keep KeepFunc) toolscache.SharedIndexInformer {
+	secrets := clientset.CoreV1().Secrets(namespace)
+
+	lw := &toolscache.ListWatch{
+		ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+			opts.LabelSelector = selector
+			list, err := secrets.List(opts)
+			if err != nil {
+				return nil, err
+			}
+			for i := range list.Items {
+				strip(&list.Items[i], keep)
+			}
+			return list, nil
+		},
+		WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+			opts.LabelSelector = selector
+			w, err := secrets.Watch(opts)
+			if err != nil {
+				return nil, err
+			}
+			return watch.Filter(w, func(event watch.Event) (watch.Event, bool) {
+				if secret, ok := event.Object.(*corev1.Secret); ok {
+					strip(secret, keep)
+				}
+				return event, true
+			}), nil
+		},
+	}
+
+	return newInstrumentedInformer(lw, namespace, resync)
+}
+
+// strip removes the data of secret unless it is to be kept
+func strip(secret *corev1.Secret, keep KeepFunc) {
+	if keep(secret) {
+		return
+	}
+	secret.Data = nil
+	secret.StringData = nil
+}
+
+func isSecret(obj runtime.Object) bool {
+	switch obj.(type) {
+	case *corev1.Secret, *corev1.SecretList:
+		return true
+	}
+	return false
+}
+
+// informerFor returns the informer watching namespace, if any
+func (c *secretCache) informerFor(namespace string) (toolscache.SharedIndexInformer, bool) {
+	if informer, ok := c.informers[metav1.NamespaceAll]; ok {
+		return informer, true
+	}
+	informer, ok := c.informers[namespace]
+	return informer, ok
+}
+
+func (c *secretCache) Get(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+	out, ok := obj.(*corev1.Secret)
+	if !ok {
+		return c.Cache.Get(ctx, key, obj)
+	}
+
+	informer, ok := c.informerFor(key.Namespace)
+	if !ok {
+		return fmt.Errorf("unable to get secret %s: namespace %s is not watched", key.Name, key.Namespace)
+	}
+
+	item, exists, err := informer.GetIndexer().GetByKey(key.String())
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errors.NewNotFound(corev1.Resource("secrets"), key.Name)
+	}
+
+	secret := item.(*corev1.Secret)
+	if c.fetch != nil && c.fetch(secret) {
+		secret, err = c.clientset.CoreV1().Secrets(key.Namespace).Get(key.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+	}
+
+	secret.DeepCopyInto(out)
+	out.SetGroupVersionKind(secretGVK)
+	return nil
+}
+
+func (c *secretCache) List(ctx context.Context, list runtime.Object, opts ...client.ListOption) error {
+	out, ok := list.(*corev1.SecretList)
+	if !ok {
+		return c.Cache.List(ctx, list, opts...)
+	}
+
+	listOpts := client.ListOptions{}
+	listOpts.ApplyOptions(opts)
+	if listOpts.FieldSelector != nil && !listOpts.FieldSelector.Empty() {
+		return fmt.Errorf("field selectors are not supported when listing secrets")
+	}
+
+	var items []interface{}
+	for namespace, informer := range c.informers {
+		if listOpts.Namespace != metav1.NamespaceAll && namespace != metav1.NamespaceAll && namespace != listOpts.Namespace {
+			continue
+		}
+
+		if listOpts.Namespace == metav1.NamespaceAll {
+			items = append(items, informer.GetIndexer().List()...)
+			continue
+		}
+
+		found, err := informer.GetIndexer().ByIndex(toolscache.NamespaceIndex, listOpts.Namespace)
+		if err != nil {
+			return err
+		}
+		items = append(items, found...)
+	}
+
+	out.Items = make([]corev1.Secret, 0, len(items))
+	for _, item := range items {
+		secret := item.(*corev1.Secret)
+		if listOpts.LabelSelector != nil && !listOpts.LabelSelector.Matches(labels.Set(secret.Labels)) {
+			continue
+		}
+		out.Items = append(out.Items, *secret.DeepCopy())
+	}
+	return nil
+}
+
+func (c *secretCache) GetInformer(obj runtime.Object) (cache.Informer, error) {
+	if !isSecret(obj) {
+		return c.Cache.GetInformer(obj)
+	}
+	return c.secretInformers(), nil
+}
+
+func (c *secretCache) GetInformerForKind(gvk schema.GroupVersionKind) (cache.Informer, error) {
+	if gvk != secretGVK && gvk != corev1.SchemeGroupVersion.WithKind("SecretList") {
+		return c.Cache.GetInformerForKind(gvk)
+	}
+	return c.secretInformers(), nil
+}
+
+func (c *secretCache) IndexField(obj runtime.Object, field string, extractValue client.IndexerFunc) error {
+	if !isSecret(obj) {
+		return c.Cache.IndexField(obj, field, extractValue)
+	}
+	return fmt.Errorf("field indexes are not supported for secrets")
+}
+
+func (c *secretCache) Start(stop <-chan struct{}) error {
+	for _, informer := range c.informers {
+		go informer.Run(stop)
+	}
+	return c.Cache.Start(stop)
+}
+
+func (c *secretCache) WaitForCacheSync(stop <-chan struct{}) bool {
+	if !toolscache.WaitForCacheSync(stop, c.secretInformers().HasSynced) {
+		return false
+	}
+	return c.Cache.WaitForCacheSync(stop)
+}
+
+func (c *secretCache) secretInformers() informers {
+	var list informers
+	for _, informer := range c.informers {
+		list = append(list, informer)
+	}
+	return list
+}
+
+// informers combines the secret informers of all watched namespaces
+type informers []toolscache.SharedIndexInformer
+
+func (i informers) AddEventHandler(handler toolscache.ResourceEventHandler) {
+	for _, informer := range i {
+		informer.AddEventHandler(handler)
+	}
+}
+
+func (i informers) AddEventHandlerWithResyncPeriod(handler toolscache.ResourceEventHandler, resyncPeriod time.Duration) {
+	for _, informer := range i {
+		informer.AddEventHandlerWithResyncPeriod(handler, resyncPeriod)
+	}
+}
+
+func (i informers) AddIndexers(indexers toolscache.Indexers) error {
+	for _, informer := range i {
+		if err := informer.AddIndexers(indexers); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (i informers) HasSynced() bool {
+	for _, informer := range i {
+		if !informer.HasSynced() {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,194 @@
+package secretcache
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/fake"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"testing"
+	"time"
+)
+
+func keepAnnotated(secret *corev1.Secret) bool {
+	return secret.Annotations["keep"] == "yes"
+}
+
+func newTestSecret(namespace, name string, keep bool) *corev1.Secret {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+			Labels:    map[string]string{"app": name},
+		},
+		Data: map[string][]byte{"password": []byte("secret")},
+	}
+	if keep {
+		secret.Annotations = map[string]string{"keep": "yes"}
+	}
+	return secret
+}
+
+func startTestCache(t *testing.T, namespaces []string, secrets ...*corev1.Secret) (*secretCache, chan struct{}) {
+	var objects []runtime.Object
+	for _, secret := range secrets {
+		objects = append(objects, secret)
+	}
+
+	clientset := fake.NewSimpleClientset(objects...)
+	c := newSecretCache(namespaces, func(namespace string) toolscache.SharedIndexInformer {
+		return newSecretInformer(clientset, namespace, "", time.Hour, keepAnnotated)
+	})
+	stop := make(chan struct{})
+	for _, informer := range c.informers {
+		go informer.Run(stop)
+	}
+	if !toolscache.WaitForCacheSync(stop, c.secretInformers().HasSynced) {
+		t.Fatal("secret informers did not sync")
+	}
+	return c, stop
+}
+
+func TestStripsDataOfUnkeptSecrets(t *testing.T) {
+	c, stop := startTestCache(t, nil, newTestSecret("default", "kept", true), newTestSecret("default", "stripped", false))
+	defer close(stop)
+
+	kept := &corev1.Secret{}
+	if err := c.Get(context.TODO(), client.ObjectKey{Namespace: "default", Name: "kept"}, kept); err != nil {
+		t.Fatal(err)
+	}
+	if string(kept.Data["password"]) != "secret" {
+		t.Errorf("expected data of kept secret to be cached, got %v", kept.Data)
+	}
+
+	stripped := &corev1.Secret{}
+	if err := c.Get(context.TODO(), client.ObjectKey{Namespace: "default", Name: "stripped"}, stripped); err != nil {
+		t.Fatal(err)
+	}
+	if len(stripped.Data) != 0 {
+		t.Errorf("expected data of secret to be stripped, got %v", stripped.Data)
+	}
+	if stripped.Labels["app"] != "stripped" {
+		t.Errorf("expected metadata of stripped secret to be cached")
+	}
+}
+
+func TestGetMissingSecret(t *testing.T) {
+	c, stop := startTestCache(t, nil)
+	defer close(stop)
+
+	err := c.Get(context.TODO(), client.ObjectKey{Namespace: "default", Name: "missing"}, &corev1.Secret{})
+	if !errors.IsNotFound(err) {
+		t.Errorf("expected not found error, got %v", err)
+	}
+}
+
+func TestListSecrets(t *testing.T) {
+	c, stop := startTestCache(t, []string{"ns1", "ns2"},
+		newTestSecret("ns1", "a", false),
+		newTestSecret("ns1", "b", true),
+		newTestSecret("ns2", "c", false),
+		newTestSecret("ns3", "d", false),
+	)
+	defer close(stop)
+
+	list := &corev1.SecretList{}
+	if err := c.List(context.TODO(), list, client.InNamespace("ns1")); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Items) != 2 {
+		t.Errorf("expected 2 secrets in ns1, got %d", len(list.Items))
+	}
+
+	list = &corev1.SecretList{}
+	if err := c.List(context.TODO(), list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Items) != 3 {
+		t.Errorf("expected 3 secrets in watched namespaces, got %d", len(list.Items))
+	}
+
+	list = &corev1.SecretList{}
+	if err := c.List(context.TODO(), list, client.MatchingLabels{"app": "c"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Items) != 1 || list.Items[0].Name != "c" {
+		t.Errorf("expected only secret c to match labels, got %v", list.Items)
+	}
+}
+
+func TestGetFetchesFullSecret(t *testing.T) {
+	clientset := fake.NewSimpleClientset(newTestSecret("default", "managed", true), newTestSecret("default", "unmanaged", false))
+	c := newSecretCache(nil, func(namespace string) toolscache.SharedIndexInformer {
+		// the informer of the metadata-only watch stores secrets without data
+		return newSecretInformer(clientset, namespace, "", time.Hour, func(*corev1.Secret) bool { return false })
+	})
+	c.clientset = clientset
+	c.fetch = keepAnnotated
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go c.informers[metav1.NamespaceAll].Run(stop)
+	if !toolscache.WaitForCacheSync(stop, c.secretInformers().HasSynced) {
+		t.Fatal("secret informers did not sync")
+	}
+
+	managed := &corev1.Secret{}
+	if err := c.Get(context.TODO(), client.ObjectKey{Namespace: "default", Name: "managed"}, managed); err != nil {
+		t.Fatal(err)
+	}
+	if string(managed.Data["password"]) != "secret" {
+		t.Errorf("expected full secret to be fetched, got %v", managed.Data)
+	}
+
+	unmanaged := &corev1.Secret{}
+	if err := c.Get(context.TODO(), client.ObjectKey{Namespace: "default", Name: "unmanaged"}, unmanaged); err != nil {
+		t.Fatal(err)
+	}
+	if len(unmanaged.Data) != 0 {
+		t.Errorf("expected only metadata of unmanaged secret, got %v", unmanaged.Data)
+	}
+}
+
+func TestLabelSelector(t *testing.T) {
+	clientset := fake.NewSimpleClientset(newTestSecret("default", "a", false), newTestSecret("default", "b", false))
+	c := newSecretCache(nil, func(namespace string) toolscache.SharedIndexInformer {
+		return newSecretInformer(clientset, namespace, "app=a", time.Hour, KeepAll)
+	})
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go c.informers[metav1.NamespaceAll].Run(stop)
+	if !toolscache.WaitForCacheSync(stop, c.secretInformers().HasSynced) {
+		t.Fatal("secret informers did not sync")
+	}
+
+	list := &corev1.SecretList{}
+	if err := c.List(context.TODO(), list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Items) != 1 || list.Items[0].Name != "a" {
+		t.Errorf("expected only secret a to be watched, got %v", list.Items)
+	}
+}
+
+func TestExportsCacheMetrics(t *testing.T) {
+	_, stop := startTestCache(t, []string{"metrics"}, newTestSecret("metrics", "first", false), newTestSecret("metrics", "second", false))
+	defer close(stop)
+
+	// event handlers are notified asynchronously after the store has synced
+	err := wait.PollImmediate(10*time.Millisecond, time.Second, func() (bool, error) {
+		return testutil.ToFloat64(cachedSecrets.WithLabelValues("metrics")) == 2, nil
+	})
+	if err != nil {
+		t.Errorf("expected 2 cached secrets, got %v", testutil.ToFloat64(cachedSecrets.WithLabelValues("metrics")))
+	}
+	if synced := testutil.ToFloat64(lastSync.WithLabelValues("metrics")); synced == 0 {
+		t.Errorf("expected last sync timestamp to be set")
+	}
+}
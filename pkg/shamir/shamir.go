@@ -0,0 +1,144 @@
+// Package shamir splits secrets into shares using Shamir's secret sharing over GF(2^8). A share holds the
+// y coordinates of all bytes of the secret followed by its x coordinate, the format used by HashiCorp Vault.
+package shamir
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+// Split divides the secret into the given number of shares, any threshold of which reconstruct it
+func Split(secret []byte, shares, threshold int) ([][]byte, error) {
+	if len(secret) == 0 {
+		return nil, errors.New("cannot split an empty secret")
+	}
+	if threshold < 2 || threshold > shares {
+		return nil, fmt.Errorf("threshold must be between 2 and the number of shares, got %d of %d", threshold, shares)
+	}
+	if shares > 255 {
+		return nil, fmt.Errorf("at most 255 shares are supported, got %d", shares)
+	}
+
+	xs, err := coordinates(shares)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([][]byte, shares)
+	for i := range out {
+		out[i] = make([]byte, len(secret)+1)
+		out[i][len(secret)] = xs[i]
+	}
+
+	coefficients := make([]byte, threshold)
+	for j, b := range secret {
+		// a random polynomial of degree threshold-1 whose intercept is the byte of the secret
+		if _, err := rand.Read(coefficients[1:]); err != nil {
+			return nil, err
+		}
+		coefficients[0] = b
+		for i, x := range xs {
+			out[i][j] = evaluate(coefficients, x)
+		}
+	}
+	return out, nil
+}
+
+// Combine reconstructs the secret from at least the threshold of its shares
+func Combine(shares [][]byte) ([]byte, error) {
+	if len(shares) < 2 {
+		return nil, errors.New("at least two shares are required")
+	}
+	length := len(shares[0])
+	if length < 2 {
+		return nil, errors.New("shares are too short")
+	}
+
+	xs := make([]byte, len(shares))
+	seen := make(map[byte]bool, len(shares))
+	for i, share := range shares {
+		if len(share) != length {
+			return nil, errors.New("shares differ in length")
+		}
+		xs[i] = share[length-1]
+		if xs[i] == 0 || seen[xs[i]] {
+			return nil, errors.New("shares have invalid or duplicate coordinates")
+		}
+		seen[xs[i]] = true
+	}
+
+	secret := make([]byte, length-1)
+	ys := make([]byte, len(shares))
+	for j := range secret {
+		for i, share := range shares {
+			ys[i] = share[j]
+		}
+		secret[j] = interpolate(xs, ys)
+	}
+	return secret, nil
+}
+
+// coordinates returns distinct random non-zero x coordinates
+func coordinates(n int) ([]byte, error) {
+	// shuffle 1..255 and use the first n
+	all := make([]byte, 255)
+	for i := range all {
+		all[i] = byte(i + 1)
+	}
+	r := make([]byte, 255)
+	if _, err := rand.Read(r); err != nil {
+		return nil, err
+	}
+	for i := len(all) - 1; i > 0; i-- {
+		j := int(r[i]) % (i + 1)
+		all[i], all[j] = all[j], all[i]
+	}
+	return all[:n], nil
+}
+
+// evaluate returns the value of the polynomial at x using Horner's method
+func evaluate(coefficients []byte, x byte) byte {
+	var y byte
+	for i := len(coefficients) - 1; i >= 0; i-- {
+		y = mul(y, x) ^ coefficients[i]
+	}
+	return y
+}
+
+// interpolate returns the value at 0 of the polynomial through the points using Lagrange interpolation
+func interpolate(xs, ys []byte) byte {
+	var result byte
+	for i := range xs {
+		basis := byte(1)
+		for j := range xs {
+			if i == j {
+				continue
+			}
+			// subtraction is addition (xor) in GF(2^8)
+			basis = mul(basis, div(xs[j], xs[i]^xs[j]))
+		}
+		result ^= mul(ys[i], basis)
+	}
+	return result
+}
+
+// mul multiplies in GF(2^8) with the AES polynomial x^8 + x^4 + x^3 + x + 1, without branching on the operands
+func mul(a, b byte) byte {
+	var p byte
+	for i := 0; i < 8; i++ {
+		p ^= a & -(b & 1)
+		a = a<<1 ^ 0x1b&-(a>>7)
+		b >>= 1
+	}
+	return p
+}
+
+// div divides a by the non-zero b, using b^254 as the inverse of b
+func div(a, b byte) byte {
+	inverse := b
+	for i := 0; i < 253; i++ {
+		inverse = mul(inverse, b)
+	}
+	return mul(a, inverse)
+}
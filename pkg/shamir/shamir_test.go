@@ -0,0 +1,57 @@
+package shamir
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSplitAndCombine(t *testing.T) {
+	secret := []byte("root of trust")
+	shares, err := Split(secret, 5, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shares) != 5 {
+		t.Fatalf("expected 5 shares, got %d", len(shares))
+	}
+
+	for _, subset := range [][]int{{0, 1, 2}, {4, 2, 0}, {1, 2, 3, 4}} {
+		var parts [][]byte
+		for _, i := range subset {
+			parts = append(parts, shares[i])
+		}
+		combined, err := Combine(parts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(secret, combined) {
+			t.Errorf("expected shares %v to combine to the secret, got %q", subset, combined)
+		}
+	}
+
+	combined, err := Combine(shares[:2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(secret, combined) {
+		t.Error("expected less than threshold shares not to reveal the secret")
+	}
+}
+
+func TestSplitRejectsInvalidThreshold(t *testing.T) {
+	for _, tt := range []struct{ shares, threshold int }{{3, 1}, {3, 4}, {256, 2}} {
+		if _, err := Split([]byte("secret"), tt.shares, tt.threshold); err == nil {
+			t.Errorf("expected %d of %d shares to be rejected", tt.threshold, tt.shares)
+		}
+	}
+}
+
+func TestMulDiv(t *testing.T) {
+	for a := 0; a < 256; a++ {
+		for b := 1; b < 256; b++ {
+			if got := div(mul(byte(a), byte(b)), byte(b)); got != byte(a) {
+				t.Fatalf("expected %d*%d/%d to be %d, got %d", a, b, b, a, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package sharding
+
+import (
+	"fmt"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	coordinationclient "k8s.io/client-go/kubernetes/typed/coordination/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"time"
+)
+
+var log = logf.Log.WithName("sharding")
+
+// Options configures how replicas coordinate the shards they hold
+type Options struct {
+	// Shards is the number of shards namespaces are distributed on
+	Shards int
+	// ShardsPerReplica is the maximum number of shards held by a single replica
+	ShardsPerReplica int
+	// Namespace and Name are the namespace and name prefix of the leases, named <Name>-<shard>
+	Namespace string
+	Name      string
+	// Identity identifies this replica as holder of leases
+	Identity string
+	// LeaseDuration is the time after its last renewal at which other replicas may take over a shard
+	LeaseDuration time.Duration
+	// RenewDeadline is the time after its last renewal at which this replica stops reconciling a shard
+	RenewDeadline time.Duration
+	// RetryPeriod is the interval in which leases are renewed and free shards are acquired
+	RetryPeriod time.Duration
+	// OnAcquired is called whenever a shard was acquired, e.g. to queue the objects of its namespaces
+	OnAcquired func(shard int)
+}
+
+// Coordinator holds the leases of shards on behalf of this replica
+type Coordinator struct {
+	client  coordinationclient.LeasesGetter
+	options Options
+}
+
+// NewCoordinator returns a coordinator acquiring shards using leases, and enables sharding. It must be
+// added to a manager, on which it runs on every replica regardless of leader election.
+func NewCoordinator(client coordinationclient.LeasesGetter, options Options) *Coordinator {
+	mu.Lock()
+	defer mu.Unlock()
+	shards = options.Shards
+	renewDeadline = options.RenewDeadline
+
+	return &Coordinator{client: client, options: options}
+}
+
+// NeedLeaderElection makes the manager run the coordinator on all replicas
+func (c *Coordinator) NeedLeaderElection() bool {
+	return false
+}
+
+// Start renews the leases of held shards and acquires free shards until stop is closed, and then releases
+// the held shards, so that other replicas can take over without waiting for the leases to expire
+func (c *Coordinator) Start(stop <-chan struct{}) error {
+	wait.Until(c.sync, c.options.RetryPeriod, stop)
+
+	for shard := 0; shard < c.options.Shards; shard++ {
+		if holds(shard) {
+			release(shard)
+			c.releaseLease(shard)
+		}
+	}
+	return nil
+}
+
+// sync renews the leases of held shards before acquiring free ones, so that they don't expire meanwhile
+func (c *Coordinator) sync() {
+	for shard := 0; shard < c.options.Shards; shard++ {
+		if holds(shard) && !c.acquire(shard) {
+			release(shard)
+			log.Info("lost shard", "shard", shard)
+		}
+	}
+
+	// Replicas look for free shards starting at different offsets, so that replicas starting
+	// at the same time don't contend for the same shards
+	offset := ShardOf(c.options.Identity, c.options.Shards)
+	for i := 0; i < c.options.Shards && heldCount() < c.options.ShardsPerReplica; i++ {
+		shard := (offset + i) % c.options.Shards
+		if holds(shard) || !c.acquire(shard) {
+			continue
+		}
+
+		log.Info("acquired shard", "shard", shard)
+		if c.options.OnAcquired != nil {
+			go c.options.OnAcquired(shard)
+		}
+	}
+}
+
+func (c *Coordinator) leaseName(shard int) string {
+	return fmt.Sprintf("%s-%d", c.options.Name, shard)
+}
+
+// acquire creates, renews or takes over the lease of the shard. It fails if the lease is held by another replica
+// and has not expired, or if the lease was changed concurrently.
+func (c *Coordinator) acquire(shard int) bool {
+	leases := c.client.Leases(c.options.Namespace)
+	current := metav1.NewMicroTime(now())
+	duration := int32(c.options.LeaseDuration.Seconds())
+
+	lease, err := leases.Get(c.leaseName(shard), metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		lease = &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{Namespace: c.options.Namespace, Name: c.leaseName(shard)},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       &c.options.Identity,
+				LeaseDurationSeconds: &duration,
+				AcquireTime:          &current,
+				RenewTime:            &current,
+			},
+		}
+		if _, err := leases.Create(lease); err != nil {
+			log.V(1).Info("could not create lease", "shard", shard, "error", err.Error())
+			return false
+		}
+		hold(shard)
+		return true
+	}
+	if err != nil {
+		log.Error(err, "could not get lease", "shard", shard)
+		return false
+	}
+
+	spec := &lease.Spec
+	held := spec.HolderIdentity != nil && *spec.HolderIdentity == c.options.Identity
+	if !held && !expired(spec) {
+		return false
+	}
+
+	if !held {
+		transitions := int32(1)
+		if spec.LeaseTransitions != nil {
+			transitions = *spec.LeaseTransitions + 1
+		}
+		spec.HolderIdentity = &c.options.Identity
+		spec.AcquireTime = &current
+		spec.LeaseTransitions = &transitions
+	}
+	spec.LeaseDurationSeconds = &duration
+	spec.RenewTime = &current
+
+	// Updates fail with a conflict if another replica took over the lease since it was read
+	if _, err := leases.Update(lease); err != nil {
+		log.V(1).Info("could not update lease", "shard", shard, "error", err.Error())
+		return false
+	}
+	hold(shard)
+	return true
+}
+
+// releaseLease clears the holder of the lease of the shard
+func (c *Coordinator) releaseLease(shard int) {
+	leases := c.client.Leases(c.options.Namespace)
+	lease, err := leases.Get(c.leaseName(shard), metav1.GetOptions{})
+	if err != nil || lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity != c.options.Identity {
+		return
+	}
+
+	lease.Spec.HolderIdentity = nil
+	if _, err := leases.Update(lease); err != nil {
+		log.Error(err, "could not release lease", "shard", shard)
+	}
+}
+
+// expired returns whether the lease has no holder or was not renewed within its duration
+func expired(spec *coordinationv1.LeaseSpec) bool {
+	if spec.HolderIdentity == nil || *spec.HolderIdentity == "" || spec.RenewTime == nil || spec.LeaseDurationSeconds == nil {
+		return true
+	}
+	return now().After(spec.RenewTime.Add(time.Duration(*spec.LeaseDurationSeconds) * time.Second))
+}
@@ -0,0 +1,100 @@
+// Package sharding distributes namespaces between replicas of the controller. Namespaces are hashed onto a fixed
+// number of shards, and each replica reconciles the namespaces of the shards whose lease it holds.
+package sharding
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"hash/fnv"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sync"
+	"time"
+)
+
+var (
+	mu     sync.RWMutex
+	shards int
+	// held maps the shards held by this replica to the time their lease was last renewed
+	held = map[int]time.Time{}
+	// renewDeadline is the time after the last renewal at which a shard is no longer considered held
+	renewDeadline time.Duration
+
+	now = time.Now
+)
+
+var shardsHeld = prometheus.NewGauge(prometheus.GaugeOpts{
+	Name: "secret_generator_shards_held",
+	Help: "Number of shards whose namespaces are reconciled by this replica",
+})
+
+func init() {
+	metrics.Registry.MustRegister(shardsHeld)
+}
+
+// Enabled returns whether namespaces are sharded between replicas
+func Enabled() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return shards > 0
+}
+
+// ShardOf returns the shard the namespace belongs to. Cluster-scoped objects belong to the shard of the empty namespace.
+func ShardOf(namespace string, count int) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(namespace))
+	return int(h.Sum32() % uint32(count))
+}
+
+// Owns returns whether objects of the namespace are reconciled by this replica, which is always the case
+// if sharding is disabled
+func Owns(namespace string) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	if shards == 0 {
+		return true
+	}
+
+	renewed, ok := held[ShardOf(namespace, shards)]
+	return ok && now().Sub(renewed) < renewDeadline
+}
+
+// Filter wraps the reconciler, skipping requests for namespaces of shards held by other replicas
+func Filter(r reconcile.Reconciler) reconcile.Reconciler {
+	return reconcile.Func(func(request reconcile.Request) (reconcile.Result, error) {
+		if !Owns(request.Namespace) {
+			return reconcile.Result{}, nil
+		}
+		return r.Reconcile(request)
+	})
+}
+
+// hold records that the lease of the shard was renewed
+func hold(shard int) {
+	mu.Lock()
+	defer mu.Unlock()
+	held[shard] = now()
+	shardsHeld.Set(float64(len(held)))
+}
+
+// release records that the shard is no longer held
+func release(shard int) {
+	mu.Lock()
+	defer mu.Unlock()
+	delete(held, shard)
+	shardsHeld.Set(float64(len(held)))
+}
+
+// holds returns whether the shard is held
+func holds(shard int) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	_, ok := held[shard]
+	return ok
+}
+
+// heldCount returns the number of shards held
+func heldCount() int {
+	mu.RLock()
+	defer mu.RUnlock()
+	return len(held)
+}
@@ -0,0 +1,116 @@
+package sharding
+
+import (
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// newTestCoordinator returns a coordinator distributing 4 shards, and a func disabling sharding again
+func newTestCoordinator(clientset *fake.Clientset, identity string) (*Coordinator, func()) {
+	c := NewCoordinator(clientset.CoordinationV1(), Options{
+		Shards:           4,
+		ShardsPerReplica: 2,
+		Namespace:        "secret-generator",
+		Name:             "shard",
+		Identity:         identity,
+		LeaseDuration:    15 * time.Second,
+		RenewDeadline:    10 * time.Second,
+		RetryPeriod:      2 * time.Second,
+	})
+	return c, func() {
+		mu.Lock()
+		defer mu.Unlock()
+		shards = 0
+		held = map[int]time.Time{}
+	}
+}
+
+func TestCoordinatorAcquiresFreeShards(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	c, reset := newTestCoordinator(clientset, "replica-1")
+	defer reset()
+
+	c.sync()
+	if heldCount() != 2 {
+		t.Fatalf("expected 2 shards to be held, got %d", heldCount())
+	}
+
+	leases, err := clientset.CoordinationV1().Leases("secret-generator").List(metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, lease := range leases.Items {
+		if *lease.Spec.HolderIdentity != "replica-1" {
+			t.Errorf("expected lease %s to be held by replica-1, got %s", lease.Name, *lease.Spec.HolderIdentity)
+		}
+	}
+
+	for _, namespace := range []string{"default", "kube-system", "team-a", "team-b", ""} {
+		if Owns(namespace) != holds(ShardOf(namespace, 4)) {
+			t.Errorf("expected ownership of namespace %q to follow its shard", namespace)
+		}
+	}
+}
+
+func TestCoordinatorRespectsLeasesOfOtherReplicas(t *testing.T) {
+	other := "replica-2"
+	duration := int32(15)
+	renewed := metav1.NewMicroTime(time.Now())
+
+	var objects []runtime.Object
+	for shard := 0; shard < 3; shard++ {
+		objects = append(objects, &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "secret-generator", Name: "shard-" + strconv.Itoa(shard)},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       &other,
+				LeaseDurationSeconds: &duration,
+				RenewTime:            &renewed,
+			},
+		})
+	}
+	clientset := fake.NewSimpleClientset(objects...)
+	c, reset := newTestCoordinator(clientset, "replica-1")
+	defer reset()
+
+	c.sync()
+	if heldCount() != 1 || !holds(3) {
+		t.Errorf("expected only the free shard to be acquired, got %v", held)
+	}
+
+	// Leases not renewed within their duration are taken over
+	now = func() time.Time { return time.Now().Add(time.Minute) }
+	defer func() { now = time.Now }()
+	c.sync()
+	if heldCount() != 2 {
+		t.Errorf("expected an expired shard to be taken over, got %v", held)
+	}
+}
+
+func TestFilterSkipsNamespacesOfOtherShards(t *testing.T) {
+	_, reset := newTestCoordinator(fake.NewSimpleClientset(), "replica-1")
+	defer reset()
+	hold(ShardOf("mine", 4))
+
+	reconciled := map[string]bool{}
+	r := Filter(reconcile.Func(func(request reconcile.Request) (reconcile.Result, error) {
+		reconciled[request.Namespace] = true
+		return reconcile.Result{}, nil
+	}))
+
+	for _, namespace := range []string{"mine", "theirs", "others"} {
+		_, _ = r.Reconcile(reconcile.Request{NamespacedName: types.NamespacedName{Namespace: namespace, Name: "secret"}})
+		if reconciled[namespace] != Owns(namespace) {
+			t.Errorf("expected namespace %q to be reconciled only if its shard is held", namespace)
+		}
+	}
+	if !reconciled["mine"] {
+		t.Error("expected the namespace of the held shard to be reconciled")
+	}
+}
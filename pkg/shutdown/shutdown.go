@@ -0,0 +1,72 @@
+// Package shutdown tracks in-flight reconciles, so that they can finish before the controller exits
+package shutdown
+
+import (
+	"context"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sync"
+	"time"
+)
+
+var (
+	mu       sync.Mutex
+	idle     = sync.NewCond(&mu)
+	inflight int
+	draining bool
+
+	ctx, cancel = context.WithCancel(context.Background())
+)
+
+// Context returns the context of reconciles, which is canceled once the grace period passed to Drain
+// has expired, aborting API calls still in flight
+func Context() context.Context {
+	return ctx
+}
+
+// Track wraps the reconciler, recording its reconciles as in flight. Once draining has started, new
+// reconciles are requeued without calling the reconciler.
+func Track(r reconcile.Reconciler) reconcile.Reconciler {
+	return reconcile.Func(func(request reconcile.Request) (reconcile.Result, error) {
+		mu.Lock()
+		if draining {
+			mu.Unlock()
+			return reconcile.Result{Requeue: true}, nil
+		}
+		inflight++
+		mu.Unlock()
+
+		defer func() {
+			mu.Lock()
+			inflight--
+			if inflight == 0 {
+				idle.Broadcast()
+			}
+			mu.Unlock()
+		}()
+		return r.Reconcile(request)
+	})
+}
+
+// Drain stops new reconciles from starting and waits up to gracePeriod for those in flight to finish.
+// It returns false if reconciles were still in flight when the grace period expired, in which case
+// their context is canceled.
+func Drain(gracePeriod time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		mu.Lock()
+		draining = true
+		for inflight > 0 {
+			idle.Wait()
+		}
+		mu.Unlock()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(gracePeriod):
+		cancel()
+		return false
+	}
+}
@@ -0,0 +1,41 @@
+package shutdown
+
+import (
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"testing"
+	"time"
+)
+
+func TestDrainWaitsForInflightReconciles(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	r := Track(reconcile.Func(func(reconcile.Request) (reconcile.Result, error) {
+		close(started)
+		<-release
+		return reconcile.Result{}, nil
+	}))
+
+	go r.Reconcile(reconcile.Request{})
+	<-started
+
+	drained := make(chan bool)
+	go func() {
+		drained <- Drain(time.Second)
+	}()
+
+	select {
+	case <-drained:
+		t.Fatal("expected drain to wait for the reconcile in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	if !<-drained {
+		t.Error("expected reconciles to be drained within the grace period")
+	}
+
+	res, err := r.Reconcile(reconcile.Request{})
+	if err != nil || !res.Requeue {
+		t.Error("expected reconciles started while draining to be requeued")
+	}
+}
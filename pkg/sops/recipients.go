@@ -0,0 +1,151 @@
+package sops
+
+import (
+	"bytes"
+	"encoding/base64"
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+	"golang.org/x/crypto/openpgp"
+	pgparmor "golang.org/x/crypto/openpgp/armor"
+	"io"
+	"strings"
+	"time"
+)
+
+type ageKey struct {
+	Recipient string `json:"recipient"`
+	Enc       string `json:"enc"`
+}
+
+type pgpKey struct {
+	CreatedAt   string `json:"created_at"`
+	Enc         string `json:"enc"`
+	Fingerprint string `json:"fp"`
+}
+
+type kmsKey struct {
+	ARN        string `json:"arn"`
+	CreatedAt  string `json:"created_at"`
+	Enc        string `json:"enc"`
+	AWSProfile string `json:"aws_profile"`
+}
+
+type ageRecipient struct {
+	recipient string
+	key       age.Recipient
+}
+
+// AgeRecipients parses the comma-separated list of age X25519 public keys
+func AgeRecipients(list string) ([]Recipient, error) {
+	var recipients []Recipient
+	for _, r := range strings.Split(list, ",") {
+		if r = strings.TrimSpace(r); r == "" {
+			continue
+		}
+		key, err := age.ParseX25519Recipient(r)
+		if err != nil {
+			return nil, fmt.Errorf("invalid age recipient %q: %w", r, err)
+		}
+		recipients = append(recipients, &ageRecipient{recipient: r, key: key})
+	}
+	return recipients, nil
+}
+
+func (r *ageRecipient) add(m *metadata, dataKey []byte, _ time.Time) error {
+	var buf bytes.Buffer
+	armored := armor.NewWriter(&buf)
+	w, err := age.Encrypt(armored, r.key)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(dataKey); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	if err := armored.Close(); err != nil {
+		return err
+	}
+
+	m.Age = append(m.Age, ageKey{Recipient: r.recipient, Enc: buf.String()})
+	return nil
+}
+
+type pgpRecipient struct {
+	entity *openpgp.Entity
+}
+
+// PGPRecipients reads the armored public keys of the PGP recipients
+func PGPRecipients(keyring io.Reader) ([]Recipient, error) {
+	entities, err := openpgp.ReadArmoredKeyRing(keyring)
+	if err != nil {
+		return nil, fmt.Errorf("invalid PGP keyring: %w", err)
+	}
+
+	recipients := make([]Recipient, 0, len(entities))
+	for _, e := range entities {
+		recipients = append(recipients, &pgpRecipient{entity: e})
+	}
+	return recipients, nil
+}
+
+func (r *pgpRecipient) add(m *metadata, dataKey []byte, now time.Time) error {
+	var buf bytes.Buffer
+	armored, err := pgparmor.Encode(&buf, "PGP MESSAGE", nil)
+	if err != nil {
+		return err
+	}
+	w, err := openpgp.Encrypt(armored, []*openpgp.Entity{r.entity}, nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(dataKey); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	if err := armored.Close(); err != nil {
+		return err
+	}
+
+	m.PGP = append(m.PGP, pgpKey{
+		CreatedAt:   now.Format(time.RFC3339),
+		Enc:         buf.String(),
+		Fingerprint: fmt.Sprintf("%X", r.entity.PrimaryKey.Fingerprint),
+	})
+	return nil
+}
+
+type kmsRecipient struct {
+	client kmsiface.KMSAPI
+	arn    string
+}
+
+// KMSRecipient encrypts the data key using the AWS KMS key with the ARN, the client has to be configured
+// for the region of the key
+func KMSRecipient(client kmsiface.KMSAPI, arn string) Recipient {
+	return &kmsRecipient{client: client, arn: arn}
+}
+
+func (r *kmsRecipient) add(m *metadata, dataKey []byte, now time.Time) error {
+	out, err := r.client.Encrypt(&kms.EncryptInput{
+		KeyId:     aws.String(r.arn),
+		Plaintext: dataKey,
+	})
+	if err != nil {
+		return fmt.Errorf("could not encrypt data key using KMS key %s: %w", r.arn, err)
+	}
+
+	m.KMS = append(m.KMS, kmsKey{
+		ARN:       r.arn,
+		CreatedAt: now.Format(time.RFC3339),
+		Enc:       base64.StdEncoding.EncodeToString(out.CiphertextBlob),
+	})
+	return nil
+}
@@ -0,0 +1,162 @@
+// Package sops encrypts secret manifests in the format of Mozilla SOPS, so that they can be decrypted using
+// "sops -d" by any of the recipients without the secret generator
+package sops
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	corev1 "k8s.io/api/core/v1"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// Version is the SOPS version the encrypted documents are compatible with
+	Version = "3.7.3"
+	// EncryptedRegex limits encryption to the values of secrets, keeping their metadata readable
+	EncryptedRegex = "^(data|stringData)$"
+
+	nonceSize = 32
+)
+
+// Recipient can decrypt the data key of encrypted documents
+type Recipient interface {
+	// add encrypts the data key for the recipient and adds it to the metadata
+	add(m *metadata, dataKey []byte, now time.Time) error
+}
+
+// document is a secret manifest encrypted by SOPS. The order of the fields is the order SOPS reads the values
+// in when verifying the MAC.
+type document struct {
+	APIVersion string            `json:"apiVersion"`
+	Kind       string            `json:"kind"`
+	Metadata   objectMeta        `json:"metadata"`
+	Type       corev1.SecretType `json:"type,omitempty"`
+	Data       map[string]string `json:"data"`
+	Sops       *metadata         `json:"sops"`
+}
+
+type objectMeta struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+}
+
+// metadata holds the encrypted data keys and the MAC of a document
+type metadata struct {
+	KMS            []kmsKey `json:"kms"`
+	Age            []ageKey `json:"age"`
+	PGP            []pgpKey `json:"pgp"`
+	LastModified   string   `json:"lastmodified"`
+	MAC            string   `json:"mac"`
+	EncryptedRegex string   `json:"encrypted_regex"`
+	Version        string   `json:"version"`
+}
+
+// Encrypt returns the secret as JSON manifest whose values are encrypted with a random data key, which is
+// encrypted for each of the recipients
+func Encrypt(secret *corev1.Secret, recipients []Recipient, now time.Time) ([]byte, error) {
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("no recipients to encrypt secret %s/%s for", secret.Namespace, secret.Name)
+	}
+
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, err
+	}
+
+	now = now.UTC()
+	doc := &document{
+		APIVersion: "v1",
+		Kind:       "Secret",
+		Metadata:   objectMeta{Name: secret.Name, Namespace: secret.Namespace},
+		Type:       secret.Type,
+		Data:       make(map[string]string, len(secret.Data)),
+		Sops: &metadata{
+			KMS:            []kmsKey{},
+			Age:            []ageKey{},
+			PGP:            []pgpKey{},
+			LastModified:   now.Format(time.RFC3339),
+			EncryptedRegex: EncryptedRegex,
+			Version:        Version,
+		},
+	}
+
+	// SOPS hashes all values, encrypted or not, in the order of the document
+	mac := sha512.New()
+	mac.Write([]byte(doc.APIVersion))
+	mac.Write([]byte(doc.Kind))
+	mac.Write([]byte(doc.Metadata.Name))
+	mac.Write([]byte(doc.Metadata.Namespace))
+	if doc.Type != "" {
+		mac.Write([]byte(doc.Type))
+	}
+
+	keys := make([]string, 0, len(secret.Data))
+	for k := range secret.Data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		value := base64.StdEncoding.EncodeToString(secret.Data[k])
+		mac.Write([]byte(value))
+
+		encrypted, err := encryptValue(dataKey, value, strings.Join([]string{"data", k}, ":")+":")
+		if err != nil {
+			return nil, err
+		}
+		doc.Data[k] = encrypted
+	}
+
+	var err error
+	doc.Sops.MAC, err = encryptValue(dataKey, fmt.Sprintf("%X", mac.Sum(nil)), doc.Sops.LastModified)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range recipients {
+		if err := r.add(doc.Sops, dataKey, now); err != nil {
+			return nil, err
+		}
+	}
+
+	var out bytes.Buffer
+	enc := json.NewEncoder(&out)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// encryptValue encrypts a string value like SOPS does, using AES-GCM with a random 32 byte nonce and the path
+// of the value as additional data
+func encryptValue(key []byte, value, additionalData string) (string, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	aead, err := cipher.NewGCMWithNonceSize(block, nonceSize)
+	if err != nil {
+		return "", err
+	}
+
+	iv := make([]byte, nonceSize)
+	if _, err := rand.Read(iv); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nil, iv, []byte(value), []byte(additionalData))
+	data, tag := sealed[:len(sealed)-aead.Overhead()], sealed[len(sealed)-aead.Overhead():]
+
+	return fmt.Sprintf("ENC[AES256_GCM,data:%s,iv:%s,tag:%s,type:str]",
+		base64.StdEncoding.EncodeToString(data),
+		base64.StdEncoding.EncodeToString(iv),
+		base64.StdEncoding.EncodeToString(tag)), nil
+}
@@ -0,0 +1,109 @@
+package sops
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"fmt"
+	"io/ioutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+var encryptedValue = regexp.MustCompile(`^ENC\[AES256_GCM,data:(.*),iv:(.*),tag:(.*),type:str\]$`)
+
+// decryptValue decrypts a value the way SOPS does
+func decryptValue(t *testing.T, key []byte, value, additionalData string) string {
+	m := encryptedValue.FindStringSubmatch(value)
+	if m == nil {
+		t.Fatalf("%q is not an encrypted value", value)
+	}
+	var parts [3][]byte
+	for i := range parts {
+		var err error
+		if parts[i], err = base64.StdEncoding.DecodeString(m[i+1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	aead, err := cipher.NewGCMWithNonceSize(block, len(parts[1]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext, err := aead.Open(nil, parts[1], append(parts[0], parts[2]...), []byte(additionalData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(plaintext)
+}
+
+func TestEncryptForAgeRecipient(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	recipients, err := AgeRecipients(identity.Recipient().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "billing", Name: "db"},
+		Type:       corev1.SecretTypeOpaque,
+		Data:       map[string][]byte{"password": []byte("secret"), "user": []byte("billing")},
+	}
+	out, err := Encrypt(secret, recipients, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc := &document{}
+	if err := json.Unmarshal(out, doc); err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Sops.Age) != 1 || doc.Sops.Age[0].Recipient != identity.Recipient().String() {
+		t.Fatalf("expected the data key to be encrypted for the age recipient, got %+v", doc.Sops.Age)
+	}
+	r, err := age.Decrypt(armor.NewReader(strings.NewReader(doc.Sops.Age[0].Enc)), identity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dataKey, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if password := decryptValue(t, dataKey, doc.Data["password"], "data:password:"); password != base64.StdEncoding.EncodeToString([]byte("secret")) {
+		t.Errorf("expected the base64 encoded password, got %q", password)
+	}
+
+	mac := sha512.New()
+	for _, v := range []string{"v1", "Secret", "db", "billing", "Opaque", "c2VjcmV0", "YmlsbGluZw=="} {
+		mac.Write([]byte(v))
+	}
+	if got := decryptValue(t, dataKey, doc.Sops.MAC, doc.Sops.LastModified); got != fmt.Sprintf("%X", mac.Sum(nil)) {
+		t.Errorf("expected the MAC to cover all values in order, got %s", got)
+	}
+	if !bytes.Contains(out, []byte(`"encrypted_regex": "^(data|stringData)$"`)) {
+		t.Errorf("expected only data to be encrypted, got\n%s", out)
+	}
+}
+
+func TestEncryptWithoutRecipientsFails(t *testing.T) {
+	if _, err := Encrypt(&corev1.Secret{}, nil, time.Now()); err == nil {
+		t.Error("expected encrypting without recipients to fail")
+	}
+}
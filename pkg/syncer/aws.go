@@ -0,0 +1,153 @@
+package syncer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/arn"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/secretsmanager/secretsmanageriface"
+	corev1 "k8s.io/api/core/v1"
+	"path"
+	"strings"
+	"unicode/utf8"
+)
+
+// AnnotationAWSSecret is the name, relative to the AWS name prefix of the secret's namespace, or the ARN of
+// the AWS Secrets Manager secret the secret is synced to
+const AnnotationAWSSecret = "secret-generator.v1.mittwald.de/aws-secret"
+
+// awsRecoveryWindowDays is the time tombstoned secrets can be restored in
+const awsRecoveryWindowDays = 30
+
+// awsBackend writes secrets into AWS Secrets Manager. Every change of the data creates a new version, so
+// that rotations are propagated to consumers reading the AWSCURRENT stage.
+type awsBackend struct {
+	client secretsmanageriface.SecretsManagerAPI
+	prefix string
+}
+
+// NewAWSClient returns a Secrets Manager client using the default credential chain, which picks up the web
+// identity token of IAM roles for service accounts (IRSA)
+func NewAWSClient(region string) (secretsmanageriface.SecretsManagerAPI, error) {
+	config := aws.NewConfig()
+	if region != "" {
+		config = config.WithRegion(region)
+	}
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config:            *config,
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return secretsmanager.New(sess), nil
+}
+
+// NewAWSBackend returns a backend syncing secrets annotated with an aws-secret to AWS Secrets Manager.
+// Secrets are named <prefix>/<namespace>/<name>, ARNs have to refer to secrets with such names.
+func NewAWSBackend(client secretsmanageriface.SecretsManagerAPI, prefix string) Backend {
+	return &awsBackend{client: client, prefix: prefix}
+}
+
+func (b *awsBackend) Name() string {
+	return "aws-secrets-manager"
+}
+
+func (b *awsBackend) Enabled(secret *corev1.Secret) bool {
+	return secret.Annotations[AnnotationAWSSecret] != ""
+}
+
+// secretID returns the name or ARN of the AWS secret, and whether it is an ARN
+func (b *awsBackend) secretID(secret *corev1.Secret) (string, bool, error) {
+	val := secret.Annotations[AnnotationAWSSecret]
+	namespacePrefix := path.Join(b.prefix, secret.Namespace) + "/"
+
+	if arn.IsARN(val) {
+		parsed, err := arn.Parse(val)
+		if err != nil {
+			return "", false, err
+		}
+		if parsed.Service != "secretsmanager" || !strings.HasPrefix(parsed.Resource, "secret:"+namespacePrefix) {
+			return "", false, fmt.Errorf("%s %s must refer to a secret named %s*", AnnotationAWSSecret, val, namespacePrefix)
+		}
+		return val, true, nil
+	}
+
+	name := strings.Trim(val, "/")
+	for _, segment := range strings.Split(name, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return "", false, fmt.Errorf("invalid %s annotation %q", AnnotationAWSSecret, val)
+		}
+	}
+	return namespacePrefix + name, false, nil
+}
+
+func (b *awsBackend) Sync(ctx context.Context, secret *corev1.Secret) error {
+	id, isARN, err := b.secretID(secret)
+	if err != nil {
+		return err
+	}
+
+	data := make(map[string]string, len(secret.Data))
+	for k, v := range secret.Data {
+		if !utf8.Valid(v) {
+			return fmt.Errorf("value of key %s is not valid UTF-8 and can't be written to AWS Secrets Manager", k)
+		}
+		data[k] = string(v)
+	}
+	value, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	_, err = b.client.PutSecretValueWithContext(ctx, &secretsmanager.PutSecretValueInput{
+		SecretId:     aws.String(id),
+		SecretString: aws.String(string(value)),
+	})
+	if !isNotFound(err) || isARN {
+		return err
+	}
+
+	// secrets referred to by name are created on their first sync
+	_, err = b.client.CreateSecretWithContext(ctx, &secretsmanager.CreateSecretInput{
+		Name:         aws.String(id),
+		Description:  aws.String(fmt.Sprintf("Generated by kubernetes-secret-generator for %s/%s", secret.Namespace, secret.Name)),
+		SecretString: aws.String(string(value)),
+		Tags: []*secretsmanager.Tag{
+			{Key: aws.String("kubernetes-namespace"), Value: aws.String(secret.Namespace)},
+			{Key: aws.String("kubernetes-name"), Value: aws.String(secret.Name)},
+		},
+	})
+	return err
+}
+
+// Delete deletes the AWS secret without a recovery window
+func (b *awsBackend) Delete(ctx context.Context, secret *corev1.Secret) error {
+	return b.delete(ctx, secret, &secretsmanager.DeleteSecretInput{ForceDeleteWithoutRecovery: aws.Bool(true)})
+}
+
+// Tombstone schedules the deletion of the AWS secret, which can be restored within the recovery window
+func (b *awsBackend) Tombstone(ctx context.Context, secret *corev1.Secret) error {
+	return b.delete(ctx, secret, &secretsmanager.DeleteSecretInput{RecoveryWindowInDays: aws.Int64(awsRecoveryWindowDays)})
+}
+
+func (b *awsBackend) delete(ctx context.Context, secret *corev1.Secret, input *secretsmanager.DeleteSecretInput) error {
+	id, _, err := b.secretID(secret)
+	if err != nil {
+		return err
+	}
+	input.SecretId = aws.String(id)
+	if _, err := b.client.DeleteSecretWithContext(ctx, input); err != nil && !isNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+func isNotFound(err error) bool {
+	aerr, ok := err.(awserr.Error)
+	return ok && aerr.Code() == secretsmanager.ErrCodeResourceNotFoundException
+}
@@ -0,0 +1,80 @@
+package syncer
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/secretsmanager/secretsmanageriface"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+)
+
+// fakeSecretsManager stores the current value of secrets by name
+type fakeSecretsManager struct {
+	secretsmanageriface.SecretsManagerAPI
+	values map[string]string
+}
+
+func (f *fakeSecretsManager) PutSecretValueWithContext(_ aws.Context, in *secretsmanager.PutSecretValueInput, _ ...request.Option) (*secretsmanager.PutSecretValueOutput, error) {
+	if _, ok := f.values[*in.SecretId]; !ok {
+		return nil, awserr.New(secretsmanager.ErrCodeResourceNotFoundException, "not found", nil)
+	}
+	f.values[*in.SecretId] = *in.SecretString
+	return &secretsmanager.PutSecretValueOutput{}, nil
+}
+
+func (f *fakeSecretsManager) CreateSecretWithContext(_ aws.Context, in *secretsmanager.CreateSecretInput, _ ...request.Option) (*secretsmanager.CreateSecretOutput, error) {
+	f.values[*in.Name] = *in.SecretString
+	return &secretsmanager.CreateSecretOutput{}, nil
+}
+
+func (f *fakeSecretsManager) DeleteSecretWithContext(_ aws.Context, in *secretsmanager.DeleteSecretInput, _ ...request.Option) (*secretsmanager.DeleteSecretOutput, error) {
+	if _, ok := f.values[*in.SecretId]; !ok {
+		return nil, awserr.New(secretsmanager.ErrCodeResourceNotFoundException, "not found", nil)
+	}
+	delete(f.values, *in.SecretId)
+	return &secretsmanager.DeleteSecretOutput{}, nil
+}
+
+func TestAWSBackendCreatesAndUpdatesSecret(t *testing.T) {
+	sm := &fakeSecretsManager{values: map[string]string{}}
+	b := NewAWSBackend(sm, "kubernetes")
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "billing",
+			Name:        "db",
+			Annotations: map[string]string{AnnotationAWSSecret: "db/credentials"},
+		},
+		Data: map[string][]byte{"password": []byte("first")},
+	}
+	require.NoError(t, b.Sync(context.TODO(), secret))
+	require.Equal(t, `{"password":"first"}`, sm.values["kubernetes/billing/db/credentials"])
+
+	secret.Data["password"] = []byte("rotated")
+	require.NoError(t, b.Sync(context.TODO(), secret))
+	require.Equal(t, `{"password":"rotated"}`, sm.values["kubernetes/billing/db/credentials"])
+
+	require.NoError(t, b.Delete(context.TODO(), secret))
+	require.NoError(t, b.Delete(context.TODO(), secret))
+	require.Empty(t, sm.values)
+}
+
+func TestAWSBackendRejectsARNsOfOtherNamespaces(t *testing.T) {
+	b := NewAWSBackend(&fakeSecretsManager{values: map[string]string{}}, "kubernetes")
+
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+		Namespace:   "billing",
+		Annotations: map[string]string{AnnotationAWSSecret: "arn:aws:secretsmanager:eu-central-1:123456789012:secret:kubernetes/payments/db-AbCdEf"},
+	}}
+	require.Error(t, b.Sync(context.TODO(), secret))
+
+	secret.Annotations[AnnotationAWSSecret] = "arn:aws:secretsmanager:eu-central-1:123456789012:secret:kubernetes/billing/db-AbCdEf"
+	_, isARN, err := b.(*awsBackend).secretID(secret)
+	require.NoError(t, err)
+	require.True(t, isARN)
+}
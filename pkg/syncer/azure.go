@@ -0,0 +1,244 @@
+package syncer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	corev1 "k8s.io/api/core/v1"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// AnnotationAzureSecret is the name, relative to the Azure name prefix of the secret's namespace, of the Key
+// Vault secret the secret is synced to
+const AnnotationAzureSecret = "secret-generator.v1.mittwald.de/azure-secret"
+
+const (
+	azureAPIVersion = "7.4"
+	// azureIMDSEndpoint is the instance metadata service issuing tokens of managed identities
+	azureIMDSEndpoint = "http://169.254.169.254/metadata/identity/oauth2/token"
+	azureResource     = "https://vault.azure.net"
+)
+
+var azureSecretName = regexp.MustCompile(`^[0-9a-zA-Z-]+$`)
+
+// azureBackend writes secrets into Azure Key Vault. Every change of the data creates a new version of the
+// Key Vault secret.
+type azureBackend struct {
+	client   *http.Client
+	vaultURL string
+	imds     string
+	clientID string
+	prefix   string
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// NewAzureBackend returns a backend syncing secrets annotated with an azure-secret to the Key Vault at
+// vaultURL, authenticating using the managed identity of the node or pod. clientID selects a user-assigned
+// identity, the system-assigned identity is used if it is empty. Secrets are named <prefix>-<namespace>-<name>.
+func NewAzureBackend(vaultURL, clientID, prefix string) Backend {
+	return &azureBackend{
+		client:   &http.Client{Timeout: 10 * time.Second},
+		vaultURL: strings.TrimSuffix(vaultURL, "/"),
+		imds:     azureIMDSEndpoint,
+		clientID: clientID,
+		prefix:   prefix,
+	}
+}
+
+func (b *azureBackend) Name() string {
+	return "azure-key-vault"
+}
+
+func (b *azureBackend) Enabled(secret *corev1.Secret) bool {
+	return secret.Annotations[AnnotationAzureSecret] != ""
+}
+
+// secretName returns the name of the Key Vault secret. Names are not unique across namespaces, as both may
+// contain dashes, so the owning namespace is recorded in a tag.
+func (b *azureBackend) secretName(secret *corev1.Secret) (string, error) {
+	val := secret.Annotations[AnnotationAzureSecret]
+	if !azureSecretName.MatchString(val) {
+		return "", fmt.Errorf("invalid %s annotation %q, may only contain letters, digits and -", AnnotationAzureSecret, val)
+	}
+	return b.prefix + "-" + secret.Namespace + "-" + val, nil
+}
+
+func (b *azureBackend) Sync(ctx context.Context, secret *corev1.Secret) error {
+	name, err := b.secretName(secret)
+	if err != nil {
+		return err
+	}
+
+	existing := &struct {
+		Tags map[string]string `json:"tags"`
+	}{}
+	err = b.request(ctx, http.MethodGet, "/secrets/"+name+"/", nil, existing)
+	if err != nil && !isAzureNotFound(err) {
+		return err
+	}
+	if err == nil && existing.Tags["kubernetes-namespace"] != secret.Namespace {
+		return fmt.Errorf("key vault secret %s belongs to namespace %q", name, existing.Tags["kubernetes-namespace"])
+	}
+
+	data := make(map[string]string, len(secret.Data))
+	for k, v := range secret.Data {
+		if !utf8.Valid(v) {
+			return fmt.Errorf("value of key %s is not valid UTF-8 and can't be written to Azure Key Vault", k)
+		}
+		data[k] = string(v)
+	}
+	value, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	return b.request(ctx, http.MethodPut, "/secrets/"+name, map[string]interface{}{
+		"value":       string(value),
+		"contentType": "application/json",
+		"tags": map[string]string{
+			"managed-by":           "kubernetes-secret-generator",
+			"kubernetes-namespace": secret.Namespace,
+			"kubernetes-name":      secret.Name,
+		},
+	}, nil)
+}
+
+// Delete deletes and purges the Key Vault secret. Purging fails while the deletion is in progress, in which
+// case deleting the secret is retried.
+func (b *azureBackend) Delete(ctx context.Context, secret *corev1.Secret) error {
+	if err := b.Tombstone(ctx, secret); err != nil {
+		return err
+	}
+	name, err := b.secretName(secret)
+	if err != nil {
+		return err
+	}
+	if err := b.request(ctx, http.MethodDelete, "/deletedsecrets/"+name, nil, nil); err != nil && !isAzureNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// Tombstone deletes the Key Vault secret, which can be recovered within the retention period of the vault
+// if soft-delete is enabled
+func (b *azureBackend) Tombstone(ctx context.Context, secret *corev1.Secret) error {
+	name, err := b.secretName(secret)
+	if err != nil {
+		return err
+	}
+	if err := b.request(ctx, http.MethodDelete, "/secrets/"+name, nil, nil); err != nil && !isAzureNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// azureError is returned for unsuccessful responses of Key Vault
+type azureError struct {
+	status int
+	msg    string
+}
+
+func (e *azureError) Error() string {
+	return fmt.Sprintf("azure key vault responded with status %d: %s", e.status, e.msg)
+}
+
+func isAzureNotFound(err error) bool {
+	aerr, ok := err.(*azureError)
+	return ok && aerr.status == http.StatusNotFound
+}
+
+func (b *azureBackend) request(ctx context.Context, method, p string, in, out interface{}) error {
+	token, err := b.accessToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	var body []byte
+	if in != nil {
+		if body, err = json.Marshal(in); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(method, b.vaultURL+p+"?api-version="+azureAPIVersion, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	res, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		msg, _ := ioutil.ReadAll(res.Body)
+		return &azureError{status: res.StatusCode, msg: strings.TrimSpace(string(msg))}
+	}
+	if out != nil {
+		return json.NewDecoder(res.Body).Decode(out)
+	}
+	return nil
+}
+
+// accessToken returns a token of the managed identity for Key Vault, requesting a new one from the
+// instance metadata service shortly before the last one expires
+func (b *azureBackend) accessToken(ctx context.Context) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.token != "" && time.Now().Before(b.expires) {
+		return b.token, nil
+	}
+
+	query := url.Values{"api-version": {"2018-02-01"}, "resource": {azureResource}}
+	if b.clientID != "" {
+		query.Set("client_id", b.clientID)
+	}
+	req, err := http.NewRequest(http.MethodGet, b.imds+"?"+query.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Metadata", "true")
+
+	res, err := b.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("could not get managed identity token: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(res.Body)
+		return "", fmt.Errorf("could not get managed identity token, status %d: %s", res.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	token := &struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   string `json:"expires_in"`
+	}{}
+	if err := json.NewDecoder(res.Body).Decode(token); err != nil {
+		return "", err
+	}
+	expiresIn, err := strconv.Atoi(token.ExpiresIn)
+	if err != nil {
+		return "", fmt.Errorf("invalid expiry of managed identity token: %w", err)
+	}
+
+	b.token = token.AccessToken
+	b.expires = time.Now().Add(time.Duration(expiresIn) * time.Second * 9 / 10)
+	return b.token, nil
+}
@@ -0,0 +1,94 @@
+package syncer
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// fakeKeyVault serves the managed identity token endpoint and stores the tags of secrets by name
+type fakeKeyVault struct {
+	tokens   int
+	tags     map[string]map[string]string
+	versions int
+}
+
+func (f *fakeKeyVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/token" {
+		f.tokens++
+		_, _ = w.Write([]byte(`{"access_token": "token", "expires_in": "3600"}`))
+		return
+	}
+	if r.Header.Get("Authorization") != "Bearer token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	name := r.URL.Path[len("/secrets/"):]
+	switch r.Method {
+	case http.MethodGet:
+		name = name[:len(name)-1]
+		tags, ok := f.tags[name]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"tags": tags})
+	case http.MethodPut:
+		in := &struct {
+			Tags map[string]string `json:"tags"`
+		}{}
+		_ = json.NewDecoder(r.Body).Decode(in)
+		f.tags[name] = in.Tags
+		f.versions++
+	}
+}
+
+func newTestAzureBackend(kv *fakeKeyVault) (*azureBackend, func()) {
+	server := httptest.NewServer(kv)
+	b := NewAzureBackend(server.URL, "", "kubernetes").(*azureBackend)
+	b.imds = server.URL + "/token"
+	return b, server.Close
+}
+
+func TestAzureBackendCreatesVersions(t *testing.T) {
+	kv := &fakeKeyVault{tags: map[string]map[string]string{}}
+	b, stop := newTestAzureBackend(kv)
+	defer stop()
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "billing",
+			Name:        "db",
+			Annotations: map[string]string{AnnotationAzureSecret: "db"},
+		},
+		Data: map[string][]byte{"password": []byte("secret")},
+	}
+	require.NoError(t, b.Sync(context.TODO(), secret))
+	require.NoError(t, b.Sync(context.TODO(), secret))
+
+	require.Equal(t, 1, kv.tokens)
+	require.Equal(t, 2, kv.versions)
+	require.Equal(t, "billing", kv.tags["kubernetes-billing-db"]["kubernetes-namespace"])
+}
+
+func TestAzureBackendRefusesSecretsOfOtherNamespaces(t *testing.T) {
+	// namespace a-b and name c share the name of namespace a and name b-c
+	kv := &fakeKeyVault{tags: map[string]map[string]string{
+		"kubernetes-a-b-c": {"kubernetes-namespace": "a-b"},
+	}}
+	b, stop := newTestAzureBackend(kv)
+	defer stop()
+
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+		Namespace:   "a",
+		Annotations: map[string]string{AnnotationAzureSecret: "b-c"},
+	}}
+	require.Error(t, b.Sync(context.TODO(), secret))
+	require.Equal(t, 0, kv.versions)
+}
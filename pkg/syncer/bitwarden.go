@@ -0,0 +1,211 @@
+package syncer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	corev1 "k8s.io/api/core/v1"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// AnnotationBitwardenItem is the name, relative to the Bitwarden name prefix of the secret's namespace, of
+// the item the secret is synced to
+const AnnotationBitwardenItem = "secret-generator.v1.mittwald.de/bitwarden-item"
+
+// bitwardenBackend writes secrets into login items of Bitwarden or Vaultwarden through the Vault Management
+// API of "bw serve", which is unauthenticated and has to listen on localhost, e.g. in a sidecar container.
+// Each key of the secret becomes a hidden custom field of the item.
+type bitwardenBackend struct {
+	client       *http.Client
+	url          string
+	organization string
+	collection   string
+	prefix       string
+}
+
+// NewBitwardenBackend returns a backend syncing secrets annotated with a bitwarden-item using the bw serve API
+// at url. Items are named <prefix>/<namespace>/<name> and shared with the organization collection, if given.
+func NewBitwardenBackend(url, organization, collection, prefix string) Backend {
+	return &bitwardenBackend{
+		client:       &http.Client{Timeout: 30 * time.Second},
+		url:          strings.TrimSuffix(url, "/"),
+		organization: organization,
+		collection:   collection,
+		prefix:       prefix,
+	}
+}
+
+func (b *bitwardenBackend) Name() string {
+	return "bitwarden"
+}
+
+func (b *bitwardenBackend) Enabled(secret *corev1.Secret) bool {
+	return secret.Annotations[AnnotationBitwardenItem] != ""
+}
+
+func (b *bitwardenBackend) itemName(secret *corev1.Secret) string {
+	return b.prefix + "/" + secret.Namespace + "/" + secret.Annotations[AnnotationBitwardenItem]
+}
+
+type bitwardenField struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	// Type 1 is a hidden field
+	Type int `json:"type"`
+}
+
+type bitwardenLogin struct {
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+type bitwardenItem struct {
+	ID             string   `json:"id,omitempty"`
+	OrganizationID string   `json:"organizationId,omitempty"`
+	CollectionIDs  []string `json:"collectionIds,omitempty"`
+	// Type 1 is a login
+	Type   int              `json:"type"`
+	Name   string           `json:"name"`
+	Notes  string           `json:"notes,omitempty"`
+	Login  bitwardenLogin   `json:"login"`
+	Fields []bitwardenField `json:"fields"`
+}
+
+func (b *bitwardenBackend) Sync(ctx context.Context, secret *corev1.Secret) error {
+	item, err := b.find(ctx, b.itemName(secret))
+	if err != nil {
+		return err
+	}
+	if item == nil {
+		item = &bitwardenItem{
+			Type:  1,
+			Name:  b.itemName(secret),
+			Notes: "Managed by kubernetes-secret-generator, changes are overwritten",
+		}
+		if b.organization != "" {
+			item.OrganizationID = b.organization
+		}
+		if b.collection != "" {
+			item.CollectionIDs = []string{b.collection}
+		}
+	}
+
+	keys := make([]string, 0, len(secret.Data))
+	for k := range secret.Data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	item.Login = bitwardenLogin{}
+	item.Fields = nil
+	for _, k := range keys {
+		v := secret.Data[k]
+		if !utf8.Valid(v) {
+			return fmt.Errorf("value of key %s is not valid UTF-8 and can't be written to Bitwarden", k)
+		}
+		switch k {
+		case "username":
+			item.Login.Username = string(v)
+		case "password":
+			item.Login.Password = string(v)
+		}
+		item.Fields = append(item.Fields, bitwardenField{Name: k, Value: string(v), Type: 1})
+	}
+
+	if item.ID == "" {
+		return b.request(ctx, http.MethodPost, "/object/item", item, nil)
+	}
+	return b.request(ctx, http.MethodPut, "/object/item/"+item.ID, item, nil)
+}
+
+// Delete moves the item to the trash, as bw serve can't delete items permanently
+func (b *bitwardenBackend) Delete(ctx context.Context, secret *corev1.Secret) error {
+	return b.Tombstone(ctx, secret)
+}
+
+// Tombstone moves the item to the trash, from which it can be restored until the trash is emptied
+func (b *bitwardenBackend) Tombstone(ctx context.Context, secret *corev1.Secret) error {
+	item, err := b.find(ctx, b.itemName(secret))
+	if err != nil || item == nil {
+		return err
+	}
+	return b.request(ctx, http.MethodDelete, "/object/item/"+item.ID, nil, nil)
+}
+
+// find returns the item with the name, or nil if there is none. The vault is synced with the server first,
+// so that changes made by others are not overwritten with stale items.
+func (b *bitwardenBackend) find(ctx context.Context, name string) (*bitwardenItem, error) {
+	if err := b.request(ctx, http.MethodPost, "/sync", nil, nil); err != nil {
+		return nil, err
+	}
+
+	list := &struct {
+		Data []bitwardenItem `json:"data"`
+	}{}
+	query := url.Values{"search": {name}}
+	if b.organization != "" {
+		query.Set("organizationId", b.organization)
+	}
+	if err := b.request(ctx, http.MethodGet, "/list/object/items?"+query.Encode(), nil, list); err != nil {
+		return nil, err
+	}
+
+	// search also matches items whose name merely contains the name
+	var found *bitwardenItem
+	for i := range list.Data {
+		if list.Data[i].Name != name {
+			continue
+		}
+		if found != nil {
+			return nil, fmt.Errorf("found several Bitwarden items named %q", name)
+		}
+		found = &list.Data[i]
+	}
+	return found, nil
+}
+
+// request calls the bw serve API, whose responses wrap the data with a success flag and error message
+func (b *bitwardenBackend) request(ctx context.Context, method, p string, in, out interface{}) error {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(method, b.url+p, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	envelope := &struct {
+		Success bool            `json:"success"`
+		Message string          `json:"message"`
+		Data    json.RawMessage `json:"data"`
+	}{}
+	if err := json.NewDecoder(res.Body).Decode(envelope); err != nil {
+		return fmt.Errorf("bw serve responded with status %d: %w", res.StatusCode, err)
+	}
+	if !envelope.Success {
+		return fmt.Errorf("bw serve responded with status %d: %s", res.StatusCode, envelope.Message)
+	}
+	if out != nil {
+		return json.Unmarshal(envelope.Data, out)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package syncer
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakeBitwarden serves the items of the vault like bw serve
+type fakeBitwarden struct {
+	items map[string]*bitwardenItem
+	syncs int
+}
+
+func (f *fakeBitwarden) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var data interface{}
+	switch {
+	case r.URL.Path == "/sync":
+		f.syncs++
+	case r.URL.Path == "/list/object/items":
+		items := []bitwardenItem{}
+		for _, item := range f.items {
+			if strings.Contains(item.Name, r.URL.Query().Get("search")) {
+				items = append(items, *item)
+			}
+		}
+		data = map[string]interface{}{"object": "list", "data": items}
+	case r.Method == http.MethodDelete:
+		delete(f.items, strings.TrimPrefix(r.URL.Path, "/object/item/"))
+	default:
+		item := &bitwardenItem{}
+		_ = json.NewDecoder(r.Body).Decode(item)
+		if item.ID == "" {
+			item.ID = item.Name
+		}
+		f.items[item.ID] = item
+		data = item
+	}
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "data": data})
+}
+
+func TestBitwardenBackendSyncsItem(t *testing.T) {
+	bw := &fakeBitwarden{items: map[string]*bitwardenItem{
+		// merely contains the name of the synced item
+		"other": {ID: "other", Name: "kubernetes/billing/db-old"},
+	}}
+	server := httptest.NewServer(bw)
+	defer server.Close()
+	b := NewBitwardenBackend(server.URL, "org", "collection", "kubernetes")
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "billing",
+			Name:        "db",
+			Annotations: map[string]string{AnnotationBitwardenItem: "db"},
+		},
+		Data: map[string][]byte{"password": []byte("secret")},
+	}
+	require.NoError(t, b.Sync(context.TODO(), secret))
+	secret.Data["password"] = []byte("rotated")
+	require.NoError(t, b.Sync(context.TODO(), secret))
+
+	require.Len(t, bw.items, 2)
+	item := bw.items["kubernetes/billing/db"]
+	require.Equal(t, "rotated", item.Login.Password)
+	require.Equal(t, []string{"collection"}, item.CollectionIDs)
+	require.Equal(t, 2, bw.syncs)
+
+	require.NoError(t, b.Tombstone(context.TODO(), secret))
+	require.Len(t, bw.items, 1)
+}
@@ -0,0 +1,199 @@
+package syncer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"golang.org/x/oauth2/google"
+	"io/ioutil"
+	corev1 "k8s.io/api/core/v1"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// AnnotationGCPSecret is the ID, relative to the GCP ID prefix of the secret's namespace, of the Google Secret
+// Manager secret the secret is synced to
+const AnnotationGCPSecret = "secret-generator.v1.mittwald.de/gcp-secret"
+
+// gcpEndpoint is the base URL of the Secret Manager API
+const gcpEndpoint = "https://secretmanager.googleapis.com/v1/"
+
+var gcpSecretID = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// gcpBackend writes secrets into Google Secret Manager. Every change of the data adds a new version, which
+// becomes the latest version read by consumers.
+type gcpBackend struct {
+	client   *http.Client
+	endpoint string
+	project  string
+	prefix   string
+}
+
+// NewGCPBackend returns a backend syncing secrets annotated with a gcp-secret to Google Secret Manager, using
+// the application default credentials, which are provided by the metadata server when using workload identity.
+// Secrets are named <prefix>_<namespace>_<id>, as namespaces can't contain underscores. If project is
+// empty, the project of the credentials is used.
+func NewGCPBackend(ctx context.Context, project, prefix string) (Backend, error) {
+	creds, err := google.FindDefaultCredentials(ctx, "https://www.googleapis.com/auth/cloud-platform")
+	if err != nil {
+		return nil, err
+	}
+	if project == "" {
+		project = creds.ProjectID
+	}
+	if project == "" {
+		return nil, fmt.Errorf("no GCP project configured and the credentials don't belong to one")
+	}
+
+	client, err := google.DefaultClient(ctx, "https://www.googleapis.com/auth/cloud-platform")
+	if err != nil {
+		return nil, err
+	}
+	return &gcpBackend{client: client, endpoint: gcpEndpoint, project: project, prefix: prefix}, nil
+}
+
+func (b *gcpBackend) Name() string {
+	return "gcp-secret-manager"
+}
+
+func (b *gcpBackend) Enabled(secret *corev1.Secret) bool {
+	return secret.Annotations[AnnotationGCPSecret] != ""
+}
+
+// resource returns the resource name of the GCP secret
+func (b *gcpBackend) resource(secret *corev1.Secret) (string, string, error) {
+	val := secret.Annotations[AnnotationGCPSecret]
+	if !gcpSecretID.MatchString(val) {
+		return "", "", fmt.Errorf("invalid %s annotation %q, may only contain letters, digits, - and _", AnnotationGCPSecret, val)
+	}
+	id := b.prefix + "_" + secret.Namespace + "_" + val
+	return "projects/" + b.project + "/secrets/" + id, id, nil
+}
+
+func (b *gcpBackend) Sync(ctx context.Context, secret *corev1.Secret) error {
+	resource, id, err := b.resource(secret)
+	if err != nil {
+		return err
+	}
+
+	data := make(map[string]string, len(secret.Data))
+	for k, v := range secret.Data {
+		if !utf8.Valid(v) {
+			return fmt.Errorf("value of key %s is not valid UTF-8 and can't be written to Google Secret Manager", k)
+		}
+		data[k] = string(v)
+	}
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	version := map[string]interface{}{"payload": map[string]interface{}{"data": payload}}
+
+	err = b.request(ctx, http.MethodPost, resource+":addVersion", version, nil)
+	if !isGCPNotFound(err) {
+		return err
+	}
+
+	// secrets are created on their first sync
+	create := map[string]interface{}{
+		"replication": map[string]interface{}{"automatic": map[string]interface{}{}},
+		"labels": map[string]string{
+			"managed-by":           "kubernetes-secret-generator",
+			"kubernetes-namespace": secret.Namespace,
+		},
+	}
+	if err := b.request(ctx, http.MethodPost, "projects/"+b.project+"/secrets?secretId="+url.QueryEscape(id), create, nil); err != nil {
+		return err
+	}
+	return b.request(ctx, http.MethodPost, resource+":addVersion", version, nil)
+}
+
+// Delete deletes the GCP secret with all its versions
+func (b *gcpBackend) Delete(ctx context.Context, secret *corev1.Secret) error {
+	resource, _, err := b.resource(secret)
+	if err != nil {
+		return err
+	}
+	if err := b.request(ctx, http.MethodDelete, resource, nil, nil); err != nil && !isGCPNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// Tombstone disables all enabled versions of the GCP secret, which can be enabled again
+func (b *gcpBackend) Tombstone(ctx context.Context, secret *corev1.Secret) error {
+	resource, _, err := b.resource(secret)
+	if err != nil {
+		return err
+	}
+
+	list := &struct {
+		Versions []struct {
+			Name string `json:"name"`
+		} `json:"versions"`
+	}{}
+	err = b.request(ctx, http.MethodGet, resource+"/versions?filter="+url.QueryEscape("state:ENABLED"), nil, list)
+	if isGCPNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, v := range list.Versions {
+		if err := b.request(ctx, http.MethodPost, v.Name+":disable", map[string]interface{}{}, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// gcpError is returned for unsuccessful responses of the Secret Manager API
+type gcpError struct {
+	status int
+	msg    string
+}
+
+func (e *gcpError) Error() string {
+	return fmt.Sprintf("google secret manager responded with status %d: %s", e.status, e.msg)
+}
+
+func isGCPNotFound(err error) bool {
+	gerr, ok := err.(*gcpError)
+	return ok && gerr.status == http.StatusNotFound
+}
+
+func (b *gcpBackend) request(ctx context.Context, method, p string, in, out interface{}) error {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(method, b.endpoint+p, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		msg, _ := ioutil.ReadAll(res.Body)
+		return &gcpError{status: res.StatusCode, msg: strings.TrimSpace(string(msg))}
+	}
+	if out != nil {
+		return json.NewDecoder(res.Body).Decode(out)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package syncer
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGCPBackendCreatesSecretAndAddsVersions(t *testing.T) {
+	var requests []string
+	var versions []string
+	created := false
+	gcp := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.RequestURI())
+		switch r.URL.Path {
+		case "/v1/projects/acme/secrets":
+			created = true
+		case "/v1/projects/acme/secrets/kubernetes_billing_db:addVersion":
+			if !created {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			version := &struct {
+				Payload struct {
+					Data string `json:"data"`
+				} `json:"payload"`
+			}{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(version))
+			data, err := base64.StdEncoding.DecodeString(version.Payload.Data)
+			require.NoError(t, err)
+			versions = append(versions, string(data))
+		}
+	}))
+	defer gcp.Close()
+
+	b := &gcpBackend{client: gcp.Client(), endpoint: gcp.URL + "/v1/", project: "acme", prefix: "kubernetes"}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "billing",
+			Annotations: map[string]string{AnnotationGCPSecret: "db"},
+		},
+		Data: map[string][]byte{"password": []byte("first")},
+	}
+	require.NoError(t, b.Sync(context.TODO(), secret))
+	secret.Data["password"] = []byte("rotated")
+	require.NoError(t, b.Sync(context.TODO(), secret))
+
+	require.Equal(t, []string{`{"password":"first"}`, `{"password":"rotated"}`}, versions)
+	require.Equal(t, []string{
+		"POST /v1/projects/acme/secrets/kubernetes_billing_db:addVersion",
+		"POST /v1/projects/acme/secrets?secretId=kubernetes_billing_db",
+		"POST /v1/projects/acme/secrets/kubernetes_billing_db:addVersion",
+		"POST /v1/projects/acme/secrets/kubernetes_billing_db:addVersion",
+	}, requests)
+}
+
+func TestGCPBackendRejectsInvalidIDs(t *testing.T) {
+	b := &gcpBackend{project: "acme", prefix: "kubernetes"}
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+		Namespace:   "billing",
+		Annotations: map[string]string{AnnotationGCPSecret: "../payments"},
+	}}
+	require.Error(t, b.Sync(context.TODO(), secret))
+}
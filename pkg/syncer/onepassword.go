@@ -0,0 +1,200 @@
+package syncer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	corev1 "k8s.io/api/core/v1"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// AnnotationOnePasswordItem is the title, relative to the 1Password title prefix of the secret's namespace,
+// of the item the secret is synced to
+const AnnotationOnePasswordItem = "secret-generator.v1.mittwald.de/onepassword-item"
+
+// onePasswordTombstoneTag marks items of deleted secrets
+const onePasswordTombstoneTag = "kubernetes-deleted"
+
+// onePasswordBackend writes secrets into items of a 1Password vault using a 1Password Connect server.
+// Each key of the secret becomes a concealed field of the item.
+type onePasswordBackend struct {
+	client *http.Client
+	host   string
+	token  string
+	vault  string
+	prefix string
+}
+
+// NewOnePasswordBackend returns a backend syncing secrets annotated with a onepassword-item to the vault with
+// the given ID, using the 1Password Connect server at host. Items are titled <prefix>/<namespace>/<name>.
+func NewOnePasswordBackend(host, token, vault, prefix string) Backend {
+	return &onePasswordBackend{
+		client: &http.Client{Timeout: 10 * time.Second},
+		host:   strings.TrimSuffix(host, "/"),
+		token:  token,
+		vault:  vault,
+		prefix: prefix,
+	}
+}
+
+func (b *onePasswordBackend) Name() string {
+	return "1password"
+}
+
+func (b *onePasswordBackend) Enabled(secret *corev1.Secret) bool {
+	return secret.Annotations[AnnotationOnePasswordItem] != ""
+}
+
+func (b *onePasswordBackend) title(secret *corev1.Secret) string {
+	return b.prefix + "/" + secret.Namespace + "/" + secret.Annotations[AnnotationOnePasswordItem]
+}
+
+type onePasswordField struct {
+	ID      string `json:"id,omitempty"`
+	Label   string `json:"label"`
+	Type    string `json:"type"`
+	Purpose string `json:"purpose,omitempty"`
+	Value   string `json:"value"`
+}
+
+type onePasswordItem struct {
+	ID       string             `json:"id,omitempty"`
+	Title    string             `json:"title"`
+	Category string             `json:"category"`
+	Vault    map[string]string  `json:"vault"`
+	Tags     []string           `json:"tags,omitempty"`
+	Fields   []onePasswordField `json:"fields"`
+}
+
+func (b *onePasswordBackend) Sync(ctx context.Context, secret *corev1.Secret) error {
+	item, err := b.find(ctx, b.title(secret))
+	if err != nil {
+		return err
+	}
+	if item == nil {
+		item = &onePasswordItem{
+			Title:    b.title(secret),
+			Category: "LOGIN",
+			Vault:    map[string]string{"id": b.vault},
+			Tags:     []string{"kubernetes-secret-generator"},
+		}
+	}
+
+	keys := make([]string, 0, len(secret.Data))
+	for k := range secret.Data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	item.Fields = nil
+	for _, k := range keys {
+		v := secret.Data[k]
+		if !utf8.Valid(v) {
+			return fmt.Errorf("value of key %s is not valid UTF-8 and can't be written to 1Password", k)
+		}
+		field := onePasswordField{ID: k, Label: k, Type: "CONCEALED", Value: string(v)}
+		switch k {
+		case "username":
+			field.Type, field.Purpose = "STRING", "USERNAME"
+		case "password":
+			field.Purpose = "PASSWORD"
+		}
+		item.Fields = append(item.Fields, field)
+	}
+	item.Tags = removeString(item.Tags, onePasswordTombstoneTag)
+
+	if item.ID == "" {
+		return b.request(ctx, http.MethodPost, "/v1/vaults/"+b.vault+"/items", item, nil)
+	}
+	return b.request(ctx, http.MethodPut, "/v1/vaults/"+b.vault+"/items/"+item.ID, item, nil)
+}
+
+func (b *onePasswordBackend) Delete(ctx context.Context, secret *corev1.Secret) error {
+	item, err := b.find(ctx, b.title(secret))
+	if err != nil || item == nil {
+		return err
+	}
+	return b.request(ctx, http.MethodDelete, "/v1/vaults/"+b.vault+"/items/"+item.ID, nil, nil)
+}
+
+// Tombstone keeps the item, tagging it with kubernetes-deleted
+func (b *onePasswordBackend) Tombstone(ctx context.Context, secret *corev1.Secret) error {
+	item, err := b.find(ctx, b.title(secret))
+	if err != nil || item == nil {
+		return err
+	}
+	item.Tags = append(removeString(item.Tags, onePasswordTombstoneTag), onePasswordTombstoneTag)
+	return b.request(ctx, http.MethodPut, "/v1/vaults/"+b.vault+"/items/"+item.ID, item, nil)
+}
+
+// find returns the item with the title, or nil if there is none
+func (b *onePasswordBackend) find(ctx context.Context, title string) (*onePasswordItem, error) {
+	var items []onePasswordItem
+	filter := url.Values{"filter": {fmt.Sprintf("title eq %q", title)}}
+	if err := b.request(ctx, http.MethodGet, "/v1/vaults/"+b.vault+"/items?"+filter.Encode(), nil, &items); err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, nil
+	}
+	if len(items) > 1 {
+		return nil, fmt.Errorf("found %d 1Password items titled %q", len(items), title)
+	}
+
+	// the list only contains the item overviews
+	item := &onePasswordItem{}
+	if err := b.request(ctx, http.MethodGet, "/v1/vaults/"+b.vault+"/items/"+items[0].ID, nil, item); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+func (b *onePasswordBackend) request(ctx context.Context, method, p string, in, out interface{}) error {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(method, b.host+p, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+b.token)
+
+	res, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 300 {
+		msg, _ := ioutil.ReadAll(res.Body)
+		return fmt.Errorf("1password connect responded with status %d: %s", res.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	if out != nil {
+		return json.NewDecoder(res.Body).Decode(out)
+	}
+	return nil
+}
+
+func removeString(list []string, s string) []string {
+	var out []string
+	for _, v := range list {
+		if v != s {
+			out = append(out, v)
+		}
+	}
+	return out
+}
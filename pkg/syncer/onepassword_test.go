@@ -0,0 +1,81 @@
+package syncer
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakeConnect stores the items of a single vault of a 1Password Connect server
+type fakeConnect struct {
+	items map[string]*onePasswordItem
+}
+
+func (f *fakeConnect) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, "/v1/vaults/vault/items")
+	id = strings.TrimPrefix(id, "/")
+	switch {
+	case r.Method == http.MethodGet && id == "":
+		items := []onePasswordItem{}
+		for _, item := range f.items {
+			if r.URL.Query().Get("filter") == `title eq "`+item.Title+`"` {
+				items = append(items, onePasswordItem{ID: item.ID, Title: item.Title})
+			}
+		}
+		_ = json.NewEncoder(w).Encode(items)
+	case r.Method == http.MethodGet:
+		_ = json.NewEncoder(w).Encode(f.items[id])
+	case r.Method == http.MethodPost || r.Method == http.MethodPut:
+		item := &onePasswordItem{}
+		_ = json.NewDecoder(r.Body).Decode(item)
+		if item.ID == "" {
+			item.ID = "item" + string(rune('0'+len(f.items)))
+		}
+		f.items[item.ID] = item
+	case r.Method == http.MethodDelete:
+		delete(f.items, id)
+	}
+}
+
+func TestOnePasswordBackendSyncsItem(t *testing.T) {
+	connect := &fakeConnect{items: map[string]*onePasswordItem{}}
+	server := httptest.NewServer(connect)
+	defer server.Close()
+	b := NewOnePasswordBackend(server.URL, "token", "vault", "kubernetes")
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "billing",
+			Name:        "db",
+			Annotations: map[string]string{AnnotationOnePasswordItem: "db"},
+		},
+		Data: map[string][]byte{"username": []byte("billing"), "password": []byte("secret")},
+	}
+	require.NoError(t, b.Sync(context.TODO(), secret))
+
+	secret.Data["password"] = []byte("rotated")
+	require.NoError(t, b.Sync(context.TODO(), secret))
+
+	require.Len(t, connect.items, 1)
+	item := connect.items["item0"]
+	require.Equal(t, "kubernetes/billing/db", item.Title)
+	require.Equal(t, "rotated", item.Fields[0].Value)
+	require.Equal(t, "PASSWORD", item.Fields[0].Purpose)
+
+	require.NoError(t, b.Tombstone(context.TODO(), secret))
+	require.Contains(t, connect.items["item0"].Tags, onePasswordTombstoneTag)
+
+	require.NoError(t, b.Delete(context.TODO(), secret))
+	require.Empty(t, connect.items)
+}
@@ -0,0 +1,101 @@
+// Package syncer mirrors generated secrets into external secret stores
+package syncer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	corev1 "k8s.io/api/core/v1"
+	"sort"
+	"sync"
+)
+
+// DeletionPolicy defines what happens to the external copy of a secret once the secret is deleted
+type DeletionPolicy string
+
+const (
+	// DeletionPolicyDelete removes the external copy
+	DeletionPolicyDelete DeletionPolicy = "Delete"
+	// DeletionPolicyTombstone marks the external copy as deleted, keeping it recoverable
+	DeletionPolicyTombstone DeletionPolicy = "Tombstone"
+	// DeletionPolicyRetain leaves the external copy untouched
+	DeletionPolicyRetain DeletionPolicy = "Retain"
+)
+
+// Backend is an external store generated secrets are synced to
+type Backend interface {
+	// Name identifies the backend in logs
+	Name() string
+	// Enabled reports whether the secret is configured to be synced to this backend
+	Enabled(secret *corev1.Secret) bool
+	// Sync writes the secret's data to the external store
+	Sync(ctx context.Context, secret *corev1.Secret) error
+	// Delete removes the external copy of the secret
+	Delete(ctx context.Context, secret *corev1.Secret) error
+	// Tombstone marks the external copy of the secret as deleted without removing it
+	Tombstone(ctx context.Context, secret *corev1.Secret) error
+}
+
+var (
+	mu       sync.RWMutex
+	backends []Backend
+)
+
+// Register adds a backend secrets can be synced to
+func Register(b Backend) {
+	mu.Lock()
+	defer mu.Unlock()
+	backends = append(backends, b)
+}
+
+// For returns all backends the given secret is configured to be synced to
+func For(secret *corev1.Secret) []Backend {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	var enabled []Backend
+	for _, b := range backends {
+		if b.Enabled(secret) {
+			enabled = append(enabled, b)
+		}
+	}
+	return enabled
+}
+
+// Finalize cleans up the external copies of the secret according to the given policy
+func Finalize(ctx context.Context, secret *corev1.Secret, policy DeletionPolicy) error {
+	for _, b := range For(secret) {
+		var err error
+		switch policy {
+		case DeletionPolicyRetain:
+			continue
+		case DeletionPolicyTombstone:
+			err = b.Tombstone(ctx, secret)
+		default:
+			err = b.Delete(ctx, secret)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Checksum returns a checksum of the given secret data, used to detect whether the
+// external copies are outdated
+func Checksum(data map[string][]byte) string {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	for _, k := range keys {
+		h.Write([]byte(k))
+		h.Write([]byte{0})
+		h.Write(data[k])
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,61 @@
+package syncer
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+)
+
+type recordingBackend struct {
+	calls []string
+}
+
+func (b *recordingBackend) Name() string { return "recording" }
+
+func (b *recordingBackend) Enabled(secret *corev1.Secret) bool {
+	return secret.Annotations["recording"] == "yes"
+}
+
+func (b *recordingBackend) Sync(_ context.Context, _ *corev1.Secret) error {
+	b.calls = append(b.calls, "sync")
+	return nil
+}
+
+func (b *recordingBackend) Delete(_ context.Context, _ *corev1.Secret) error {
+	b.calls = append(b.calls, "delete")
+	return nil
+}
+
+func (b *recordingBackend) Tombstone(_ context.Context, _ *corev1.Secret) error {
+	b.calls = append(b.calls, "tombstone")
+	return nil
+}
+
+func TestFinalizeHonorsPolicy(t *testing.T) {
+	b := &recordingBackend{}
+	Register(b)
+
+	enabled := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{"recording": "yes"}}}
+	disabled := &corev1.Secret{}
+
+	require.Len(t, For(enabled), 1)
+	require.Len(t, For(disabled), 0)
+
+	require.NoError(t, Finalize(context.TODO(), enabled, DeletionPolicyDelete))
+	require.NoError(t, Finalize(context.TODO(), enabled, DeletionPolicyTombstone))
+	require.NoError(t, Finalize(context.TODO(), enabled, DeletionPolicyRetain))
+	require.NoError(t, Finalize(context.TODO(), disabled, DeletionPolicyDelete))
+
+	require.Equal(t, []string{"delete", "tombstone"}, b.calls)
+}
+
+func TestChecksumIsStable(t *testing.T) {
+	a := map[string][]byte{"a": []byte("1"), "b": []byte("2")}
+	b := map[string][]byte{"b": []byte("2"), "a": []byte("1")}
+	c := map[string][]byte{"a": []byte("12")}
+
+	require.Equal(t, Checksum(a), Checksum(b))
+	require.NotEqual(t, Checksum(a), Checksum(c))
+}
@@ -0,0 +1,193 @@
+package syncer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	corev1 "k8s.io/api/core/v1"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// AnnotationVaultPath is the path, relative to the Vault prefix of the secret's namespace, of the KV secret
+// the secret is synced to
+const AnnotationVaultPath = "secret-generator.v1.mittwald.de/vault-path"
+
+// VaultOptions configure the Vault backend
+type VaultOptions struct {
+	Address string
+	// Mount is the mount path of the KV version 2 secrets engine
+	Mount string
+	// PathPrefix is prepended to the namespace and path of synced secrets, so that secrets can only be
+	// synced to paths below the prefix and the namespace
+	PathPrefix string
+	// Token is used to authenticate if set, otherwise the controller logs in using the Kubernetes auth method
+	Token string
+	// AuthMount is the mount path of the Kubernetes auth method
+	AuthMount string
+	// Role is the role of the Kubernetes auth method
+	Role string
+	// TokenFile contains the service account token used to log in
+	TokenFile string
+	Client    *http.Client
+}
+
+// vaultBackend writes secrets into a KV version 2 secrets engine of HashiCorp Vault
+type vaultBackend struct {
+	opts VaultOptions
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// NewVaultBackend returns a backend syncing secrets annotated with a vault-path to Vault
+func NewVaultBackend(opts VaultOptions) Backend {
+	if opts.Client == nil {
+		opts.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &vaultBackend{opts: opts}
+}
+
+func (b *vaultBackend) Name() string {
+	return "vault"
+}
+
+func (b *vaultBackend) Enabled(secret *corev1.Secret) bool {
+	return secret.Annotations[AnnotationVaultPath] != ""
+}
+
+// path returns the path of the secret within the KV mount
+func (b *vaultBackend) path(secret *corev1.Secret) (string, error) {
+	p := strings.Trim(secret.Annotations[AnnotationVaultPath], "/")
+	for _, segment := range strings.Split(p, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return "", fmt.Errorf("invalid %s annotation %q", AnnotationVaultPath, p)
+		}
+	}
+	return path.Join(b.opts.PathPrefix, secret.Namespace, p), nil
+}
+
+func (b *vaultBackend) Sync(ctx context.Context, secret *corev1.Secret) error {
+	p, err := b.path(secret)
+	if err != nil {
+		return err
+	}
+
+	data := make(map[string]string, len(secret.Data))
+	for k, v := range secret.Data {
+		// KV secrets are JSON, which can't hold arbitrary bytes
+		if !utf8.Valid(v) {
+			return fmt.Errorf("value of key %s is not valid UTF-8 and can't be written to Vault", k)
+		}
+		data[k] = string(v)
+	}
+	return b.request(ctx, http.MethodPost, path.Join(b.opts.Mount, "data", p), map[string]interface{}{"data": data}, nil)
+}
+
+// Delete removes all versions and the metadata of the KV secret
+func (b *vaultBackend) Delete(ctx context.Context, secret *corev1.Secret) error {
+	p, err := b.path(secret)
+	if err != nil {
+		return err
+	}
+	return b.request(ctx, http.MethodDelete, path.Join(b.opts.Mount, "metadata", p), nil, nil)
+}
+
+// Tombstone deletes the latest version of the KV secret, which can be undeleted
+func (b *vaultBackend) Tombstone(ctx context.Context, secret *corev1.Secret) error {
+	p, err := b.path(secret)
+	if err != nil {
+		return err
+	}
+	return b.request(ctx, http.MethodDelete, path.Join(b.opts.Mount, "data", p), nil, nil)
+}
+
+// request calls the Vault API, decoding the response into out if it is not nil. Deleting secrets that
+// don't exist succeeds.
+func (b *vaultBackend) request(ctx context.Context, method, p string, in, out interface{}) error {
+	token, err := b.authToken(ctx)
+	if err != nil {
+		return err
+	}
+	return b.do(ctx, method, p, token, in, out)
+}
+
+func (b *vaultBackend) do(ctx context.Context, method, p, token string, in, out interface{}) error {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(method, strings.TrimSuffix(b.opts.Address, "/")+"/v1/"+p, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+
+	res, err := b.opts.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if method == http.MethodDelete && res.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	if res.StatusCode >= 300 {
+		msg, _ := ioutil.ReadAll(res.Body)
+		return fmt.Errorf("vault responded to %s %s with status %d: %s", method, p, res.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	if out != nil {
+		return json.NewDecoder(res.Body).Decode(out)
+	}
+	return nil
+}
+
+// authToken returns the configured token, or logs in using the Kubernetes auth method if the token of
+// the last login is about to expire
+func (b *vaultBackend) authToken(ctx context.Context) (string, error) {
+	if b.opts.Token != "" {
+		return b.opts.Token, nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.token != "" && time.Now().Before(b.expires) {
+		return b.token, nil
+	}
+
+	jwt, err := ioutil.ReadFile(b.opts.TokenFile)
+	if err != nil {
+		return "", err
+	}
+
+	login := &struct {
+		Auth struct {
+			ClientToken   string `json:"client_token"`
+			LeaseDuration int    `json:"lease_duration"`
+		} `json:"auth"`
+	}{}
+	in := map[string]string{"role": b.opts.Role, "jwt": strings.TrimSpace(string(jwt))}
+	if err := b.do(ctx, http.MethodPost, path.Join("auth", b.opts.AuthMount, "login"), "", in, login); err != nil {
+		return "", fmt.Errorf("could not log in to vault: %w", err)
+	}
+
+	b.token = login.Auth.ClientToken
+	// renew by logging in again shortly before the token expires
+	b.expires = time.Now().Add(time.Duration(login.Auth.LeaseDuration) * time.Second * 9 / 10)
+	return b.token, nil
+}
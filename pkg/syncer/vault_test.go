@@ -0,0 +1,83 @@
+package syncer
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestVaultBackendWritesToNamespacedPath(t *testing.T) {
+	var logins int
+	var requests []string
+	written := map[string]interface{}{}
+	vault := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/auth/kubernetes/login" {
+			logins++
+			_, _ = w.Write([]byte(`{"auth": {"client_token": "s.token", "lease_duration": 3600}}`))
+			return
+		}
+		require.Equal(t, "s.token", r.Header.Get("X-Vault-Token"))
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		if r.Method == http.MethodPost {
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&written))
+		}
+		if r.Method == http.MethodDelete {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer vault.Close()
+
+	tokenFile, err := ioutil.TempFile("", "token")
+	require.NoError(t, err)
+	defer os.Remove(tokenFile.Name())
+	_, _ = tokenFile.WriteString("jwt")
+
+	b := NewVaultBackend(VaultOptions{
+		Address:    vault.URL,
+		Mount:      "secret",
+		PathPrefix: "kubernetes",
+		AuthMount:  "kubernetes",
+		Role:       "secret-generator",
+		TokenFile:  tokenFile.Name(),
+	})
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "billing",
+			Annotations: map[string]string{AnnotationVaultPath: "db/credentials"},
+		},
+		Data: map[string][]byte{"password": []byte("secret")},
+	}
+	require.True(t, b.Enabled(secret))
+	require.NoError(t, b.Sync(context.TODO(), secret))
+	require.NoError(t, b.Sync(context.TODO(), secret))
+	require.NoError(t, b.Tombstone(context.TODO(), secret))
+	require.NoError(t, b.Delete(context.TODO(), secret))
+
+	require.Equal(t, 1, logins)
+	require.Equal(t, map[string]interface{}{"data": map[string]interface{}{"password": "secret"}}, written)
+	require.Equal(t, []string{
+		"POST /v1/secret/data/kubernetes/billing/db/credentials",
+		"POST /v1/secret/data/kubernetes/billing/db/credentials",
+		"DELETE /v1/secret/data/kubernetes/billing/db/credentials",
+		"DELETE /v1/secret/metadata/kubernetes/billing/db/credentials",
+	}, requests)
+}
+
+func TestVaultBackendRejectsPathsOutsideOfNamespace(t *testing.T) {
+	b := NewVaultBackend(VaultOptions{Token: "s.token", Mount: "secret"})
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "billing",
+			Annotations: map[string]string{AnnotationVaultPath: "../payments/db"},
+		},
+	}
+	require.Error(t, b.Sync(context.TODO(), secret))
+}
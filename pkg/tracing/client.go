@@ -0,0 +1,125 @@
+package tracing
+
+import (
+	"context"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"reflect"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Client returns a client recording its calls as child spans of the span in ctx. The contexts passed to
+// its methods are replaced by ctx, so the controllers don't need to pass the reconcile context through.
+func Client(ctx context.Context, c client.Client) client.Client {
+	return &tracedClient{Client: c, ctx: ctx}
+}
+
+// Reader returns a reader recording its calls as child spans of the span in ctx, like Client
+func Reader(ctx context.Context, r client.Reader) client.Reader {
+	return &tracedReader{Reader: r, ctx: ctx}
+}
+
+// startCall starts the span of a call of the API for obj, named after the operation and the kind of obj
+func startCall(ctx context.Context, operation string, obj runtime.Object) (context.Context, trace.Span) {
+	kind := reflect.Indirect(reflect.ValueOf(obj)).Type().Name()
+	attrs := []attribute.KeyValue{attribute.String("k8s.object.kind", kind)}
+	if accessor, err := meta.Accessor(obj); err == nil {
+		attrs = append(attrs,
+			attribute.String("k8s.namespace.name", accessor.GetNamespace()),
+			attribute.String("k8s.object.name", accessor.GetName()))
+	}
+	return tracer().Start(ctx, operation+" "+kind, trace.WithAttributes(attrs...))
+}
+
+type tracedReader struct {
+	client.Reader
+	ctx context.Context
+}
+
+func (r *tracedReader) Get(_ context.Context, key client.ObjectKey, obj runtime.Object) error {
+	ctx, span := startCall(r.ctx, "get", obj)
+	span.SetAttributes(attribute.String("k8s.namespace.name", key.Namespace), attribute.String("k8s.object.name", key.Name))
+	err := r.Reader.Get(ctx, key, obj)
+	End(span, err)
+	return err
+}
+
+func (r *tracedReader) List(_ context.Context, list runtime.Object, opts ...client.ListOption) error {
+	ctx, span := startCall(r.ctx, "list", list)
+	err := r.Reader.List(ctx, list, opts...)
+	End(span, err)
+	return err
+}
+
+type tracedClient struct {
+	client.Client
+	ctx context.Context
+}
+
+func (c *tracedClient) Get(ctx context.Context, key client.ObjectKey, obj runtime.Object) error {
+	return (&tracedReader{Reader: c.Client, ctx: c.ctx}).Get(ctx, key, obj)
+}
+
+func (c *tracedClient) List(ctx context.Context, list runtime.Object, opts ...client.ListOption) error {
+	return (&tracedReader{Reader: c.Client, ctx: c.ctx}).List(ctx, list, opts...)
+}
+
+func (c *tracedClient) Create(_ context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+	ctx, span := startCall(c.ctx, "create", obj)
+	err := c.Client.Create(ctx, obj, opts...)
+	End(span, err)
+	return err
+}
+
+func (c *tracedClient) Delete(_ context.Context, obj runtime.Object, opts ...client.DeleteOption) error {
+	ctx, span := startCall(c.ctx, "delete", obj)
+	err := c.Client.Delete(ctx, obj, opts...)
+	End(span, err)
+	return err
+}
+
+func (c *tracedClient) Update(_ context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+	ctx, span := startCall(c.ctx, "update", obj)
+	err := c.Client.Update(ctx, obj, opts...)
+	End(span, err)
+	return err
+}
+
+func (c *tracedClient) Patch(_ context.Context, obj runtime.Object, patch client.Patch, opts ...client.PatchOption) error {
+	ctx, span := startCall(c.ctx, "patch", obj)
+	err := c.Client.Patch(ctx, obj, patch, opts...)
+	End(span, err)
+	return err
+}
+
+func (c *tracedClient) DeleteAllOf(_ context.Context, obj runtime.Object, opts ...client.DeleteAllOfOption) error {
+	ctx, span := startCall(c.ctx, "deletecollection", obj)
+	err := c.Client.DeleteAllOf(ctx, obj, opts...)
+	End(span, err)
+	return err
+}
+
+func (c *tracedClient) Status() client.StatusWriter {
+	return &tracedStatusWriter{StatusWriter: c.Client.Status(), ctx: c.ctx}
+}
+
+type tracedStatusWriter struct {
+	client.StatusWriter
+	ctx context.Context
+}
+
+func (w *tracedStatusWriter) Update(_ context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+	ctx, span := startCall(w.ctx, "update status", obj)
+	err := w.StatusWriter.Update(ctx, obj, opts...)
+	End(span, err)
+	return err
+}
+
+func (w *tracedStatusWriter) Patch(_ context.Context, obj runtime.Object, patch client.Patch, opts ...client.PatchOption) error {
+	ctx, span := startCall(w.ctx, "patch status", obj)
+	err := w.StatusWriter.Patch(ctx, obj, patch, opts...)
+	End(span, err)
+	return err
+}
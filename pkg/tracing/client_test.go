@@ -0,0 +1,41 @@
+package tracing
+
+import (
+	"context"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"testing"
+)
+
+func TestClientCallsAreChildSpansOfReconcile(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "test"}}
+	c := fake.NewFakeClient(secret)
+
+	request := reconcile.Request{}
+	request.Namespace, request.Name = "default", "test"
+	ctx, span := StartReconcile(context.Background(), "secret-controller", request)
+	if err := Client(ctx, c).Get(context.TODO(), client.ObjectKey{Namespace: "default", Name: "test"}, &corev1.Secret{}); err != nil {
+		t.Fatal(err)
+	}
+	End(span, nil)
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	if spans[0].Name() != "get Secret" {
+		t.Errorf("expected span of get call, got %q", spans[0].Name())
+	}
+	if spans[0].Parent().SpanID() != spans[1].SpanContext().SpanID() {
+		t.Errorf("expected get call to be a child of the reconcile span")
+	}
+}
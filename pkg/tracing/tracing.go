@@ -0,0 +1,60 @@
+// Package tracing exports spans of reconciles and the API calls made by them to an OpenTelemetry collector
+package tracing
+
+import (
+	"context"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const instrumentationName = "github.com/mittwald/kubernetes-secret-generator"
+
+// Setup exports spans to the OTLP gRPC endpoint. Until it is called, all spans are discarded.
+// The returned function flushes pending spans and stops the exporter.
+func Setup(ctx context.Context, endpoint string, insecure bool) (func(context.Context) error, error) {
+	opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(endpoint)}
+	if insecure {
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	}
+
+	exporter, err := otlptracegrpc.New(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL,
+			semconv.ServiceNameKey.String("kubernetes-secret-generator"))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+func tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// StartReconcile starts the span of a reconcile of the object identified by request as child of ctx
+func StartReconcile(ctx context.Context, controller string, request reconcile.Request) (context.Context, trace.Span) {
+	return tracer().Start(ctx, controller+" reconcile", trace.WithAttributes(
+		attribute.String("k8s.namespace.name", request.Namespace),
+		attribute.String("k8s.object.name", request.Name),
+	))
+}
+
+// End records err on span, if any, and ends it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,124 @@
+// Package wasmplugin runs generator plugins compiled to WebAssembly (wasm32-wasi) in a sandbox inside the
+// controller process. Modules can't access files, the network or the environment; they only read the request
+// from their standard input and write the response to their standard output.
+package wasmplugin
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/mittwald/kubernetes-secret-generator/pkg/entropy"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"io/ioutil"
+	"strings"
+	"sync"
+)
+
+// maxStderr limits the output of a failed module included in errors
+const maxStderr = 1024
+
+// Runtime runs the modules registered for custom types. Modules are compiled once, every run instantiates
+// a new module, so that runs don't share any state.
+type Runtime struct {
+	runtime wazero.Runtime
+	modules map[string]wazero.CompiledModule
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewRuntime compiles the modules at paths, by type. Instances of the modules may use at most maxMemoryMiB
+// of memory.
+func NewRuntime(ctx context.Context, paths map[string]string, maxMemoryMiB int) (*Runtime, error) {
+	// 64KiB pages
+	config := wazero.NewRuntimeConfig().
+		WithMemoryLimitPages(uint32(maxMemoryMiB * 16)).
+		WithCloseOnContextDone(true)
+	r := &Runtime{
+		runtime: wazero.NewRuntimeWithConfig(ctx, config),
+		modules: make(map[string]wazero.CompiledModule, len(paths)),
+	}
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, r.runtime); err != nil {
+		return nil, err
+	}
+
+	for sType, path := range paths {
+		code, err := ioutil.ReadFile(path)
+		if err != nil {
+			_ = r.runtime.Close(ctx)
+			return nil, err
+		}
+		module, err := r.runtime.CompileModule(ctx, code)
+		if err != nil {
+			_ = r.runtime.Close(ctx)
+			return nil, fmt.Errorf("could not compile generator plugin %s: %w", path, err)
+		}
+		r.modules[sType] = module
+	}
+	return r, nil
+}
+
+// Has reports whether a module is registered for the type
+func (r *Runtime) Has(sType string) bool {
+	_, ok := r.modules[sType]
+	return ok
+}
+
+// Run runs the module registered for the type with the given standard input until it exits or ctx is done,
+// returning its standard output
+func (r *Runtime) Run(ctx context.Context, sType string, stdin []byte) ([]byte, error) {
+	module, ok := r.modules[sType]
+	if !ok {
+		return nil, fmt.Errorf("no generator plugin registered for type %s", sType)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.closed {
+		return nil, fmt.Errorf("generator plugins have been stopped")
+	}
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	config := wazero.NewModuleConfig().
+		// anonymous, so that the module can be instantiated concurrently
+		WithName("").
+		WithStdin(bytes.NewReader(stdin)).
+		WithStdout(stdout).
+		WithStderr(stderr).
+		// the default source is deterministic
+		WithRandSource(entropy.Reader).
+		WithSysWalltime().
+		WithSysNanotime()
+
+	// the module runs its main function when it is instantiated, exiting with 0 closes it without error
+	instance, err := r.runtime.InstantiateModule(ctx, module, config)
+	if err != nil {
+		msg := stderr.String()
+		if len(msg) > maxStderr {
+			msg = msg[:maxStderr]
+		}
+		return nil, fmt.Errorf("%w: %s", err, strings.TrimSpace(msg))
+	}
+	_ = instance.Close(ctx)
+	return stdout.Bytes(), nil
+}
+
+// NeedLeaderElection makes the manager start the runtime on all replicas
+func (r *Runtime) NeedLeaderElection() bool {
+	return false
+}
+
+// Start releases the compiled modules once stop is closed
+func (r *Runtime) Start(stop <-chan struct{}) error {
+	<-stop
+	return r.Close(context.Background())
+}
+
+// Close releases the compiled modules, waiting for running modules to exit
+func (r *Runtime) Close(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = true
+	return r.runtime.Close(ctx)
+}
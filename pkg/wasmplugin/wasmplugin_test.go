@@ -0,0 +1,150 @@
+package wasmplugin
+
+import (
+	"context"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// uleb128 encodes n as unsigned LEB128, the variable-length integer encoding of WebAssembly
+func uleb128(n int) []byte {
+	var b []byte
+	for {
+		c := byte(n & 0x7f)
+		n >>= 7
+		if n != 0 {
+			c |= 0x80
+		}
+		b = append(b, c)
+		if n == 0 {
+			return b
+		}
+	}
+}
+
+func vector(items ...[]byte) []byte {
+	b := uleb128(len(items))
+	for _, item := range items {
+		b = append(b, item...)
+	}
+	return b
+}
+
+func name(s string) []byte {
+	return append(uleb128(len(s)), s...)
+}
+
+func section(id byte, content []byte) []byte {
+	return append(append([]byte{id}, uleb128(len(content))...), content...)
+}
+
+// module assembles a WASI command whose _start function runs code. Its memory starts with an iovec pointing
+// to output at offset 16, so that code can write output by calling fd_write with the iovec at offset 0.
+func module(output string, code []byte) []byte {
+	data := make([]byte, 16)
+	binary.LittleEndian.PutUint32(data[0:], 16)
+	binary.LittleEndian.PutUint32(data[4:], uint32(len(output)))
+	data = append(data, output...)
+
+	body := append([]byte{0x00}, code...) // no locals
+	b := []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}
+	b = append(b, section(1, vector(
+		[]byte{0x60, 0x04, 0x7f, 0x7f, 0x7f, 0x7f, 0x01, 0x7f}, // fd_write: (i32, i32, i32, i32) -> i32
+		[]byte{0x60, 0x00, 0x00},                               // _start: () -> ()
+	))...)
+	b = append(b, section(2, vector(
+		append(append(name("wasi_snapshot_preview1"), name("fd_write")...), 0x00, 0x00),
+	))...)
+	b = append(b, section(3, vector([]byte{0x01}))...)
+	b = append(b, section(5, vector([]byte{0x00, 0x01}))...)
+	b = append(b, section(7, vector(
+		append(name("memory"), 0x02, 0x00),
+		append(name("_start"), 0x00, 0x01),
+	))...)
+	b = append(b, section(10, vector(append(uleb128(len(body)), body...)))...)
+	b = append(b, section(11, vector(
+		append(append([]byte{0x00, 0x41, 0x00, 0x0b}, uleb128(len(data))...), data...),
+	))...)
+	return b
+}
+
+var (
+	// fd_write(1, 0, 1, 8)
+	writeOutput = []byte{0x41, 0x01, 0x41, 0x00, 0x41, 0x01, 0x41, 0x08, 0x10, 0x00, 0x1a, 0x0b}
+	// loop forever
+	spin = []byte{0x03, 0x40, 0x0c, 0x00, 0x0b, 0x0b}
+)
+
+func newTestRuntime(t *testing.T, modules map[string][]byte) (*Runtime, func()) {
+	dir, err := ioutil.TempDir("", "wasmplugin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths := make(map[string]string)
+	for sType, code := range modules {
+		paths[sType] = filepath.Join(dir, sType+".wasm")
+		if err := ioutil.WriteFile(paths[sType], code, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r, err := NewRuntime(context.Background(), paths, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r, func() {
+		_ = r.Close(context.Background())
+		_ = os.RemoveAll(dir)
+	}
+}
+
+func TestRunReturnsStandardOutput(t *testing.T) {
+	r, cleanup := newTestRuntime(t, map[string][]byte{"totp": module(`{"values": {}}`, writeOutput)})
+	defer cleanup()
+
+	if !r.Has("totp") || r.Has("x509") {
+		t.Error("expected only totp to be registered")
+	}
+
+	// instances don't share state, so running the module twice returns the same output
+	for i := 0; i < 2; i++ {
+		out, err := r.Run(context.Background(), "totp", []byte("{}"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != `{"values": {}}` {
+			t.Errorf("unexpected output %q", out)
+		}
+	}
+}
+
+func TestRunAbortsModulesAfterTimeout(t *testing.T) {
+	r, cleanup := newTestRuntime(t, map[string][]byte{"totp": module("", spin)})
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := r.Run(ctx, "totp", nil); err == nil {
+		t.Error("expected module running forever to be aborted")
+	}
+}
+
+func TestNewRuntimeRejectsInvalidModules(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wasmplugin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "totp.wasm")
+	if err := ioutil.WriteFile(path, []byte("#!/bin/sh"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewRuntime(context.Background(), map[string]string{"totp": path}, 1); err == nil {
+		t.Error("expected invalid module to be rejected")
+	}
+}